@@ -0,0 +1,55 @@
+package admin
+
+import (
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// defaultPeekLimit 为查看元素时默认返回的最大数量。
+const defaultPeekLimit = 100
+
+// Config 定义管理接口配置。
+type Config struct {
+	mutable   bool
+	registry  *wkq.CodecRegistry
+	peekLimit int
+}
+
+// NewConfig 返回带默认值的管理接口配置，默认为只读模式。
+func NewConfig() *Config {
+	return &Config{
+		peekLimit: defaultPeekLimit,
+	}
+}
+
+// WithMutations 开启清空队列、取消定时元素、确认与重放死信等变更操作，未开启时这些请求返回 403。
+func (c *Config) WithMutations() *Config {
+	c.mutable = true
+
+	return c
+}
+
+// WithRegistry 设置元素 JSON 编码使用的类型注册表，未设置时使用 workqueue.RegisterPayloadType 维护的默认注册表。
+func (c *Config) WithRegistry(registry *wkq.CodecRegistry) *Config {
+	c.registry = registry
+
+	return c
+}
+
+// WithPeekLimit 设置查看元素时默认返回的最大数量，请求可通过 limit 参数调整。
+func (c *Config) WithPeekLimit(limit int) *Config {
+	c.peekLimit = limit
+
+	return c
+}
+
+func isConfigEffective(c *Config) *Config {
+	if c != nil {
+		if c.peekLimit <= 0 {
+			c.peekLimit = defaultPeekLimit
+		}
+	} else {
+		c = NewConfig()
+	}
+
+	return c
+}
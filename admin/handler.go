@@ -0,0 +1,594 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// ErrQueueNotFound 表示注册表中不存在该名称的队列。
+var ErrQueueNotFound = errors.New("queue not found")
+
+// ErrUnsupportedOperation 表示队列类型不支持该操作。
+var ErrUnsupportedOperation = errors.New("unsupported operation")
+
+// ErrReadOnly 表示管理接口处于只读模式。
+var ErrReadOnly = errors.New("admin handler is read-only")
+
+// ErrDeadLetterNotFound 表示死信队列中不存在该编号的死信。
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// ErrInvalidRequest 表示请求参数或请求体不合法。
+var ErrInvalidRequest = errors.New("invalid request")
+
+// 队列类型名称。
+const (
+	kindQueue        = "queue"
+	kindDelaying     = "delaying"
+	kindPriority     = "priority"
+	kindRateLimiting = "rate_limiting"
+	kindRetry        = "retry"
+	kindLeased       = "leased"
+	kindBounded      = "bounded"
+	kindTimer        = "timer"
+	kindFair         = "fair"
+	kindDeadLetter   = "dead_letter"
+)
+
+type queueInfo struct {
+	Name     string          `json:"name"`
+	Kind     string          `json:"kind"`
+	Len      int             `json:"len"`
+	InFlight int             `json:"in_flight"`
+	Capacity int             `json:"capacity,omitempty"`
+	Closed   bool            `json:"closed"`
+	Stats    *wkq.QueueStats `json:"stats,omitempty"`
+}
+
+type queuesResponse struct {
+	Queues []queueInfo `json:"queues"`
+}
+
+// item 为查看时的单个元素，Priority 仅出现在优先队列中，At 仅出现在延迟与定时元素中。
+type item struct {
+	Value    json.RawMessage `json:"value"`
+	Priority *int64          `json:"priority,omitempty"`
+	At       *time.Time      `json:"at,omitempty"`
+}
+
+// itemsResponse 中 Ready 为就绪元素，Scheduled 为尚未到期的延迟或定时元素。
+type itemsResponse struct {
+	Ready     []item `json:"ready"`
+	Scheduled []item `json:"scheduled,omitempty"`
+	Truncated bool   `json:"truncated"`
+}
+
+type purgeResponse struct {
+	Purged    int `json:"purged"`
+	Cancelled int `json:"cancelled,omitempty"`
+}
+
+type valueRequest struct {
+	Value json.RawMessage `json:"value"`
+}
+
+type cancelResponse struct {
+	Cancelled bool `json:"cancelled"`
+}
+
+type lease struct {
+	LeaseID  string          `json:"lease_id"`
+	Value    json.RawMessage `json:"value"`
+	Deadline time.Time       `json:"deadline"`
+}
+
+type leasesResponse struct {
+	Leases    []lease `json:"leases"`
+	Truncated bool    `json:"truncated"`
+}
+
+type deadLetter struct {
+	ID          string            `json:"id"`
+	Payload     json.RawMessage   `json:"payload"`
+	SourceQueue string            `json:"source_queue,omitempty"`
+	Attempts    int               `json:"attempts"`
+	LastError   string            `json:"last_error,omitempty"`
+	FailedAt    time.Time         `json:"failed_at"`
+	Meta        map[string]string `json:"meta,omitempty"`
+}
+
+type deadLettersResponse struct {
+	Letters   []deadLetter `json:"letters"`
+	Truncated bool         `json:"truncated"`
+}
+
+type requeueRequest struct {
+	Target string `json:"target"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type handlerImpl struct {
+	registry *Registry
+	config   *Config
+	codec    wkq.Codec
+}
+
+// NewHandler 返回管理 registry 中队列的 http.Handler，挂载到子路径时需配合 http.StripPrefix 使用。
+//
+//	GET  /queues                             列出全部队列
+//	GET  /queues/{name}                      查看队列概况与指标
+//	GET  /queues/{name}/items?limit=N        查看就绪元素以及延迟、定时元素
+//	POST /queues/{name}/purge                清空就绪元素，定时队列同时取消全部定时元素
+//	POST /queues/{name}/timers/cancel        取消定时队列中的元素，请求体为 {"value": ...}
+//	GET  /queues/{name}/leases?limit=N       查看租约队列中尚未确认的租约
+//	GET  /queues/{name}/dead?limit=N         查看死信，可用 source 与 since 参数按来源队列和失败时间过滤
+//	POST /queues/{name}/dead/{id}/ack        确认死信
+//	POST /queues/{name}/dead/{id}/requeue    将死信重放到 {"target": name} 指定的队列
+//
+// 元素以 workqueue.NewJSONCodec 编码展示。POST 请求仅在配置 WithMutations 后可用。
+func NewHandler(registry *Registry, config *Config) http.Handler {
+	if registry == nil {
+		registry = NewRegistry()
+	}
+	config = isConfigEffective(config)
+
+	return &handlerImpl{
+		registry: registry,
+		config:   config,
+		codec:    wkq.NewJSONCodec(config.registry),
+	}
+}
+
+func (h *handlerImpl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 队列名称与死信编号可能包含转义字符，需按未解码的路径切分。
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for i := range parts {
+		part, err := url.PathUnescape(parts[i])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		parts[i] = part
+	}
+	if len(parts) == 0 || parts[0] != "queues" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(parts) == 1 {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, h.list())
+		return
+	}
+
+	queue, ok := h.registry.lookup(parts[1])
+	if !ok {
+		writeError(w, ErrQueueNotFound)
+		return
+	}
+
+	var (
+		resp interface{}
+		err  error
+	)
+	switch op := strings.Join(parts[2:], "/"); {
+	case op == "":
+		if allowMethod(w, r, http.MethodGet) {
+			info := describe(parts[1], queue)
+			stats := queue.Stats()
+			info.Stats = &stats
+			resp = info
+		}
+	case op == "items":
+		if allowMethod(w, r, http.MethodGet) {
+			resp, err = h.items(queue, r)
+		}
+	case op == "leases":
+		if allowMethod(w, r, http.MethodGet) {
+			resp, err = h.leases(queue, r)
+		}
+	case op == "dead":
+		if allowMethod(w, r, http.MethodGet) {
+			resp, err = h.deadLetters(queue, r)
+		}
+	case op == "purge":
+		if h.allowMutation(w, r) {
+			resp, err = h.purge(queue)
+		}
+	case op == "timers/cancel":
+		if h.allowMutation(w, r) {
+			resp, err = h.cancelTimer(queue, r)
+		}
+	case len(parts) == 5 && parts[2] == "dead" && parts[4] == "ack":
+		if h.allowMutation(w, r) {
+			resp, err = h.ackDead(queue, parts[3])
+		}
+	case len(parts) == 5 && parts[2] == "dead" && parts[4] == "requeue":
+		if h.allowMutation(w, r) {
+			resp, err = h.requeueDead(queue, parts[3], r)
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case err != nil:
+		writeError(w, err)
+	case resp != nil:
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// allowMethod 校验请求方法，不匹配时写出 405 并返回 false。
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeJSON(w, http.StatusMethodNotAllowed, &errorResponse{Error: http.StatusText(http.StatusMethodNotAllowed)})
+	return false
+}
+
+// allowMutation 校验变更请求的方法与只读模式，不允许时写出错误并返回 false。
+func (h *handlerImpl) allowMutation(w http.ResponseWriter, r *http.Request) bool {
+	if !allowMethod(w, r, http.MethodPost) {
+		return false
+	}
+	if !h.config.mutable {
+		writeError(w, ErrReadOnly)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrQueueNotFound), errors.Is(err, ErrDeadLetterNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrReadOnly):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrUnsupportedOperation), errors.Is(err, ErrInvalidRequest),
+		errors.Is(err, wkq.ErrInvalidTargetQueue), errors.Is(err, wkq.ErrUnknownCodecType):
+		status = http.StatusBadRequest
+	case errors.Is(err, wkq.ErrQueueIsClosed), errors.Is(err, wkq.ErrQueueIsDraining),
+		errors.Is(err, wkq.ErrElementAlreadyExist), errors.Is(err, wkq.ErrQueueIsFull):
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, &errorResponse{Error: err.Error()})
+}
+
+func (h *handlerImpl) list() *queuesResponse {
+	entries := h.registry.list()
+	resp := &queuesResponse{Queues: make([]queueInfo, 0, len(entries))}
+	for _, e := range entries {
+		resp.Queues = append(resp.Queues, describe(e.name, e.queue))
+	}
+	return resp
+}
+
+func describe(name string, queue wkq.Queue) queueInfo {
+	info := queueInfo{
+		Name:     name,
+		Kind:     kindOf(queue),
+		Len:      queue.Len(),
+		InFlight: queue.Stats().InFlight,
+		Closed:   queue.IsClosed(),
+	}
+	if bounded, ok := queue.(wkq.BoundedBlockingQueue); ok {
+		info.Capacity = bounded.Cap()
+	}
+	return info
+}
+
+// kindOf 按能力从具体到一般识别队列类型。
+func kindOf(queue wkq.Queue) string {
+	switch queue.(type) {
+	case wkq.DeadLetterQueue:
+		return kindDeadLetter
+	case wkq.LeasedQueue:
+		return kindLeased
+	case wkq.TimerQueue:
+		return kindTimer
+	case wkq.RetryQueue:
+		return kindRetry
+	case wkq.RateLimitingQueue:
+		return kindRateLimiting
+	case wkq.DelayingQueue:
+		return kindDelaying
+	case wkq.PriorityQueue:
+		return kindPriority
+	case wkq.FairQueue:
+		return kindFair
+	case wkq.BoundedBlockingQueue:
+		return kindBounded
+	default:
+		return kindQueue
+	}
+}
+
+// limitOf 读取 limit 参数，缺省时使用配置的默认值。
+func (h *handlerImpl) limitOf(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return h.config.peekLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("%w: limit %q", ErrInvalidRequest, raw)
+	}
+	return limit, nil
+}
+
+// sinceOf 读取 since 参数，可以是 RFC 3339 时间，也可以是相对当前时间的时长，如 1h。
+func sinceOf(r *http.Request) (time.Time, error) {
+	raw := r.URL.Query().Get("since")
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d >= 0 {
+		return time.Now().Add(-d), nil
+	}
+	since, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: since %q", ErrInvalidRequest, raw)
+	}
+	return since, nil
+}
+
+func (h *handlerImpl) items(queue wkq.Queue, r *http.Request) (interface{}, error) {
+	limit, err := h.limitOf(r)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &itemsResponse{Ready: []item{}}
+
+	// collect 编码一个元素，达到上限时标记截断并停止遍历。
+	collect := func(items *[]item, value interface{}, priority *int64, at *time.Time) bool {
+		if len(*items) >= limit {
+			resp.Truncated = true
+			return false
+		}
+		var data []byte
+		if data, err = h.codec.Encode(value); err != nil {
+			return false
+		}
+		*items = append(*items, item{Value: data, Priority: priority, At: at})
+		return true
+	}
+	scheduled := func(value interface{}, at int64) bool {
+		t := time.UnixMilli(at)
+		return collect(&resp.Scheduled, value, nil, &t)
+	}
+
+	switch q := queue.(type) {
+	case wkq.PriorityQueue:
+		// 优先队列的就绪元素保存在堆中，按出队顺序遍历并附带优先级。
+		q.HeapRange(func(value interface{}, priority int64) bool {
+			return collect(&resp.Ready, value, &priority, nil)
+		})
+	case wkq.TimerQueue:
+		q.Range(func(value interface{}) bool { return collect(&resp.Ready, value, nil, nil) })
+		if err == nil {
+			q.HeapRange(scheduled)
+		}
+	case wkq.DelayingQueue:
+		q.Range(func(value interface{}) bool { return collect(&resp.Ready, value, nil, nil) })
+		if err == nil {
+			q.HeapRange(scheduled)
+		}
+	default:
+		q.Range(func(value interface{}) bool { return collect(&resp.Ready, value, nil, nil) })
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// purge 丢弃全部就绪元素，定时队列同时取消全部一次性定时元素，周期调度不受影响。延迟队列中尚未到期的元素无法取消，不受影响。
+// 就绪元素经 workqueue.Purge 直接移除，不触发消费端回调与死信确认。
+func (h *handlerImpl) purge(queue wkq.Queue) (interface{}, error) {
+	purged, err := wkq.Purge(queue)
+	if errors.Is(err, wkq.ErrUnsupportedQueue) {
+		return nil, ErrUnsupportedOperation
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp := &purgeResponse{Purged: purged}
+
+	if timer, ok := queue.(wkq.TimerQueue); ok {
+		var values []interface{}
+		timer.HeapRange(func(value interface{}, _ int64) bool {
+			values = append(values, value)
+			return true
+		})
+		for _, value := range values {
+			if timer.Cancel(value) {
+				resp.Cancelled++
+			}
+		}
+	}
+	return resp, nil
+}
+
+// cancelTimer 按编码结果找到定时元素后取消，避免 JSON 解码改变数值类型导致无法匹配。
+func (h *handlerImpl) cancelTimer(queue wkq.Queue, r *http.Request) (interface{}, error) {
+	timer, ok := queue.(wkq.TimerQueue)
+	if !ok {
+		return nil, ErrUnsupportedOperation
+	}
+
+	var req valueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Value) == 0 {
+		return nil, fmt.Errorf("%w: value is required", ErrInvalidRequest)
+	}
+	value, err := h.codec.Decode(req.Value)
+	if err != nil {
+		return nil, err
+	}
+	key, err := h.codec.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var target interface{}
+	timer.HeapRange(func(value interface{}, _ int64) bool {
+		if data, err := h.codec.Encode(value); err == nil && string(data) == string(key) {
+			target = value
+			return false
+		}
+		return true
+	})
+	if target == nil {
+		return &cancelResponse{}, nil
+	}
+	return &cancelResponse{Cancelled: timer.Cancel(target)}, nil
+}
+
+func (h *handlerImpl) leases(queue wkq.Queue, r *http.Request) (interface{}, error) {
+	leased, ok := queue.(wkq.LeasedQueue)
+	if !ok {
+		return nil, ErrUnsupportedOperation
+	}
+	limit, err := h.limitOf(r)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &leasesResponse{Leases: []lease{}}
+	leased.RangeLeases(func(leaseID string, value interface{}, deadline time.Time) bool {
+		if len(resp.Leases) >= limit {
+			resp.Truncated = true
+			return false
+		}
+		var data []byte
+		if data, err = h.codec.Encode(value); err != nil {
+			return false
+		}
+		resp.Leases = append(resp.Leases, lease{LeaseID: leaseID, Value: data, Deadline: deadline})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (h *handlerImpl) deadLetters(queue wkq.Queue, r *http.Request) (interface{}, error) {
+	dead, ok := queue.(wkq.DeadLetterQueue)
+	if !ok {
+		return nil, ErrUnsupportedOperation
+	}
+	limit, err := h.limitOf(r)
+	if err != nil {
+		return nil, err
+	}
+	since, err := sinceOf(r)
+	if err != nil {
+		return nil, err
+	}
+	source := r.URL.Query().Get("source")
+
+	resp := &deadLettersResponse{Letters: []deadLetter{}}
+	dead.RangeDead(func(letter *wkq.DeadLetter) bool {
+		if (source != "" && letter.SourceQueue != source) || letter.FailedAt.Before(since) {
+			return true
+		}
+		if len(resp.Letters) >= limit {
+			resp.Truncated = true
+			return false
+		}
+		var data []byte
+		if data, err = h.codec.Encode(letter.Payload); err != nil {
+			return false
+		}
+		resp.Letters = append(resp.Letters, deadLetter{
+			ID:          letter.ID,
+			Payload:     data,
+			SourceQueue: letter.SourceQueue,
+			Attempts:    letter.Attempts,
+			LastError:   letter.LastError,
+			FailedAt:    letter.FailedAt,
+			Meta:        letter.Meta,
+		})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// findDead 在死信队列中查找编号为 id 的死信。
+func findDead(queue wkq.Queue, id string) (wkq.DeadLetterQueue, *wkq.DeadLetter, error) {
+	dead, ok := queue.(wkq.DeadLetterQueue)
+	if !ok {
+		return nil, nil, ErrUnsupportedOperation
+	}
+
+	var found *wkq.DeadLetter
+	dead.RangeDead(func(letter *wkq.DeadLetter) bool {
+		if letter.ID == id {
+			found = letter
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return nil, nil, ErrDeadLetterNotFound
+	}
+	return dead, found, nil
+}
+
+func (h *handlerImpl) ackDead(queue wkq.Queue, id string) (interface{}, error) {
+	dead, letter, err := findDead(queue, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := dead.AckDead(letter); err != nil {
+		return nil, err
+	}
+	return struct{}{}, nil
+}
+
+func (h *handlerImpl) requeueDead(queue wkq.Queue, id string, r *http.Request) (interface{}, error) {
+	var req requeueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Target == "" {
+		return nil, fmt.Errorf("%w: target is required", ErrInvalidRequest)
+	}
+	target, ok := h.registry.lookup(req.Target)
+	if !ok {
+		return nil, wkq.ErrInvalidTargetQueue
+	}
+
+	dead, letter, err := findDead(queue, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := dead.RequeueDead(letter, target); err != nil {
+		return nil, err
+	}
+	return struct{}{}, nil
+}
@@ -0,0 +1,239 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func request(t *testing.T, handler http.Handler, method, path, body string, out interface{}) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if out != nil {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+	return rec.Code
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := NewRegistry()
+	q := wkq.NewQueue(nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, registry.Register("orders", q))
+	assert.ErrorIs(t, registry.Register("orders", q), ErrQueueAlreadyRegistered)
+	assert.ErrorIs(t, registry.Register("", q), ErrInvalidQueueName)
+	assert.ErrorIs(t, registry.Register("nil", nil), wkq.ErrQueueIsNil)
+
+	registry.Unregister("orders")
+	assert.NoError(t, registry.Register("orders", q))
+}
+
+func TestHandler_ListAndItems(t *testing.T) {
+	registry := NewRegistry()
+
+	q := wkq.NewQueue(nil)
+	defer q.Shutdown()
+	pq := wkq.NewPriorityQueue(nil)
+	defer pq.Shutdown()
+	dq := wkq.NewDelayingQueue(nil)
+	defer dq.Shutdown()
+	bq := wkq.NewBoundedBlockingQueue(wkq.NewBoundedBlockingQueueConfig().WithCapacity(8))
+	defer bq.Shutdown()
+
+	assert.NoError(t, registry.Register("orders", q))
+	assert.NoError(t, registry.Register("priority", pq))
+	assert.NoError(t, registry.Register("delayed", dq))
+	assert.NoError(t, registry.Register("bounded", bq))
+	handler := NewHandler(registry, nil)
+
+	assert.NoError(t, q.Put("test1"))
+	assert.NoError(t, q.Put("test2"))
+	assert.NoError(t, q.Put("test3"))
+	_, err := q.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, pq.PutWithPriority("low", 10))
+	assert.NoError(t, pq.PutWithPriority("high", 1))
+	assert.NoError(t, dq.Put("now"))
+	assert.NoError(t, dq.PutWithDelay("later", 60000))
+
+	var list queuesResponse
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodGet, "/queues", "", &list))
+	assert.Equal(t, []queueInfo{
+		{Name: "bounded", Kind: kindBounded, Capacity: 8},
+		{Name: "delayed", Kind: kindDelaying, Len: 2},
+		{Name: "orders", Kind: kindQueue, Len: 2, InFlight: 1},
+		{Name: "priority", Kind: kindPriority, Len: 2},
+	}, list.Queues)
+
+	var info queueInfo
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodGet, "/queues/orders", "", &info))
+	assert.Equal(t, uint64(3), info.Stats.Adds)
+
+	var items itemsResponse
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodGet, "/queues/orders/items?limit=1", "", &items))
+	assert.Len(t, items.Ready, 1)
+	assert.JSONEq(t, `{"value":"test2"}`, string(items.Ready[0].Value))
+	assert.True(t, items.Truncated)
+
+	items = itemsResponse{}
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodGet, "/queues/priority/items", "", &items))
+	assert.Len(t, items.Ready, 2)
+	assert.JSONEq(t, `{"value":"high"}`, string(items.Ready[0].Value))
+	assert.Equal(t, int64(1), *items.Ready[0].Priority)
+
+	items = itemsResponse{}
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodGet, "/queues/delayed/items", "", &items))
+	assert.Len(t, items.Ready, 1)
+	assert.Len(t, items.Scheduled, 1)
+	assert.JSONEq(t, `{"value":"later"}`, string(items.Scheduled[0].Value))
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *items.Scheduled[0].At, 5*time.Second)
+
+	assert.Equal(t, http.StatusBadRequest, request(t, handler, http.MethodGet, "/queues/orders/items?limit=x", "", nil))
+	assert.Equal(t, http.StatusNotFound, request(t, handler, http.MethodGet, "/queues/missing", "", nil))
+	assert.Equal(t, http.StatusBadRequest, request(t, handler, http.MethodGet, "/queues/orders/leases", "", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, request(t, handler, http.MethodPost, "/queues", "", nil))
+}
+
+func TestHandler_ReadOnly(t *testing.T) {
+	registry := NewRegistry()
+	q := wkq.NewQueue(nil)
+	defer q.Shutdown()
+	assert.NoError(t, registry.Register("orders", q))
+	assert.NoError(t, q.Put("test1"))
+
+	var resp errorResponse
+	assert.Equal(t, http.StatusForbidden, request(t, NewHandler(registry, nil), http.MethodPost, "/queues/orders/purge", "", &resp))
+	assert.Equal(t, ErrReadOnly.Error(), resp.Error)
+	assert.Equal(t, 1, q.Len())
+
+	var purged purgeResponse
+	handler := NewHandler(registry, NewConfig().WithMutations())
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodPost, "/queues/orders/purge", "", &purged))
+	assert.Equal(t, 1, purged.Purged)
+	assert.Equal(t, 0, q.Len())
+	assert.Equal(t, 0, q.Stats().InFlight)
+}
+
+func TestHandler_Timers(t *testing.T) {
+	registry := NewRegistry()
+	tq := wkq.NewTimerQueue(nil)
+	defer tq.Shutdown()
+	assert.NoError(t, registry.Register("timers", tq))
+	handler := NewHandler(registry, NewConfig().WithMutations())
+
+	at := time.Now().Add(time.Hour)
+	assert.NoError(t, tq.PutAt(1, at))
+	assert.NoError(t, tq.PutAt(2, at))
+	assert.NoError(t, tq.PutAt(3, at))
+	assert.NoError(t, tq.Put(4))
+
+	// JSON 中的数字解码为 float64，仍能按编码结果匹配到 int 元素。
+	var cancelled cancelResponse
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodPost, "/queues/timers/timers/cancel", `{"value":{"value":2}}`, &cancelled))
+	assert.True(t, cancelled.Cancelled)
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodPost, "/queues/timers/timers/cancel", `{"value":{"value":2}}`, &cancelled))
+	assert.False(t, cancelled.Cancelled)
+	assert.Equal(t, http.StatusBadRequest, request(t, handler, http.MethodPost, "/queues/timers/timers/cancel", `{}`, nil))
+
+	var purged purgeResponse
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodPost, "/queues/timers/purge", "", &purged))
+	assert.Equal(t, purgeResponse{Purged: 1, Cancelled: 2}, purged)
+	assert.Equal(t, 0, tq.Len())
+}
+
+func TestHandler_Leases(t *testing.T) {
+	registry := NewRegistry()
+	q := wkq.NewLeasedQueue(nil)
+	defer q.Shutdown()
+	assert.NoError(t, registry.Register("jobs", q))
+	handler := NewHandler(registry, nil)
+
+	assert.NoError(t, q.Put("test1"))
+	assert.NoError(t, q.Put("test2"))
+	_, first, err := q.GetWithLease(time.Minute)
+	assert.NoError(t, err)
+	_, second, err := q.GetWithLease(time.Second)
+	assert.NoError(t, err)
+
+	var resp leasesResponse
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodGet, "/queues/jobs/leases", "", &resp))
+	assert.Len(t, resp.Leases, 2)
+	assert.Equal(t, second, resp.Leases[0].LeaseID)
+	assert.JSONEq(t, `{"value":"test2"}`, string(resp.Leases[0].Value))
+	assert.Equal(t, first, resp.Leases[1].LeaseID)
+	assert.True(t, resp.Leases[0].Deadline.Before(resp.Leases[1].Deadline))
+}
+
+func TestHandler_DeadLetters(t *testing.T) {
+	registry := NewRegistry()
+	dlq := wkq.NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+	q := wkq.NewQueue(nil)
+	defer q.Shutdown()
+	assert.NoError(t, registry.Register("dead", dlq))
+	assert.NoError(t, registry.Register("orders", q))
+	handler := NewHandler(registry, NewConfig().WithMutations())
+
+	assert.NoError(t, dlq.PutDead(&wkq.DeadLetter{Payload: "bad1", LastError: "boom", Attempts: 3}))
+	assert.NoError(t, dlq.PutDead(&wkq.DeadLetter{Payload: "bad2"}))
+	assert.NoError(t, dlq.PutDead(&wkq.DeadLetter{Payload: "bad3"}))
+
+	var resp deadLettersResponse
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodGet, "/queues/dead/dead", "", &resp))
+	assert.Len(t, resp.Letters, 3)
+	assert.JSONEq(t, `{"value":"bad1"}`, string(resp.Letters[0].Payload))
+	assert.Equal(t, "boom", resp.Letters[0].LastError)
+	assert.Equal(t, 3, resp.Letters[0].Attempts)
+
+	// 确认与重放会把死信从队列中移除。
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodPost, "/queues/dead/dead/"+resp.Letters[0].ID+"/ack", "", nil))
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodPost, "/queues/dead/dead/"+resp.Letters[2].ID+"/requeue", `{"target":"orders"}`, nil))
+	assert.Equal(t, http.StatusNotFound, request(t, handler, http.MethodPost, "/queues/dead/dead/"+resp.Letters[0].ID+"/ack", "", nil))
+	assert.Equal(t, http.StatusBadRequest, request(t, handler, http.MethodPost, "/queues/dead/dead/"+resp.Letters[1].ID+"/requeue", `{"target":"missing"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, request(t, handler, http.MethodPost, "/queues/orders/dead/1/ack", "", nil))
+
+	assert.Equal(t, []interface{}{"bad3"}, q.Values())
+	assert.Equal(t, 1, dlq.Len())
+	letter, err := dlq.GetDead()
+	assert.NoError(t, err)
+	assert.Equal(t, "bad2", letter.Payload)
+	assert.NoError(t, dlq.AckDead(letter))
+	assert.Equal(t, 0, dlq.Stats().InFlight)
+}
+
+func TestHandler_DeadLetterFilters(t *testing.T) {
+	registry := NewRegistry()
+	dlq := wkq.NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+	assert.NoError(t, registry.Register("dead", dlq))
+	handler := NewHandler(registry, nil)
+
+	now := time.Now()
+	assert.NoError(t, dlq.PutDead(&wkq.DeadLetter{Payload: "old", SourceQueue: "orders", FailedAt: now.Add(-2 * time.Hour)}))
+	assert.NoError(t, dlq.PutDead(&wkq.DeadLetter{Payload: "new", SourceQueue: "orders", FailedAt: now}))
+	assert.NoError(t, dlq.PutDead(&wkq.DeadLetter{Payload: "other", SourceQueue: "billing", FailedAt: now}))
+
+	var resp deadLettersResponse
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodGet, "/queues/dead/dead?source=orders&since=1h", "", &resp))
+	assert.Len(t, resp.Letters, 1)
+	assert.JSONEq(t, `{"value":"new"}`, string(resp.Letters[0].Payload))
+
+	resp = deadLettersResponse{}
+	since := url.QueryEscape(now.Add(-3 * time.Hour).Format(time.RFC3339))
+	assert.Equal(t, http.StatusOK, request(t, handler, http.MethodGet, "/queues/dead/dead?source=orders&since="+since, "", &resp))
+	assert.Len(t, resp.Letters, 2)
+
+	assert.Equal(t, http.StatusBadRequest, request(t, handler, http.MethodGet, "/queues/dead/dead?since=yesterday", "", nil))
+}
@@ -0,0 +1,79 @@
+// Package admin 提供查看与运维已注册队列的 HTTP 管理接口，仅依赖标准库。
+package admin
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// ErrInvalidQueueName 表示注册名称为空。
+var ErrInvalidQueueName = errors.New("invalid queue name")
+
+// ErrQueueAlreadyRegistered 表示同名队列已注册。
+var ErrQueueAlreadyRegistered = errors.New("queue already registered")
+
+// Registry 维护可被管理接口访问的队列集合，可并发使用。
+type Registry struct {
+	lock   sync.RWMutex
+	queues map[string]wkq.Queue
+}
+
+// NewRegistry 创建空的注册表。
+func NewRegistry() *Registry {
+	return &Registry{queues: make(map[string]wkq.Queue)}
+}
+
+// Register 以 name 注册队列。
+func (r *Registry) Register(name string, queue wkq.Queue) error {
+	if name == "" {
+		return ErrInvalidQueueName
+	}
+	if queue == nil {
+		return wkq.ErrQueueIsNil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.queues[name]; ok {
+		return ErrQueueAlreadyRegistered
+	}
+	r.queues[name] = queue
+	return nil
+}
+
+// Unregister 移除已注册的队列，队列关闭后应及时移除。
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()
+	delete(r.queues, name)
+	r.lock.Unlock()
+}
+
+func (r *Registry) lookup(name string) (wkq.Queue, bool) {
+	r.lock.RLock()
+	queue, ok := r.queues[name]
+	r.lock.RUnlock()
+	return queue, ok
+}
+
+// entry 为注册表中的一个队列。
+type entry struct {
+	name  string
+	queue wkq.Queue
+}
+
+// list 按名称排序返回全部队列，保证输出稳定。
+func (r *Registry) list() []entry {
+	r.lock.RLock()
+	entries := make([]entry, 0, len(r.queues))
+	for name, queue := range r.queues {
+		entries = append(entries, entry{name: name, queue: queue})
+	}
+	r.lock.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries
+}
@@ -4,17 +4,22 @@ go 1.19
 
 require (
 	github.com/shengyanli1982/workqueue/v2 v2.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.4
 	github.com/vearne/mem-align v0.0.0-20211210060219-77c37a66875c
 )
 
 require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fatih/color v1.17.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/olekukonko/tablewriter v0.0.5 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/time v0.5.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace github.com/shengyanli1982/workqueue/v2 => ../
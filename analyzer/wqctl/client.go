@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 以下类型与 admin 包的响应结构一一对应。
+
+type queueInfo struct {
+	Name     string      `json:"name"`
+	Kind     string      `json:"kind"`
+	Len      int         `json:"len"`
+	InFlight int         `json:"in_flight"`
+	Capacity int         `json:"capacity,omitempty"`
+	Closed   bool        `json:"closed"`
+	Stats    *queueStats `json:"stats,omitempty"`
+}
+
+type histogram struct {
+	Count uint64  `json:"Count"`
+	Sum   float64 `json:"Sum"`
+}
+
+// mean 返回观测值的平均时长。
+func (h histogram) mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return time.Duration(h.Sum / float64(h.Count) * float64(time.Second))
+}
+
+type queueStats struct {
+	Depth            int           `json:"Depth"`
+	InFlight         int           `json:"InFlight"`
+	Adds             uint64        `json:"Adds"`
+	Retries          uint64        `json:"Retries"`
+	LeaseExpirations uint64        `json:"LeaseExpirations"`
+	Latency          histogram     `json:"Latency"`
+	WorkDuration     histogram     `json:"WorkDuration"`
+	UnfinishedWork   time.Duration `json:"UnfinishedWork"`
+	LongestRunning   time.Duration `json:"LongestRunning"`
+}
+
+type queuesResponse struct {
+	Queues []queueInfo `json:"queues"`
+}
+
+type item struct {
+	Value    json.RawMessage `json:"value"`
+	Priority *int64          `json:"priority,omitempty"`
+	At       *time.Time      `json:"at,omitempty"`
+}
+
+type itemsResponse struct {
+	Ready     []item `json:"ready"`
+	Scheduled []item `json:"scheduled,omitempty"`
+	Truncated bool   `json:"truncated"`
+}
+
+type purgeResponse struct {
+	Purged    int `json:"purged"`
+	Cancelled int `json:"cancelled,omitempty"`
+}
+
+type deadLetter struct {
+	ID          string            `json:"id"`
+	Payload     json.RawMessage   `json:"payload"`
+	SourceQueue string            `json:"source_queue,omitempty"`
+	Attempts    int               `json:"attempts"`
+	LastError   string            `json:"last_error,omitempty"`
+	FailedAt    time.Time         `json:"failed_at"`
+	Meta        map[string]string `json:"meta,omitempty"`
+}
+
+type deadLettersResponse struct {
+	Letters   []deadLetter `json:"letters"`
+	Truncated bool         `json:"truncated"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// deadFilter 为死信列表的过滤条件，零值表示不过滤。
+type deadFilter struct {
+	source string
+	since  time.Duration
+	limit  int
+}
+
+// client 调用运行中服务挂载的 admin 接口。
+type client struct {
+	baseURL string
+	http    *http.Client
+}
+
+func newClient(addr string, timeout time.Duration) *client {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &client{
+		baseURL: strings.TrimRight(addr, "/"),
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *client) list(ctx context.Context) (*queuesResponse, error) {
+	resp := &queuesResponse{}
+	return resp, c.do(ctx, http.MethodGet, "/queues", nil, nil, resp)
+}
+
+func (c *client) describe(ctx context.Context, name string) (*queueInfo, error) {
+	resp := &queueInfo{}
+	return resp, c.do(ctx, http.MethodGet, queuePath(name, ""), nil, nil, resp)
+}
+
+func (c *client) items(ctx context.Context, name string, limit int) (*itemsResponse, error) {
+	resp := &itemsResponse{}
+	return resp, c.do(ctx, http.MethodGet, queuePath(name, "items"), limitQuery(limit), nil, resp)
+}
+
+func (c *client) purge(ctx context.Context, name string) (*purgeResponse, error) {
+	resp := &purgeResponse{}
+	return resp, c.do(ctx, http.MethodPost, queuePath(name, "purge"), nil, nil, resp)
+}
+
+func (c *client) deadLetters(ctx context.Context, name string, filter deadFilter) (*deadLettersResponse, error) {
+	query := limitQuery(filter.limit)
+	if filter.source != "" {
+		query.Set("source", filter.source)
+	}
+	if filter.since > 0 {
+		query.Set("since", filter.since.String())
+	}
+	resp := &deadLettersResponse{}
+	return resp, c.do(ctx, http.MethodGet, queuePath(name, "dead"), query, nil, resp)
+}
+
+func (c *client) requeueDead(ctx context.Context, name, id, target string) error {
+	body := struct {
+		Target string `json:"target"`
+	}{Target: target}
+	return c.do(ctx, http.MethodPost, queuePath(name, "dead/"+url.PathEscape(id)+"/requeue"), nil, body, nil)
+}
+
+func queuePath(name, op string) string {
+	path := "/queues/" + url.PathEscape(name)
+	if op != "" {
+		path += "/" + op
+	}
+	return path
+}
+
+func limitQuery(limit int) url.Values {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query
+}
+
+func (c *client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("%s %s: %s (%d)", method, path, e.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// redriveBatch 为重放死信时每次拉取的死信数量。
+const redriveBatch = 100
+
+const usage = `wqctl operates queues through the admin endpoint of a running service.
+
+Usage:
+  wqctl [global flags] <command> [flags] [args]
+
+Commands:
+  ls                                   list registered queues
+  peek [-limit N] <queue>              show ready and scheduled items
+  purge -yes <queue>                   drop every ready item (and cancel timers)
+  stats [-watch] [-interval D] [queue...]
+                                       show queue metrics, optionally refreshing
+  dlq list [-source Q] [-since D] [-limit N] <queue>
+                                       list dead letters
+  dlq redrive [-source Q] [-since D] [-id ID] [-target Q] [-dry-run] <queue>
+                                       move dead letters back to a queue, by default
+                                       the queue each letter came from
+
+Global flags:
+`
+
+// errUsage 表示命令行参数不合法，进程以状态码 2 退出。
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	client *client
+	output string
+	out    io.Writer
+	errOut io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := execute(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// execute 解析全局参数并执行命令，返回进程退出码：成功为 0，执行失败为 1，参数不合法为 2。
+func execute(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	global := flag.NewFlagSet("wqctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	addr := global.String("addr", envOr("WQCTL_ADDR", "http://127.0.0.1:8080"), "admin endpoint address, defaults to $WQCTL_ADDR")
+	output := global.String("o", "table", "output format: table|json")
+	timeout := global.Duration("timeout", 10*time.Second, "request timeout")
+	global.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		global.PrintDefaults()
+	}
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "unknown output format: %s\n", *output)
+		return 2
+	}
+
+	cmd := &command{client: newClient(*addr, *timeout), output: *output, out: stdout, errOut: stderr}
+	err := cmd.run(ctx, global.Args())
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "%v\n\n", err)
+		global.Usage()
+		return 2
+	default:
+		fmt.Fprintf(stderr, "wqctl: %v\n", err)
+		return 1
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (c *command) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	name, args := args[0], args[1:]
+	switch name {
+	case "ls":
+		return c.ls(ctx, args)
+	case "peek":
+		return c.peek(ctx, args)
+	case "purge":
+		return c.purge(ctx, args)
+	case "stats":
+		return c.stats(ctx, args)
+	case "dlq":
+		if len(args) == 0 {
+			return fmt.Errorf("%w: dlq requires list or redrive", errUsage)
+		}
+		switch args[0] {
+		case "list":
+			return c.dlqList(ctx, args[1:])
+		case "redrive":
+			return c.dlqRedrive(ctx, args[1:])
+		}
+		return fmt.Errorf("%w: unknown dlq command %q", errUsage, args[0])
+	}
+	return fmt.Errorf("%w: unknown command %q", errUsage, name)
+}
+
+// parseArgs 解析子命令参数，允许标志出现在位置参数之后，如 peek orders -limit 5。
+// 不合法的标志按 errUsage 返回，由 execute 统一输出，-h 时只打印子命令的参数说明。
+func (c *command) parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(c.errOut, "Usage of %s:\n", fs.Name())
+			fs.SetOutput(c.errOut)
+			fs.PrintDefaults()
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// queueArg 返回唯一的队列名称参数。
+func queueArg(fs *flag.FlagSet, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%w: %s requires exactly one queue name", errUsage, fs.Name())
+	}
+	return args[0], nil
+}
+
+func (c *command) ls(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("ls", flag.ContinueOnError)
+	if args, err := c.parseArgs(fs, args); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return fmt.Errorf("%w: ls takes no arguments", errUsage)
+	}
+
+	resp, err := c.client.list(ctx)
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return printJSON(c.out, resp)
+	}
+	return printQueues(c.out, resp.Queues)
+}
+
+func (c *command) peek(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("peek", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "maximum number of items, defaults to the server limit")
+	args, err := c.parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	name, err := queueArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.items(ctx, name, *limit)
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return printJSON(c.out, resp)
+	}
+	return printItems(c.out, resp)
+}
+
+func (c *command) purge(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "confirm that every ready item will be dropped")
+	args, err := c.parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	name, err := queueArg(fs, args)
+	if err != nil {
+		return err
+	}
+	if !*yes {
+		return fmt.Errorf("%w: purge drops every ready item of %q, pass -yes to confirm", errUsage, name)
+	}
+
+	resp, err := c.client.purge(ctx, name)
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return printJSON(c.out, resp)
+	}
+	fmt.Fprintf(c.out, "purged %d items, cancelled %d timers from %s\n", resp.Purged, resp.Cancelled, name)
+	return nil
+}
+
+func (c *command) stats(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	watch := fs.Bool("watch", false, "refresh until interrupted")
+	interval := fs.Duration("interval", 2*time.Second, "refresh interval with -watch")
+	names, err := c.parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("%w: interval must be positive", errUsage)
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		infos, err := c.collectStats(ctx, names)
+		if err != nil {
+			return err
+		}
+		if c.output == "json" {
+			err = printJSON(c.out, infos)
+		} else {
+			if *watch {
+				fmt.Fprintf(c.out, "%s\n", time.Now().Format(time.RFC3339))
+			}
+			err = printStats(c.out, infos)
+		}
+		if err != nil || !*watch {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if c.output == "table" {
+				fmt.Fprintln(c.out)
+			}
+		}
+	}
+}
+
+// collectStats 查询给定队列的指标，未指定队列时查询全部已注册队列。
+func (c *command) collectStats(ctx context.Context, names []string) ([]queueInfo, error) {
+	if len(names) == 0 {
+		resp, err := c.client.list(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range resp.Queues {
+			names = append(names, info.Name)
+		}
+	}
+
+	infos := make([]queueInfo, 0, len(names))
+	for _, name := range names {
+		info, err := c.client.describe(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, *info)
+	}
+	return infos, nil
+}
+
+func dlqFlags(name string) (*flag.FlagSet, *string, *time.Duration) {
+	fs := flag.NewFlagSet("dlq "+name, flag.ContinueOnError)
+	source := fs.String("source", "", "only letters that failed in this queue")
+	since := fs.Duration("since", 0, "only letters that failed within this duration, e.g. 1h")
+	return fs, source, since
+}
+
+func (c *command) dlqList(ctx context.Context, args []string) error {
+	fs, source, since := dlqFlags("list")
+	limit := fs.Int("limit", 0, "maximum number of letters, defaults to the server limit")
+	args, err := c.parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	name, err := queueArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.deadLetters(ctx, name, deadFilter{source: *source, since: *since, limit: *limit})
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return printJSON(c.out, resp)
+	}
+	return printDeadLetters(c.out, resp)
+}
+
+type redriveResult struct {
+	ID     string `json:"id"`
+	Target string `json:"target,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (c *command) dlqRedrive(ctx context.Context, args []string) error {
+	fs, source, since := dlqFlags("redrive")
+	id := fs.String("id", "", "only the letter with this ID")
+	target := fs.String("target", "", "destination queue, defaults to each letter's source queue")
+	dryRun := fs.Bool("dry-run", false, "print what would be redriven without changing anything")
+	args, err := c.parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	name, err := queueArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var (
+		results []redriveResult
+		failed  int
+		seen    = make(map[string]struct{})
+		// kept 为已处理但仍留在死信队列中的数量，下一轮多拉取这些死信以跳过它们。
+		kept int
+	)
+	filter := deadFilter{source: *source, since: *since}
+	for {
+		filter.limit = kept + redriveBatch
+		resp, err := c.client.deadLetters(ctx, name, filter)
+		if err != nil {
+			return err
+		}
+
+		fresh := 0
+		for _, letter := range resp.Letters {
+			if _, ok := seen[letter.ID]; ok {
+				continue
+			}
+			seen[letter.ID] = struct{}{}
+			fresh++
+			if *id != "" && letter.ID != *id {
+				kept++
+				continue
+			}
+
+			result := redriveResult{ID: letter.ID, Target: *target}
+			if result.Target == "" {
+				result.Target = letter.SourceQueue
+			}
+			switch {
+			case result.Target == "":
+				result.Error = "no source queue, pass -target"
+			case !*dryRun:
+				if err = c.client.requeueDead(ctx, name, letter.ID, result.Target); err != nil {
+					result.Error = err.Error()
+				}
+			}
+			if result.Error != "" {
+				failed++
+			}
+			if result.Error != "" || *dryRun {
+				kept++
+			}
+			results = append(results, result)
+		}
+
+		if !resp.Truncated || fresh == 0 || (*id != "" && len(results) > 0) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if c.output == "json" {
+		if err = printJSON(c.out, struct {
+			DryRun  bool            `json:"dry_run"`
+			Results []redriveResult `json:"results"`
+		}{*dryRun, results}); err != nil {
+			return err
+		}
+	} else if err = printRedrive(c.out, results, *dryRun); err != nil {
+		return err
+	}
+
+	switch {
+	case *id != "" && len(results) == 0:
+		return fmt.Errorf("dead letter %q not found in %s", *id, name)
+	case failed > 0:
+		return fmt.Errorf("%d of %d dead letters were not redriven", failed, len(results))
+	}
+	return nil
+}
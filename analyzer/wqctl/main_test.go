@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubResponses 为测试服务按 "METHOD path" 返回的响应体。
+var stubResponses = map[string]string{
+	"GET /queues": `{"queues":[
+		{"name":"orders","kind":"queue","len":2,"in_flight":1,"closed":false},
+		{"name":"bounded","kind":"bounded","len":0,"in_flight":0,"capacity":8,"closed":true}]}`,
+	"GET /queues/orders": `{"name":"orders","kind":"queue","len":2,"in_flight":1,"closed":false,"stats":{
+		"Depth":2,"InFlight":1,"Adds":10,"Retries":3,"LeaseExpirations":0,
+		"Latency":{"Count":2,"Sum":3},"WorkDuration":{"Count":0,"Sum":0},
+		"UnfinishedWork":0,"LongestRunning":2000000000}}`,
+	"GET /queues/bounded": `{"name":"bounded","kind":"bounded","len":0,"in_flight":0,"capacity":8,"closed":true,"stats":{
+		"Depth":0,"InFlight":0,"Adds":1,"Retries":0,"LeaseExpirations":1,
+		"Latency":{"Count":1,"Sum":0.5},"WorkDuration":{"Count":1,"Sum":0.25},
+		"UnfinishedWork":0,"LongestRunning":0}}`,
+	"GET /queues/orders/items": `{"ready":[{"value":"a"},{"value":{"id":1},"priority":5}],
+		"scheduled":[{"value":"b","at":"2024-01-01T00:00:00Z"}],"truncated":true}`,
+	"POST /queues/orders/purge": `{"purged":2,"cancelled":1}`,
+	"GET /queues/dead/dead": `{"letters":[
+		{"id":"1","payload":"job","source_queue":"orders","attempts":3,"last_error":"boom","failed_at":"2024-01-01T00:00:00Z"},
+		{"id":"2","payload":{"n":1},"attempts":1,"failed_at":"2024-01-01T00:00:00Z"}],"truncated":false}`,
+	"POST /queues/dead/dead/1/requeue": `{}`,
+	"POST /queues/dead/dead/2/requeue": `{}`,
+}
+
+// stubServer 是模拟 admin 接口的测试服务，记录收到的请求。
+type stubServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	requests []string
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	t.Helper()
+
+	s := &stubServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+		s.lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		body, ok := stubResponses[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":"queue not found"}`)
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// run 以 args 执行 wqctl，返回退出码、标准输出与标准错误。
+func (s *stubServer) run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := execute(context.Background(), append([]string{"-addr", s.URL}, args...), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestExecute_Args(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		code     int
+		requests []string
+		stderr   string
+	}{
+		{name: "no command", code: 2, stderr: "invalid usage"},
+		{name: "unknown command", args: []string{"bogus"}, code: 2, stderr: `unknown command "bogus"`},
+		{name: "unknown output", args: []string{"-o", "yaml", "ls"}, code: 2, stderr: "unknown output format: yaml"},
+		{name: "unknown global flag", args: []string{"-verbose", "ls"}, code: 2},
+		{name: "ls", args: []string{"ls"}, requests: []string{"GET /queues"}},
+		{name: "ls extra args", args: []string{"ls", "orders"}, code: 2, stderr: "ls takes no arguments"},
+		{name: "peek", args: []string{"peek", "orders"}, requests: []string{"GET /queues/orders/items"}},
+		{name: "peek flag after queue", args: []string{"peek", "orders", "-limit", "5"}, requests: []string{"GET /queues/orders/items?limit=5"}},
+		{name: "peek without queue", args: []string{"peek"}, code: 2, stderr: "peek requires exactly one queue name"},
+		{name: "peek bad limit", args: []string{"peek", "-limit", "many", "orders"}, code: 2, stderr: `invalid value "many" for flag -limit`},
+		{name: "peek missing queue", args: []string{"peek", "missing"}, code: 1, requests: []string{"GET /queues/missing/items"}, stderr: "queue not found (404)"},
+		{name: "purge without confirmation", args: []string{"purge", "orders"}, code: 2, stderr: "pass -yes to confirm"},
+		{name: "purge", args: []string{"purge", "-yes", "orders"}, requests: []string{"POST /queues/orders/purge"}},
+		{name: "stats all", args: []string{"stats"}, requests: []string{"GET /queues", "GET /queues/orders", "GET /queues/bounded"}},
+		{name: "stats named", args: []string{"stats", "bounded"}, requests: []string{"GET /queues/bounded"}},
+		{name: "stats bad interval", args: []string{"stats", "-interval", "0s"}, code: 2, stderr: "interval must be positive"},
+		{name: "dlq without subcommand", args: []string{"dlq"}, code: 2, stderr: "dlq requires list or redrive"},
+		{name: "dlq unknown subcommand", args: []string{"dlq", "drop", "dead"}, code: 2, stderr: `unknown dlq command "drop"`},
+		{name: "dlq list", args: []string{"dlq", "list", "dead"}, requests: []string{"GET /queues/dead/dead"}},
+		{name: "dlq list since", args: []string{"dlq", "list", "--since", "90m", "dead"}, requests: []string{"GET /queues/dead/dead?since=1h30m0s"}},
+		{name: "dlq list all filters", args: []string{"dlq", "list", "dead", "-source", "orders", "-since=2h", "-limit", "3"}, requests: []string{"GET /queues/dead/dead?limit=3&since=2h0m0s&source=orders"}},
+		{name: "dlq list bad since", args: []string{"dlq", "list", "--since", "yesterday", "dead"}, code: 2, stderr: `invalid value "yesterday" for flag -since`},
+		{name: "dlq list negative since", args: []string{"dlq", "list", "--since", "-1h", "dead"}, requests: []string{"GET /queues/dead/dead"}},
+		{name: "dlq redrive dry run", args: []string{"dlq", "redrive", "-dry-run", "-target", "retry", "dead"}, requests: []string{"GET /queues/dead/dead?limit=100"}},
+		{name: "dlq redrive by id", args: []string{"dlq", "redrive", "-id", "1", "dead"}, requests: []string{"GET /queues/dead/dead?limit=100", "POST /queues/dead/dead/1/requeue"}},
+		{name: "dlq redrive unknown id", args: []string{"dlq", "redrive", "-id", "9", "dead"}, code: 1, requests: []string{"GET /queues/dead/dead?limit=100"}, stderr: `dead letter "9" not found in dead`},
+		{name: "dlq redrive without source", args: []string{"dlq", "redrive", "dead"}, code: 1, requests: []string{"GET /queues/dead/dead?limit=100", "POST /queues/dead/dead/1/requeue"}, stderr: "1 of 2 dead letters were not redriven"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newStubServer(t)
+			code, _, stderr := server.run(tc.args...)
+
+			assert.Equal(t, tc.code, code, "stderr: %s", stderr)
+			assert.Equal(t, tc.requests, server.requests)
+			assert.Contains(t, stderr, tc.stderr)
+		})
+	}
+}
+
+func TestExecute_TableOutput(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "ls",
+			args: []string{"ls"},
+			want: []string{
+				"NAME     KIND     LEN  IN_FLIGHT  CAPACITY  CLOSED",
+				"orders   queue    2    1          -         false",
+				"bounded  bounded  0    0          8         true",
+			},
+		},
+		{
+			name: "peek",
+			args: []string{"peek", "orders"},
+			want: []string{
+				"STATE      PRIORITY  AT                    VALUE",
+				`ready      -         -                     "a"`,
+				`ready      5         -                     {"id":1}`,
+				`scheduled  -         2024-01-01T00:00:00Z  "b"`,
+				"(truncated, raise -limit to see more)",
+			},
+		},
+		{
+			name: "purge",
+			args: []string{"purge", "-yes", "orders"},
+			want: []string{"purged 2 items, cancelled 1 timers from orders"},
+		},
+		{
+			name: "stats",
+			args: []string{"stats"},
+			want: []string{
+				"NAME     KIND     DEPTH  IN_FLIGHT  ADDS  RETRIES  LEASE_EXPIRED  AVG_WAIT  AVG_WORK  LONGEST_RUNNING",
+				"orders   queue    2      1          10    3        0              1.5s      0s        2s",
+				"bounded  bounded  0      0          1     0        1              500ms     250ms     0s",
+			},
+		},
+		{
+			name: "dlq list",
+			args: []string{"dlq", "list", "dead"},
+			want: []string{
+				"ID  SOURCE  ATTEMPTS  FAILED_AT             LAST_ERROR  PAYLOAD",
+				`1   orders  3         2024-01-01T00:00:00Z  boom        "job"`,
+				`2   -       1         2024-01-01T00:00:00Z              {"n":1}`,
+			},
+		},
+		{
+			name: "dlq redrive dry run",
+			args: []string{"dlq", "redrive", "-dry-run", "dead"},
+			want: []string{
+				"ID  TARGET  RESULT",
+				"1   orders  would redrive",
+				"2   -       failed: no source queue, pass -target",
+			},
+		},
+		{
+			name: "dlq redrive",
+			args: []string{"dlq", "redrive", "-target", "retry", "dead"},
+			want: []string{
+				"ID  TARGET  RESULT",
+				"1   retry   redriven",
+				"2   retry   redriven",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newStubServer(t)
+			_, stdout, _ := server.run(tc.args...)
+
+			lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+			for i := range lines {
+				// tabwriter 会为最后一列之前的单元格补齐空格，比较前去掉行尾空白。
+				lines[i] = strings.TrimRight(lines[i], " ")
+			}
+			assert.Equal(t, tc.want, lines)
+		})
+	}
+}
+
+func TestExecute_JSONOutput(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "ls",
+			args: []string{"ls"},
+			want: `{"queues":[
+				{"name":"orders","kind":"queue","len":2,"in_flight":1,"closed":false},
+				{"name":"bounded","kind":"bounded","len":0,"in_flight":0,"capacity":8,"closed":true}]}`,
+		},
+		{
+			name: "peek",
+			args: []string{"peek", "orders"},
+			want: `{"ready":[{"value":"a"},{"value":{"id":1},"priority":5}],
+				"scheduled":[{"value":"b","at":"2024-01-01T00:00:00Z"}],"truncated":true}`,
+		},
+		{
+			name: "purge",
+			args: []string{"purge", "-yes", "orders"},
+			want: `{"purged":2,"cancelled":1}`,
+		},
+		{
+			name: "stats",
+			args: []string{"stats", "bounded"},
+			want: `[{"name":"bounded","kind":"bounded","len":0,"in_flight":0,"capacity":8,"closed":true,"stats":{
+				"Depth":0,"InFlight":0,"Adds":1,"Retries":0,"LeaseExpirations":1,
+				"Latency":{"Count":1,"Sum":0.5},"WorkDuration":{"Count":1,"Sum":0.25},
+				"UnfinishedWork":0,"LongestRunning":0}}]`,
+		},
+		{
+			name: "dlq list",
+			args: []string{"dlq", "list", "dead"},
+			want: stubResponses["GET /queues/dead/dead"],
+		},
+		{
+			name: "dlq redrive dry run",
+			args: []string{"dlq", "redrive", "-dry-run", "dead"},
+			want: `{"dry_run":true,"results":[
+				{"id":"1","target":"orders"},
+				{"id":"2","error":"no source queue, pass -target"}]}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newStubServer(t)
+			_, stdout, _ := server.run(append([]string{"-o", "json"}, tc.args...)...)
+
+			assert.JSONEq(t, tc.want, stdout)
+		})
+	}
+}
+
+func TestShorten(t *testing.T) {
+	assert.Equal(t, "short", shorten("short"))
+
+	long := strings.Repeat("界", maxValueWidth+1)
+	short := shorten(long)
+	assert.Equal(t, maxValueWidth, len([]rune(short)))
+	assert.True(t, strings.HasSuffix(short, "..."), "A shortened value should end with an ellipsis")
+	assert.Equal(t, long[:len("界")*(maxValueWidth-3)], strings.TrimSuffix(short, "..."))
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// maxValueWidth 为表格中元素内容的最大展示宽度，超出部分截断。
+const maxValueWidth = 60
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printTable 逐行输出以制表符分隔的表格，首行为表头。
+func printTable(out io.Writer, rows [][]string) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, cell)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+func printQueues(w io.Writer, queues []queueInfo) error {
+	rows := [][]string{{"NAME", "KIND", "LEN", "IN_FLIGHT", "CAPACITY", "CLOSED"}}
+	for _, q := range queues {
+		capacity := "-"
+		if q.Capacity > 0 {
+			capacity = strconv.Itoa(q.Capacity)
+		}
+		rows = append(rows, []string{q.Name, q.Kind, strconv.Itoa(q.Len), strconv.Itoa(q.InFlight), capacity, strconv.FormatBool(q.Closed)})
+	}
+	return printTable(w, rows)
+}
+
+func printItems(w io.Writer, resp *itemsResponse) error {
+	rows := [][]string{{"STATE", "PRIORITY", "AT", "VALUE"}}
+	for _, it := range resp.Ready {
+		rows = append(rows, itemRow("ready", it))
+	}
+	for _, it := range resp.Scheduled {
+		rows = append(rows, itemRow("scheduled", it))
+	}
+	if err := printTable(w, rows); err != nil {
+		return err
+	}
+	if resp.Truncated {
+		fmt.Fprintln(w, "(truncated, raise -limit to see more)")
+	}
+	return nil
+}
+
+func itemRow(state string, it item) []string {
+	priority, at := "-", "-"
+	if it.Priority != nil {
+		priority = strconv.FormatInt(*it.Priority, 10)
+	}
+	if it.At != nil {
+		at = it.At.Format(time.RFC3339)
+	}
+	return []string{state, priority, at, shorten(string(it.Value))}
+}
+
+func printStats(w io.Writer, infos []queueInfo) error {
+	rows := [][]string{{"NAME", "KIND", "DEPTH", "IN_FLIGHT", "ADDS", "RETRIES", "LEASE_EXPIRED", "AVG_WAIT", "AVG_WORK", "LONGEST_RUNNING"}}
+	for _, info := range infos {
+		var s queueStats
+		if info.Stats != nil {
+			s = *info.Stats
+		}
+		rows = append(rows, []string{
+			info.Name, info.Kind,
+			strconv.Itoa(s.Depth), strconv.Itoa(s.InFlight),
+			strconv.FormatUint(s.Adds, 10), strconv.FormatUint(s.Retries, 10), strconv.FormatUint(s.LeaseExpirations, 10),
+			s.Latency.mean().String(), s.WorkDuration.mean().String(), s.LongestRunning.String(),
+		})
+	}
+	return printTable(w, rows)
+}
+
+func printDeadLetters(w io.Writer, resp *deadLettersResponse) error {
+	rows := [][]string{{"ID", "SOURCE", "ATTEMPTS", "FAILED_AT", "LAST_ERROR", "PAYLOAD"}}
+	for _, letter := range resp.Letters {
+		source := letter.SourceQueue
+		if source == "" {
+			source = "-"
+		}
+		rows = append(rows, []string{
+			letter.ID, source, strconv.Itoa(letter.Attempts), letter.FailedAt.Format(time.RFC3339),
+			shorten(letter.LastError), shorten(string(letter.Payload)),
+		})
+	}
+	if err := printTable(w, rows); err != nil {
+		return err
+	}
+	if resp.Truncated {
+		fmt.Fprintln(w, "(truncated, raise -limit to see more)")
+	}
+	return nil
+}
+
+func printRedrive(w io.Writer, results []redriveResult, dryRun bool) error {
+	rows := [][]string{{"ID", "TARGET", "RESULT"}}
+	for _, result := range results {
+		status := "redriven"
+		switch {
+		case result.Error != "":
+			status = "failed: " + result.Error
+		case dryRun:
+			status = "would redrive"
+		}
+		target := result.Target
+		if target == "" {
+			target = "-"
+		}
+		rows = append(rows, []string{result.ID, target, status})
+	}
+	return printTable(w, rows)
+}
+
+// shorten 截断过长的内容，避免撑开表格。
+func shorten(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxValueWidth {
+		return s
+	}
+	return string(runes[:maxValueWidth-3]) + "..."
+}
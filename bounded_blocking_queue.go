@@ -2,6 +2,8 @@ package workqueue
 
 import (
 	"context"
+	"errors"
+	"io"
 	"sync"
 )
 
@@ -20,21 +22,29 @@ type boundedBlockingQueueImpl struct {
 func NewBoundedBlockingQueue(config *BoundedBlockingQueueConfig) BoundedBlockingQueue {
 	config = isBoundedBlockingQueueConfigEffective(config)
 
+	return newBoundedBlockingQueue(NewQueue(&config.QueueConfig), config)
+}
+
+// newBoundedBlockingQueue 在 queue 外层叠加容量限制，供其他队列复用有界阻塞语义。
+func newBoundedBlockingQueue(queue Queue, config *BoundedBlockingQueueConfig) *boundedBlockingQueueImpl {
 	capacity := config.capacity
-	if capacity <= 0 {
-		capacity = 1024
-	}
 
 	q := &boundedBlockingQueueImpl{
-		Queue:  NewQueue(&config.QueueConfig),
+		Queue:  queue,
 		config: config,
 		slots:  make(chan struct{}, capacity),
 		items:  make(chan struct{}, capacity),
 		closed: make(chan struct{}),
 	}
 
+	// 从 WAL 恢复的元素预先占用容量，恢复数量超过容量时按容量上限计。
+	recovered := q.Queue.Len()
 	for i := 0; i < capacity; i++ {
-		q.slots <- struct{}{}
+		if i < recovered {
+			q.items <- struct{}{}
+		} else {
+			q.slots <- struct{}{}
+		}
 	}
 
 	return q
@@ -45,32 +55,7 @@ func (q *boundedBlockingQueueImpl) Cap() int {
 }
 
 func (q *boundedBlockingQueueImpl) Put(value interface{}) error {
-	if q.IsClosed() {
-		return ErrQueueIsClosed
-	}
-	if value == nil {
-		return ErrElementIsNil
-	}
-
-	select {
-	case <-q.closed:
-		return ErrQueueIsClosed
-	case <-q.slots:
-	}
-
-	err := q.Queue.Put(value)
-	if err != nil {
-		q.releaseSlot()
-		return err
-	}
-
-	select {
-	case <-q.closed:
-		q.releaseSlot()
-		return ErrQueueIsClosed
-	case q.items <- struct{}{}:
-		return nil
-	}
+	return q.PutWithContext(context.Background(), value)
 }
 
 func (q *boundedBlockingQueueImpl) Get() (value interface{}, err error) {
@@ -95,6 +80,11 @@ func (q *boundedBlockingQueueImpl) Get() (value interface{}, err error) {
 }
 
 func (q *boundedBlockingQueueImpl) PutWithContext(ctx context.Context, value interface{}) error {
+	return q.putWith(ctx, value, q.Queue.Put)
+}
+
+// putWith 占用一个容量槽位后调用 put 入队，容量不足时阻塞直至 ctx 结束或队列关闭。
+func (q *boundedBlockingQueueImpl) putWith(ctx context.Context, value interface{}, put func(value interface{}) error) error {
 	if q.IsClosed() {
 		return ErrQueueIsClosed
 	}
@@ -110,7 +100,7 @@ func (q *boundedBlockingQueueImpl) PutWithContext(ctx context.Context, value int
 	case <-q.slots:
 	}
 
-	err := q.Queue.Put(value)
+	err := put(value)
 	if err != nil {
 		q.releaseSlot()
 		return err
@@ -148,6 +138,149 @@ func (q *boundedBlockingQueueImpl) GetWithContext(ctx context.Context) (interfac
 	return value, nil
 }
 
+func (q *boundedBlockingQueueImpl) GetBlocking() (interface{}, error) {
+	return q.GetWithContext(context.Background())
+}
+
+func (q *boundedBlockingQueueImpl) PutBatch(values []interface{}) error {
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	// 逐个占用容量槽位，容量不足时与 Put 一样阻塞等待消费端释放。
+	for i := range values {
+		select {
+		case <-q.closed:
+			q.releaseSlots(i)
+			return ErrQueueIsClosed
+		case <-q.slots:
+		}
+	}
+
+	accepted := len(values)
+	err := q.Queue.PutBatch(values)
+	if err != nil {
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) {
+			q.releaseSlots(len(values))
+			return err
+		}
+		for _, e := range batchErr.Errors {
+			if e != nil {
+				accepted--
+			}
+		}
+		q.releaseSlots(len(values) - accepted)
+	}
+
+	for i := 0; i < accepted; i++ {
+		select {
+		case <-q.closed:
+			q.releaseSlots(accepted - i)
+			return ErrQueueIsClosed
+		case q.items <- struct{}{}:
+		}
+	}
+
+	return err
+}
+
+func (q *boundedBlockingQueueImpl) GetBatch(max int) ([]interface{}, error) {
+	if q.IsClosed() {
+		return nil, ErrQueueIsClosed
+	}
+	if max <= 0 {
+		return nil, ErrInvalidBatchSize
+	}
+
+	n := q.acquireItems(max)
+	if n == 0 {
+		return nil, ErrQueueIsEmpty
+	}
+
+	return q.getBatch(n)
+}
+
+func (q *boundedBlockingQueueImpl) GetBatchWithContext(ctx context.Context, max int) ([]interface{}, error) {
+	if q.IsClosed() {
+		return nil, ErrQueueIsClosed
+	}
+	if max <= 0 {
+		return nil, ErrInvalidBatchSize
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-q.closed:
+		return nil, ErrQueueIsClosed
+	case <-q.items:
+	}
+
+	return q.getBatch(1 + q.acquireItems(max-1))
+}
+
+// acquireItems 非阻塞地获取最多 max 个元素信号。
+func (q *boundedBlockingQueueImpl) acquireItems(max int) int {
+	n := 0
+	for n < max {
+		select {
+		case <-q.items:
+			n++
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// purge 先取走当前全部元素信号，使消费者无法同时取走这些元素，再移除同样数量的就绪元素并释放容量槽位。
+// 未能移除的部分归还元素信号。
+func (q *boundedBlockingQueueImpl) purge() int {
+	n := q.acquireItems(q.config.capacity)
+	purged, _ := purgeAtMost(q.Queue, n)
+	q.releaseSlots(purged)
+	for i := purged; i < n; i++ {
+		select {
+		case q.items <- struct{}{}:
+		default:
+		}
+	}
+	return purged
+}
+
+// requeue 结束元素的处理后占用一个容量槽位放回队列，容量不足时阻塞直至有空闲容量或队列关闭，
+// 期间元素登记为处理中，排空会等待其完成。
+func (q *boundedBlockingQueueImpl) requeue(value interface{}) error {
+	base, ok := baseOf(q.Queue)
+	if !ok {
+		return ErrUnsupportedQueue
+	}
+
+	base.retain()
+	defer base.release()
+
+	q.Queue.Done(value)
+	// 已与处理期间的入队请求合并时，putWith 已归还槽位。
+	if err := q.putWith(context.Background(), value, base.requeue); err != nil && !errors.Is(err, ErrElementAlreadyExist) {
+		return err
+	}
+	return nil
+}
+
+// getBatch 在已持有 n 个元素信号的前提下批量出队，并释放对应的容量槽位。
+func (q *boundedBlockingQueueImpl) getBatch(n int) ([]interface{}, error) {
+	values, err := q.Queue.GetBatch(n)
+	q.releaseSlots(n)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
 func (q *boundedBlockingQueueImpl) Shutdown() {
 	q.once.Do(func() {
 		close(q.closed)
@@ -155,6 +288,66 @@ func (q *boundedBlockingQueueImpl) Shutdown() {
 	q.Queue.Shutdown()
 }
 
+// ShutdownWithDrain 复用基础队列的排空语义；排空期间阻塞中的 Put 获得槽位后会收到 ErrQueueIsDraining。
+func (q *boundedBlockingQueueImpl) ShutdownWithDrain(ctx context.Context) ([]interface{}, error) {
+	pending, err := q.Queue.ShutdownWithDrain(ctx)
+	q.once.Do(func() {
+		close(q.closed)
+	})
+	return pending, err
+}
+
+// Restore 恢复快照中的元素，容量不足时不阻塞，剩余元素返回 ErrQueueIsFull。
+func (q *boundedBlockingQueueImpl) Restore(r io.Reader) error {
+	s, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+	values, err := decodeItems(q.config.codec, s.readyItems(true))
+	if err != nil {
+		return err
+	}
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+
+	errs := make([]error, len(values))
+	for i, value := range values {
+		errs[i] = q.tryPut(value)
+	}
+	return newBatchError(errs)
+}
+
+// tryPut 在有空闲容量时入队，否则立即返回 ErrQueueIsFull。
+func (q *boundedBlockingQueueImpl) tryPut(value interface{}) error {
+	select {
+	case <-q.closed:
+		return ErrQueueIsClosed
+	case <-q.slots:
+	default:
+		return ErrQueueIsFull
+	}
+
+	if err := q.Queue.Put(value); err != nil {
+		q.releaseSlot()
+		return err
+	}
+
+	select {
+	case <-q.closed:
+		q.releaseSlot()
+		return ErrQueueIsClosed
+	case q.items <- struct{}{}:
+		return nil
+	}
+}
+
+func (q *boundedBlockingQueueImpl) releaseSlots(n int) {
+	for i := 0; i < n; i++ {
+		q.releaseSlot()
+	}
+}
+
 func (q *boundedBlockingQueueImpl) releaseSlot() {
 	select {
 	case q.slots <- struct{}{}:
@@ -94,3 +94,74 @@ func TestBoundedBlockingQueue_GetWithContext_ReleasesSlotOnGetError(t *testing.T
 	defer cancel()
 	assert.NoError(t, q.PutWithContext(ctx, "ok"))
 }
+
+func TestBoundedBlockingQueue_PutBatchAndGetBatch(t *testing.T) {
+	q := NewBoundedBlockingQueue(NewBoundedBlockingQueueConfig().WithCapacity(3))
+	defer q.Shutdown()
+
+	err := q.PutBatch([]interface{}{"a", nil, "b"})
+	assert.ErrorIs(t, err, ErrElementIsNil)
+	assert.Equal(t, 2, q.Len())
+
+	// 失败元素的槽位应被释放，剩余容量为 1。
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, q.PutWithContext(ctx, "c"))
+	assert.ErrorIs(t, q.PutWithContext(ctx, "d"), context.DeadlineExceeded)
+
+	values, err := q.GetBatch(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, values)
+
+	values, err = q.GetBatchWithContext(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"c"}, values)
+
+	_, err = q.GetBatch(1)
+	assert.ErrorIs(t, err, ErrQueueIsEmpty)
+
+	assert.NoError(t, q.PutBatch([]interface{}{"x", "y", "z"}), "All slots should be available again")
+}
+
+func TestBoundedBlockingQueue_Purge(t *testing.T) {
+	q := NewBoundedBlockingQueue(NewBoundedBlockingQueueConfig().WithCapacity(3))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutBatch([]interface{}{"a", "b", "c"}))
+
+	purged, err := Purge(q)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, purged)
+	assert.Equal(t, 0, q.Len())
+
+	// 被丢弃元素的槽位应被释放，元素信号也应同步收回。
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, q.PutBatch([]interface{}{"x", "y", "z"}), "All slots should be available again")
+	assert.ErrorIs(t, q.PutWithContext(ctx, "w"), context.DeadlineExceeded)
+
+	values, err := q.GetBatch(5)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"x", "y", "z"}, values)
+}
+
+func TestBoundedBlockingQueue_Requeue(t *testing.T) {
+	q := NewBoundedBlockingQueue(NewBoundedBlockingQueueConfig().WithCapacity(1))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("a"))
+	v, err := q.Get()
+	assert.NoError(t, err)
+
+	assert.NoError(t, Requeue(q, v))
+	assert.Equal(t, 1, q.Len())
+	assert.Equal(t, 0, q.Stats().InFlight)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.PutWithContext(ctx, "b"), context.DeadlineExceeded, "Requeued value should occupy the slot")
+
+	v, err = q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "a", v)
+}
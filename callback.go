@@ -43,6 +43,24 @@ func NewNopPriorityQueueCallbackImpl() *priorityQueueCallbackImpl {
 
 func (impl *priorityQueueCallbackImpl) OnPriority(interface{}, int64) {}
 
+func (impl *priorityQueueCallbackImpl) OnUpdatePriority(interface{}, int64) {}
+
+func (impl *priorityQueueCallbackImpl) OnRemove(interface{}) {}
+
+type fairQueueCallbackImpl struct {
+	queueCallbackImpl
+}
+
+// NewNopFairQueueCallbackImpl 返回空实现公平队列回调。
+func NewNopFairQueueCallbackImpl() *fairQueueCallbackImpl {
+
+	return &fairQueueCallbackImpl{
+		queueCallbackImpl: queueCallbackImpl{},
+	}
+}
+
+func (impl *fairQueueCallbackImpl) OnFlow(interface{}, string) {}
+
 type ratelimitingQueueCallbackImpl struct {
 	delayingQueueCallbackImpl
 }
@@ -96,3 +114,14 @@ func (impl *deadLetterQueueCallbackImpl) OnDead(*DeadLetter) {}
 func (impl *deadLetterQueueCallbackImpl) OnAckDead(*DeadLetter) {}
 
 func (impl *deadLetterQueueCallbackImpl) OnRequeueDead(*DeadLetter, Queue) {}
+
+type runnerCallbackImpl struct{}
+
+// NewNopRunnerCallbackImpl 返回空实现 Runner 回调。
+func NewNopRunnerCallbackImpl() *runnerCallbackImpl { return &runnerCallbackImpl{} }
+
+func (impl *runnerCallbackImpl) OnSuccess(interface{}, time.Duration) {}
+
+func (impl *runnerCallbackImpl) OnFailure(interface{}, error) {}
+
+func (impl *runnerCallbackImpl) OnPanic(interface{}, interface{}) {}
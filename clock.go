@@ -0,0 +1,120 @@
+package workqueue
+
+import (
+	"sync"
+	"time"
+)
+
+type realClockImpl struct{}
+
+func (realClockImpl) Now() time.Time { return time.Now() }
+
+func (realClockImpl) AfterFunc(d time.Duration, fn func()) ClockTimer { return time.AfterFunc(d, fn) }
+
+// NewRealClock 返回基于系统时间的时钟，为各队列的默认时钟。
+func NewRealClock() Clock { return realClockImpl{} }
+
+// FakeClock 是手动推进的时钟，用于测试。时间只在调用 Advance 或 Set 时前进，
+// 期间到期的计时器按到期先后在调用方协程中同步执行，回调返回后 Advance 才继续，
+// 因此 Advance 返回时到期的元素已经被搬运、过期的租约已经被重新入队。
+type FakeClock struct {
+	lock    sync.Mutex
+	advance sync.Mutex
+	now     time.Time
+	seq     uint64
+	timers  map[*fakeTimer]struct{}
+}
+
+// NewFakeClock 创建从 now 开始计时的 FakeClock。
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, timers: make(map[*fakeTimer]struct{})}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+// AfterFunc 登记在 d 之后执行的回调。d <= 0 的回调在下一次 Advance 时执行。
+func (c *FakeClock) AfterFunc(d time.Duration, fn func()) ClockTimer {
+	t := &fakeTimer{clock: c, fn: fn}
+	t.Reset(d)
+	return t
+}
+
+// Advance 将时间推进 d，并依次执行期间到期的计时器回调。回调中新建或重置的计时器
+// 只要在目标时刻之前到期，同样会在本次 Advance 中执行。
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set 将时间推进到 t，语义与 Advance 相同；t 早于当前时间时只执行已到期的计时器。
+func (c *FakeClock) Set(t time.Time) {
+	c.advance.Lock()
+	defer c.advance.Unlock()
+
+	for {
+		c.lock.Lock()
+		next := c.nextLocked(t)
+		if next == nil {
+			if t.After(c.now) {
+				c.now = t
+			}
+			c.lock.Unlock()
+			return
+		}
+		if next.at.After(c.now) {
+			c.now = next.at
+		}
+		delete(c.timers, next)
+		c.lock.Unlock()
+
+		next.fn()
+	}
+}
+
+// nextLocked 返回不晚于 t 的最早计时器，到期时间相同时先登记的先执行。
+func (c *FakeClock) nextLocked(t time.Time) *fakeTimer {
+	var next *fakeTimer
+	for timer := range c.timers {
+		if timer.at.After(t) {
+			continue
+		}
+		if next == nil || timer.at.Before(next.at) || (timer.at.Equal(next.at) && timer.seq < next.seq) {
+			next = timer
+		}
+	}
+	return next
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	fn    func()
+	at    time.Time
+	seq   uint64
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, active := c.timers[t]
+	delete(c.timers, t)
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, active := c.timers[t]
+	c.seq++
+	t.seq = c.seq
+	t.at = c.now.Add(d)
+	c.timers[t] = struct{}{}
+	return active
+}
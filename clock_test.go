@@ -0,0 +1,181 @@
+package workqueue
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClock_AfterFunc(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+
+	var fired []string
+	clock.AfterFunc(30*time.Millisecond, func() { fired = append(fired, "c") })
+	clock.AfterFunc(10*time.Millisecond, func() {
+		fired = append(fired, "a")
+		// 回调中登记的计时器在目标时刻之前到期时，同样在本次 Advance 中执行。
+		clock.AfterFunc(5*time.Millisecond, func() { fired = append(fired, "a+5") })
+	})
+	stopped := clock.AfterFunc(20*time.Millisecond, func() { fired = append(fired, "b") })
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop(), "Stopping twice should report an inactive timer")
+
+	clock.Advance(9 * time.Millisecond)
+	assert.Empty(t, fired)
+
+	clock.Advance(21 * time.Millisecond)
+	assert.Equal(t, []string{"a", "a+5", "c"}, fired, "Timers should fire in due order")
+	assert.Equal(t, fakeEpoch.Add(30*time.Millisecond), clock.Now())
+
+	// Reset 重新登记已触发或已停止的计时器。
+	assert.False(t, stopped.Reset(time.Millisecond))
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, []string{"a", "a+5", "c", "b"}, fired)
+}
+
+func TestDelayingQueue_FakeClock(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	q := NewDelayingQueue(NewDelayingQueueConfig().WithClock(clock))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithDelay("later", 2000))
+	assert.NoError(t, q.PutWithDelay("sooner", 1000))
+
+	clock.Advance(999 * time.Millisecond)
+	_, err := q.Get()
+	assert.ErrorIs(t, err, ErrQueueIsEmpty, "Value should not fire before its deadline")
+
+	clock.Advance(time.Millisecond)
+	v, err := q.Get()
+	assert.NoError(t, err, "Value should fire as soon as the clock reaches its deadline")
+	assert.Equal(t, "sooner", v)
+
+	clock.Advance(time.Second)
+	v, err = q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "later", v)
+}
+
+func TestTimerQueue_FakeClock(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	q := NewTimerQueue(NewTimerQueueConfig().WithClock(clock))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutAt("at", fakeEpoch.Add(time.Hour)))
+	r, err := q.PutEvery("every", 10*time.Minute, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, fakeEpoch.Add(10*time.Minute), r.Next())
+
+	clock.Advance(time.Hour)
+
+	var values []interface{}
+	for {
+		v, err := q.Get()
+		if err != nil {
+			break
+		}
+		values = append(values, v)
+		q.Done(v)
+	}
+	// 幂等关闭时同一值可以重复入队：周期调度在一小时内触发 6 次。
+	assert.Len(t, values, 7)
+	assert.Contains(t, values, "at")
+	assert.Equal(t, fakeEpoch.Add(70*time.Minute), r.Next())
+}
+
+func TestLeasedQueue_FakeClock(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	q := NewLeasedQueue(NewLeasedQueueConfig().WithScanInterval(time.Second).WithClock(clock))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("job"))
+	v, _, err := q.GetWithLease(5 * time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "job", v)
+
+	clock.Advance(4 * time.Second)
+	_, err = q.Get()
+	assert.ErrorIs(t, err, ErrQueueIsEmpty, "Lease should still be held")
+
+	clock.Advance(time.Second)
+	v, err = q.Get()
+	assert.NoError(t, err, "Expired lease should be requeued by the next scan")
+	assert.Equal(t, "job", v)
+}
+
+func TestRetryQueue_FakeClock(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	q := NewRetryQueue(NewRetryQueueConfig().WithPolicy(NewExponentialRetryPolicy(time.Second, time.Minute, 3)).WithClock(clock))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Retry("job", errors.New("boom")))
+	_, err := q.Get()
+	assert.ErrorIs(t, err, ErrQueueIsEmpty)
+
+	clock.Advance(time.Second)
+	v, err := q.Get()
+	assert.NoError(t, err, "Retry should fire after the policy delay")
+	assert.Equal(t, "job", v)
+}
+
+func TestBucketRateLimiter_FakeClock(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	limiter := NewBucketRateLimiterImplWithClock(10, 1, clock)
+
+	assert.Equal(t, time.Duration(0), limiter.When("a"), "Burst token should be free")
+	assert.Equal(t, 100*time.Millisecond, limiter.When("b"))
+
+	clock.Advance(time.Second)
+	assert.Equal(t, time.Duration(0), limiter.When("c"), "Tokens should refill with the fake clock")
+}
+
+func TestPriorityQueue_AgingFakeClock(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	q := NewPriorityQueue(NewPriorityQueueConfig().WithLinearAging(1, time.Second).WithClock(clock))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithPriority("old", 10))
+	clock.Advance(5 * time.Second)
+	assert.NoError(t, q.PutWithPriority("new", 7))
+
+	priorities := make(map[interface{}]int64)
+	q.HeapRange(func(value interface{}, priority int64) bool {
+		priorities[value] = priority
+		return true
+	})
+	assert.Equal(t, map[interface{}]int64{"old": 5, "new": 7}, priorities, "Aging should follow the fake clock")
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "old", v)
+}
+
+func TestRateLimitingQueue_FakeClock(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	q := NewRateLimitingQueue(NewRateLimitingQueueConfig().WithLimiter(NewBucketRateLimiterImpl(1, 1)).WithClock(clock))
+	defer q.Shutdown()
+
+	// 未显式指定时钟的令牌桶随队列时钟补充令牌。
+	assert.NoError(t, q.PutWithLimited("a"))
+	assert.NoError(t, q.PutWithLimited("b"))
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "a", v)
+	_, err = q.Get()
+	assert.ErrorIs(t, err, ErrQueueIsEmpty, "The second value should wait for a token")
+
+	clock.Advance(time.Second)
+	v, err = q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "b", v)
+
+	clock.Advance(time.Second)
+	assert.NoError(t, q.PutWithLimited("c"))
+	v, err = q.Get()
+	assert.NoError(t, err, "Tokens should refill with the queue clock")
+	assert.Equal(t, "c", v)
+}
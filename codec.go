@@ -0,0 +1,335 @@
+package workqueue
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// deadLetterTypeName 为 DeadLetter 在编解码器中的内置类型名。
+const deadLetterTypeName = "workqueue.DeadLetter"
+
+func init() {
+	gob.Register(&DeadLetter{})
+}
+
+// Codec 定义元素与字节之间的转换方式，供持久化、导出与远程传输使用。
+type Codec = interface {
+	// Encode 将元素编码为字节。
+	Encode(value interface{}) ([]byte, error)
+
+	// Decode 将 Encode 的结果还原为元素。
+	Decode(data []byte) (interface{}, error)
+}
+
+// TypedDecoder 为能够按目标类型解码的编解码器，target 为指向目标值的指针。
+// 例如 JSON 未注册的类型默认解码为通用 JSON 值，按目标类型解码时整数可以直接还原为 int。
+type TypedDecoder = interface {
+	DecodeInto(data []byte, target interface{}) error
+}
+
+// DecodeInto 将 data 解码到 target 指向的值。编解码器实现了 TypedDecoder 时按目标类型解码，
+// 否则先 Decode 再赋值，解码结果无法赋给目标类型时返回 ErrCodecTypeMismatch。
+func DecodeInto(codec Codec, data []byte, target interface{}) error {
+	if decoder, ok := codec.(TypedDecoder); ok {
+		return decoder.DecodeInto(data, target)
+	}
+	value, err := codec.Decode(data)
+	if err != nil {
+		return err
+	}
+	return assign(target, value)
+}
+
+// assign 将 value 赋给 target 指向的值。
+func assign(target interface{}, value interface{}) error {
+	dst := reflect.ValueOf(target)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("%w: target %T is not a non-nil pointer", ErrCodecTypeMismatch, target)
+	}
+	dst = dst.Elem()
+	if value == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	src := reflect.ValueOf(value)
+	if !src.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf("%w: cannot decode %T into %s", ErrCodecTypeMismatch, value, dst.Type())
+	}
+	dst.Set(src)
+	return nil
+}
+
+// CodecRegistry 维护类型名与具体类型的对应关系，使 JSON 等自描述较弱的格式也能还原出具体类型。
+// 注册的类型同时以相同名称注册到全局的 encoding/gob，gob 中已有的注册保持不变。可并发使用。
+type CodecRegistry struct {
+	lock   sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}
+
+// NewCodecRegistry 创建空的类型注册表。
+func NewCodecRegistry() *CodecRegistry {
+	return &CodecRegistry{
+		byName: make(map[string]reflect.Type),
+		byType: make(map[reflect.Type]string),
+	}
+}
+
+var defaultCodecRegistry = NewCodecRegistry()
+
+// RegisterPayloadType 将 sample 的类型以 name 注册到默认注册表，NewJSONCodec(nil) 使用该注册表。
+func RegisterPayloadType(name string, sample interface{}) error {
+	return defaultCodecRegistry.Register(name, sample)
+}
+
+// Register 以 name 注册 sample 的类型，解码时按注册的值或指针类型还原。
+// 同一名称或类型重复注册为不同的对应关系时返回 ErrCodecTypeConflict。
+func (r *CodecRegistry) Register(name string, sample interface{}) error {
+	if name == "" || name == deadLetterTypeName || sample == nil {
+		return ErrInvalidCodecType
+	}
+	typ := reflect.TypeOf(sample)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if known, ok := r.byName[name]; ok {
+		if known != typ {
+			return ErrCodecTypeConflict
+		}
+		return nil
+	}
+	if _, ok := r.byType[typ]; ok {
+		return ErrCodecTypeConflict
+	}
+
+	registerGob(name, sample)
+
+	r.byName[name] = typ
+	r.byType[typ] = name
+	return nil
+}
+
+// registerGob 向 gob 注册类型。gob 的注册是进程级的，同一类型可能已被其他注册表或 gob.Register 以别的名称注册，
+// 此时 gob 会 panic，但已有注册足以完成编解码，因此忽略。
+func registerGob(name string, sample interface{}) {
+	defer func() { _ = recover() }()
+	gob.RegisterName(name, sample)
+}
+
+func (r *CodecRegistry) nameOf(typ reflect.Type) (string, bool) {
+	r.lock.RLock()
+	name, ok := r.byType[typ]
+	r.lock.RUnlock()
+	return name, ok
+}
+
+func (r *CodecRegistry) typeOf(name string) (reflect.Type, bool) {
+	r.lock.RLock()
+	typ, ok := r.byName[name]
+	r.lock.RUnlock()
+	return typ, ok
+}
+
+// gobCodec 使用 encoding/gob 编码，能还原全部已通过 gob.Register 或 CodecRegistry 注册的类型。
+type gobCodec struct{}
+
+// gobEnvelope 包装任意元素，使 gob 能够编码接口类型的值。
+type gobEnvelope struct {
+	Value interface{}
+}
+
+// NewGobCodec 创建 gob 编解码器，也是持久化日志的默认编解码器。
+// 自定义类型（包括 DeadLetter.Payload 中的类型）需要先通过 gob.Register 或 CodecRegistry 注册。
+func NewGobCodec() Codec {
+	return gobCodec{}
+}
+
+func (gobCodec) Encode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&gobEnvelope{Value: value}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Decode(data []byte) (interface{}, error) {
+	var envelope gobEnvelope
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	return envelope.Value, nil
+}
+
+// jsonCodec 使用 encoding/json 编码，已注册类型携带类型名以便还原。
+type jsonCodec struct {
+	registry *CodecRegistry
+}
+
+// jsonEnvelope 为 JSON 编码的外层结构，Type 为空时 Value 按通用 JSON 值解码。
+type jsonEnvelope struct {
+	Type  string          `json:"type,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonDeadLetter 为 DeadLetter 的 JSON 形式，Payload 单独携带类型名。
+type jsonDeadLetter struct {
+	ID          string            `json:"id"`
+	Payload     jsonEnvelope      `json:"payload"`
+	SourceQueue string            `json:"source_queue,omitempty"`
+	Attempts    int               `json:"attempts"`
+	LastError   string            `json:"last_error,omitempty"`
+	FailedAt    time.Time         `json:"failed_at"`
+	Meta        map[string]string `json:"meta,omitempty"`
+}
+
+// NewJSONCodec 创建 JSON 编解码器，registry 为空时使用 RegisterPayloadType 维护的默认注册表。
+// 未注册的类型按通用 JSON 值解码（对象为 map[string]interface{}，数字为 float64）。
+// DeadLetter 及其 Payload 会被完整还原。
+func NewJSONCodec(registry *CodecRegistry) Codec {
+	if registry == nil {
+		registry = defaultCodecRegistry
+	}
+	return &jsonCodec{registry: registry}
+}
+
+func (c *jsonCodec) Encode(value interface{}) ([]byte, error) {
+	envelope, err := c.wrap(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope)
+}
+
+func (c *jsonCodec) Decode(data []byte) (interface{}, error) {
+	var envelope jsonEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	return c.unwrap(&envelope)
+}
+
+// DecodeInto 未携带类型名的值直接按 target 的类型解码，其余与 Decode 相同。
+func (c *jsonCodec) DecodeInto(data []byte, target interface{}) error {
+	var envelope jsonEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return err
+	}
+	if envelope.Type == "" {
+		return json.Unmarshal(envelope.Value, target)
+	}
+	value, err := c.unwrap(&envelope)
+	if err != nil {
+		return err
+	}
+	return assign(target, value)
+}
+
+func (c *jsonCodec) wrap(value interface{}) (*jsonEnvelope, error) {
+	if letter, ok := toDeadLetter(value); ok {
+		payload, err := c.wrap(letter.Payload)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(&jsonDeadLetter{
+			ID:          letter.ID,
+			Payload:     *payload,
+			SourceQueue: letter.SourceQueue,
+			Attempts:    letter.Attempts,
+			LastError:   letter.LastError,
+			FailedAt:    letter.FailedAt,
+			Meta:        letter.Meta,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &jsonEnvelope{Type: deadLetterTypeName, Value: raw}, nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := &jsonEnvelope{Value: raw}
+	if value != nil {
+		envelope.Type, _ = c.registry.nameOf(reflect.TypeOf(value))
+	}
+	return envelope, nil
+}
+
+func (c *jsonCodec) unwrap(envelope *jsonEnvelope) (interface{}, error) {
+	switch envelope.Type {
+	case "":
+		var value interface{}
+		if err := json.Unmarshal(envelope.Value, &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+
+	case deadLetterTypeName:
+		var letter jsonDeadLetter
+		if err := json.Unmarshal(envelope.Value, &letter); err != nil {
+			return nil, err
+		}
+		payload, err := c.unwrap(&letter.Payload)
+		if err != nil {
+			return nil, err
+		}
+		return &DeadLetter{
+			ID:          letter.ID,
+			Payload:     payload,
+			SourceQueue: letter.SourceQueue,
+			Attempts:    letter.Attempts,
+			LastError:   letter.LastError,
+			FailedAt:    letter.FailedAt,
+			Meta:        letter.Meta,
+		}, nil
+	}
+
+	typ, ok := c.registry.typeOf(envelope.Type)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCodecType, envelope.Type)
+	}
+
+	// 注册为指针类型时返回指针，否则返回值。
+	if typ.Kind() == reflect.Ptr {
+		target := reflect.New(typ.Elem())
+		if err := json.Unmarshal(envelope.Value, target.Interface()); err != nil {
+			return nil, err
+		}
+		return target.Interface(), nil
+	}
+
+	target := reflect.New(typ)
+	if err := json.Unmarshal(envelope.Value, target.Interface()); err != nil {
+		return nil, err
+	}
+	return target.Elem().Interface(), nil
+}
+
+// bytesCodec 原样传递字节切片。
+type bytesCodec struct{}
+
+// NewBytesCodec 创建原始字节编解码器，只接受 []byte 元素，编解码时均复制数据。
+// []byte 不可哈希，用于持久化日志时需要配置 key 函数。
+func NewBytesCodec() Codec {
+	return bytesCodec{}
+}
+
+func (bytesCodec) Encode(value interface{}) ([]byte, error) {
+	data, ok := value.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedCodecValue, value)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (bytesCodec) Decode(data []byte) (interface{}, error) {
+	return append([]byte(nil), data...), nil
+}
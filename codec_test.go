@@ -0,0 +1,144 @@
+package workqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type codecTestOrder struct {
+	ID    string
+	Count int
+}
+
+func TestCodecRegistry_Register(t *testing.T) {
+	registry := NewCodecRegistry()
+
+	assert.NoError(t, registry.Register("codec.order", codecTestOrder{}))
+	assert.NoError(t, registry.Register("codec.order", codecTestOrder{}), "Registering the same mapping twice should be a no-op")
+	assert.ErrorIs(t, registry.Register("codec.other", codecTestOrder{}), ErrCodecTypeConflict)
+	assert.ErrorIs(t, registry.Register("codec.order", &codecTestOrder{}), ErrCodecTypeConflict)
+	assert.ErrorIs(t, registry.Register("", codecTestOrder{}), ErrInvalidCodecType)
+	assert.ErrorIs(t, registry.Register("codec.nil", nil), ErrInvalidCodecType)
+}
+
+func TestJSONCodec_RoundTrip(t *testing.T) {
+	registry := NewCodecRegistry()
+	assert.NoError(t, registry.Register("codec.json.order", &codecTestOrder{}))
+	codec := NewJSONCodec(registry)
+
+	data, err := codec.Encode(&codecTestOrder{ID: "o-1", Count: 2})
+	assert.NoError(t, err)
+	value, err := codec.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, &codecTestOrder{ID: "o-1", Count: 2}, value, "Registered types should be restored")
+
+	data, err = codec.Encode(map[string]int{"count": 1})
+	assert.NoError(t, err)
+	value, err = codec.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"count": float64(1)}, value, "Unregistered types should decode as generic JSON")
+
+	_, err = NewJSONCodec(NewCodecRegistry()).Decode(mustEncode(t, codec, &codecTestOrder{}))
+	assert.ErrorIs(t, err, ErrUnknownCodecType)
+}
+
+func TestDecodeInto(t *testing.T) {
+	registry := NewCodecRegistry()
+	assert.NoError(t, registry.Register("codec.decode.order", &codecTestOrder{}))
+	codec := NewJSONCodec(registry)
+
+	var n int
+	assert.NoError(t, DecodeInto(codec, mustEncode(t, codec, 42), &n))
+	assert.Equal(t, 42, n, "Unregistered values should decode into the target type")
+
+	var order *codecTestOrder
+	assert.NoError(t, DecodeInto(codec, mustEncode(t, codec, &codecTestOrder{ID: "o-1"}), &order))
+	assert.Equal(t, &codecTestOrder{ID: "o-1"}, order)
+
+	gob := NewGobCodec()
+	assert.NoError(t, DecodeInto(gob, mustEncode(t, gob, 7), &n))
+	assert.Equal(t, 7, n)
+	assert.ErrorIs(t, DecodeInto(gob, mustEncode(t, gob, "7"), &n), ErrCodecTypeMismatch)
+}
+
+func TestJSONCodec_DeadLetter(t *testing.T) {
+	registry := NewCodecRegistry()
+	assert.NoError(t, registry.Register("codec.letter.order", codecTestOrder{}))
+	codec := NewJSONCodec(registry)
+
+	letter := &DeadLetter{
+		ID:          "1",
+		Payload:     codecTestOrder{ID: "o-2", Count: 3},
+		SourceQueue: "orders",
+		Attempts:    5,
+		LastError:   "boom",
+		FailedAt:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Meta:        map[string]string{"tenant": "a"},
+	}
+
+	value, err := codec.Decode(mustEncode(t, codec, letter))
+	assert.NoError(t, err)
+	assert.Equal(t, letter, value)
+}
+
+func TestGobCodec_RoundTrip(t *testing.T) {
+	registry := NewCodecRegistry()
+	assert.NoError(t, registry.Register("codec.gob.order", codecTestOrder{}))
+	codec := NewGobCodec()
+
+	letter := &DeadLetter{ID: "1", Payload: codecTestOrder{ID: "o-3"}, FailedAt: time.Unix(100, 0).UTC()}
+	value, err := codec.Decode(mustEncode(t, codec, letter))
+	assert.NoError(t, err)
+	assert.Equal(t, letter, value, "Types registered through the registry should be known to gob")
+
+	value, err = codec.Decode(mustEncode(t, codec, "plain"))
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", value)
+}
+
+func TestBytesCodec(t *testing.T) {
+	codec := NewBytesCodec()
+
+	raw := []byte("raw")
+	data, err := codec.Encode(raw)
+	assert.NoError(t, err)
+	raw[0] = 'R'
+	value, err := codec.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("raw"), value, "Encoded bytes should not alias the input")
+
+	_, err = codec.Encode("raw")
+	assert.ErrorIs(t, err, ErrUnsupportedCodecValue)
+}
+
+func TestQueueImpl_WAL_Codec(t *testing.T) {
+	dir := t.TempDir()
+	registry := NewCodecRegistry()
+	assert.NoError(t, registry.Register("codec.wal.order", codecTestOrder{}))
+	config := func(w *WAL) *QueueConfig {
+		return NewQueueConfig().WithWAL(w).WithCodec(NewJSONCodec(registry))
+	}
+
+	w := openTestWAL(t, dir)
+	q := NewQueue(config(w))
+	assert.NoError(t, q.Put(codecTestOrder{ID: "o-4", Count: 1}))
+	q.Shutdown()
+	assert.NoError(t, w.Close())
+
+	w = openTestWAL(t, dir)
+	defer w.Close()
+	q = NewQueue(config(w))
+	defer q.Shutdown()
+
+	assert.Equal(t, []interface{}{codecTestOrder{ID: "o-4", Count: 1}}, q.Values())
+}
+
+func mustEncode(t *testing.T, codec Codec, value interface{}) []byte {
+	t.Helper()
+
+	data, err := codec.Encode(value)
+	assert.NoError(t, err)
+	return data
+}
@@ -2,6 +2,7 @@ package workqueue
 
 import (
 	"fmt"
+	"runtime"
 	"time"
 
 	"github.com/shengyanli1982/workqueue/v2/internal/container/set"
@@ -25,6 +26,12 @@ type QueueConfig struct {
 	callback   QueueCallback
 	idempotent bool
 	setCreator NewSetFunc
+	keyFunc    KeyFunc
+	name       string
+	metrics    MetricsProvider
+	wal        *WAL
+	codec      Codec
+	clock      Clock
 }
 
 // NewQueueConfig 返回带默认值的基础队列配置。
@@ -32,6 +39,8 @@ func NewQueueConfig() *QueueConfig {
 	return &QueueConfig{
 		callback:   NewNopQueueCallbackImpl(),
 		setCreator: defaultNewSetFunc,
+		codec:      NewGobCodec(),
+		clock:      NewRealClock(),
 	}
 }
 
@@ -55,6 +64,53 @@ func (c *QueueConfig) WithSetCreator(fn NewSetFunc) *QueueConfig {
 	return c
 }
 
+// WithKeyFunc 设置幂等判重的 key 生成函数，PriorityQueue 同样以其按值定位元素。
+// 未设置时直接以值本身判重，不可哈希的值会被拒绝并返回 ErrElementNotHashable。
+func (c *QueueConfig) WithKeyFunc(fn KeyFunc) *QueueConfig {
+	c.keyFunc = fn
+
+	return c
+}
+
+// WithName 设置队列名称，用于指标标识。
+func (c *QueueConfig) WithName(name string) *QueueConfig {
+	c.name = name
+
+	return c
+}
+
+// WithMetricsProvider 设置指标提供者，设置后会周期刷新处理中耗时类指标。
+// 处理时长与处理中耗时类指标需要逐个记录出队元素，仅在幂等模式下统计；非幂等模式下 Done 是可选的，只统计处理中数量。
+func (c *QueueConfig) WithMetricsProvider(provider MetricsProvider) *QueueConfig {
+	c.metrics = provider
+
+	return c
+}
+
+// WithWAL 设置持久化日志，仅对 Queue、DelayingQueue 以及基于它们构建的队列生效。
+// 元素在入队时写入日志、Done 时确认，创建队列时会恢复日志中尚未确认的元素，
+// 因此开启后元素需可哈希或配置 key 函数，且能被配置的 Codec 编码。
+func (c *QueueConfig) WithWAL(w *WAL) *QueueConfig {
+	c.wal = w
+
+	return c
+}
+
+// WithCodec 设置元素的编解码器，用于持久化日志等需要序列化元素的场景，默认为 gob 编解码器。
+func (c *QueueConfig) WithCodec(codec Codec) *QueueConfig {
+	c.codec = codec
+
+	return c
+}
+
+// WithClock 设置时钟。延迟、定时、租约、重试与优先级老化等时间相关的行为都从该时钟读取时间，
+// 测试中可以传入 FakeClock 并通过 Advance 确定性地推进，默认为系统时钟。
+func (c *QueueConfig) WithClock(clock Clock) *QueueConfig {
+	c.clock = clock
+
+	return c
+}
+
 func isQueueConfigEffective(c *QueueConfig) *QueueConfig {
 	if c != nil {
 		if c.callback == nil {
@@ -64,6 +120,14 @@ func isQueueConfigEffective(c *QueueConfig) *QueueConfig {
 		if c.setCreator == nil {
 			c.setCreator = defaultNewSetFunc
 		}
+
+		if c.codec == nil {
+			c.codec = NewGobCodec()
+		}
+
+		if c.clock == nil {
+			c.clock = NewRealClock()
+		}
 	} else {
 		c = NewQueueConfig()
 	}
@@ -71,10 +135,84 @@ func isQueueConfigEffective(c *QueueConfig) *QueueConfig {
 	return c
 }
 
+// WALConfig 定义持久化日志配置。
+type WALConfig struct {
+	syncPolicy      WALSyncPolicy
+	syncInterval    time.Duration
+	segmentSize     int64
+	compactInterval time.Duration
+	compactRatio    float64
+}
+
+// NewWALConfig 返回带默认值的持久化日志配置。
+func NewWALConfig() *WALConfig {
+	return &WALConfig{
+		syncPolicy:      WAL_SYNC_ALWAYS,
+		syncInterval:    time.Second,
+		segmentSize:     64 << 20,
+		compactInterval: time.Minute,
+		compactRatio:    1,
+	}
+}
+
+// WithSyncPolicy 设置刷盘策略。
+func (c *WALConfig) WithSyncPolicy(policy WALSyncPolicy) *WALConfig {
+	c.syncPolicy = policy
+	return c
+}
+
+// WithSyncInterval 设置 WAL_SYNC_INTERVAL 策略下的刷盘周期。
+func (c *WALConfig) WithSyncInterval(interval time.Duration) *WALConfig {
+	c.syncInterval = interval
+	return c
+}
+
+// WithSegmentSize 设置单个段文件的滚动阈值（字节）。
+func (c *WALConfig) WithSegmentSize(size int64) *WALConfig {
+	c.segmentSize = size
+	return c
+}
+
+// WithCompactInterval 设置后台压缩周期，小于 0 时关闭后台压缩。
+func (c *WALConfig) WithCompactInterval(interval time.Duration) *WALConfig {
+	c.compactInterval = interval
+	return c
+}
+
+// WithCompactRatio 设置后台压缩的触发比例：已确认记录数不少于存活记录数的 ratio 倍时才压缩，默认为 1。
+func (c *WALConfig) WithCompactRatio(ratio float64) *WALConfig {
+	c.compactRatio = ratio
+	return c
+}
+
+func isWALConfigEffective(c *WALConfig) *WALConfig {
+	if c != nil {
+		if c.syncPolicy > WAL_SYNC_NONE {
+			c.syncPolicy = WAL_SYNC_ALWAYS
+		}
+		if c.syncInterval <= 0 {
+			c.syncInterval = time.Second
+		}
+		if c.segmentSize <= 0 {
+			c.segmentSize = 64 << 20
+		}
+		if c.compactInterval == 0 {
+			c.compactInterval = time.Minute
+		}
+		if c.compactRatio <= 0 {
+			c.compactRatio = 1
+		}
+	} else {
+		c = NewWALConfig()
+	}
+	return c
+}
+
 // DelayingQueueConfig 定义延迟队列配置。
 type DelayingQueueConfig struct {
 	QueueConfig
-	callback DelayingQueueCallback
+	callback  DelayingQueueCallback
+	wheelTick time.Duration
 }
 
 // NewDelayingQueueConfig 返回带默认值的延迟队列配置。
@@ -94,6 +232,21 @@ func (c *DelayingQueueConfig) WithCallback(cb DelayingQueueCallback) *DelayingQu
 	return c
 }
 
+// WithTimingWheel 以刻度为 tick 的分层时间轮代替红黑树调度延迟元素，插入与取消为 O(1)，
+// 元素最多晚于到期时间一个 tick，tick 不足 1 毫秒时按 1 毫秒处理。
+func (c *DelayingQueueConfig) WithTimingWheel(tick time.Duration) *DelayingQueueConfig {
+	c.wheelTick = tick
+
+	return c
+}
+
+// WithClock 设置时钟，参见 QueueConfig.WithClock。
+func (c *DelayingQueueConfig) WithClock(clock Clock) *DelayingQueueConfig {
+	c.QueueConfig.WithClock(clock)
+
+	return c
+}
+
 func isDelayingQueueConfigEffective(c *DelayingQueueConfig) *DelayingQueueConfig {
 	if c != nil {
 		if c.callback == nil {
@@ -103,6 +256,10 @@ func isDelayingQueueConfigEffective(c *DelayingQueueConfig) *DelayingQueueConfig
 		if c.QueueConfig.callback == nil {
 			c.QueueConfig.callback = NewNopQueueCallbackImpl()
 		}
+
+		if c.QueueConfig.clock == nil {
+			c.QueueConfig.clock = NewRealClock()
+		}
 	} else {
 		c = NewDelayingQueueConfig()
 	}
@@ -113,7 +270,11 @@ func isDelayingQueueConfigEffective(c *DelayingQueueConfig) *DelayingQueueConfig
 // PriorityQueueConfig 定义优先级队列配置。
 type PriorityQueueConfig struct {
 	QueueConfig
-	callback PriorityQueueCallback
+	callback    PriorityQueueCallback
+	comparator  ComparatorFunc
+	agingStep   int64
+	agingEvery  time.Duration
+	agingLinear bool
 }
 
 // NewPriorityQueueConfig 返回带默认值的优先级队列配置。
@@ -133,15 +294,57 @@ func (c *PriorityQueueConfig) WithCallback(cb PriorityQueueCallback) *PriorityQu
 	return c
 }
 
+// WithComparator 设置优先级相同时的次级排序，例如按截止时间排序，比较结果仍相同的元素按入队先后出队。
+func (c *PriorityQueueConfig) WithComparator(fn ComparatorFunc) *PriorityQueueConfig {
+	c.comparator = fn
+
+	return c
+}
+
+// WithLinearAging 开启线性老化：元素每等待 every，有效优先级提升 step（数值减小 step），按等待时长连续累积。
+func (c *PriorityQueueConfig) WithLinearAging(step int64, every time.Duration) *PriorityQueueConfig {
+	c.agingStep = step
+	c.agingEvery = every
+	c.agingLinear = true
+
+	return c
+}
+
+// WithStepAging 开启阶梯老化：以队列创建时刻为起点每隔 every 为一个边界，
+// 元素每跨过一个边界有效优先级提升 step，因此首次提升可能早于 every。
+func (c *PriorityQueueConfig) WithStepAging(step int64, every time.Duration) *PriorityQueueConfig {
+	c.agingStep = step
+	c.agingEvery = every
+	c.agingLinear = false
+
+	return c
+}
+
+// WithClock 设置时钟，参见 QueueConfig.WithClock。
+func (c *PriorityQueueConfig) WithClock(clock Clock) *PriorityQueueConfig {
+	c.QueueConfig.WithClock(clock)
+
+	return c
+}
+
 func isPriorityQueueConfigEffective(c *PriorityQueueConfig) *PriorityQueueConfig {
 	if c != nil {
 		if c.callback == nil {
 			c.callback = NewNopPriorityQueueCallbackImpl()
 		}
 
+		if c.agingStep <= 0 || c.agingEvery <= 0 {
+			c.agingStep = 0
+			c.agingEvery = 0
+		}
+
 		if c.QueueConfig.callback == nil {
 			c.QueueConfig.callback = NewNopQueueCallbackImpl()
 		}
+
+		if c.QueueConfig.clock == nil {
+			c.QueueConfig.clock = NewRealClock()
+		}
 	} else {
 		c = NewPriorityQueueConfig()
 	}
@@ -149,6 +352,114 @@ func isPriorityQueueConfigEffective(c *PriorityQueueConfig) *PriorityQueueConfig
 	return c
 }
 
+// defaultFlowIdleTimeout 为流清空后保留其指标的默认时长。
+const defaultFlowIdleTimeout = time.Minute
+
+// FairQueueConfig 定义公平队列配置。
+type FairQueueConfig struct {
+	QueueConfig
+	callback    FairQueueCallback
+	flowFunc    FlowFunc
+	weightFunc  FlowWeightFunc
+	costFunc    CostFunc
+	quantum     int
+	capacity    int
+	idleTimeout time.Duration
+}
+
+// NewFairQueueConfig 返回带默认值的公平队列配置。
+func NewFairQueueConfig() *FairQueueConfig {
+	return &FairQueueConfig{
+		QueueConfig: *NewQueueConfig(),
+		callback:    NewNopFairQueueCallbackImpl(),
+		quantum:     1,
+		idleTimeout: defaultFlowIdleTimeout,
+	}
+}
+
+// WithCallback 设置公平队列回调。
+func (c *FairQueueConfig) WithCallback(cb FairQueueCallback) *FairQueueConfig {
+	c.callback = cb
+	c.QueueConfig.callback = cb
+
+	return c
+}
+
+// WithFlowFunc 设置 Put 与 PutBatch 使用的流归属函数，未设置时全部元素归入名称为空的默认流。
+func (c *FairQueueConfig) WithFlowFunc(fn FlowFunc) *FairQueueConfig {
+	c.flowFunc = fn
+
+	return c
+}
+
+// WithWeightFunc 设置流的权重，流首次出现时求值，未设置或结果小于 1 时权重为 1。
+func (c *FairQueueConfig) WithWeightFunc(fn FlowWeightFunc) *FairQueueConfig {
+	c.weightFunc = fn
+
+	return c
+}
+
+// WithCostFunc 设置元素的出队成本，元素入队时求值，未设置或结果小于 1 时成本为 1。
+func (c *FairQueueConfig) WithCostFunc(fn CostFunc) *FairQueueConfig {
+	c.costFunc = fn
+
+	return c
+}
+
+// WithQuantum 设置每轮按权重发放的出队额度，流每轮获得 quantum*weight 的额度，默认为 1。
+// 元素成本普遍较大时调大该值可以减少轮转次数。
+func (c *FairQueueConfig) WithQuantum(quantum int) *FairQueueConfig {
+	c.quantum = quantum
+
+	return c
+}
+
+// WithCapacity 设置容量上限，大于 0 时队列具备 BoundedBlockingQueue 的阻塞语义，默认不限制容量。
+func (c *FairQueueConfig) WithCapacity(capacity int) *FairQueueConfig {
+	c.capacity = capacity
+
+	return c
+}
+
+// WithFlowIdleTimeout 设置流清空后保留的时长，超时后该流及其指标被自动清理。
+func (c *FairQueueConfig) WithFlowIdleTimeout(timeout time.Duration) *FairQueueConfig {
+	c.idleTimeout = timeout
+
+	return c
+}
+
+// WithClock 设置时钟，参见 QueueConfig.WithClock。
+func (c *FairQueueConfig) WithClock(clock Clock) *FairQueueConfig {
+	c.QueueConfig.WithClock(clock)
+
+	return c
+}
+
+func isFairQueueConfigEffective(c *FairQueueConfig) *FairQueueConfig {
+	if c != nil {
+		c.QueueConfig = *isQueueConfigEffective(&c.QueueConfig)
+
+		if c.callback == nil {
+			c.callback = NewNopFairQueueCallbackImpl()
+		}
+		c.QueueConfig.callback = c.callback
+
+		if c.quantum <= 0 {
+			c.quantum = 1
+		}
+		if c.capacity < 0 {
+			c.capacity = 0
+		}
+		if c.idleTimeout <= 0 {
+			c.idleTimeout = defaultFlowIdleTimeout
+		}
+	} else {
+		c = NewFairQueueConfig()
+	}
+
+	return c
+}
+
 // LeasedQueueConfig 定义租约队列配置。
 type LeasedQueueConfig struct {
 	QueueConfig
@@ -177,6 +488,13 @@ func (c *LeasedQueueConfig) WithScanInterval(interval time.Duration) *LeasedQueu
 	return c
 }
 
+// WithClock 设置时钟，参见 QueueConfig.WithClock。
+func (c *LeasedQueueConfig) WithClock(clock Clock) *LeasedQueueConfig {
+	c.QueueConfig.WithClock(clock)
+
+	return c
+}
+
 func isLeasedQueueConfigEffective(c *LeasedQueueConfig) *LeasedQueueConfig {
 	if c != nil {
 		c.QueueConfig = *isQueueConfigEffective(&c.QueueConfig)
@@ -213,6 +531,13 @@ func (c *BoundedBlockingQueueConfig) WithCapacity(capacity int) *BoundedBlocking
 	return c
 }
 
+// WithClock 设置时钟，参见 QueueConfig.WithClock。
+func (c *BoundedBlockingQueueConfig) WithClock(clock Clock) *BoundedBlockingQueueConfig {
+	c.QueueConfig.WithClock(clock)
+
+	return c
+}
+
 func isBoundedBlockingQueueConfigEffective(c *BoundedBlockingQueueConfig) *BoundedBlockingQueueConfig {
 	if c != nil {
 		c.QueueConfig = *isQueueConfigEffective(&c.QueueConfig)
@@ -230,6 +555,7 @@ func isBoundedBlockingQueueConfigEffective(c *BoundedBlockingQueueConfig) *Bound
 // TimerQueueConfig 定义定时队列配置。
 type TimerQueueConfig struct {
 	QueueConfig
+	wheelTick time.Duration
 }
 
 // NewTimerQueueConfig 返回带默认值的定时队列配置。
@@ -239,6 +565,21 @@ func NewTimerQueueConfig() *TimerQueueConfig {
 	}
 }
 
+// WithTimingWheel 以刻度为 tick 的分层时间轮代替红黑树调度定时元素，插入与取消为 O(1)，
+// 元素最多晚于触发时间一个 tick，tick 不足 1 毫秒时按 1 毫秒处理。
+func (c *TimerQueueConfig) WithTimingWheel(tick time.Duration) *TimerQueueConfig {
+	c.wheelTick = tick
+
+	return c
+}
+
+// WithClock 设置时钟，参见 QueueConfig.WithClock。
+func (c *TimerQueueConfig) WithClock(clock Clock) *TimerQueueConfig {
+	c.QueueConfig.WithClock(clock)
+
+	return c
+}
+
 func isTimerQueueConfigEffective(c *TimerQueueConfig) *TimerQueueConfig {
 	if c != nil {
 		c.QueueConfig = *isQueueConfigEffective(&c.QueueConfig)
@@ -248,6 +589,51 @@ func isTimerQueueConfigEffective(c *TimerQueueConfig) *TimerQueueConfig {
 	return c
 }
 
+// RecurringConfig 定义周期调度的生效时间窗口与时区。
+type RecurringConfig struct {
+	startAt  time.Time
+	endAt    time.Time
+	location *time.Location
+}
+
+// NewRecurringConfig 返回带默认值的周期调度配置：立即开始、不设结束时间、使用本地时区。
+func NewRecurringConfig() *RecurringConfig {
+	return &RecurringConfig{location: time.Local}
+}
+
+// WithStartAt 设置最早的触发时间。PutEvery 以该时刻为首次触发并按间隔对齐，PutCron 从该时刻起匹配表达式。
+func (c *RecurringConfig) WithStartAt(at time.Time) *RecurringConfig {
+	c.startAt = at
+
+	return c
+}
+
+// WithEndAt 设置最晚的触发时间，超过后调度自动终止。
+func (c *RecurringConfig) WithEndAt(at time.Time) *RecurringConfig {
+	c.endAt = at
+
+	return c
+}
+
+// WithLocation 设置 cron 表达式使用的时区。
+func (c *RecurringConfig) WithLocation(loc *time.Location) *RecurringConfig {
+	c.location = loc
+
+	return c
+}
+
+func isRecurringConfigEffective(c *RecurringConfig) *RecurringConfig {
+	if c != nil {
+		if c.location == nil {
+			c.location = time.Local
+		}
+	} else {
+		c = NewRecurringConfig()
+	}
+
+	return c
+}
+
 // RetryQueueConfig 定义重试队列配置。
 type RetryQueueConfig struct {
 	DelayingQueueConfig
@@ -286,6 +672,13 @@ func (c *RetryQueueConfig) WithKeyFunc(fn RetryKeyFunc) *RetryQueueConfig {
 	return c
 }
 
+// WithClock 设置时钟，参见 QueueConfig.WithClock。
+func (c *RetryQueueConfig) WithClock(clock Clock) *RetryQueueConfig {
+	c.DelayingQueueConfig.WithClock(clock)
+
+	return c
+}
+
 func isRetryQueueConfigEffective(c *RetryQueueConfig) *RetryQueueConfig {
 	if c != nil {
 		c.DelayingQueueConfig = *isDelayingQueueConfigEffective(&c.DelayingQueueConfig)
@@ -330,6 +723,13 @@ func (c *DeadLetterQueueConfig) WithCallback(cb DeadLetterQueueCallback) *DeadLe
 	return c
 }
 
+// WithClock 设置时钟，参见 QueueConfig.WithClock。
+func (c *DeadLetterQueueConfig) WithClock(clock Clock) *DeadLetterQueueConfig {
+	c.QueueConfig.WithClock(clock)
+
+	return c
+}
+
 func isDeadLetterQueueConfigEffective(c *DeadLetterQueueConfig) *DeadLetterQueueConfig {
 	if c != nil {
 		c.QueueConfig = *isQueueConfigEffective(&c.QueueConfig)
@@ -379,6 +779,13 @@ func (c *RateLimitingQueueConfig) WithLimiter(limiter Limiter) *RateLimitingQueu
 	return c
 }
 
+// WithClock 设置时钟，参见 QueueConfig.WithClock。未显式指定时钟的 NewBucketRateLimiterImpl 限流器同样使用该时钟。
+func (c *RateLimitingQueueConfig) WithClock(clock Clock) *RateLimitingQueueConfig {
+	c.DelayingQueueConfig.WithClock(clock)
+
+	return c
+}
+
 func isRateLimitingQueueConfigEffective(c *RateLimitingQueueConfig) *RateLimitingQueueConfig {
 	if c != nil {
 
@@ -400,3 +807,84 @@ func isRateLimitingQueueConfigEffective(c *RateLimitingQueueConfig) *RateLimitin
 	}
 	return c
 }
+
+// RunnerConfig 定义 Runner 配置。
+type RunnerConfig struct {
+	callback      RunnerCallback
+	workers       int
+	timeout       time.Duration
+	leaseDuration time.Duration
+	deadLetter    DeadLetterQueue
+	source        string
+	clock         Clock
+}
+
+// NewRunnerConfig 返回带默认值的 Runner 配置。
+func NewRunnerConfig() *RunnerConfig {
+	return &RunnerConfig{
+		callback: NewNopRunnerCallbackImpl(),
+		workers:  runtime.GOMAXPROCS(0),
+	}
+}
+
+// WithCallback 设置 Runner 回调。
+func (c *RunnerConfig) WithCallback(cb RunnerCallback) *RunnerConfig {
+	c.callback = cb
+	return c
+}
+
+// WithWorkers 设置并发工作协程数量。
+func (c *RunnerConfig) WithWorkers(workers int) *RunnerConfig {
+	c.workers = workers
+	return c
+}
+
+// WithTimeout 设置单个元素的处理超时，0 表示不限制。超时通过 ctx 传给 Handler，Runner 等待 Handler 返回后再按失败处理。
+func (c *RunnerConfig) WithTimeout(timeout time.Duration) *RunnerConfig {
+	c.timeout = timeout
+	return c
+}
+
+// WithLeaseDuration 设置消费 LeasedQueue 时的租约时长，0 表示使用队列默认值。
+func (c *RunnerConfig) WithLeaseDuration(duration time.Duration) *RunnerConfig {
+	c.leaseDuration = duration
+	return c
+}
+
+// WithDeadLetterQueue 设置失败终态元素投递的死信队列。
+func (c *RunnerConfig) WithDeadLetterQueue(dlq DeadLetterQueue) *RunnerConfig {
+	c.deadLetter = dlq
+	return c
+}
+
+// WithSourceName 设置写入死信 SourceQueue 字段的来源队列名称。
+func (c *RunnerConfig) WithSourceName(name string) *RunnerConfig {
+	c.source = name
+	return c
+}
+
+// WithClock 设置计算处理耗时的时钟，未设置时使用被驱动队列配置的时钟。
+func (c *RunnerConfig) WithClock(clock Clock) *RunnerConfig {
+	c.clock = clock
+	return c
+}
+
+func isRunnerConfigEffective(c *RunnerConfig) *RunnerConfig {
+	if c != nil {
+		if c.callback == nil {
+			c.callback = NewNopRunnerCallbackImpl()
+		}
+		if c.workers <= 0 {
+			c.workers = runtime.GOMAXPROCS(0)
+		}
+		if c.timeout < 0 {
+			c.timeout = 0
+		}
+		if c.leaseDuration < 0 {
+			c.leaseDuration = 0
+		}
+	} else {
+		c = NewRunnerConfig()
+	}
+	return c
+}
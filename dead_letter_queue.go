@@ -1,25 +1,43 @@
 package workqueue
 
 import (
+	"context"
+	"errors"
+	"io"
 	"strconv"
+	"sync"
 	"sync/atomic"
-	"time"
 )
 
 type deadLetterQueueImpl struct {
 	Queue
 	config *DeadLetterQueueConfig
 	seed   atomic.Uint64
+
+	// taken 记录已被取出尚未确认的死信编号，AckDead 据此区分处理中与仍在队列中的死信。
+	lock  sync.Mutex
+	taken map[string]int
 }
 
 // NewDeadLetterQueue 创建死信队列。
 func NewDeadLetterQueue(config *DeadLetterQueueConfig) DeadLetterQueue {
 	config = isDeadLetterQueueConfigEffective(config)
 
-	return &deadLetterQueueImpl{
+	q := &deadLetterQueueImpl{
 		Queue:  NewQueue(&config.QueueConfig),
 		config: config,
+		taken:  make(map[string]int),
 	}
+
+	// 从 WAL 恢复的死信已有编号，序列需越过这些编号以免重复。
+	q.Queue.Range(func(value interface{}) bool {
+		if letter, ok := toDeadLetter(value); ok {
+			q.observeID(letter.ID)
+		}
+		return true
+	})
+
+	return q
 }
 
 func (q *deadLetterQueueImpl) Put(value interface{}) error {
@@ -31,10 +49,81 @@ func (q *deadLetterQueueImpl) Put(value interface{}) error {
 	return q.PutDead(letter)
 }
 
+func (q *deadLetterQueueImpl) PutBatch(values []interface{}) error {
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+
+	errs := make([]error, len(values))
+	letters := make([]interface{}, 0, len(values))
+	index := make([]int, 0, len(values))
+	for i, value := range values {
+		letter, ok := toDeadLetter(value)
+		if !ok {
+			errs[i] = ErrInvalidDeadLetter
+			continue
+		}
+		if letter.Payload == nil {
+			errs[i] = ErrElementIsNil
+			continue
+		}
+
+		letters = append(letters, q.normalize(letter))
+		index = append(index, i)
+	}
+
+	if err := q.Queue.PutBatch(letters); err != nil {
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) {
+			return err
+		}
+		// 将底层批量结果映射回原始输入下标。
+		for j, e := range batchErr.Errors {
+			errs[index[j]] = e
+		}
+	}
+
+	for j, letter := range letters {
+		if errs[index[j]] == nil {
+			q.config.callback.OnDead(letter.(*DeadLetter))
+		}
+	}
+
+	return newBatchError(errs)
+}
+
 func (q *deadLetterQueueImpl) Get() (interface{}, error) {
 	return q.GetDead()
 }
 
+func (q *deadLetterQueueImpl) GetWithContext(ctx context.Context) (interface{}, error) {
+	return q.GetDeadWithContext(ctx)
+}
+
+func (q *deadLetterQueueImpl) GetBlocking() (interface{}, error) {
+	return q.GetDeadWithContext(context.Background())
+}
+
+func (q *deadLetterQueueImpl) GetBatch(max int) ([]interface{}, error) {
+	values, err := q.Queue.GetBatch(max)
+	if err != nil {
+		return nil, err
+	}
+
+	q.take(values...)
+	return values, nil
+}
+
+func (q *deadLetterQueueImpl) GetBatchWithContext(ctx context.Context, max int) ([]interface{}, error) {
+	values, err := q.Queue.GetBatchWithContext(ctx, max)
+	if err != nil {
+		return nil, err
+	}
+
+	q.take(values...)
+	return values, nil
+}
+
 func (q *deadLetterQueueImpl) Done(value interface{}) {
 	letter, ok := toDeadLetter(value)
 	if !ok {
@@ -75,6 +164,23 @@ func (q *deadLetterQueueImpl) GetDead() (*DeadLetter, error) {
 		return nil, ErrInvalidDeadLetter
 	}
 
+	q.take(letter)
+	return letter, nil
+}
+
+// GetDeadWithContext 阻塞等待下一封死信，直至 ctx 结束或队列关闭。
+func (q *deadLetterQueueImpl) GetDeadWithContext(ctx context.Context) (*DeadLetter, error) {
+	value, err := q.Queue.GetWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	letter, ok := toDeadLetter(value)
+	if !ok {
+		return nil, ErrInvalidDeadLetter
+	}
+
+	q.take(letter)
 	return letter, nil
 }
 
@@ -83,7 +189,13 @@ func (q *deadLetterQueueImpl) AckDead(letter *DeadLetter) error {
 		return ErrInvalidDeadLetter
 	}
 
-	q.Queue.Done(letter)
+	// 仍在队列中的死信（例如通过 RangeDead 找到的）直接移除，已取出的死信按处理完成结束。
+	if q.release(letter) || !q.base().remove(func(value interface{}) bool {
+		queued, ok := toDeadLetter(value)
+		return ok && (queued == letter || (letter.ID != "" && queued.ID == letter.ID))
+	}) {
+		q.Queue.Done(letter)
+	}
 	q.config.callback.OnAckDead(letter)
 	return nil
 }
@@ -122,12 +234,79 @@ func (q *deadLetterQueueImpl) RangeDead(fn func(letter *DeadLetter) bool) {
 	})
 }
 
+// Restore 恢复快照中的死信，保留原有编号与失败信息。
+func (q *deadLetterQueueImpl) Restore(r io.Reader) error {
+	s, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+	values, err := decodeItems(q.config.codec, s.readyItems(true))
+	if err != nil {
+		return err
+	}
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+
+	errs := make([]error, len(values))
+	for i, value := range values {
+		letter, ok := toDeadLetter(value)
+		if !ok {
+			errs[i] = ErrInvalidDeadLetter
+			continue
+		}
+		q.observeID(letter.ID)
+		errs[i] = q.PutDead(letter)
+	}
+	return newBatchError(errs)
+}
+
+// take 登记已被取出的死信。
+func (q *deadLetterQueueImpl) take(values ...interface{}) {
+	q.lock.Lock()
+	for _, value := range values {
+		if letter, ok := toDeadLetter(value); ok {
+			q.taken[letter.ID]++
+		}
+	}
+	q.lock.Unlock()
+}
+
+// release 撤销一次取出登记，返回死信是否处于已取出状态。
+func (q *deadLetterQueueImpl) release(letter *DeadLetter) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	count, ok := q.taken[letter.ID]
+	if !ok {
+		return false
+	}
+	if count > 1 {
+		q.taken[letter.ID] = count - 1
+	} else {
+		delete(q.taken, letter.ID)
+	}
+	return true
+}
+
+// untake 撤销死信的取出登记并结束其在基础队列中的处理，供 Requeue 放回未确认的死信，不触发 OnAckDead。
+func (q *deadLetterQueueImpl) untake(value interface{}) {
+	if letter, ok := toDeadLetter(value); ok {
+		q.release(letter)
+	}
+	q.Queue.Done(value)
+}
+
+func (q *deadLetterQueueImpl) base() *queueImpl {
+	return q.Queue.(*queueImpl)
+}
+
 func (q *deadLetterQueueImpl) normalize(letter *DeadLetter) *DeadLetter {
 	if letter.ID == "" {
 		letter.ID = q.nextID()
 	}
 	if letter.FailedAt.IsZero() {
-		letter.FailedAt = time.Now()
+		letter.FailedAt = q.config.clock.Now()
 	}
 	return letter
 }
@@ -141,6 +320,20 @@ func (q *deadLetterQueueImpl) nextID() string {
 	return string(buf)
 }
 
+// observeID 使序列越过已存在的编号，避免生成重复的死信编号。
+func (q *deadLetterQueueImpl) observeID(id string) {
+	seq, err := strconv.ParseUint(id, 36, 64)
+	if err != nil {
+		return
+	}
+	for {
+		current := q.seed.Load()
+		if seq <= current || q.seed.CompareAndSwap(current, seq) {
+			return
+		}
+	}
+}
+
 func toDeadLetter(value interface{}) (*DeadLetter, bool) {
 	switch v := value.(type) {
 	case *DeadLetter:
@@ -1,6 +1,8 @@
 package workqueue
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -108,6 +110,25 @@ func TestDeadLetterQueue_RangeDead(t *testing.T) {
 	assert.ElementsMatch(t, []interface{}{"a", "b"}, got)
 }
 
+func TestDeadLetterQueue_AckDead_Queued(t *testing.T) {
+	dlq := NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+
+	assert.NoError(t, dlq.PutDead(&DeadLetter{ID: "dlq-1", Payload: "a"}))
+	assert.NoError(t, dlq.PutDead(&DeadLetter{ID: "dlq-2", Payload: "b"}))
+
+	// 尚未被取出的死信可以直接按 ID 确认，确认后从队列中移除。
+	assert.NoError(t, dlq.AckDead(&DeadLetter{ID: "dlq-1"}))
+	assert.Equal(t, 1, dlq.Len())
+
+	letter, err := dlq.GetDead()
+	assert.NoError(t, err)
+	assert.Equal(t, "dlq-2", letter.ID)
+	assert.NoError(t, dlq.AckDead(letter))
+	assert.Equal(t, 0, dlq.Len())
+	assert.Equal(t, 0, dlq.Stats().InFlight)
+}
+
 type testDeadLetterQueueCallback struct {
 	mu sync.Mutex
 
@@ -165,3 +186,103 @@ func TestDeadLetterQueue_Callback(t *testing.T) {
 	assert.Equal(t, []string{"dlq-cb-1"}, callback.acks)
 	assert.Equal(t, []string{"dlq-cb-1"}, callback.requeues)
 }
+
+func TestDeadLetterQueue_GetDeadWithContext(t *testing.T) {
+	dlq := NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+
+	result := make(chan *DeadLetter, 1)
+	go func() {
+		letter, err := dlq.GetDeadWithContext(context.Background())
+		assert.NoError(t, err)
+		result <- letter
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, dlq.PutDead(&DeadLetter{Payload: "payload"}))
+
+	select {
+	case letter := <-result:
+		assert.Equal(t, "payload", letter.Payload)
+	case <-time.After(time.Second):
+		t.Fatal("GetDeadWithContext should be woken by PutDead")
+	}
+
+	dlq.Shutdown()
+	_, err := dlq.GetBlocking()
+	assert.ErrorIs(t, err, ErrQueueIsClosed)
+}
+
+func TestDeadLetterQueue_PutBatch(t *testing.T) {
+	callback := &testDeadLetterQueueCallback{}
+	dlq := NewDeadLetterQueue(NewDeadLetterQueueConfig().WithCallback(callback))
+	defer dlq.Shutdown()
+
+	err := dlq.PutBatch([]interface{}{
+		&DeadLetter{ID: "dlq-batch-1", Payload: "a"},
+		"not-a-letter",
+		&DeadLetter{ID: "dlq-batch-2"},
+		DeadLetter{ID: "dlq-batch-3", Payload: "c"},
+	})
+
+	var batchErr *BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.Nil(t, batchErr.Errors[0])
+	assert.ErrorIs(t, batchErr.Errors[1], ErrInvalidDeadLetter)
+	assert.ErrorIs(t, batchErr.Errors[2], ErrElementIsNil)
+	assert.Nil(t, batchErr.Errors[3])
+
+	values, err := dlq.GetBatch(10)
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+	assert.Equal(t, "dlq-batch-3", values[1].(*DeadLetter).ID)
+
+	callback.mu.Lock()
+	defer callback.mu.Unlock()
+	assert.Equal(t, []string{"dlq-batch-1", "dlq-batch-3"}, callback.deads)
+}
+
+func TestDeadLetterQueue_Purge(t *testing.T) {
+	callback := &testDeadLetterQueueCallback{}
+	dlq := NewDeadLetterQueue(NewDeadLetterQueueConfig().WithCallback(callback))
+	defer dlq.Shutdown()
+
+	assert.NoError(t, dlq.PutDead(&DeadLetter{Payload: "a"}))
+	assert.NoError(t, dlq.PutDead(&DeadLetter{Payload: "b"}))
+
+	purged, err := Purge(dlq)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.Equal(t, 0, dlq.Len())
+	assert.Empty(t, callback.acks, "Purge should not acknowledge dead letters")
+	assert.Equal(t, 0, dlq.Stats().InFlight)
+}
+
+func TestDeadLetterQueue_Requeue(t *testing.T) {
+	w := openTestWAL(t, t.TempDir())
+	defer w.Close()
+	callback := &testDeadLetterQueueCallback{}
+	config := NewDeadLetterQueueConfig().WithCallback(callback)
+	config.WithWAL(w)
+	dlq := NewDeadLetterQueue(config)
+	defer dlq.Shutdown()
+
+	assert.NoError(t, dlq.PutDead(&DeadLetter{ID: "dlq-requeue-1", Payload: "a"}))
+	letter, err := dlq.GetDead()
+	assert.NoError(t, err)
+
+	// 放回未确认的死信不是确认，不触发 OnAckDead，日志中的记录仍然存活。
+	assert.NoError(t, Requeue(dlq, letter))
+	assert.Empty(t, callback.acks, "Requeue should not acknowledge the dead letter")
+	assert.Equal(t, 1, dlq.Len())
+	assert.Equal(t, 0, dlq.Stats().InFlight)
+	assert.Equal(t, 1, w.Len())
+
+	letter, err = dlq.GetDead()
+	assert.NoError(t, err)
+	assert.Equal(t, "dlq-requeue-1", letter.ID)
+	assert.NoError(t, dlq.AckDead(letter))
+	assert.Equal(t, []string{"dlq-requeue-1"}, callback.acks)
+	assert.Equal(t, 0, dlq.Stats().InFlight)
+	assert.Equal(t, 0, w.Len())
+}
@@ -1,63 +1,117 @@
 package workqueue
 
 import (
+	"context"
+	"errors"
+	"io"
 	"sync"
 	"time"
 
-	hp "github.com/shengyanli1982/workqueue/v2/internal/container/heap"
 	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
 )
 
-// toDelay 将相对毫秒延迟转换为绝对 Unix 毫秒时间戳。
-func toDelay(duration int64) int64 {
-	return time.Now().Add(time.Millisecond * time.Duration(duration)).UnixMilli()
-}
-
-// delayingQueueImpl 通过排序树维护尚未到期的元素。
+// delayingQueueImpl 通过排序树或时间轮维护尚未到期的元素。
 type delayingQueueImpl struct {
 	Queue
 	config      *DelayingQueueConfig
-	sorting     *hp.RBTree
+	sorting     schedule
 	elementpool *lst.NodePool
 	lock        sync.Mutex
 	once        sync.Once
-	wg          sync.WaitGroup
+	scheduler   *scheduler
 	closed      bool
+	transit     transit
+
+	// wakeAt 为调度协程计划醒来的时刻（Unix 毫秒），更早到期的元素入队时才需要唤醒它。
+	wakeAt int64
 }
 
-// NewDelayingQueue 创建延迟队列并启动搬运协程。
+// NewDelayingQueue 创建延迟队列并启动调度协程，元素在到期时刻被精确地搬运到就绪队列。
 func NewDelayingQueue(config *DelayingQueueConfig) DelayingQueue {
 	config = isDelayingQueueConfigEffective(config)
 	q := &delayingQueueImpl{
 		config:      config,
-		sorting:     hp.New(),
+		sorting:     newSchedule(config.wheelTick, config.clock.Now()),
 		elementpool: lst.NewNodePool(),
 		once:        sync.Once{},
-		wg:          sync.WaitGroup{},
+		scheduler:   newScheduler(config.clock),
+	}
+	q.transit.init(&q.lock)
+
+	base := newQueue(&wrapInternalList{List: lst.New()}, q.elementpool, &config.QueueConfig)
+	q.Queue = base
+
+	// 延迟元素以到期时间写入日志，恢复时未到期的元素重新进入延迟树。
+	if config.wal != nil {
+		base.journal = newJournal(config.wal, base.config.codec, base.metrics)
+		base.replay(q.schedule)
 	}
 
-	q.Queue = newQueue(&wrapInternalList{List: lst.New()}, q.elementpool, &config.QueueConfig)
-	q.wg.Add(1)
-	go q.puller()
+	q.scheduler.start(q.fire)
 	return q
 }
 
 func (q *delayingQueueImpl) Shutdown() {
-	q.Queue.Shutdown()
+	q.shutdown(false)
+}
+
+// ShutdownWithDrain 在基础队列排空语义之上，额外等待延迟树中的元素全部到期并被消费。
+// ctx 先结束时，尚未到期的元素与基础队列中的剩余元素一并返回。
+func (q *delayingQueueImpl) ShutdownWithDrain(ctx context.Context) ([]interface{}, error) {
+	if err := q.base().drain(ctx, q.scheduledEmpty); err != nil {
+		if errors.Is(err, ErrQueueIsClosed) {
+			return nil, err
+		}
+		return q.shutdown(true), err
+	}
+
+	q.shutdown(false)
+	return nil, nil
+}
+
+// shutdown 先关闭延迟树并等待调度协程退出，再关闭基础队列，保证搬运途中的元素不会丢失。
+func (q *delayingQueueImpl) shutdown(collect bool) (pending []interface{}) {
+	var scheduled []interface{}
+
 	q.once.Do(func() {
 		q.lock.Lock()
 		q.closed = true
+		var nodes []*lst.Node
 		q.sorting.Range(func(node *lst.Node) bool {
-			q.elementpool.Put(node)
+			if collect {
+				scheduled = append(scheduled, node.Value)
+			}
+			nodes = append(nodes, node)
 			return true
 		})
 		q.sorting.Cleanup()
+		for _, node := range nodes {
+			q.elementpool.Put(node)
+		}
 		q.lock.Unlock()
-		q.wg.Wait()
+		q.scheduler.stop()
 	})
+
+	pending = q.base().shutdown(collect)
+	return append(pending, scheduled...)
 }
 
 func (q *delayingQueueImpl) PutWithDelay(value interface{}, delay int64) error {
+	return q.putWithDelay(value, delay, false)
+}
+
+// putWithDelay 延迟入队，requeue 为真时表示内部重新入队，排空期间仍然允许。
+func (q *delayingQueueImpl) putWithDelay(value interface{}, delay int64, requeue bool) error {
+	return q.putAt(value, q.toDelay(delay), delay, requeue)
+}
+
+// toDelay 将相对毫秒延迟转换为绝对 Unix 毫秒时间戳。
+func (q *delayingQueueImpl) toDelay(duration int64) int64 {
+	return q.config.clock.Now().Add(time.Millisecond * time.Duration(duration)).UnixMilli()
+}
+
+// putAt 以绝对到期时间（Unix 毫秒）入队，delay 仅用于回调。
+func (q *delayingQueueImpl) putAt(value interface{}, due, delay int64, requeue bool) error {
 
 	if q.IsClosed() {
 		return ErrQueueIsClosed
@@ -66,43 +120,180 @@ func (q *delayingQueueImpl) PutWithDelay(value interface{}, delay int64) error {
 		return ErrElementIsNil
 	}
 
+	base := q.base()
+
+	var key interface{}
+	var id uint64
+	if base.journal != nil {
+		var err error
+		if key, err = base.keyOf(value); err != nil {
+			return err
+		}
+		if id, err = base.journal.append(value, due); err != nil {
+			return err
+		}
+	}
+
 	last := q.elementpool.Get()
 	last.Value = value
-	last.Priority = toDelay(delay)
+	last.Priority = due
 
+	var err error
+	var shouldWake bool
 	q.lock.Lock()
-	q.sorting.Push(last)
+	switch {
+	case q.closed:
+		err = ErrQueueIsClosed
+	case !requeue && base.isDraining():
+		err = ErrQueueIsDraining
+	default:
+		q.sorting.Push(last)
+		shouldWake = due < q.wakeAt
+		if id > 0 {
+			base.commit(key, id)
+		}
+	}
 	q.lock.Unlock()
 
+	if shouldWake {
+		q.scheduler.notify()
+	}
+
+	if err != nil {
+		q.elementpool.Put(last)
+		if id > 0 {
+			base.journal.ack(id)
+		}
+		return err
+	}
+
 	q.config.callback.OnDelay(value, delay)
 	return nil
 }
 
-func (q *delayingQueueImpl) puller() {
-	// 心跳轮询用于从延迟树中搬运已到期元素。
-	heartbeat := time.NewTicker(time.Millisecond * 300)
-	defer func() {
-		heartbeat.Stop()
-		q.wg.Done()
-	}()
+// schedule 将恢复的未到期元素放回延迟树。
+func (q *delayingQueueImpl) schedule(value interface{}, due int64) {
+	node := q.elementpool.Get()
+	node.Value = value
+	node.Priority = due
 
-	for !q.IsClosed() {
-		q.lock.Lock()
+	q.lock.Lock()
+	q.sorting.Push(node)
+	q.lock.Unlock()
+	q.scheduler.notify()
+}
 
-		if q.sorting.Len() > 0 && q.sorting.Front().Priority <= time.Now().UnixMilli() {
-			top := q.sorting.Pop()
-			value := top.Value
-			q.lock.Unlock()
+// fire 将一个已到期的元素搬运到基础队列，否则返回距离下一次需要检查的时长。
+func (q *delayingQueueImpl) fire() (bool, time.Duration) {
+	q.lock.Lock()
+	if q.closed {
+		q.lock.Unlock()
+		return false, 0
+	}
 
-			q.elementpool.Put(top)
-			if err := q.Queue.Put(value); err != nil {
-				q.config.callback.OnPullError(value, err)
-			}
+	top, wait := popExpired(q.sorting, &q.wakeAt, q.config.clock.Now().UnixMilli())
+	if top == nil {
+		q.lock.Unlock()
+		return false, wait
+	}
+
+	value := top.Value
+	// 搬运途中元素不在任何容器内，先登记为处理中，避免排空提前结束或快照遗漏。
+	q.base().retain()
+	q.transit.beginLocked(1)
+	q.lock.Unlock()
+
+	q.elementpool.Put(top)
+	if err := q.base().transfer(value); err != nil {
+		q.config.callback.OnPullError(value, err)
+	}
+	q.base().release()
+
+	q.lock.Lock()
+	q.transit.endLocked(1)
+	q.lock.Unlock()
+	return true, 0
+}
+
+// Snapshot 在基础队列快照之上额外保存延迟树中的元素及其绝对到期时间。
+func (q *delayingQueueImpl) Snapshot(w io.Writer) error {
+	s, err := q.snapshot()
+	if err != nil {
+		return err
+	}
+	return writeSnapshot(w, s)
+}
+
+// Restore 恢复快照，延迟元素按原到期时间重新进入延迟树，已过期的元素立即可用。
+func (q *delayingQueueImpl) Restore(r io.Reader) error {
+	s, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+	return q.restore(s)
+}
+
+func (q *delayingQueueImpl) snapshot() (*queueSnapshot, error) {
+	s := &queueSnapshot{}
+	base := q.base()
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.transit.waitLocked()
+	if q.closed {
+		return nil, ErrQueueIsClosed
+	}
+
+	base.lock.Lock()
+	err := base.snapshotLocked(s, nil)
+	base.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	q.sorting.Range(func(node *lst.Node) bool {
+		var item snapshotItem
+		if item, err = encodeItem(base.config.codec, node.Value, node.Priority, 0); err != nil {
+			return false
+		}
+		s.Scheduled = append(s.Scheduled, item)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (q *delayingQueueImpl) restore(s *queueSnapshot) error {
+	base := q.base()
+	codec := base.config.codec
+
+	ready, err := decodeItems(codec, s.readyItems(false))
+	if err != nil {
+		return err
+	}
+	scheduled, err := decodeItems(codec, s.Scheduled)
+	if err != nil {
+		return err
+	}
+
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+
+	errs := base.restoreReady(ready)
+	now := q.config.clock.Now().UnixMilli()
+	for i, value := range scheduled {
+		due := s.Scheduled[i].Time
+		if due <= now {
+			errs = append(errs, base.Put(value))
 			continue
 		}
-		q.lock.Unlock()
-		<-heartbeat.C
+		errs = append(errs, q.putAt(value, due, due-now, false))
 	}
+	return newBatchError(errs)
 }
 
 func (q *delayingQueueImpl) HeapRange(fn func(value interface{}, delay int64) bool) {
@@ -115,7 +306,19 @@ func (q *delayingQueueImpl) HeapRange(fn func(value interface{}, delay int64) bo
 
 func (q *delayingQueueImpl) Len() int {
 	q.lock.Lock()
-	count := int(q.sorting.Len() + q.Queue.(*queueImpl).list.Len())
+	count := int(q.sorting.Len() + q.base().list.Len())
 	q.lock.Unlock()
 	return count
 }
+
+// scheduledEmpty 报告延迟树是否已清空，供排空检查使用。
+func (q *delayingQueueImpl) scheduledEmpty() bool {
+	q.lock.Lock()
+	empty := q.sorting.Len() == 0
+	q.lock.Unlock()
+	return empty
+}
+
+func (q *delayingQueueImpl) base() *queueImpl {
+	return q.Queue.(*queueImpl)
+}
@@ -1,6 +1,9 @@
 package workqueue
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func BenchmarkDelayingQueue_Put(b *testing.B) {
 	q := NewDelayingQueue(nil)
@@ -57,3 +60,61 @@ func BenchmarkDelayingQueue_PutWithDelayAndGet(b *testing.B) {
 		_, _ = q.Get()
 	}
 }
+
+// BenchmarkDelayingQueue_FireLatency 统计元素从到期到可被消费的延迟，late-ms/op 越接近 0 越精确。
+func BenchmarkDelayingQueue_FireLatency(b *testing.B) {
+	benchmarkFireLatency(b, 1)
+}
+
+func BenchmarkDelayingQueue_FireLatency10ms(b *testing.B) {
+	benchmarkFireLatency(b, 10)
+}
+
+func benchmarkFireLatency(b *testing.B, delay int64) {
+	q := NewDelayingQueue(nil)
+	defer q.Shutdown()
+	b.ResetTimer()
+
+	var late, worst time.Duration
+	for i := 0; i < b.N; i++ {
+		due := time.Now().Add(time.Duration(delay) * time.Millisecond)
+		_ = q.PutWithDelay(i, delay)
+		v, _ := q.GetBlocking()
+		q.Done(v)
+
+		if d := time.Since(due); d > 0 {
+			late += d
+			if d > worst {
+				worst = d
+			}
+		}
+	}
+
+	b.ReportMetric(float64(late.Microseconds())/1000/float64(b.N), "late-ms/op")
+	b.ReportMetric(float64(worst.Microseconds())/1000, "worst-late-ms")
+}
+
+// BenchmarkDelayingQueue_FireLatencyLoaded 在大量待调度元素下统计到期元素的平均延迟。
+func BenchmarkDelayingQueue_FireLatencyLoaded(b *testing.B) {
+	q := NewDelayingQueue(nil)
+	defer q.Shutdown()
+
+	// 远期元素使延迟树保持一定规模。
+	for i := 0; i < 10000; i++ {
+		_ = q.PutWithDelay(-i-1, int64(time.Hour/time.Millisecond))
+	}
+	b.ResetTimer()
+
+	var late time.Duration
+	for i := 0; i < b.N; i++ {
+		due := time.Now().Add(2 * time.Millisecond)
+		_ = q.PutWithDelay(i, 2)
+		v, _ := q.GetBlocking()
+		q.Done(v)
+		if d := time.Since(due); d > 0 {
+			late += d
+		}
+	}
+
+	b.ReportMetric(float64(late.Microseconds())/1000/float64(b.N), "late-ms/op")
+}
@@ -1,6 +1,7 @@
 package workqueue
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
@@ -276,3 +277,148 @@ func TestDelayingQueueImpl_ConcurrentShutdown(t *testing.T) {
 	err := q.PutWithDelay("after-shutdown", DELAYDUCRATION)
 	assert.ErrorIs(t, err, ErrQueueIsClosed, "Put after shutdown should return ErrQueueIsClosed")
 }
+
+func TestDelayingQueueImpl_GetWithContext(t *testing.T) {
+	q := NewDelayingQueue(nil)
+	defer q.Shutdown()
+
+	start := time.Now()
+	err := q.PutWithDelay("delayed", DELAYDUCRATION)
+	assert.NoError(t, err, "Put should not return an error")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	v, err := q.GetWithContext(ctx)
+	assert.NoError(t, err, "GetWithContext should not return an error")
+	assert.Equal(t, "delayed", v, "GetWithContext value should be delayed")
+	assert.True(t, time.Since(start) >= time.Duration(DELAYDUCRATION)*time.Millisecond, "Value should not be delivered before its delay")
+}
+
+func TestDelayingQueueImpl_Idempotent_PutWithDelayWhileProcessing(t *testing.T) {
+	config := NewDelayingQueueConfig()
+	config.WithValueIdempotent()
+	q := NewDelayingQueue(config)
+	defer q.Shutdown()
+
+	err := q.Put("test1")
+	assert.NoError(t, err, "Put should not return an error")
+
+	v, err := q.Get()
+	assert.NoError(t, err, "Get should not return an error")
+
+	err = q.PutWithDelay("test1", 10)
+	assert.NoError(t, err, "PutWithDelay should not return an error")
+
+	time.Sleep(time.Second)
+	assert.Equal(t, 0, q.Len(), "Delayed value should stay dirty while processing")
+
+	q.Done(v)
+	assert.Equal(t, 1, q.Len(), "Done should re-enqueue the dirty value")
+}
+
+func TestDelayingQueueImpl_ShutdownWithDrain(t *testing.T) {
+	q := NewDelayingQueue(nil)
+
+	assert.NoError(t, q.PutWithDelay("delayed", 50))
+
+	consumed := make(chan interface{}, 1)
+	go func() {
+		v, err := q.GetBlocking()
+		if err == nil {
+			q.Done(v)
+			consumed <- v
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	pending, err := q.ShutdownWithDrain(ctx)
+	assert.NoError(t, err, "ShutdownWithDrain should wait for scheduled values")
+	assert.Empty(t, pending)
+	assert.Equal(t, "delayed", <-consumed, "Scheduled value should be delivered before shutdown")
+
+	err = q.PutWithDelay("late", 0)
+	assert.ErrorIs(t, err, ErrQueueIsClosed)
+}
+
+func TestDelayingQueueImpl_ShutdownWithDrain_Timeout(t *testing.T) {
+	q := NewDelayingQueue(nil)
+
+	assert.NoError(t, q.Put("ready"))
+	assert.NoError(t, q.PutWithDelay("scheduled", 60*60*1000))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	pending, err := q.ShutdownWithDrain(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []interface{}{"ready", "scheduled"}, pending, "Pending should contain queued and scheduled values")
+}
+
+func TestDelayingQueueImpl_PreciseFire(t *testing.T) {
+	q := NewDelayingQueue(nil)
+
+	// 先放入远期元素，之后插入的更早元素应唤醒调度协程并按时到期。
+	assert.NoError(t, q.PutWithDelay("later", 60*60*1000))
+	start := time.Now()
+	assert.NoError(t, q.PutWithDelay("soon", 20))
+
+	v, err := q.GetBlocking()
+	assert.NoError(t, err)
+	assert.Equal(t, "soon", v)
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 19*time.Millisecond)
+	assert.Less(t, elapsed, 100*time.Millisecond, "Value should fire close to its deadline")
+	q.Done(v)
+
+	// 关闭不必等待调度周期。
+	start = time.Now()
+	q.Shutdown()
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestDelayingQueueImpl_TimingWheel(t *testing.T) {
+	q := NewDelayingQueue(NewDelayingQueueConfig().WithTimingWheel(5 * time.Millisecond))
+	defer q.Shutdown()
+
+	start := time.Now()
+	assert.NoError(t, q.PutWithDelay("later", 60*60*1000))
+	assert.NoError(t, q.PutWithDelay("second", 40))
+	assert.NoError(t, q.PutWithDelay("first", 20))
+
+	var values []interface{}
+	for i := 0; i < 2; i++ {
+		v, err := q.GetBlocking()
+		assert.NoError(t, err)
+		values = append(values, v)
+		q.Done(v)
+	}
+	elapsed := time.Since(start)
+	assert.Equal(t, []interface{}{"first", "second"}, values, "Values should fire in deadline order")
+	assert.GreaterOrEqual(t, elapsed, 39*time.Millisecond)
+	assert.Less(t, elapsed, 150*time.Millisecond, "Value should fire within a tick of its deadline")
+
+	count := 0
+	q.HeapRange(func(value interface{}, _ int64) bool {
+		assert.Equal(t, "later", value)
+		count++
+		return true
+	})
+	assert.Equal(t, 1, count, "Only the far value should remain scheduled")
+}
+
+func TestDelayingQueueImpl_ShutdownWithDrain_TimeoutReturnsAllScheduled(t *testing.T) {
+	q := NewDelayingQueue(nil)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, q.PutWithDelay(i, 60*60*1000))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	pending, err := q.ShutdownWithDrain(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ElementsMatch(t, []interface{}{0, 1, 2, 3, 4}, pending, "Every scheduled value should be returned")
+}
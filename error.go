@@ -2,11 +2,18 @@ package workqueue
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/shengyanli1982/workqueue/v2/internal/cron"
+	"github.com/shengyanli1982/workqueue/v2/internal/wal"
 )
 
 // ErrQueueIsClosed 表示队列已关闭或正在关闭。
 var ErrQueueIsClosed = errors.New("queue is shutting down")
 
+// ErrQueueIsDraining 表示队列正在排空，不再接收新元素。
+var ErrQueueIsDraining = errors.New("queue is draining")
+
 // ErrQueueIsEmpty 表示当前没有可消费元素。
 var ErrQueueIsEmpty = errors.New("queue is empty")
 
@@ -16,6 +23,45 @@ var ErrElementIsNil = errors.New("element is nil")
 // ErrElementAlreadyExist 表示幂等模式下重复入队。
 var ErrElementAlreadyExist = errors.New("element already exist")
 
+// ErrElementNotExist 表示队列中没有与之对应的就绪元素。
+var ErrElementNotExist = errors.New("element not exist")
+
+// ErrElementTypeMismatch 表示类型化队列中的元素与元素类型不符。
+var ErrElementTypeMismatch = errors.New("element type mismatch")
+
+// ErrElementNotHashable 表示幂等或持久化模式下元素不可哈希且未配置 key 函数。
+var ErrElementNotHashable = errors.New("element is not hashable")
+
+// ErrWALIsClosed 表示持久化日志已关闭。
+var ErrWALIsClosed = wal.ErrClosed
+
+// ErrWALCorrupted 表示日志中除最后一个段末尾之外的记录损坏，打开日志时返回。
+var ErrWALCorrupted = wal.ErrCorrupted
+
+// ErrInvalidCodecType 表示注册的类型名或样例值不合法。
+var ErrInvalidCodecType = errors.New("invalid codec type")
+
+// ErrCodecTypeConflict 表示类型名或类型已注册为不同的对应关系。
+var ErrCodecTypeConflict = errors.New("codec type conflict")
+
+// ErrUnknownCodecType 表示解码时遇到未注册的类型名。
+var ErrUnknownCodecType = errors.New("unknown codec type")
+
+// ErrUnsupportedCodecValue 表示编解码器不支持该类型的元素。
+var ErrUnsupportedCodecValue = errors.New("unsupported codec value")
+
+// ErrCodecTypeMismatch 表示解码结果与目标类型不符。
+var ErrCodecTypeMismatch = errors.New("codec type mismatch")
+
+// ErrInvalidSnapshot 表示快照数据格式不受支持。
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// ErrQueueIsFull 表示队列容量已满。
+var ErrQueueIsFull = errors.New("queue is full")
+
+// ErrInvalidBatchSize 表示批量读取的数量不合法。
+var ErrInvalidBatchSize = errors.New("invalid batch size")
+
 // ErrInvalidQueueCapacity 表示队列容量配置不合法。
 var ErrInvalidQueueCapacity = errors.New("invalid queue capacity")
 
@@ -36,3 +82,70 @@ var ErrInvalidDeadLetter = errors.New("invalid dead letter")
 
 // ErrInvalidTargetQueue 表示死信重放目标队列不合法。
 var ErrInvalidTargetQueue = errors.New("invalid target queue")
+
+// ErrQueueIsNil 表示传入的队列为空。
+var ErrQueueIsNil = errors.New("queue is nil")
+
+// ErrHandlerIsNil 表示传入的处理函数为空。
+var ErrHandlerIsNil = errors.New("handler is nil")
+
+// ErrHandlerPanic 表示处理函数发生 panic 并已被恢复。
+var ErrHandlerPanic = errors.New("handler panic")
+
+// ErrRunnerIsRunning 表示 Runner 已经启动过。
+var ErrRunnerIsRunning = errors.New("runner is already running")
+
+// ErrInvalidInterval 表示周期调度的间隔不合法。
+var ErrInvalidInterval = errors.New("invalid interval")
+
+// ErrInvalidCronExpression 表示 cron 表达式不合法。
+var ErrInvalidCronExpression = cron.ErrInvalidExpression
+
+// ErrRecurringNeverFires 表示周期调度在其时间窗口内不会触发。
+var ErrRecurringNeverFires = errors.New("recurring schedule never fires")
+
+// ErrTimerNotFound 表示定时 ID 不存在，或对应的元素已经触发或被取消。
+var ErrTimerNotFound = errors.New("timer not found")
+
+// ErrUnsupportedQueue 表示队列不是本包创建的实现，无法执行该操作。
+var ErrUnsupportedQueue = errors.New("unsupported queue")
+
+// BatchError 描述批量入队中逐元素的失败原因。
+// Errors 与输入按下标一一对应，成功的位置为 nil。
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e.Errors {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("batch: %d of %d elements failed, first error: %v", failed, len(e.Errors), first)
+}
+
+// Is 使 errors.Is 能匹配任一元素的失败原因。
+func (e *BatchError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if err != nil && errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// newBatchError 在存在失败元素时返回 *BatchError，否则返回 nil。
+func newBatchError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return &BatchError{Errors: errs}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shengyanli1982/workqueue/v2/generic"
+)
+
+type order struct {
+	ID     int
+	Amount float64
+}
+
+func main() {
+	cfg := generic.NewRetryQueueConfig[*order]().
+		WithPolicy(generic.NewExponentialRetryPolicy[*order](50*time.Millisecond, 200*time.Millisecond, 3)).
+		WithKeyFunc(func(o *order) string { return strconv.Itoa(o.ID) })
+
+	q := generic.NewRetryQueue(cfg)
+	defer q.Shutdown()
+
+	_ = q.Put(&order{ID: 1001, Amount: 99.5})
+
+	// Get 直接返回 *order，无需类型断言。
+	o, err := q.Get()
+	if err != nil {
+		fmt.Println("first get failed:", err)
+		return
+	}
+	fmt.Printf("first consume: order=%d amount=%.1f\n", o.ID, o.Amount)
+
+	if err = q.Retry(o, errors.New("upstream 503")); err != nil {
+		fmt.Println("retry failed:", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	o, err = q.GetWithContext(ctx)
+	if err != nil {
+		fmt.Println("second get failed:", err)
+		return
+	}
+	fmt.Printf("second consume: order=%d retries=%d\n", o.ID, q.NumRequeues(o))
+	q.Done(o)
+	q.Forget(o)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/shengyanli1982/workqueue/v2/metrics"
+)
+
+func main() {
+	q := wkq.NewRetryQueue(nil)
+	defer q.Shutdown()
+
+	registry := metrics.NewRegistry()
+	_ = registry.Register("orders", q)
+
+	// 实际服务中可直接挂载：http.Handle("/metrics", metrics.NewHandler(registry))
+	server := httptest.NewServer(metrics.NewHandler(registry))
+	defer server.Close()
+
+	for i := 0; i < 3; i++ {
+		_ = q.Put(fmt.Sprintf("order-%d", i))
+	}
+	value, _ := q.Get()
+	q.Done(value)
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		fmt.Println("scrape failed:", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Print(string(body))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+func main() {
+	q := wkq.NewRetryQueue(
+		wkq.NewRetryQueueConfig().
+			WithPolicy(wkq.NewExponentialRetryPolicy(20*time.Millisecond, 100*time.Millisecond, 2)),
+	)
+	defer q.Shutdown()
+
+	dlq := wkq.NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+
+	var processed atomic.Int64
+	runner := wkq.NewRunner(q, func(_ context.Context, value interface{}) error {
+		if value == "job-bad" {
+			return errors.New("invalid payload")
+		}
+		processed.Add(1)
+		return nil
+	}, wkq.NewRunnerConfig().
+		WithWorkers(4).
+		WithTimeout(time.Second).
+		WithDeadLetterQueue(dlq).
+		WithSourceName("orders"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	go func() {
+		_ = runner.Run(ctx)
+	}()
+
+	for _, job := range []string{"job-1", "job-2", "job-bad", "job-3"} {
+		_ = q.Put(job)
+	}
+
+	letter, err := dlq.GetDeadWithContext(ctx)
+	if err != nil {
+		fmt.Println("wait dead letter failed:", err)
+		return
+	}
+	runner.Stop()
+
+	fmt.Println("processed:", processed.Load())
+	fmt.Printf("dead letter: payload=%v source=%s attempts=%d error=%s\n",
+		letter.Payload, letter.SourceQueue, letter.Attempts, letter.LastError)
+}
@@ -0,0 +1,371 @@
+package workqueue
+
+import (
+	"context"
+	"sort"
+
+	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
+)
+
+// fairFlow 为单个流的子队列，link 为其挂在轮询环上的节点。
+// deficit 为该流尚未用完的出队额度，turn 表示当前是否正轮到该流出队。
+type fairFlow struct {
+	name      string
+	items     *lst.List
+	link      lst.Node
+	weight    int64
+	deficit   int64
+	turn      bool
+	adds      uint64
+	gets      uint64
+	idleSince int64
+}
+
+// flowContainer 按流拆分就绪元素，Pop 按差额轮询（deficit round robin）在有积压的流之间分配出队份额，
+// 全部方法由基础队列在持有锁时调用。元素成本在入队时求值并保存在节点的 Priority 字段中。
+type flowContainer struct {
+	flowOf      FlowFunc
+	weightOf    FlowWeightFunc
+	costOf      CostFunc
+	quantum     int64
+	idleTimeout int64
+	clock       Clock
+
+	flows    map[string]*fairFlow
+	ring     *lst.List
+	assigned map[*lst.Node]*fairFlow
+	// owners 记录已挂接节点所属的流，Remove 借此直接定位，无需遍历全部流。
+	owners map[*lst.Node]*fairFlow
+	count  int64
+
+	// idle 为已清空但尚未清理的流数量，lastSweep 为上次清理的时刻。
+	idle      int
+	lastSweep int64
+}
+
+func newFlowContainer(config *FairQueueConfig) *flowContainer {
+	return &flowContainer{
+		flowOf:      config.flowFunc,
+		weightOf:    config.weightFunc,
+		costOf:      config.costFunc,
+		quantum:     int64(config.quantum),
+		idleTimeout: int64(config.idleTimeout),
+		clock:       config.clock,
+		flows:       make(map[string]*fairFlow),
+		ring:        lst.New(),
+		assigned:    make(map[*lst.Node]*fairFlow),
+		owners:      make(map[*lst.Node]*fairFlow),
+	}
+}
+
+// assign 指定节点所属的流，节点随后由 Push 挂接；未指定的节点按 flowOf 归属。
+func (c *flowContainer) assign(node *lst.Node, name string) {
+	c.assigned[node] = c.flow(name)
+}
+
+// flow 返回名称为 name 的流，不存在时创建。
+func (c *flowContainer) flow(name string) *fairFlow {
+	f, ok := c.flows[name]
+	if !ok {
+		f = &fairFlow{name: name, items: lst.New(), weight: 1}
+		if c.weightOf != nil {
+			if weight := c.weightOf(name); weight > 1 {
+				f.weight = int64(weight)
+			}
+		}
+		f.link.Value = f
+		c.flows[name] = f
+	}
+	return f
+}
+
+func (c *flowContainer) nameOf(value interface{}) string {
+	if c.flowOf == nil {
+		return ""
+	}
+	return c.flowOf(value)
+}
+
+// cost 返回元素的出队成本，最小为 1。
+func (c *flowContainer) cost(value interface{}) int64 {
+	if c.costOf == nil {
+		return 1
+	}
+	if cost := c.costOf(value); cost > 1 {
+		return int64(cost)
+	}
+	return 1
+}
+
+func (c *flowContainer) Push(value interface{}) {
+	node := value.(*lst.Node)
+	node.Priority = c.cost(node.Value)
+
+	f, ok := c.assigned[node]
+	if ok {
+		delete(c.assigned, node)
+	} else {
+		f = c.flow(c.nameOf(node.Value))
+	}
+
+	if f.items.Len() == 0 {
+		c.ring.PushBack(&f.link)
+		if f.idleSince != 0 {
+			f.idleSince = 0
+			c.idle--
+		}
+	}
+	f.items.PushBack(node)
+	c.owners[node] = f
+	f.adds++
+	c.count++
+
+	// 节点在挂接前已记录入队时刻，借此定期清理长时间空闲的流。
+	c.sweep(node.Timestamp)
+}
+
+func (c *flowContainer) Pop() interface{} {
+	for skipped := 0; ; {
+		link := c.ring.Front()
+		if link == nil {
+			return nil
+		}
+
+		// 轮到该流时按权重发放额度，额度足以支付队首元素的成本时出队并继续占据环首。
+		f := link.Value.(*fairFlow)
+		if !f.turn {
+			f.turn = true
+			f.deficit += c.quantum * f.weight
+		}
+		if head := f.items.Front(); f.deficit >= head.Priority {
+			f.deficit -= head.Priority
+			f.items.Remove(head)
+			delete(c.owners, head)
+			f.gets++
+			c.count--
+			if f.items.Len() == 0 {
+				c.retire(f)
+			}
+			return head
+		}
+
+		// 额度不足时结束该流本轮，剩余额度留到下一轮。
+		f.turn = false
+		c.ring.MoveToBack(link)
+		if skipped++; skipped >= int(c.ring.Len()) {
+			c.fastForward()
+			skipped = 0
+		}
+	}
+}
+
+// fastForward 在一整轮都没有流能够出队时，一次性补发中间若干轮的额度，
+// 使成本远大于额度时 Pop 不必逐轮空转。
+func (c *flowContainer) fastForward() {
+	rounds := int64(-1)
+	for link := c.ring.Front(); link != nil; link = link.Right {
+		f := link.Value.(*fairFlow)
+		credit := c.quantum * f.weight
+		need := (f.items.Front().Priority - f.deficit + credit - 1) / credit
+		if rounds < 0 || need < rounds {
+			rounds = need
+		}
+	}
+	if rounds <= 1 {
+		return
+	}
+
+	for link := c.ring.Front(); link != nil; link = link.Right {
+		f := link.Value.(*fairFlow)
+		f.deficit += (rounds - 1) * c.quantum * f.weight
+	}
+}
+
+func (c *flowContainer) Remove(node *lst.Node) {
+	f, ok := c.owners[node]
+	if !ok {
+		return
+	}
+	delete(c.owners, node)
+	f.items.Remove(node)
+	c.count--
+	if f.items.Len() == 0 {
+		c.retire(f)
+	}
+}
+
+// retire 将已清空的流移出轮询环并清零额度，其指标保留至空闲超时。
+func (c *flowContainer) retire(f *fairFlow) {
+	now := c.clock.Now().UnixNano()
+	c.ring.Remove(&f.link)
+	f.deficit = 0
+	f.turn = false
+	f.idleSince = now
+	c.idle++
+
+	// 出队后不再有写入时，同样借清空流的时机清理更早空闲的流。
+	c.sweep(now)
+}
+
+// sweep 删除空闲超过 idleTimeout 的流，同一周期内只扫描一次。
+func (c *flowContainer) sweep(now int64) {
+	if c.idle == 0 || now-c.lastSweep < c.idleTimeout {
+		return
+	}
+	c.lastSweep = now
+
+	for name, f := range c.flows {
+		if f.idleSince != 0 && now-f.idleSince >= c.idleTimeout {
+			delete(c.flows, name)
+			c.idle--
+		}
+	}
+}
+
+// Range 按轮询环的顺序逐个流遍历元素。
+func (c *flowContainer) Range(fn func(value interface{}) bool) {
+	for link := c.ring.Front(); link != nil; link = link.Right {
+		next := true
+		link.Value.(*fairFlow).items.Range(func(node *lst.Node) bool {
+			next = fn(node)
+			return next
+		})
+		if !next {
+			return
+		}
+	}
+}
+
+func (c *flowContainer) Slice() []interface{} {
+	values := make([]interface{}, 0, c.count)
+	c.Range(func(value interface{}) bool {
+		values = append(values, value.(*lst.Node).Value)
+		return true
+	})
+	return values
+}
+
+func (c *flowContainer) Len() int64 { return c.count }
+
+func (c *flowContainer) Cleanup() {
+	c.flows = make(map[string]*fairFlow)
+	c.ring.Cleanup()
+	c.assigned = make(map[*lst.Node]*fairFlow)
+	c.owners = make(map[*lst.Node]*fairFlow)
+	c.count = 0
+	c.idle = 0
+}
+
+// stats 返回各个流的指标快照，按流名称排序。快照前先清理已空闲超时的流。
+func (c *flowContainer) stats() []FlowStats {
+	c.sweep(c.clock.Now().UnixNano())
+
+	stats := make([]FlowStats, 0, len(c.flows))
+	for _, f := range c.flows {
+		stats = append(stats, FlowStats{
+			Flow:   f.name,
+			Depth:  int(f.items.Len()),
+			Adds:   f.adds,
+			Gets:   f.gets,
+			Weight: int(f.weight),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Flow < stats[j].Flow })
+	return stats
+}
+
+// fairQueueImpl 以 flowContainer 作为基础队列的容器，锁、指标、排空与快照均复用基础队列。
+type fairQueueImpl struct {
+	Queue
+	config      *FairQueueConfig
+	flows       *flowContainer
+	elementpool *lst.NodePool
+}
+
+// NewFairQueue 创建公平队列。配置容量后返回的队列同时实现 BoundedBlockingQueue。
+func NewFairQueue(config *FairQueueConfig) FairQueue {
+	config = isFairQueueConfigEffective(config)
+
+	q := &fairQueueImpl{
+		config:      config,
+		flows:       newFlowContainer(config),
+		elementpool: lst.NewNodePool(),
+	}
+
+	q.Queue = newQueue(q.flows, q.elementpool, &config.QueueConfig)
+
+	if config.capacity > 0 {
+		bounded := &BoundedBlockingQueueConfig{QueueConfig: config.QueueConfig, capacity: config.capacity}
+		return &boundedFairQueueImpl{
+			boundedBlockingQueueImpl: newBoundedBlockingQueue(q, bounded),
+			fair:                     q,
+		}
+	}
+
+	return q
+}
+
+func (q *fairQueueImpl) Put(value interface{}) error {
+	if err := q.Queue.Put(value); err != nil {
+		return err
+	}
+
+	q.config.callback.OnFlow(value, q.flows.nameOf(value))
+	return nil
+}
+
+func (q *fairQueueImpl) PutBatch(values []interface{}) error {
+	err := q.Queue.PutBatch(values)
+
+	var batch *BatchError
+	switch e := err.(type) {
+	case nil:
+	case *BatchError:
+		batch = e
+	default:
+		return err
+	}
+
+	for i, value := range values {
+		if batch == nil || batch.Errors[i] == nil {
+			q.config.callback.OnFlow(value, q.flows.nameOf(value))
+		}
+	}
+	return err
+}
+
+// PutWithFlow 与 Put 相同，按同样的幂等判重与日志语义入队，只是元素归入 flow 而不是由 FlowFunc 决定。
+func (q *fairQueueImpl) PutWithFlow(value interface{}, flow string) error {
+	attach := func(node *lst.Node) { q.flows.assign(node, flow) }
+	if err := q.Queue.(*queueImpl).putAttached(value, putExternal, attach); err != nil {
+		return err
+	}
+
+	q.config.callback.OnFlow(value, flow)
+	return nil
+}
+
+func (q *fairQueueImpl) Flows() []FlowStats {
+	base := q.Queue.(*queueImpl)
+	base.lock.Lock()
+	stats := q.flows.stats()
+	base.lock.Unlock()
+	return stats
+}
+
+// boundedFairQueueImpl 在公平队列外层叠加有界阻塞语义，元素按权重出队，容量由全部流共享。
+type boundedFairQueueImpl struct {
+	*boundedBlockingQueueImpl
+	fair *fairQueueImpl
+}
+
+// PutWithFlow 在容量不足时阻塞，直至有空闲容量或队列关闭。
+func (q *boundedFairQueueImpl) PutWithFlow(value interface{}, flow string) error {
+	return q.putWith(context.Background(), value, func(value interface{}) error {
+		return q.fair.PutWithFlow(value, flow)
+	})
+}
+
+func (q *boundedFairQueueImpl) Flows() []FlowStats {
+	return q.fair.Flows()
+}
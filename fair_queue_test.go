@@ -0,0 +1,304 @@
+package workqueue
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
+	"github.com/stretchr/testify/assert"
+)
+
+// tenantOf 取元素中冒号前的部分作为租户。
+func tenantOf(value interface{}) string {
+	return strings.SplitN(value.(string), ":", 2)[0]
+}
+
+func drainQueue(t *testing.T, q Queue) []interface{} {
+	t.Helper()
+
+	var values []interface{}
+	for q.Len() > 0 {
+		value, err := q.Get()
+		assert.NoError(t, err)
+		q.Done(value)
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestFairQueue_RoundRobin(t *testing.T) {
+	q := NewFairQueue(NewFairQueueConfig().WithFlowFunc(tenantOf))
+	defer q.Shutdown()
+
+	// 嘈杂租户先写入大量元素，其他租户的元素仍然轮流得到处理。
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, q.Put("noisy:"+string(rune('a'+i))))
+	}
+	assert.NoError(t, q.Put("quiet:a"))
+	assert.NoError(t, q.PutBatch([]interface{}{"other:a", "other:b"}))
+
+	assert.Equal(t, 7, q.Len())
+	assert.Equal(t, []interface{}{
+		"noisy:a", "quiet:a", "other:a",
+		"noisy:b", "other:b",
+		"noisy:c", "noisy:d",
+	}, drainQueue(t, q))
+}
+
+func TestFairQueue_PutWithFlow(t *testing.T) {
+	q := NewFairQueue(nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithFlow("test1", "a"))
+	assert.NoError(t, q.PutWithFlow("test2", "a"))
+	assert.NoError(t, q.PutWithFlow("test3", "b"))
+	assert.NoError(t, q.Put("test4"))
+	assert.ErrorIs(t, q.PutWithFlow(nil, "a"), ErrElementIsNil)
+
+	value, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", value)
+	q.Done(value)
+
+	assert.Equal(t, []FlowStats{
+		{Flow: "", Depth: 1, Adds: 1, Weight: 1},
+		{Flow: "a", Depth: 1, Adds: 2, Gets: 1, Weight: 1},
+		{Flow: "b", Depth: 1, Adds: 1, Weight: 1},
+	}, q.Flows())
+	assert.Equal(t, []interface{}{"test3", "test4", "test2"}, drainQueue(t, q))
+
+	q.Shutdown()
+	assert.ErrorIs(t, q.PutWithFlow("test5", "a"), ErrQueueIsClosed)
+}
+
+type testFairQueueCallback struct {
+	mu    sync.Mutex
+	puts  []interface{}
+	flows []string
+}
+
+func (c *testFairQueueCallback) OnPut(value interface{}) {
+	c.mu.Lock()
+	c.puts = append(c.puts, value)
+	c.mu.Unlock()
+}
+
+func (c *testFairQueueCallback) OnGet(interface{}) {}
+
+func (c *testFairQueueCallback) OnDone(interface{}) {}
+
+func (c *testFairQueueCallback) OnFlow(_ interface{}, flow string) {
+	c.mu.Lock()
+	c.flows = append(c.flows, flow)
+	c.mu.Unlock()
+}
+
+func TestFairQueue_Callback(t *testing.T) {
+	callback := &testFairQueueCallback{}
+	q := NewFairQueue(NewFairQueueConfig().WithFlowFunc(tenantOf).WithCallback(callback))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("a:1"))
+	assert.NoError(t, q.PutWithFlow("x:1", "b"))
+	assert.Error(t, q.PutBatch([]interface{}{"c:1", nil}))
+
+	assert.Equal(t, []interface{}{"a:1", "x:1", "c:1"}, callback.puts)
+	assert.Equal(t, []string{"a", "b", "c"}, callback.flows)
+}
+
+func TestFairQueue_IdleFlowCleanup(t *testing.T) {
+	q := NewFairQueue(NewFairQueueConfig().WithFlowFunc(tenantOf).WithFlowIdleTimeout(10 * time.Millisecond))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("a:1"))
+	assert.Equal(t, []interface{}{"a:1"}, drainQueue(t, q))
+
+	// 刚清空的流仍保留指标。
+	assert.Equal(t, []FlowStats{{Flow: "a", Adds: 1, Gets: 1, Weight: 1}}, q.Flows())
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, q.Put("b:1"))
+	assert.Equal(t, []FlowStats{{Flow: "b", Depth: 1, Adds: 1, Weight: 1}}, q.Flows())
+}
+
+func TestFairQueue_IdleFlowCleanup_NoPut(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	q := NewFairQueue(NewFairQueueConfig().WithFlowFunc(tenantOf).WithFlowIdleTimeout(time.Minute).WithClock(clock))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("a:1"))
+	assert.NoError(t, q.Put("b:1"))
+	assert.Equal(t, []interface{}{"a:1", "b:1"}, drainQueue(t, q))
+	assert.Len(t, q.Flows(), 2)
+
+	// 最后一次写入之后变为空闲的流，无需新的写入也会被清理。
+	clock.Advance(time.Minute)
+	assert.Empty(t, q.Flows(), "Idle flows should be reclaimed without further puts")
+}
+
+func TestFairQueue_PutWithFlow_Idempotent(t *testing.T) {
+	config := NewFairQueueConfig()
+	config.WithValueIdempotent()
+	q := NewFairQueue(config)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithFlow("x", "a"))
+	assert.ErrorIs(t, q.PutWithFlow("x", "a"), ErrElementAlreadyExist, "Queued value should not be added twice")
+	assert.ErrorIs(t, q.PutWithFlow("x", "b"), ErrElementAlreadyExist)
+	assert.Equal(t, 1, q.Len())
+
+	// 处理中的值只合并一次，Done 后按 PutWithFlow 指定的流重新入队。
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, q.PutWithFlow("x", "b"))
+	assert.ErrorIs(t, q.PutWithFlow("x", "b"), ErrElementAlreadyExist)
+	assert.Equal(t, 0, q.Len())
+	q.Done(v)
+
+	assert.Equal(t, 1, q.Len())
+	stats := q.Flows()
+	assert.Len(t, stats, 2)
+	assert.Equal(t, FlowStats{Flow: "b", Depth: 1, Adds: 1, Weight: 1}, stats[1])
+}
+
+func TestFairQueue_IdempotentAndSnapshot(t *testing.T) {
+	config := NewFairQueueConfig().WithFlowFunc(tenantOf)
+	config.WithValueIdempotent()
+	q := NewFairQueue(config)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("a:1"))
+	assert.ErrorIs(t, q.Put("a:1"), ErrElementAlreadyExist)
+	assert.NoError(t, q.Put("a:2"))
+	assert.NoError(t, q.Put("b:1"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	restored := NewFairQueue(NewFairQueueConfig().WithFlowFunc(tenantOf))
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(&buf))
+	assert.Equal(t, []interface{}{"a:1", "b:1", "a:2"}, drainQueue(t, restored))
+}
+
+func TestFairQueue_WeightedShares(t *testing.T) {
+	weights := map[string]int{"gold": 3, "free": 1}
+	q := NewFairQueue(NewFairQueueConfig().
+		WithFlowFunc(tenantOf).
+		WithWeightFunc(func(flow string) int { return weights[flow] }))
+	defer q.Shutdown()
+
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, q.Put(fmt.Sprintf("free:%d", i)))
+		assert.NoError(t, q.Put(fmt.Sprintf("gold:%d", i)))
+	}
+
+	// 竞争期间按 3:1 分配出队份额，gold 清空后剩余份额全部归 free。
+	assert.Equal(t, []interface{}{
+		"free:0", "gold:0", "gold:1", "gold:2",
+		"free:1", "gold:3", "gold:4", "gold:5",
+		"free:2", "free:3", "free:4", "free:5",
+	}, drainQueue(t, q))
+
+	stats := q.Flows()
+	assert.Equal(t, 1, stats[0].Weight)
+	assert.Equal(t, 3, stats[1].Weight)
+}
+
+func TestFairQueue_Cost(t *testing.T) {
+	// 元素成本为冒号后的数字。
+	cost := func(value interface{}) int {
+		var n int
+		_, _ = fmt.Sscanf(strings.SplitN(value.(string), ":", 2)[1], "%d", &n)
+		return n
+	}
+	q := NewFairQueue(NewFairQueueConfig().WithFlowFunc(tenantOf).WithCostFunc(cost).WithQuantum(2))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutBatch([]interface{}{"big:2", "big:2", "big:2"}))
+	assert.NoError(t, q.PutBatch([]interface{}{"small:1", "small:1", "small:1", "small:1"}))
+	assert.NoError(t, q.Put("huge:1000"))
+
+	// 每轮额度为 2：big 出队一个，small 出队两个，huge 积累额度直至足以支付成本。
+	assert.Equal(t, []interface{}{
+		"big:2", "small:1", "small:1",
+		"big:2", "small:1", "small:1",
+		"big:2", "huge:1000",
+	}, drainQueue(t, q))
+}
+
+func TestFairQueue_Capacity(t *testing.T) {
+	q := NewFairQueue(NewFairQueueConfig().WithFlowFunc(tenantOf).WithCapacity(2))
+	defer q.Shutdown()
+
+	bounded, ok := q.(BoundedBlockingQueue)
+	assert.True(t, ok)
+	assert.Equal(t, 2, bounded.Cap())
+
+	assert.NoError(t, q.Put("a:1"))
+	assert.NoError(t, q.PutWithFlow("x", "b"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, bounded.PutWithContext(ctx, "a:2"), context.DeadlineExceeded)
+
+	done := make(chan error, 1)
+	go func() { done <- q.PutWithFlow("y", "b") }()
+
+	value, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "a:1", value)
+	assert.NoError(t, <-done)
+
+	assert.Equal(t, []interface{}{"x", "y"}, drainQueue(t, q))
+	assert.Equal(t, []FlowStats{
+		{Flow: "a", Adds: 1, Gets: 1, Weight: 1},
+		{Flow: "b", Adds: 2, Gets: 2, Weight: 1},
+	}, q.Flows())
+}
+
+func TestFairQueue_Purge(t *testing.T) {
+	q := NewFairQueue(NewFairQueueConfig().WithFlowFunc(tenantOf).WithCapacity(2))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("a:1"))
+	assert.NoError(t, q.Put("b:1"))
+
+	purged, err := Purge(q)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+
+	assert.NoError(t, q.Put("a:2"), "Purged values should release their capacity")
+	assert.NoError(t, q.Put("b:2"))
+	assert.Equal(t, []interface{}{"a:2", "b:2"}, drainQueue(t, q))
+}
+
+func TestFlowContainer_Remove(t *testing.T) {
+	c := newFlowContainer(isFairQueueConfigEffective(NewFairQueueConfig().WithFlowFunc(tenantOf)))
+
+	nodes := make(map[string]*lst.Node)
+	for _, value := range []string{"a:1", "b:1", "b:2", "c:1"} {
+		node := &lst.Node{Value: value}
+		nodes[value] = node
+		c.Push(node)
+	}
+
+	// 按节点记录的所属流直接移除，清空的流离开轮询环。
+	c.Remove(nodes["c:1"])
+	c.Remove(nodes["b:1"])
+	c.Remove(nodes["c:1"])
+	c.Remove(&lst.Node{Value: "a:1"})
+	assert.Equal(t, int64(2), c.Len())
+	assert.Equal(t, int64(2), c.ring.Len(), "The emptied flow should leave the ring")
+	assert.Len(t, c.owners, 2)
+
+	assert.Equal(t, "a:1", c.Pop().(*lst.Node).Value)
+	assert.Equal(t, "b:2", c.Pop().(*lst.Node).Value)
+	assert.Nil(t, c.Pop())
+	assert.Empty(t, c.owners, "Popped nodes should not keep their flow")
+}
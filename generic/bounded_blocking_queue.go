@@ -0,0 +1,24 @@
+package generic
+
+import (
+	"context"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+type boundedBlockingQueueImpl[T any] struct {
+	*queueImpl[T]
+	queue wkq.BoundedBlockingQueue
+}
+
+// NewBoundedBlockingQueue 创建类型化有界阻塞队列。
+func NewBoundedBlockingQueue[T any](config *BoundedBlockingQueueConfig[T]) BoundedBlockingQueue[T] {
+	queue := wkq.NewBoundedBlockingQueue(config.build())
+	return &boundedBlockingQueueImpl[T]{queueImpl: newQueue[T](queue), queue: queue}
+}
+
+func (q *boundedBlockingQueueImpl[T]) Cap() int { return q.queue.Cap() }
+
+func (q *boundedBlockingQueueImpl[T]) PutWithContext(ctx context.Context, value T) error {
+	return q.queue.PutWithContext(ctx, value)
+}
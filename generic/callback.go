@@ -0,0 +1,142 @@
+package generic
+
+import "time"
+
+type queueCallbackImpl[T any] struct{}
+
+// NewNopQueueCallbackImpl 返回空实现回调，可嵌入自定义回调以省略不关心的方法。
+func NewNopQueueCallbackImpl[T any]() *queueCallbackImpl[T] { return &queueCallbackImpl[T]{} }
+
+func (impl *queueCallbackImpl[T]) OnPut(T) {}
+
+func (impl *queueCallbackImpl[T]) OnGet(T) {}
+
+func (impl *queueCallbackImpl[T]) OnDone(T) {}
+
+type delayingQueueCallbackImpl[T any] struct {
+	queueCallbackImpl[T]
+}
+
+// NewNopDelayingQueueCallbackImpl 返回空实现延迟回调。
+func NewNopDelayingQueueCallbackImpl[T any]() *delayingQueueCallbackImpl[T] {
+	return &delayingQueueCallbackImpl[T]{}
+}
+
+func (impl *delayingQueueCallbackImpl[T]) OnDelay(T, int64) {}
+
+func (impl *delayingQueueCallbackImpl[T]) OnPullError(T, error) {}
+
+type priorityQueueCallbackImpl[T any] struct {
+	queueCallbackImpl[T]
+}
+
+// NewNopPriorityQueueCallbackImpl 返回空实现优先级回调。
+func NewNopPriorityQueueCallbackImpl[T any]() *priorityQueueCallbackImpl[T] {
+	return &priorityQueueCallbackImpl[T]{}
+}
+
+func (impl *priorityQueueCallbackImpl[T]) OnPriority(T, int64) {}
+
+func (impl *priorityQueueCallbackImpl[T]) OnUpdatePriority(T, int64) {}
+
+func (impl *priorityQueueCallbackImpl[T]) OnRemove(T) {}
+
+type ratelimitingQueueCallbackImpl[T any] struct {
+	delayingQueueCallbackImpl[T]
+}
+
+// NewNopRateLimitingQueueCallbackImpl 返回空实现限流回调。
+func NewNopRateLimitingQueueCallbackImpl[T any]() *ratelimitingQueueCallbackImpl[T] {
+	return &ratelimitingQueueCallbackImpl[T]{}
+}
+
+func (impl *ratelimitingQueueCallbackImpl[T]) OnLimited(T) {}
+
+type retryQueueCallbackImpl[T any] struct {
+	delayingQueueCallbackImpl[T]
+}
+
+// NewNopRetryQueueCallbackImpl 返回空实现重试回调。
+func NewNopRetryQueueCallbackImpl[T any]() *retryQueueCallbackImpl[T] {
+	return &retryQueueCallbackImpl[T]{}
+}
+
+func (impl *retryQueueCallbackImpl[T]) OnRetry(T, int, time.Duration, error) {}
+
+func (impl *retryQueueCallbackImpl[T]) OnRetryExhausted(T, int, error) {}
+
+func (impl *retryQueueCallbackImpl[T]) OnForget(T) {}
+
+// queueCallbackAdapter 将类型化回调适配为 workqueue.QueueCallback。
+type queueCallbackAdapter[T any] struct {
+	cb QueueCallback[T]
+}
+
+func (a *queueCallbackAdapter[T]) OnPut(value interface{}) { a.cb.OnPut(mustCast[T](value)) }
+
+func (a *queueCallbackAdapter[T]) OnGet(value interface{}) { a.cb.OnGet(mustCast[T](value)) }
+
+func (a *queueCallbackAdapter[T]) OnDone(value interface{}) { a.cb.OnDone(mustCast[T](value)) }
+
+type delayingQueueCallbackAdapter[T any] struct {
+	queueCallbackAdapter[T]
+	cb DelayingQueueCallback[T]
+}
+
+func newDelayingQueueCallbackAdapter[T any](cb DelayingQueueCallback[T]) *delayingQueueCallbackAdapter[T] {
+	return &delayingQueueCallbackAdapter[T]{
+		queueCallbackAdapter: queueCallbackAdapter[T]{cb: cb},
+		cb:                   cb,
+	}
+}
+
+func (a *delayingQueueCallbackAdapter[T]) OnDelay(value interface{}, delay int64) {
+	a.cb.OnDelay(mustCast[T](value), delay)
+}
+
+func (a *delayingQueueCallbackAdapter[T]) OnPullError(value interface{}, reason error) {
+	a.cb.OnPullError(mustCast[T](value), reason)
+}
+
+type priorityQueueCallbackAdapter[T any] struct {
+	queueCallbackAdapter[T]
+	cb PriorityQueueCallback[T]
+}
+
+func (a *priorityQueueCallbackAdapter[T]) OnPriority(value interface{}, priority int64) {
+	a.cb.OnPriority(mustCast[T](value), priority)
+}
+
+func (a *priorityQueueCallbackAdapter[T]) OnUpdatePriority(value interface{}, priority int64) {
+	a.cb.OnUpdatePriority(mustCast[T](value), priority)
+}
+
+func (a *priorityQueueCallbackAdapter[T]) OnRemove(value interface{}) {
+	a.cb.OnRemove(mustCast[T](value))
+}
+
+type ratelimitingQueueCallbackAdapter[T any] struct {
+	delayingQueueCallbackAdapter[T]
+	cb RateLimitingQueueCallback[T]
+}
+
+func (a *ratelimitingQueueCallbackAdapter[T]) OnLimited(value interface{}) {
+	a.cb.OnLimited(mustCast[T](value))
+}
+
+type retryQueueCallbackAdapter[T any] struct {
+	delayingQueueCallbackAdapter[T]
+	cb RetryQueueCallback[T]
+}
+
+func (a *retryQueueCallbackAdapter[T]) OnRetry(value interface{}, attempt int, delay time.Duration, reason error) {
+	a.cb.OnRetry(mustCast[T](value), attempt, delay, reason)
+}
+
+func (a *retryQueueCallbackAdapter[T]) OnRetryExhausted(value interface{}, attempt int, reason error) {
+	a.cb.OnRetryExhausted(mustCast[T](value), attempt, reason)
+}
+
+func (a *retryQueueCallbackAdapter[T]) OnForget(value interface{}) {
+	a.cb.OnForget(mustCast[T](value))
+}
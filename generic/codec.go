@@ -0,0 +1,28 @@
+package generic
+
+import (
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// typedCodec 包装底层编解码器，解码时按 T 还原元素，使快照与持久化日志恢复出的元素类型与入队时一致。
+type typedCodec[T any] struct {
+	codec wkq.Codec
+}
+
+// newTypedCodec 包装 codec，codec 为空时使用与 workqueue 相同的默认编解码器。
+func newTypedCodec[T any](codec wkq.Codec) wkq.Codec {
+	if codec == nil {
+		codec = wkq.NewGobCodec()
+	}
+	return &typedCodec[T]{codec: codec}
+}
+
+func (c *typedCodec[T]) Encode(value interface{}) ([]byte, error) { return c.codec.Encode(value) }
+
+func (c *typedCodec[T]) Decode(data []byte) (interface{}, error) {
+	var value T
+	if err := wkq.DecodeInto(c.codec, data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
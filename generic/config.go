@@ -0,0 +1,403 @@
+package generic
+
+import (
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// QueueConfig 定义类型化基础队列配置。
+type QueueConfig[T any] struct {
+	callback   QueueCallback[T]
+	idempotent bool
+	setCreator wkq.NewSetFunc
+	keyFunc    KeyFunc[T]
+	name       string
+	metrics    wkq.MetricsProvider
+	wal        *wkq.WAL
+	codec      wkq.Codec
+	clock      wkq.Clock
+}
+
+// NewQueueConfig 返回带默认值的基础队列配置。
+func NewQueueConfig[T any]() *QueueConfig[T] {
+	return &QueueConfig[T]{}
+}
+
+// WithCallback 设置基础回调。
+func (c *QueueConfig[T]) WithCallback(cb QueueCallback[T]) *QueueConfig[T] {
+	c.callback = cb
+	return c
+}
+
+// WithValueIdempotent 开启值幂等模式，未设置 key 函数时 T 需为可哈希类型。
+func (c *QueueConfig[T]) WithValueIdempotent() *QueueConfig[T] {
+	c.idempotent = true
+	return c
+}
+
+// WithSetCreator 设置幂等集合构造器。
+func (c *QueueConfig[T]) WithSetCreator(fn wkq.NewSetFunc) *QueueConfig[T] {
+	c.setCreator = fn
+	return c
+}
+
+// WithKeyFunc 设置幂等判重的 key 生成函数，仅在幂等模式下生效。
+func (c *QueueConfig[T]) WithKeyFunc(fn KeyFunc[T]) *QueueConfig[T] {
+	c.keyFunc = fn
+	return c
+}
+
+// WithName 设置队列名称，用于指标标识。
+func (c *QueueConfig[T]) WithName(name string) *QueueConfig[T] {
+	c.name = name
+	return c
+}
+
+// WithMetricsProvider 设置指标提供者。
+func (c *QueueConfig[T]) WithMetricsProvider(provider wkq.MetricsProvider) *QueueConfig[T] {
+	c.metrics = provider
+	return c
+}
+
+// WithWAL 设置持久化日志，语义与 workqueue.QueueConfig.WithWAL 一致。
+func (c *QueueConfig[T]) WithWAL(w *wkq.WAL) *QueueConfig[T] {
+	c.wal = w
+	return c
+}
+
+// WithCodec 设置元素的编解码器，语义与 workqueue.QueueConfig.WithCodec 一致。
+// 恢复快照与持久化日志时元素按 T 解码，例如 JSON 中的数字会还原为 T 而不是 float64。
+func (c *QueueConfig[T]) WithCodec(codec wkq.Codec) *QueueConfig[T] {
+	c.codec = codec
+	return c
+}
+
+// WithClock 设置时钟，语义与 workqueue.QueueConfig.WithClock 一致。
+func (c *QueueConfig[T]) WithClock(clock wkq.Clock) *QueueConfig[T] {
+	c.clock = clock
+	return c
+}
+
+// applyTo 将通用选项写入 workqueue 的基础配置。
+func (c *QueueConfig[T]) applyTo(config *wkq.QueueConfig) {
+	config.WithName(c.name)
+	if c.metrics != nil {
+		config.WithMetricsProvider(c.metrics)
+	}
+	if c.wal != nil {
+		config.WithWAL(c.wal)
+	}
+	if c.codec != nil {
+		config.WithCodec(newTypedCodec[T](c.codec))
+	}
+	if c.clock != nil {
+		config.WithClock(c.clock)
+	}
+	if c.idempotent {
+		config.WithValueIdempotent()
+	}
+	if c.setCreator != nil {
+		config.WithSetCreator(c.setCreator)
+	}
+	if c.keyFunc != nil {
+		fn := c.keyFunc
+		config.WithKeyFunc(func(value interface{}) string { return fn(mustCast[T](value)) })
+	}
+	if c.callback != nil {
+		config.WithCallback(&queueCallbackAdapter[T]{cb: c.callback})
+	}
+}
+
+// build 系列方法总是安装按 T 解码的编解码器，未配置编解码器时包装默认实现。
+func (c *QueueConfig[T]) build() *wkq.QueueConfig {
+	config := wkq.NewQueueConfig()
+	config.WithCodec(newTypedCodec[T](nil))
+	if c != nil {
+		c.applyTo(config)
+	}
+	return config
+}
+
+// DelayingQueueConfig 定义类型化延迟队列配置。
+type DelayingQueueConfig[T any] struct {
+	QueueConfig[T]
+	callback  DelayingQueueCallback[T]
+	wheelTick time.Duration
+}
+
+// NewDelayingQueueConfig 返回带默认值的延迟队列配置。
+func NewDelayingQueueConfig[T any]() *DelayingQueueConfig[T] {
+	return &DelayingQueueConfig[T]{}
+}
+
+// WithCallback 设置延迟队列回调。
+func (c *DelayingQueueConfig[T]) WithCallback(cb DelayingQueueCallback[T]) *DelayingQueueConfig[T] {
+	c.callback = cb
+	c.QueueConfig.callback = cb
+	return c
+}
+
+// WithTimingWheel 以刻度为 tick 的分层时间轮调度延迟元素。
+func (c *DelayingQueueConfig[T]) WithTimingWheel(tick time.Duration) *DelayingQueueConfig[T] {
+	c.wheelTick = tick
+	return c
+}
+
+func (c *DelayingQueueConfig[T]) applyTo(config *wkq.DelayingQueueConfig) {
+	c.QueueConfig.applyTo(&config.QueueConfig)
+	if c.callback != nil {
+		config.WithCallback(newDelayingQueueCallbackAdapter(c.callback))
+	}
+	if c.wheelTick > 0 {
+		config.WithTimingWheel(c.wheelTick)
+	}
+}
+
+func (c *DelayingQueueConfig[T]) build() *wkq.DelayingQueueConfig {
+	config := wkq.NewDelayingQueueConfig()
+	config.WithCodec(newTypedCodec[T](nil))
+	if c != nil {
+		c.applyTo(config)
+	}
+	return config
+}
+
+// PriorityQueueConfig 定义类型化优先级队列配置。
+type PriorityQueueConfig[T any] struct {
+	QueueConfig[T]
+	callback   PriorityQueueCallback[T]
+	comparator func(a, b T) int
+}
+
+// NewPriorityQueueConfig 返回带默认值的优先级队列配置。
+func NewPriorityQueueConfig[T any]() *PriorityQueueConfig[T] {
+	return &PriorityQueueConfig[T]{}
+}
+
+// WithCallback 设置优先级队列回调。
+func (c *PriorityQueueConfig[T]) WithCallback(cb PriorityQueueCallback[T]) *PriorityQueueConfig[T] {
+	c.callback = cb
+	c.QueueConfig.callback = cb
+	return c
+}
+
+// WithComparator 设置优先级相同时的次级排序，比较结果仍相同的元素按入队先后出队。
+func (c *PriorityQueueConfig[T]) WithComparator(fn func(a, b T) int) *PriorityQueueConfig[T] {
+	c.comparator = fn
+	return c
+}
+
+func (c *PriorityQueueConfig[T]) build() *wkq.PriorityQueueConfig {
+	config := wkq.NewPriorityQueueConfig()
+	config.WithCodec(newTypedCodec[T](nil))
+	if c != nil {
+		c.QueueConfig.applyTo(&config.QueueConfig)
+		if fn := c.comparator; fn != nil {
+			config.WithComparator(func(a, b interface{}) int { return fn(mustCast[T](a), mustCast[T](b)) })
+		}
+		if c.callback != nil {
+			config.WithCallback(&priorityQueueCallbackAdapter[T]{
+				queueCallbackAdapter: queueCallbackAdapter[T]{cb: c.callback},
+				cb:                   c.callback,
+			})
+		}
+	}
+	return config
+}
+
+// RateLimitingQueueConfig 定义类型化限流队列配置。
+type RateLimitingQueueConfig[T any] struct {
+	DelayingQueueConfig[T]
+	callback RateLimitingQueueCallback[T]
+	limiter  Limiter[T]
+}
+
+// NewRateLimitingQueueConfig 返回带默认值的限流队列配置。
+func NewRateLimitingQueueConfig[T any]() *RateLimitingQueueConfig[T] {
+	return &RateLimitingQueueConfig[T]{}
+}
+
+// WithCallback 设置限流队列回调。
+func (c *RateLimitingQueueConfig[T]) WithCallback(cb RateLimitingQueueCallback[T]) *RateLimitingQueueConfig[T] {
+	c.callback = cb
+	c.DelayingQueueConfig.callback = cb
+	c.DelayingQueueConfig.QueueConfig.callback = cb
+	return c
+}
+
+// WithLimiter 设置限流器实现。
+func (c *RateLimitingQueueConfig[T]) WithLimiter(limiter Limiter[T]) *RateLimitingQueueConfig[T] {
+	c.limiter = limiter
+	return c
+}
+
+func (c *RateLimitingQueueConfig[T]) build() *wkq.RateLimitingQueueConfig {
+	config := wkq.NewRateLimitingQueueConfig()
+	config.WithCodec(newTypedCodec[T](nil))
+	if c != nil {
+		c.DelayingQueueConfig.applyTo(&config.DelayingQueueConfig)
+		if c.callback != nil {
+			config.WithCallback(&ratelimitingQueueCallbackAdapter[T]{
+				delayingQueueCallbackAdapter: *newDelayingQueueCallbackAdapter[T](c.callback),
+				cb:                           c.callback,
+			})
+		}
+		if c.limiter != nil {
+			config.WithLimiter(&limiterAdapter[T]{limiter: c.limiter})
+		}
+	}
+	return config
+}
+
+// RetryQueueConfig 定义类型化重试队列配置。
+type RetryQueueConfig[T any] struct {
+	DelayingQueueConfig[T]
+	callback RetryQueueCallback[T]
+	policy   RetryPolicy[T]
+	keyFunc  RetryKeyFunc[T]
+}
+
+// NewRetryQueueConfig 返回带默认值的重试队列配置。
+func NewRetryQueueConfig[T any]() *RetryQueueConfig[T] {
+	return &RetryQueueConfig[T]{}
+}
+
+// WithCallback 设置重试队列回调。
+func (c *RetryQueueConfig[T]) WithCallback(cb RetryQueueCallback[T]) *RetryQueueConfig[T] {
+	c.callback = cb
+	c.DelayingQueueConfig.callback = cb
+	c.DelayingQueueConfig.QueueConfig.callback = cb
+	return c
+}
+
+// WithPolicy 设置重试策略。
+func (c *RetryQueueConfig[T]) WithPolicy(policy RetryPolicy[T]) *RetryQueueConfig[T] {
+	c.policy = policy
+	return c
+}
+
+// WithKeyFunc 设置重试 key 生成函数。
+func (c *RetryQueueConfig[T]) WithKeyFunc(fn RetryKeyFunc[T]) *RetryQueueConfig[T] {
+	c.keyFunc = fn
+	return c
+}
+
+func (c *RetryQueueConfig[T]) build() *wkq.RetryQueueConfig {
+	config := wkq.NewRetryQueueConfig()
+	config.WithCodec(newTypedCodec[T](nil))
+	if c != nil {
+		c.DelayingQueueConfig.applyTo(&config.DelayingQueueConfig)
+		if c.callback != nil {
+			config.WithCallback(&retryQueueCallbackAdapter[T]{
+				delayingQueueCallbackAdapter: *newDelayingQueueCallbackAdapter[T](c.callback),
+				cb:                           c.callback,
+			})
+		}
+		if c.policy != nil {
+			config.WithPolicy(&retryPolicyAdapter[T]{policy: c.policy})
+		}
+		if c.keyFunc != nil {
+			fn := c.keyFunc
+			config.WithKeyFunc(func(value interface{}) string { return fn(mustCast[T](value)) })
+		}
+	}
+	return config
+}
+
+// LeasedQueueConfig 定义类型化租约队列配置。
+type LeasedQueueConfig[T any] struct {
+	QueueConfig[T]
+	leaseDuration time.Duration
+	scanInterval  time.Duration
+}
+
+// NewLeasedQueueConfig 返回带默认值的租约队列配置。
+func NewLeasedQueueConfig[T any]() *LeasedQueueConfig[T] {
+	return &LeasedQueueConfig[T]{}
+}
+
+// WithLeaseDuration 设置默认租约时长。
+func (c *LeasedQueueConfig[T]) WithLeaseDuration(duration time.Duration) *LeasedQueueConfig[T] {
+	c.leaseDuration = duration
+	return c
+}
+
+// WithScanInterval 设置租约扫描间隔。
+func (c *LeasedQueueConfig[T]) WithScanInterval(interval time.Duration) *LeasedQueueConfig[T] {
+	c.scanInterval = interval
+	return c
+}
+
+func (c *LeasedQueueConfig[T]) build() *wkq.LeasedQueueConfig {
+	config := wkq.NewLeasedQueueConfig()
+	config.WithCodec(newTypedCodec[T](nil))
+	if c != nil {
+		c.QueueConfig.applyTo(&config.QueueConfig)
+		if c.leaseDuration > 0 {
+			config.WithLeaseDuration(c.leaseDuration)
+		}
+		if c.scanInterval > 0 {
+			config.WithScanInterval(c.scanInterval)
+		}
+	}
+	return config
+}
+
+// BoundedBlockingQueueConfig 定义类型化有界阻塞队列配置。
+type BoundedBlockingQueueConfig[T any] struct {
+	QueueConfig[T]
+	capacity int
+}
+
+// NewBoundedBlockingQueueConfig 返回带默认值的有界阻塞队列配置。
+func NewBoundedBlockingQueueConfig[T any]() *BoundedBlockingQueueConfig[T] {
+	return &BoundedBlockingQueueConfig[T]{}
+}
+
+// WithCapacity 设置队列容量上限。
+func (c *BoundedBlockingQueueConfig[T]) WithCapacity(capacity int) *BoundedBlockingQueueConfig[T] {
+	c.capacity = capacity
+	return c
+}
+
+func (c *BoundedBlockingQueueConfig[T]) build() *wkq.BoundedBlockingQueueConfig {
+	config := wkq.NewBoundedBlockingQueueConfig()
+	config.WithCodec(newTypedCodec[T](nil))
+	if c != nil {
+		c.QueueConfig.applyTo(&config.QueueConfig)
+		if c.capacity > 0 {
+			config.WithCapacity(c.capacity)
+		}
+	}
+	return config
+}
+
+// TimerQueueConfig 定义类型化定时队列配置。
+type TimerQueueConfig[T any] struct {
+	QueueConfig[T]
+	wheelTick time.Duration
+}
+
+// NewTimerQueueConfig 返回带默认值的定时队列配置。
+func NewTimerQueueConfig[T any]() *TimerQueueConfig[T] {
+	return &TimerQueueConfig[T]{}
+}
+
+// WithTimingWheel 以刻度为 tick 的分层时间轮调度定时元素。
+func (c *TimerQueueConfig[T]) WithTimingWheel(tick time.Duration) *TimerQueueConfig[T] {
+	c.wheelTick = tick
+	return c
+}
+
+func (c *TimerQueueConfig[T]) build() *wkq.TimerQueueConfig {
+	config := wkq.NewTimerQueueConfig()
+	config.WithCodec(newTypedCodec[T](nil))
+	if c != nil {
+		c.QueueConfig.applyTo(&config.QueueConfig)
+		if c.wheelTick > 0 {
+			config.WithTimingWheel(c.wheelTick)
+		}
+	}
+	return config
+}
@@ -0,0 +1,29 @@
+package generic
+
+import wkq "github.com/shengyanli1982/workqueue/v2"
+
+type delayingQueueImpl[T any] struct {
+	*queueImpl[T]
+	queue wkq.DelayingQueue
+}
+
+// NewDelayingQueue 创建类型化延迟队列。
+func NewDelayingQueue[T any](config *DelayingQueueConfig[T]) DelayingQueue[T] {
+	return newDelayingQueue[T](wkq.NewDelayingQueue(config.build()))
+}
+
+func newDelayingQueue[T any](queue wkq.DelayingQueue) *delayingQueueImpl[T] {
+	return &delayingQueueImpl[T]{queueImpl: newQueue[T](queue), queue: queue}
+}
+
+func (q *delayingQueueImpl[T]) PutWithDelay(value T, delay int64) error {
+	return q.queue.PutWithDelay(value, delay)
+}
+
+func (q *delayingQueueImpl[T]) HeapRange(fn func(value T, delay int64) bool) {
+	if fn == nil {
+		return
+	}
+
+	q.queue.HeapRange(func(value interface{}, delay int64) bool { return fn(mustCast[T](value), delay) })
+}
@@ -0,0 +1,208 @@
+// Package generic 基于泛型提供类型安全的队列 API，底层复用 workqueue 的全部实现。
+package generic
+
+import (
+	"context"
+	"io"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// Queue 是 workqueue.Queue 的类型安全版本：消费端 Get 成功后应调用 Done。
+type Queue[T any] interface {
+	Put(value T) error
+
+	Get() (value T, err error)
+
+	GetWithContext(ctx context.Context) (value T, err error)
+
+	GetBlocking() (value T, err error)
+
+	PutBatch(values []T) error
+
+	GetBatch(max int) (values []T, err error)
+
+	GetBatchWithContext(ctx context.Context, max int) (values []T, err error)
+
+	Done(value T)
+
+	Len() int
+
+	Values() []T
+
+	Range(fn func(value T) bool)
+
+	Shutdown()
+
+	// ShutdownWithDrain 停止接收新元素并等待剩余与处理中元素完成；ctx 先结束时返回尚未投递的元素。
+	ShutdownWithDrain(ctx context.Context) (pending []T, err error)
+
+	IsClosed() bool
+
+	// Stats 返回队列指标快照。
+	Stats() wkq.QueueStats
+
+	// Snapshot 将尚未投递的元素及调度状态写入 w。
+	Snapshot(w io.Writer) error
+
+	// Restore 读取 Snapshot 写出的数据，将元素及调度状态追加到队列中。
+	Restore(r io.Reader) error
+}
+
+// DelayingQueue 在普通队列基础上支持按延迟时间入队。
+type DelayingQueue[T any] interface {
+	Queue[T]
+
+	PutWithDelay(value T, delay int64) error
+
+	HeapRange(fn func(value T, delay int64) bool)
+}
+
+// PriorityQueue 在普通队列基础上支持按优先级入队。
+type PriorityQueue[T any] interface {
+	Queue[T]
+
+	PutWithPriority(value T, priority int64) error
+
+	UpdatePriority(value T, priority int64) error
+
+	Remove(value T) bool
+
+	Contains(value T) bool
+
+	HeapRange(fn func(value T, priority int64) bool)
+}
+
+// RateLimitingQueue 在 DelayingQueue 基础上提供限流入队能力。
+type RateLimitingQueue[T any] interface {
+	DelayingQueue[T]
+
+	PutWithLimited(value T) error
+}
+
+// RetryQueue 在 DelayingQueue 基础上提供失败重试能力。
+type RetryQueue[T any] interface {
+	DelayingQueue[T]
+
+	Retry(value T, reason error) error
+
+	Forget(value T)
+
+	NumRequeues(value T) int
+}
+
+// LeasedQueue 在基础队列上提供租约消费语义。
+type LeasedQueue[T any] interface {
+	Queue[T]
+
+	GetWithLease(timeout time.Duration) (value T, leaseID string, err error)
+
+	GetWithLeaseContext(ctx context.Context, timeout time.Duration) (value T, leaseID string, err error)
+
+	Ack(leaseID string) error
+
+	Nack(leaseID string, reason error) error
+
+	ExtendLease(leaseID string, timeout time.Duration) error
+
+	// RangeLeases 按截止时间先后遍历尚未确认的租约。
+	RangeLeases(fn func(leaseID string, value T, deadline time.Time) bool)
+}
+
+// BoundedBlockingQueue 在基础队列上提供容量限制和阻塞读写。
+type BoundedBlockingQueue[T any] interface {
+	Queue[T]
+
+	Cap() int
+
+	PutWithContext(ctx context.Context, value T) error
+}
+
+// TimerQueue 在基础队列上提供按绝对时间调度入队。
+type TimerQueue[T any] interface {
+	Queue[T]
+
+	PutAt(value T, at time.Time) error
+
+	PutAfter(value T, after time.Duration) error
+
+	Cancel(value T) bool
+
+	HeapRange(fn func(value T, at int64) bool)
+
+	PutAtWithID(value T, at time.Time) (uint64, error)
+
+	CancelByID(id uint64) bool
+
+	Reschedule(id uint64, at time.Time) error
+
+	Lookup(id uint64) (value T, at time.Time, ok bool)
+
+	PutEvery(value T, interval time.Duration, config *wkq.RecurringConfig) (wkq.Recurring, error)
+
+	PutCron(value T, expr string, config *wkq.RecurringConfig) (wkq.Recurring, error)
+}
+
+// QueueCallback 定义基础队列生命周期回调。
+type QueueCallback[T any] interface {
+	OnPut(value T)
+
+	OnGet(value T)
+
+	OnDone(value T)
+}
+
+// DelayingQueueCallback 扩展延迟队列回调。
+type DelayingQueueCallback[T any] interface {
+	QueueCallback[T]
+
+	OnDelay(value T, delay int64)
+
+	OnPullError(value T, reason error)
+}
+
+// PriorityQueueCallback 扩展优先队列回调。
+type PriorityQueueCallback[T any] interface {
+	QueueCallback[T]
+
+	OnPriority(value T, priority int64)
+
+	OnUpdatePriority(value T, priority int64)
+
+	OnRemove(value T)
+}
+
+// RateLimitingQueueCallback 扩展限流队列回调。
+type RateLimitingQueueCallback[T any] interface {
+	DelayingQueueCallback[T]
+
+	OnLimited(value T)
+}
+
+// RetryQueueCallback 扩展重试队列回调。
+type RetryQueueCallback[T any] interface {
+	DelayingQueueCallback[T]
+
+	OnRetry(value T, attempt int, delay time.Duration, reason error)
+
+	OnRetryExhausted(value T, attempt int, reason error)
+
+	OnForget(value T)
+}
+
+// Limiter 决定元素下一次允许入队的等待时长。
+type Limiter[T any] interface {
+	When(value T) time.Duration
+}
+
+// RetryPolicy 决定元素下一次重试的等待时长以及是否继续重试。
+type RetryPolicy[T any] interface {
+	NextDelay(value T, attempt int, reason error) (delay time.Duration, retry bool)
+}
+
+// KeyFunc 生成幂等判重所使用的稳定 key。
+type KeyFunc[T any] func(value T) string
+
+// RetryKeyFunc 生成重试计数所使用的稳定 key。
+type RetryKeyFunc[T any] func(value T) string
@@ -0,0 +1,58 @@
+package generic
+
+import (
+	"context"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+type leasedQueueImpl[T any] struct {
+	*queueImpl[T]
+	queue wkq.LeasedQueue
+}
+
+// NewLeasedQueue 创建类型化租约队列。
+func NewLeasedQueue[T any](config *LeasedQueueConfig[T]) LeasedQueue[T] {
+	queue := wkq.NewLeasedQueue(config.build())
+	return &leasedQueueImpl[T]{queueImpl: newQueue[T](queue), queue: queue}
+}
+
+func (q *leasedQueueImpl[T]) GetWithLease(timeout time.Duration) (T, string, error) {
+	value, leaseID, err := q.queue.GetWithLease(timeout)
+	if err != nil {
+		var zero T
+		return zero, "", err
+	}
+	item, err := cast[T](value)
+	return item, leaseID, err
+}
+
+func (q *leasedQueueImpl[T]) GetWithLeaseContext(ctx context.Context, timeout time.Duration) (T, string, error) {
+	value, leaseID, err := q.queue.GetWithLeaseContext(ctx, timeout)
+	if err != nil {
+		var zero T
+		return zero, "", err
+	}
+	item, err := cast[T](value)
+	return item, leaseID, err
+}
+
+func (q *leasedQueueImpl[T]) Ack(leaseID string) error { return q.queue.Ack(leaseID) }
+
+func (q *leasedQueueImpl[T]) Nack(leaseID string, reason error) error {
+	return q.queue.Nack(leaseID, reason)
+}
+
+func (q *leasedQueueImpl[T]) ExtendLease(leaseID string, timeout time.Duration) error {
+	return q.queue.ExtendLease(leaseID, timeout)
+}
+
+func (q *leasedQueueImpl[T]) RangeLeases(fn func(leaseID string, value T, deadline time.Time) bool) {
+	if fn == nil {
+		return
+	}
+	q.queue.RangeLeases(func(leaseID string, value interface{}, deadline time.Time) bool {
+		return fn(leaseID, mustCast[T](value), deadline)
+	})
+}
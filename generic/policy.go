@@ -0,0 +1,67 @@
+package generic
+
+import (
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// untypedRetryPolicy 复用 workqueue 内置的非类型化重试策略。
+type untypedRetryPolicy[T any] struct {
+	policy wkq.RetryPolicy
+}
+
+func (p *untypedRetryPolicy[T]) NextDelay(value T, attempt int, reason error) (time.Duration, bool) {
+	return p.policy.NextDelay(value, attempt, reason)
+}
+
+// NewNopRetryPolicyImpl 返回始终不重试的策略。
+func NewNopRetryPolicyImpl[T any]() RetryPolicy[T] {
+	return &untypedRetryPolicy[T]{policy: wkq.NewNopRetryPolicyImpl()}
+}
+
+// NewExponentialRetryPolicy 使用指数退避策略创建重试策略。
+// maxRetries 小于 0 表示不限制最大重试次数。
+func NewExponentialRetryPolicy[T any](baseDelay, maxDelay time.Duration, maxRetries int) RetryPolicy[T] {
+	return &untypedRetryPolicy[T]{policy: wkq.NewExponentialRetryPolicy(baseDelay, maxDelay, maxRetries)}
+}
+
+// untypedLimiter 复用 workqueue 内置的非类型化限流器。
+type untypedLimiter[T any] struct {
+	limiter wkq.Limiter
+}
+
+func (l *untypedLimiter[T]) When(value T) time.Duration { return l.limiter.When(value) }
+
+// NewNopRateLimiterImpl 返回始终无等待的限流器。
+func NewNopRateLimiterImpl[T any]() Limiter[T] {
+	return &untypedLimiter[T]{limiter: wkq.NewNopRateLimiterImpl()}
+}
+
+// NewBucketRateLimiterImpl 使用 token bucket 策略创建限流器。
+func NewBucketRateLimiterImpl[T any](r float64, burst int64) Limiter[T] {
+	return &untypedLimiter[T]{limiter: wkq.NewBucketRateLimiterImpl(r, burst)}
+}
+
+// NewBucketRateLimiterImplWithClock 使用 token bucket 策略创建限流器，令牌按 clock 的时间补充。
+func NewBucketRateLimiterImplWithClock[T any](r float64, burst int64, clock wkq.Clock) Limiter[T] {
+	return &untypedLimiter[T]{limiter: wkq.NewBucketRateLimiterImplWithClock(r, burst, clock)}
+}
+
+// retryPolicyAdapter 将类型化重试策略适配为 workqueue.RetryPolicy。
+type retryPolicyAdapter[T any] struct {
+	policy RetryPolicy[T]
+}
+
+func (a *retryPolicyAdapter[T]) NextDelay(value interface{}, attempt int, reason error) (time.Duration, bool) {
+	return a.policy.NextDelay(mustCast[T](value), attempt, reason)
+}
+
+// limiterAdapter 将类型化限流器适配为 workqueue.Limiter。
+type limiterAdapter[T any] struct {
+	limiter Limiter[T]
+}
+
+func (a *limiterAdapter[T]) When(value interface{}) time.Duration {
+	return a.limiter.When(mustCast[T](value))
+}
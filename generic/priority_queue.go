@@ -0,0 +1,38 @@
+package generic
+
+import wkq "github.com/shengyanli1982/workqueue/v2"
+
+type priorityQueueImpl[T any] struct {
+	*queueImpl[T]
+	queue wkq.PriorityQueue
+}
+
+// NewPriorityQueue 创建类型化优先级队列。
+func NewPriorityQueue[T any](config *PriorityQueueConfig[T]) PriorityQueue[T] {
+	queue := wkq.NewPriorityQueue(config.build())
+	return &priorityQueueImpl[T]{queueImpl: newQueue[T](queue), queue: queue}
+}
+
+func (q *priorityQueueImpl[T]) PutWithPriority(value T, priority int64) error {
+	return q.queue.PutWithPriority(value, priority)
+}
+
+func (q *priorityQueueImpl[T]) UpdatePriority(value T, priority int64) error {
+	return q.queue.UpdatePriority(value, priority)
+}
+
+func (q *priorityQueueImpl[T]) Remove(value T) bool {
+	return q.queue.Remove(value)
+}
+
+func (q *priorityQueueImpl[T]) Contains(value T) bool {
+	return q.queue.Contains(value)
+}
+
+func (q *priorityQueueImpl[T]) HeapRange(fn func(value T, priority int64) bool) {
+	if fn == nil {
+		return
+	}
+
+	q.queue.HeapRange(func(value interface{}, priority int64) bool { return fn(mustCast[T](value), priority) })
+}
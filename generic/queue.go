@@ -0,0 +1,142 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// queueImpl 将 workqueue.Queue 适配为类型安全的 Queue[T]，存储与调度逻辑完全复用底层实现。
+type queueImpl[T any] struct {
+	queue wkq.Queue
+}
+
+// NewQueue 创建类型化基础队列。
+func NewQueue[T any](config *QueueConfig[T]) Queue[T] {
+	return newQueue[T](wkq.NewQueue(config.build()))
+}
+
+func newQueue[T any](queue wkq.Queue) *queueImpl[T] {
+	return &queueImpl[T]{queue: queue}
+}
+
+func (q *queueImpl[T]) Put(value T) error { return q.queue.Put(value) }
+
+func (q *queueImpl[T]) Get() (T, error) { return result[T](q.queue.Get()) }
+
+func (q *queueImpl[T]) GetWithContext(ctx context.Context) (T, error) {
+	return result[T](q.queue.GetWithContext(ctx))
+}
+
+func (q *queueImpl[T]) GetBlocking() (T, error) { return result[T](q.queue.GetBlocking()) }
+
+func (q *queueImpl[T]) PutBatch(values []T) error {
+	items := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		items = append(items, value)
+	}
+	return q.queue.PutBatch(items)
+}
+
+func (q *queueImpl[T]) GetBatch(max int) ([]T, error) { return results[T](q.queue.GetBatch(max)) }
+
+func (q *queueImpl[T]) GetBatchWithContext(ctx context.Context, max int) ([]T, error) {
+	return results[T](q.queue.GetBatchWithContext(ctx, max))
+}
+
+func (q *queueImpl[T]) Done(value T) { q.queue.Done(value) }
+
+func (q *queueImpl[T]) Len() int { return q.queue.Len() }
+
+func (q *queueImpl[T]) Values() []T { return mustCastAll[T](q.queue.Values()) }
+
+func (q *queueImpl[T]) Range(fn func(value T) bool) {
+	if fn == nil {
+		return
+	}
+
+	q.queue.Range(func(value interface{}) bool { return fn(mustCast[T](value)) })
+}
+
+func (q *queueImpl[T]) Shutdown() { q.queue.Shutdown() }
+
+func (q *queueImpl[T]) ShutdownWithDrain(ctx context.Context) ([]T, error) {
+	pending, err := q.queue.ShutdownWithDrain(ctx)
+	items, castErr := castAll[T](pending)
+	if err == nil {
+		err = castErr
+	}
+	return items, err
+}
+
+func (q *queueImpl[T]) IsClosed() bool { return q.queue.IsClosed() }
+
+func (q *queueImpl[T]) Stats() wkq.QueueStats { return q.queue.Stats() }
+
+func (q *queueImpl[T]) Snapshot(w io.Writer) error { return q.queue.Snapshot(w) }
+
+func (q *queueImpl[T]) Restore(r io.Reader) error { return q.queue.Restore(r) }
+
+// result 将底层 Get 系列方法的返回值还原为 T。
+func result[T any](value interface{}, err error) (T, error) {
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return cast[T](value)
+}
+
+// results 将底层批量读取的返回值还原为 []T。
+func results[T any](values []interface{}, err error) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	return castAll[T](values)
+}
+
+// castAll 将 []interface{} 逐个还原为 []T，跳过类型不符的元素并返回第一个错误。
+func castAll[T any](values []interface{}) ([]T, error) {
+	items := make([]T, 0, len(values))
+	var first error
+	for _, value := range values {
+		item, err := cast[T](value)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, first
+}
+
+// mustCastAll 与 castAll 相同，用于无法返回错误的路径。
+func mustCastAll[T any](values []interface{}) []T {
+	items := make([]T, 0, len(values))
+	for _, value := range values {
+		items = append(items, mustCast[T](value))
+	}
+	return items
+}
+
+// cast 将内部存储的 interface{} 还原为 T，类型不符时返回 ErrElementTypeMismatch。
+func cast[T any](value interface{}) (T, error) {
+	v, ok := value.(T)
+	if !ok {
+		return v, fmt.Errorf("%w: %T is not %T", wkq.ErrElementTypeMismatch, value, v)
+	}
+	return v, nil
+}
+
+// mustCast 用于回调、比较函数等无法返回错误的路径。元素只会经由 Put 或按 T 解码的快照与日志进入队列，
+// 类型不符说明出现了实现错误，直接 panic 而不是静默地使用零值。
+func mustCast[T any](value interface{}) T {
+	v, err := cast[T](value)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
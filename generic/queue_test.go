@@ -0,0 +1,213 @@
+package generic
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type testJob struct {
+	ID   int
+	Name string
+}
+
+type testQueueCallback struct {
+	queueCallbackImpl[*testJob]
+	puts, gets, dones []int
+}
+
+func (c *testQueueCallback) OnPut(value *testJob) { c.puts = append(c.puts, value.ID) }
+
+func (c *testQueueCallback) OnGet(value *testJob) { c.gets = append(c.gets, value.ID) }
+
+func (c *testQueueCallback) OnDone(value *testJob) { c.dones = append(c.dones, value.ID) }
+
+func TestQueue_PutAndGet(t *testing.T) {
+	q := NewQueue[*testJob](nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put(&testJob{ID: 1, Name: "a"}))
+	assert.NoError(t, q.Put(&testJob{ID: 2, Name: "b"}))
+	assert.Equal(t, 2, q.Len())
+
+	job, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, job.ID, "Get should return the typed value without assertion")
+
+	values := q.Values()
+	assert.Len(t, values, 1)
+	assert.Equal(t, "b", values[0].Name)
+}
+
+func TestQueue_Get_Empty(t *testing.T) {
+	q := NewQueue[int](nil)
+	defer q.Shutdown()
+
+	v, err := q.Get()
+	assert.ErrorIs(t, err, wkq.ErrQueueIsEmpty)
+	assert.Equal(t, 0, v, "Get should return the zero value on error")
+}
+
+func TestQueue_Put_NilPointer(t *testing.T) {
+	q := NewQueue[*testJob](nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put(nil), "A typed nil pointer is a valid element")
+}
+
+func TestQueue_GetWithContext(t *testing.T) {
+	q := NewQueue[string](nil)
+	defer q.Shutdown()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = q.Put("hello")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	v, err := q.GetWithContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", v)
+}
+
+func TestQueue_Idempotent(t *testing.T) {
+	q := NewQueue(NewQueueConfig[string]().WithValueIdempotent())
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("job"))
+	assert.ErrorIs(t, q.Put("job"), wkq.ErrElementAlreadyExist)
+}
+
+func TestQueue_Range(t *testing.T) {
+	q := NewQueue[int](nil)
+	defer q.Shutdown()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, q.Put(i))
+	}
+
+	sum := 0
+	q.Range(func(value int) bool {
+		sum += value
+		return value < 2
+	})
+	assert.Equal(t, 3, sum, "Range should stop once fn returns false")
+}
+
+func TestQueue_Callback(t *testing.T) {
+	callback := &testQueueCallback{}
+	q := NewQueue(NewQueueConfig[*testJob]().WithCallback(callback).WithValueIdempotent())
+	defer q.Shutdown()
+
+	job := &testJob{ID: 7}
+	assert.NoError(t, q.Put(job))
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	q.Done(v)
+
+	assert.Equal(t, []int{7}, callback.puts)
+	assert.Equal(t, []int{7}, callback.gets)
+	assert.Equal(t, []int{7}, callback.dones)
+}
+
+func TestQueue_KeyFunc(t *testing.T) {
+	q := NewQueue(NewQueueConfig[*testJob]().
+		WithValueIdempotent().
+		WithKeyFunc(func(job *testJob) string { return job.Name }))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put(&testJob{ID: 1, Name: "same"}))
+	assert.ErrorIs(t, q.Put(&testJob{ID: 2, Name: "same"}), wkq.ErrElementAlreadyExist)
+}
+
+func TestQueue_Batch(t *testing.T) {
+	q := NewQueue[int](nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutBatch([]int{1, 2, 3}))
+
+	values, err := q.GetBatch(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, values)
+
+	values, err = q.GetBatchWithContext(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3}, values)
+}
+
+func TestQueue_SnapshotRestore(t *testing.T) {
+	q := NewQueue[int](nil)
+	defer q.Shutdown()
+	assert.NoError(t, q.PutBatch([]int{1, 2, 3}))
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	restored := NewQueue[int](nil)
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(&buf))
+	assert.Equal(t, []int{1, 2, 3}, restored.Values())
+}
+
+func TestQueue_SnapshotRestore_JSON(t *testing.T) {
+	codec := wkq.NewJSONCodec(wkq.NewCodecRegistry())
+
+	q := NewQueue[int](NewQueueConfig[int]().WithCodec(codec))
+	defer q.Shutdown()
+	assert.NoError(t, q.Put(42))
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	// JSON 将数字解码为 float64，类型化队列应按 T 还原。
+	restored := NewQueue[int](NewQueueConfig[int]().WithCodec(codec))
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(&buf))
+	v, err := restored.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+
+	jobs := NewQueue[testJob](NewQueueConfig[testJob]().WithCodec(codec))
+	defer jobs.Shutdown()
+	assert.NoError(t, jobs.Put(testJob{ID: 7, Name: "job"}))
+	buf.Reset()
+	assert.NoError(t, jobs.Snapshot(&buf))
+
+	restoredJobs := NewQueue[testJob](NewQueueConfig[testJob]().WithCodec(codec))
+	defer restoredJobs.Shutdown()
+	assert.NoError(t, restoredJobs.Restore(&buf))
+	assert.Equal(t, []testJob{{ID: 7, Name: "job"}}, restoredJobs.Values())
+}
+
+func TestQueue_Restore_TypeMismatch(t *testing.T) {
+	strings := wkq.NewQueue(nil)
+	defer strings.Shutdown()
+	assert.NoError(t, strings.Put("not a number"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, strings.Snapshot(&buf))
+
+	q := NewQueue[int](nil)
+	defer q.Shutdown()
+	assert.ErrorIs(t, q.Restore(&buf), wkq.ErrCodecTypeMismatch, "Mismatched values should not be restored as zero values")
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestCast_TypeMismatch(t *testing.T) {
+	_, err := cast[int]("42")
+	assert.ErrorIs(t, err, wkq.ErrElementTypeMismatch)
+
+	_, err = result[int](42.0, nil)
+	assert.ErrorIs(t, err, wkq.ErrElementTypeMismatch)
+
+	v, err := cast[int](42)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
@@ -0,0 +1,18 @@
+package generic
+
+import wkq "github.com/shengyanli1982/workqueue/v2"
+
+type ratelimitingQueueImpl[T any] struct {
+	*delayingQueueImpl[T]
+	queue wkq.RateLimitingQueue
+}
+
+// NewRateLimitingQueue 创建类型化限流队列。
+func NewRateLimitingQueue[T any](config *RateLimitingQueueConfig[T]) RateLimitingQueue[T] {
+	queue := wkq.NewRateLimitingQueue(config.build())
+	return &ratelimitingQueueImpl[T]{delayingQueueImpl: newDelayingQueue[T](queue), queue: queue}
+}
+
+func (q *ratelimitingQueueImpl[T]) PutWithLimited(value T) error {
+	return q.queue.PutWithLimited(value)
+}
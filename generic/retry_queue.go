@@ -0,0 +1,20 @@
+package generic
+
+import wkq "github.com/shengyanli1982/workqueue/v2"
+
+type retryQueueImpl[T any] struct {
+	*delayingQueueImpl[T]
+	queue wkq.RetryQueue
+}
+
+// NewRetryQueue 创建类型化重试队列。
+func NewRetryQueue[T any](config *RetryQueueConfig[T]) RetryQueue[T] {
+	queue := wkq.NewRetryQueue(config.build())
+	return &retryQueueImpl[T]{delayingQueueImpl: newDelayingQueue[T](queue), queue: queue}
+}
+
+func (q *retryQueueImpl[T]) Retry(value T, reason error) error { return q.queue.Retry(value, reason) }
+
+func (q *retryQueueImpl[T]) Forget(value T) { q.queue.Forget(value) }
+
+func (q *retryQueueImpl[T]) NumRequeues(value T) int { return q.queue.NumRequeues(value) }
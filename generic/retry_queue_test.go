@@ -0,0 +1,125 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRetryPolicy struct {
+	values []*testJob
+}
+
+func (p *testRetryPolicy) NextDelay(value *testJob, attempt int, _ error) (time.Duration, bool) {
+	p.values = append(p.values, value)
+	return 10 * time.Millisecond, attempt < 2
+}
+
+type testRetryQueueCallback struct {
+	retryQueueCallbackImpl[*testJob]
+	retries   []int
+	exhausted []int
+}
+
+func (c *testRetryQueueCallback) OnRetry(value *testJob, _ int, _ time.Duration, _ error) {
+	c.retries = append(c.retries, value.ID)
+}
+
+func (c *testRetryQueueCallback) OnRetryExhausted(value *testJob, _ int, _ error) {
+	c.exhausted = append(c.exhausted, value.ID)
+}
+
+func TestRetryQueue_TypedPolicyAndCallback(t *testing.T) {
+	policy := &testRetryPolicy{}
+	callback := &testRetryQueueCallback{}
+	config := NewRetryQueueConfig[*testJob]().
+		WithPolicy(policy).
+		WithCallback(callback).
+		WithKeyFunc(func(value *testJob) string { return strconv.Itoa(value.ID) })
+	q := NewRetryQueue(config)
+	defer q.Shutdown()
+
+	job := &testJob{ID: 42}
+	assert.NoError(t, q.Put(job))
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, q.Retry(v, errors.New("failed")))
+	assert.Equal(t, 1, q.NumRequeues(&testJob{ID: 42}), "Key func should identify equal jobs")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	v, err = q.GetWithContext(ctx)
+	assert.NoError(t, err)
+	assert.Same(t, job, v)
+
+	assert.ErrorIs(t, q.Retry(v, errors.New("failed again")), wkq.ErrRetryExhausted)
+	assert.Equal(t, []*testJob{job, job}, policy.values)
+	assert.Equal(t, []int{42}, callback.retries)
+	assert.Equal(t, []int{42}, callback.exhausted)
+}
+
+func TestRetryQueue_ExponentialPolicy(t *testing.T) {
+	policy := NewExponentialRetryPolicy[string](100*time.Millisecond, time.Second, 3)
+
+	delay, retry := policy.NextDelay("task", 2, nil)
+	assert.True(t, retry)
+	assert.Equal(t, 200*time.Millisecond, delay)
+}
+
+func TestDelayingQueue_HeapRange(t *testing.T) {
+	q := NewDelayingQueue[int](nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithDelay(1, 1000))
+	assert.NoError(t, q.PutWithDelay(2, 2000))
+
+	var values []int
+	q.HeapRange(func(value int, _ int64) bool {
+		values = append(values, value)
+		return true
+	})
+	assert.Equal(t, []int{1, 2}, values)
+	assert.Equal(t, 2, q.Len())
+}
+
+func TestPriorityQueue_Order(t *testing.T) {
+	q := NewPriorityQueue[string](nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithPriority("low", wkq.PRIORITY_LOW))
+	assert.NoError(t, q.PutWithPriority("high", wkq.PRIORITY_HIGH))
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "high", v)
+}
+
+func TestPriorityQueue_Comparator(t *testing.T) {
+	q := NewPriorityQueue(NewPriorityQueueConfig[string]().WithComparator(func(a, b string) int { return len(a) - len(b) }))
+	defer q.Shutdown()
+
+	for _, v := range []string{"ccc", "a", "bb", "d"} {
+		assert.NoError(t, q.PutWithPriority(v, wkq.PRIORITY_NORMAL))
+	}
+	assert.Equal(t, []string{"a", "d", "bb", "ccc"}, q.Values())
+}
+
+func TestLeasedQueue_GetWithLease(t *testing.T) {
+	q := NewLeasedQueue(NewLeasedQueueConfig[int]().WithScanInterval(5 * time.Millisecond))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put(9))
+
+	v, leaseID, err := q.GetWithLease(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, v)
+	assert.NoError(t, q.Ack(leaseID))
+	assert.ErrorIs(t, q.Ack(leaseID), wkq.ErrLeaseNotFound)
+}
@@ -0,0 +1,61 @@
+package generic
+
+import (
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+type timerQueueImpl[T any] struct {
+	*queueImpl[T]
+	queue wkq.TimerQueue
+}
+
+// NewTimerQueue 创建类型化定时队列。
+func NewTimerQueue[T any](config *TimerQueueConfig[T]) TimerQueue[T] {
+	queue := wkq.NewTimerQueue(config.build())
+	return &timerQueueImpl[T]{queueImpl: newQueue[T](queue), queue: queue}
+}
+
+func (q *timerQueueImpl[T]) PutAt(value T, at time.Time) error { return q.queue.PutAt(value, at) }
+
+func (q *timerQueueImpl[T]) PutAfter(value T, after time.Duration) error {
+	return q.queue.PutAfter(value, after)
+}
+
+func (q *timerQueueImpl[T]) Cancel(value T) bool { return q.queue.Cancel(value) }
+
+func (q *timerQueueImpl[T]) PutAtWithID(value T, at time.Time) (uint64, error) {
+	return q.queue.PutAtWithID(value, at)
+}
+
+func (q *timerQueueImpl[T]) CancelByID(id uint64) bool { return q.queue.CancelByID(id) }
+
+func (q *timerQueueImpl[T]) Reschedule(id uint64, at time.Time) error {
+	return q.queue.Reschedule(id, at)
+}
+
+func (q *timerQueueImpl[T]) Lookup(id uint64) (T, time.Time, bool) {
+	value, at, ok := q.queue.Lookup(id)
+	if !ok {
+		var zero T
+		return zero, at, false
+	}
+	return mustCast[T](value), at, true
+}
+
+func (q *timerQueueImpl[T]) PutEvery(value T, interval time.Duration, config *wkq.RecurringConfig) (wkq.Recurring, error) {
+	return q.queue.PutEvery(value, interval, config)
+}
+
+func (q *timerQueueImpl[T]) PutCron(value T, expr string, config *wkq.RecurringConfig) (wkq.Recurring, error) {
+	return q.queue.PutCron(value, expr, config)
+}
+
+func (q *timerQueueImpl[T]) HeapRange(fn func(value T, at int64) bool) {
+	if fn == nil {
+		return
+	}
+
+	q.queue.HeapRange(func(value interface{}, at int64) bool { return fn(mustCast[T](value), at) })
+}
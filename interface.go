@@ -2,10 +2,13 @@ package workqueue
 
 import (
 	"context"
+	"io"
+	"sort"
 	"time"
 
 	hp "github.com/shengyanli1982/workqueue/v2/internal/container/heap"
 	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
+	"github.com/shengyanli1982/workqueue/v2/internal/container/wheel"
 )
 
 // Queue 定义基础队列语义：消费端 Get 成功后应调用 Done。
@@ -14,6 +17,23 @@ type Queue = interface {
 
 	Get() (value interface{}, err error)
 
+	// GetWithContext 阻塞等待直至有可消费元素、ctx 结束或队列关闭。
+	GetWithContext(ctx context.Context) (value interface{}, err error)
+
+	// GetBlocking 阻塞等待直至有可消费元素或队列关闭。
+	GetBlocking() (value interface{}, err error)
+
+	// PutBatch 批量入队，部分元素失败时返回 *BatchError。
+	PutBatch(values []interface{}) error
+
+	// GetBatch 非阻塞地读取最多 max 个元素。
+	GetBatch(max int) (values []interface{}, err error)
+
+	// GetBatchWithContext 阻塞等待至少一个元素后读取最多 max 个元素。
+	GetBatchWithContext(ctx context.Context, max int) (values []interface{}, err error)
+
+	// Done 结束元素的处理。非幂等模式下处理中数量只是计数，不记录具体出队了哪些值，
+	// 对未出队的值调用 Done 同样会减少计数，但不会减到零以下。
 	Done(value interface{})
 
 	Len() int
@@ -24,7 +44,20 @@ type Queue = interface {
 
 	Shutdown()
 
+	// ShutdownWithDrain 停止接收新元素并等待剩余与处理中元素完成后关闭队列；
+	// ctx 先结束时关闭队列并返回尚未投递的元素。
+	ShutdownWithDrain(ctx context.Context) (pending []interface{}, err error)
+
 	IsClosed() bool
+
+	// Stats 返回队列指标快照。
+	Stats() QueueStats
+
+	// Snapshot 将尚未投递的元素及调度状态写入 w，元素使用配置的 Codec 编码。
+	Snapshot(w io.Writer) error
+
+	// Restore 读取 Snapshot 写出的数据，将元素及调度状态追加到队列中。
+	Restore(r io.Reader) error
 }
 
 // DelayingQueue 在普通队列基础上支持按延迟时间入队。
@@ -33,6 +66,7 @@ type DelayingQueue = interface {
 
 	PutWithDelay(value interface{}, delay int64) error
 
+	// HeapRange 按到期时间从早到晚遍历尚未到期的元素，红黑树与时间轮两种调度方式顺序一致。
 	HeapRange(fn func(value interface{}, delay int64) bool)
 }
 
@@ -42,6 +76,14 @@ type PriorityQueue = interface {
 
 	PutWithPriority(value interface{}, priority int64) error
 
+	// UpdatePriority 修改仍在队列中的元素的优先级，元素按 Put 时的 key 定位。
+	UpdatePriority(value interface{}, priority int64) error
+
+	// Remove 撤回仍在队列中的元素，返回是否找到。
+	Remove(value interface{}) bool
+
+	Contains(value interface{}) bool
+
 	HeapRange(fn func(value interface{}, delay int64) bool)
 }
 
@@ -82,6 +124,8 @@ type DeadLetterQueue = interface {
 
 	GetDead() (*DeadLetter, error)
 
+	GetDeadWithContext(ctx context.Context) (*DeadLetter, error)
+
 	AckDead(letter *DeadLetter) error
 
 	RequeueDead(letter *DeadLetter, target Queue) error
@@ -95,11 +139,16 @@ type LeasedQueue = interface {
 
 	GetWithLease(timeout time.Duration) (value interface{}, leaseID string, err error)
 
+	GetWithLeaseContext(ctx context.Context, timeout time.Duration) (value interface{}, leaseID string, err error)
+
 	Ack(leaseID string) error
 
 	Nack(leaseID string, reason error) error
 
 	ExtendLease(leaseID string, timeout time.Duration) error
+
+	// RangeLeases 按截止时间先后遍历尚未确认的租约。
+	RangeLeases(fn func(leaseID string, value interface{}, deadline time.Time) bool)
 }
 
 // BoundedBlockingQueue 在基础队列上提供容量限制和阻塞读写。
@@ -109,8 +158,6 @@ type BoundedBlockingQueue = interface {
 	Cap() int
 
 	PutWithContext(ctx context.Context, value interface{}) error
-
-	GetWithContext(ctx context.Context) (value interface{}, err error)
 }
 
 // TimerQueue 在基础队列上提供按绝对时间调度入队。
@@ -121,9 +168,91 @@ type TimerQueue = interface {
 
 	PutAfter(value interface{}, after time.Duration) error
 
+	// Cancel 取消与 value 匹配且最早触发的一次性定时元素，返回是否找到。
+	// 周期调度不受影响，需通过 PutEvery 与 PutCron 返回的 Recurring 终止。
 	Cancel(value interface{}) bool
 
+	// HeapRange 按触发时间从早到晚遍历尚未触发的元素，红黑树与时间轮两种调度方式顺序一致。
 	HeapRange(fn func(value interface{}, at int64) bool)
+
+	// PutAtWithID 与 PutAt 相同，额外返回定时 ID，供 CancelByID、Reschedule 与 Lookup 以 O(1) 定位元素。
+	// at 已过时元素同样经由定时容器立即触发，触发前 ID 仍然有效。
+	PutAtWithID(value interface{}, at time.Time) (id uint64, err error)
+
+	// CancelByID 取消尚未触发的定时元素，返回是否找到。
+	CancelByID(id uint64) bool
+
+	// Reschedule 修改尚未触发的定时元素的触发时间，找不到时返回 ErrTimerNotFound。
+	Reschedule(id uint64, at time.Time) error
+
+	// Lookup 返回尚未触发的定时元素及其触发时间。
+	Lookup(id uint64) (value interface{}, at time.Time, ok bool)
+
+	// PutEvery 按固定间隔周期性地将元素放入队列，config 为 nil 时立即开始且不设结束时间。
+	PutEvery(value interface{}, interval time.Duration, config *RecurringConfig) (Recurring, error)
+
+	// PutCron 按 cron 表达式周期性地将元素放入队列，支持 5 或 6 个字段（带秒）。
+	PutCron(value interface{}, expr string, config *RecurringConfig) (Recurring, error)
+}
+
+// Recurring 为周期调度的句柄，各方法可以并发调用。
+type Recurring = interface {
+	// Pause 暂停调度并撤销尚未触发的一次，调度由运行变为暂停时返回 true。
+	Pause() bool
+
+	// Resume 恢复调度，下一次触发时间从当前时刻起重新计算，错过的触发不会补发。
+	// 调度由暂停恢复为运行时返回 true。
+	Resume() bool
+
+	// Cancel 终止调度，终止后不可恢复。调度由未终止变为终止时返回 true。
+	Cancel() bool
+
+	// Next 返回下一次触发时间，暂停或已终止时返回零值。
+	Next() time.Time
+
+	IsPaused() bool
+
+	// IsDone 报告调度是否已终止：被取消、超出结束时间或队列已关闭。
+	IsDone() bool
+}
+
+// FlowStats 为公平队列中单个流的指标快照。
+type FlowStats struct {
+	Flow string
+
+	// Depth 为该流当前可消费的元素数量。
+	Depth int
+
+	// Adds 为该流累计接收的入队次数。
+	Adds uint64
+
+	// Gets 为该流累计被取出的元素数量。
+	Gets uint64
+
+	// Weight 为该流的权重。
+	Weight int
+}
+
+// FairQueue 按流（例如租户）隔离元素，Get 在有积压的流之间按权重轮流出队，避免单个流占满队列。
+type FairQueue = interface {
+	Queue
+
+	// PutWithFlow 将元素放入指定的流，Put 与 PutBatch 按配置的 FlowFunc 归属。
+	PutWithFlow(value interface{}, flow string) error
+
+	// Flows 返回各个流的指标快照，按流名称排序。
+	Flows() []FlowStats
+}
+
+// Handler 处理单个元素，返回错误时由 Runner 触发重试、Nack 或死信。
+type Handler = func(ctx context.Context, value interface{}) error
+
+// Runner 以固定数量的工作协程持续消费队列并调用 Handler。
+type Runner = interface {
+	// Run 启动工作协程并阻塞，直至 ctx 结束、调用 Stop 或队列关闭。Run 返回后可以再次调用。
+	Run(ctx context.Context) error
+
+	Stop()
 }
 
 // QueueCallback 定义基础队列生命周期回调。
@@ -149,6 +278,17 @@ type PriorityQueueCallback = interface {
 	QueueCallback
 
 	OnPriority(value interface{}, priority int64)
+
+	OnUpdatePriority(value interface{}, priority int64)
+
+	OnRemove(value interface{})
+}
+
+// FairQueueCallback 扩展公平队列回调。
+type FairQueueCallback = interface {
+	QueueCallback
+
+	OnFlow(value interface{}, flow string)
 }
 
 // RateLimitingQueueCallback 扩展限流队列回调。
@@ -180,6 +320,72 @@ type DeadLetterQueueCallback = interface {
 	OnRequeueDead(letter *DeadLetter, target Queue)
 }
 
+// RunnerCallback 定义 Runner 处理结果回调。
+type RunnerCallback = interface {
+	OnSuccess(value interface{}, elapsed time.Duration)
+
+	OnFailure(value interface{}, reason error)
+
+	OnPanic(value interface{}, recovered interface{})
+}
+
+// GaugeMetric 记录可增减的瞬时值，例如队列深度。
+type GaugeMetric = interface {
+	Inc()
+
+	Dec()
+}
+
+// SettableGaugeMetric 记录可直接设置的瞬时值。
+type SettableGaugeMetric = interface {
+	Set(value float64)
+}
+
+// CounterMetric 记录单调递增的计数。
+type CounterMetric = interface {
+	Inc()
+}
+
+// HistogramMetric 记录观测值的分布。
+type HistogramMetric = interface {
+	Observe(value float64)
+}
+
+// MetricsProvider 按队列名称创建各项指标，指标语义与 client-go workqueue 保持一致，
+// 时长类指标单位均为秒。
+type MetricsProvider = interface {
+	NewDepthMetric(name string) GaugeMetric
+
+	NewAddsMetric(name string) CounterMetric
+
+	NewLatencyMetric(name string) HistogramMetric
+
+	NewWorkDurationMetric(name string) HistogramMetric
+
+	NewUnfinishedWorkSecondsMetric(name string) SettableGaugeMetric
+
+	NewLongestRunningProcessorSecondsMetric(name string) SettableGaugeMetric
+
+	NewRetriesMetric(name string) CounterMetric
+
+	NewLeaseExpirationsMetric(name string) CounterMetric
+}
+
+// Clock 为时间相关的队列提供当前时间与计时器，测试中可以替换为 FakeClock。
+type Clock = interface {
+	Now() time.Time
+
+	// AfterFunc 在 d 之后调用 fn，返回的计时器可以停止或重置。
+	AfterFunc(d time.Duration, fn func()) ClockTimer
+}
+
+// ClockTimer 为 Clock.AfterFunc 返回的计时器，*time.Timer 满足该接口。
+type ClockTimer = interface {
+	Stop() bool
+
+	Reset(d time.Duration) bool
+}
+
 // Limiter 决定元素下一次允许入队的等待时长。
 type Limiter = interface {
 	When(value interface{}) time.Duration
@@ -190,6 +396,21 @@ type RetryPolicy = interface {
 	NextDelay(value interface{}, attempt int, reason error) (delay time.Duration, retry bool)
 }
 
+// KeyFunc 生成幂等判重所使用的稳定 key。
+type KeyFunc = func(value interface{}) string
+
+// ComparatorFunc 比较优先级相同的两个元素，返回负数表示 a 先于 b 出队，返回 0 时按入队先后出队。
+type ComparatorFunc = func(a, b interface{}) int
+
+// FlowFunc 返回元素所属的流，例如租户 ID。
+type FlowFunc = func(value interface{}) string
+
+// FlowWeightFunc 返回流的权重，公平队列按权重比例分配出队份额。
+type FlowWeightFunc = func(flow string) int
+
+// CostFunc 返回元素的出队成本，例如按负载大小计费。
+type CostFunc = func(value interface{}) int
+
 // RetryKeyFunc 生成重试计数所使用的稳定 key。
 type RetryKeyFunc = func(value interface{}) string
 
@@ -218,6 +439,8 @@ type container = interface {
 
 	Range(fn func(value interface{}) bool)
 
+	Remove(node *lst.Node)
+
 	Len() int64
 
 	Cleanup()
@@ -248,3 +471,69 @@ func (sh *wrapInternalHeap) Pop() interface{} { return sh.RBTree.Pop() }
 func (sh *wrapInternalHeap) Range(fn func(value interface{}) bool) {
 	sh.RBTree.Range(func(node *lst.Node) bool { return fn(node) })
 }
+
+// schedule 统一了红黑树与时间轮两种调度容器，节点的 Priority 为到期的 Unix 毫秒时间戳。
+type schedule = interface {
+	Push(node *lst.Node)
+
+	Remove(node *lst.Node)
+
+	// PopExpired 弹出一个在 now 之前到期的节点，没有时返回 nil。
+	PopExpired(now int64) *lst.Node
+
+	// Next 返回调度协程下一次需要醒来的时刻，不晚于最早节点的到期时刻。
+	Next() (at int64, ok bool)
+
+	Range(fn func(node *lst.Node) bool)
+
+	Len() int64
+
+	Cleanup()
+}
+
+// newSchedule 在 tick 大于 0 时返回从 now 开始计时的时间轮，否则返回按到期时间排序的红黑树。
+func newSchedule(tick time.Duration, now time.Time) schedule {
+	if tick > 0 {
+		return &wrapScheduleWheel{Wheel: wheel.New(tick.Milliseconds(), now.UnixMilli())}
+	}
+	return &wrapScheduleHeap{RBTree: hp.New()}
+}
+
+// wrapScheduleWheel 适配 wheel.Wheel 到 schedule。时间轮按槽位遍历，同一槽位内的节点不按到期时间排列，
+// Range 先收集再按到期时间稳定排序，使遍历顺序与红黑树一致。
+type wrapScheduleWheel struct {
+	*wheel.Wheel
+}
+
+func (sw *wrapScheduleWheel) Range(fn func(node *lst.Node) bool) {
+	nodes := make([]*lst.Node, 0, sw.Len())
+	sw.Wheel.Range(func(node *lst.Node) bool {
+		nodes = append(nodes, node)
+		return true
+	})
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Priority < nodes[j].Priority })
+	for _, node := range nodes {
+		if !fn(node) {
+			return
+		}
+	}
+}
+
+// wrapScheduleHeap 适配 heap.RBTree 到 schedule。
+type wrapScheduleHeap struct {
+	*hp.RBTree
+}
+
+func (sh *wrapScheduleHeap) PopExpired(now int64) *lst.Node {
+	if front := sh.Front(); front != nil && front.Priority <= now {
+		return sh.Pop()
+	}
+	return nil
+}
+
+func (sh *wrapScheduleHeap) Next() (int64, bool) {
+	if front := sh.Front(); front != nil {
+		return front.Priority, true
+	}
+	return 0, false
+}
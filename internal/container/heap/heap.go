@@ -5,16 +5,49 @@ import (
 	"github.com/shengyanli1982/workqueue/v2/internal/ternary"
 )
 
-// RBTree 是按 Priority 排序的红黑树实现。
+// RBTree 是按 Priority 排序的红黑树实现。优先级相同时先按 compare 排序，
+// 仍然相同时按 Push 的先后排序，因此相同优先级的节点严格先进先出。
 type RBTree struct {
-	count int64
-	root  *lst.Node
-	head  *lst.Node
-	tail  *lst.Node
+	count    int64
+	sequence uint64
+	compare  func(a, b interface{}) int
+	priority func(a, b *lst.Node) int
+	root     *lst.Node
+	head     *lst.Node
+	tail     *lst.Node
 }
 
 func New() *RBTree { return &RBTree{} }
 
+// NewWithComparator 创建在优先级相同时使用 compare 比较节点值的红黑树，
+// compare 返回负数表示 a 排在 b 之前，返回 0 时按 Push 的先后排序。
+func NewWithComparator(compare func(a, b interface{}) int) *RBTree {
+	return &RBTree{compare: compare}
+}
+
+// SetPriorityComparator 使用 compare 代替 Priority 字段比较节点的优先级，
+// compare 返回负数表示 a 的优先级高于 b。需在插入节点之前设置。
+func (tree *RBTree) SetPriorityComparator(compare func(a, b *lst.Node) int) {
+	tree.priority = compare
+}
+
+// less 判断节点 a 是否排在 b 之前。
+func (tree *RBTree) less(a, b *lst.Node) bool {
+	if tree.priority != nil {
+		if c := tree.priority(a, b); c != 0 {
+			return c < 0
+		}
+	} else if a.Priority != b.Priority {
+		return a.Priority < b.Priority
+	}
+	if tree.compare != nil {
+		if c := tree.compare(a.Value, b.Value); c != 0 {
+			return c < 0
+		}
+	}
+	return a.Sequence < b.Sequence
+}
+
 func leftRotate(tree *RBTree, node *lst.Node) {
 	if node == nil || node.Right == nil {
 		return
@@ -116,7 +149,7 @@ func (tree *RBTree) insert(node *lst.Node) {
 
 	for current != nil {
 		parent = current
-		current = ternary.If(node.Priority < current.Priority, current.Left, current.Right)
+		current = ternary.If(tree.less(node, current), current.Left, current.Right)
 	}
 
 	node.Parent = parent
@@ -125,7 +158,7 @@ func (tree *RBTree) insert(node *lst.Node) {
 		tree.root = node
 	} else {
 
-		if node.Priority < parent.Priority {
+		if tree.less(node, parent) {
 			parent.Left = node
 		} else {
 			parent.Right = node
@@ -139,85 +172,80 @@ func (tree *RBTree) insert(node *lst.Node) {
 	insertFixUp(tree, node)
 	tree.count++
 
-	if tree.head == nil || node.Priority < tree.head.Priority {
+	if tree.head == nil || tree.less(node, tree.head) {
 		tree.head = node
 	}
-	if tree.tail == nil || node.Priority > tree.tail.Priority {
+	if tree.tail == nil || tree.less(tree.tail, node) {
 		tree.tail = node
 	}
 }
 
-func deleteFixUp(tree *RBTree, node *lst.Node) {
-	// 删除黑节点后可能破坏红黑树性质，这里执行标准修复流程。
-	for node != tree.root && (node == nil || node.Color == lst.BLACK) {
-		if node == nil || node.Parent == nil {
-			break
-		}
-
-		isLeftChild := node == node.Parent.Left
-		var sibling *lst.Node
-		if isLeftChild {
-			sibling = node.Parent.Right
-		} else {
-			sibling = node.Parent.Left
-		}
-
-		if sibling == nil {
-			break
-		}
-
-		if sibling.Color == lst.RED {
-			sibling.Color = lst.BLACK
-			node.Parent.Color = lst.RED
-			if isLeftChild {
-				leftRotate(tree, node.Parent)
-				sibling = node.Parent.Right
-			} else {
-				rightRotate(tree, node.Parent)
-				sibling = node.Parent.Left
+func isBlack(node *lst.Node) bool { return node == nil || node.Color == lst.BLACK }
+
+// deleteFixUp 在删除黑节点后恢复红黑树性质。node 为顶替被删节点的子节点，可能为 nil，因此需要单独传入其父节点。
+func deleteFixUp(tree *RBTree, node, parent *lst.Node) {
+	for node != tree.root && isBlack(node) && parent != nil {
+		if node == parent.Left {
+			sibling := parent.Right
+			if !isBlack(sibling) {
+				sibling.Color = lst.BLACK
+				parent.Color = lst.RED
+				leftRotate(tree, parent)
+				sibling = parent.Right
 			}
-		}
-
-		siblingLeftBlack := sibling.Left == nil || sibling.Left.Color == lst.BLACK
-		siblingRightBlack := sibling.Right == nil || sibling.Right.Color == lst.BLACK
-
-		if siblingLeftBlack && siblingRightBlack {
-			sibling.Color = lst.RED
-			node = node.Parent
+			if sibling == nil {
+				node, parent = parent, parent.Parent
+				continue
+			}
+			if isBlack(sibling.Left) && isBlack(sibling.Right) {
+				sibling.Color = lst.RED
+				node, parent = parent, parent.Parent
+				continue
+			}
+			if isBlack(sibling.Right) {
+				sibling.Left.Color = lst.BLACK
+				sibling.Color = lst.RED
+				rightRotate(tree, sibling)
+				sibling = parent.Right
+			}
+			sibling.Color = parent.Color
+			parent.Color = lst.BLACK
+			if sibling.Right != nil {
+				sibling.Right.Color = lst.BLACK
+			}
+			leftRotate(tree, parent)
 		} else {
-			if isLeftChild {
-				if siblingRightBlack {
-					if sibling.Left != nil {
-						sibling.Left.Color = lst.BLACK
-					}
-					sibling.Color = lst.RED
-					rightRotate(tree, sibling)
-					sibling = node.Parent.Right
-				}
-			} else {
-				if siblingLeftBlack {
-					if sibling.Right != nil {
-						sibling.Right.Color = lst.BLACK
-					}
-					sibling.Color = lst.RED
-					leftRotate(tree, sibling)
-					sibling = node.Parent.Left
-				}
+			sibling := parent.Left
+			if !isBlack(sibling) {
+				sibling.Color = lst.BLACK
+				parent.Color = lst.RED
+				rightRotate(tree, parent)
+				sibling = parent.Left
 			}
-
-			sibling.Color = node.Parent.Color
-			node.Parent.Color = lst.BLACK
-
-			if isLeftChild && sibling.Right != nil {
+			if sibling == nil {
+				node, parent = parent, parent.Parent
+				continue
+			}
+			if isBlack(sibling.Left) && isBlack(sibling.Right) {
+				sibling.Color = lst.RED
+				node, parent = parent, parent.Parent
+				continue
+			}
+			if isBlack(sibling.Left) {
 				sibling.Right.Color = lst.BLACK
-				leftRotate(tree, node.Parent)
-			} else if !isLeftChild && sibling.Left != nil {
+				sibling.Color = lst.RED
+				leftRotate(tree, sibling)
+				sibling = parent.Left
+			}
+			sibling.Color = parent.Color
+			parent.Color = lst.BLACK
+			if sibling.Left != nil {
 				sibling.Left.Color = lst.BLACK
-				rightRotate(tree, node.Parent)
 			}
-
-			node = tree.root
+			rightRotate(tree, parent)
 		}
+		node = tree.root
+		break
 	}
 
 	if node != nil {
@@ -225,6 +253,21 @@ func deleteFixUp(tree *RBTree, node *lst.Node) {
 	}
 }
 
+// transplant 用 child 替换 node 在父节点中的位置。
+func (tree *RBTree) transplant(node, child *lst.Node) {
+	if node.Parent == nil {
+		tree.root = child
+	} else if node == node.Parent.Left {
+		node.Parent.Left = child
+	} else {
+		node.Parent.Right = child
+	}
+	if child != nil {
+		child.Parent = node.Parent
+	}
+}
+
+// delete 将 node 本身从树中摘除，调用方随后可以安全地复用该节点。
 func (tree *RBTree) delete(node *lst.Node) {
 	if node == nil {
 		return
@@ -245,43 +288,42 @@ func (tree *RBTree) delete(node *lst.Node) {
 		nextTail = tree.predecessor(node)
 	}
 
-	var target *lst.Node
-	if node.Left == nil || node.Right == nil {
-		target = node
-	} else {
-		target = tree.successor(node)
-	}
-
-	var child *lst.Node
-	if target.Left != nil {
-		child = target.Left
-	} else {
-		child = target.Right
-	}
-
-	if child != nil {
-		child.Parent = target.Parent
-	}
-
-	if target.Parent == nil {
-		tree.root = child
-	} else {
-		if target == target.Parent.Left {
-			target.Parent.Left = child
+	// 两个子节点都存在时由后继节点接替 node 的位置与颜色，而不是复制后继节点的数据，
+	// 避免外部持有的节点引用失效。
+	var child, parent *lst.Node
+	color := node.Color
+	switch {
+	case node.Left == nil:
+		child, parent = node.Right, node.Parent
+		tree.transplant(node, node.Right)
+	case node.Right == nil:
+		child, parent = node.Left, node.Parent
+		tree.transplant(node, node.Left)
+	default:
+		next := tree.minimum(node.Right)
+		color = next.Color
+		child = next.Right
+		if next.Parent == node {
+			parent = next
 		} else {
-			target.Parent.Right = child
+			parent = next.Parent
+			tree.transplant(next, next.Right)
+			next.Right = node.Right
+			next.Right.Parent = next
 		}
+		tree.transplant(node, next)
+		next.Left = node.Left
+		next.Left.Parent = next
+		next.Color = node.Color
 	}
 
-	if target != node {
-		node.Value = target.Value
-		node.Priority = target.Priority
-	}
-
-	if target.Color == lst.BLACK {
-		deleteFixUp(tree, child)
+	if color == lst.BLACK {
+		deleteFixUp(tree, child, parent)
 	}
 
+	node.Left = nil
+	node.Right = nil
+	node.Parent = nil
 	tree.count--
 
 	if tree.count == 0 {
@@ -360,21 +402,16 @@ func (tree *RBTree) popMin() *lst.Node {
 	nextHead := tree.successor(node)
 	parent := node.Parent
 	child := node.Right
-
-	if child != nil {
-		child.Parent = parent
-	}
-
-	if parent == nil {
-		tree.root = child
-	} else {
-		parent.Left = child
-	}
+	tree.transplant(node, child)
 
 	if node.Color == lst.BLACK {
-		deleteFixUp(tree, child)
+		deleteFixUp(tree, child, parent)
 	}
 
+	node.Left = nil
+	node.Right = nil
+	node.Parent = nil
+
 	tree.count--
 	if tree.count == 0 {
 		tree.head = nil
@@ -400,6 +437,14 @@ func (tree *RBTree) Back() *lst.Node { return tree.tail }
 
 func (tree *RBTree) Remove(node *lst.Node) { tree.delete(node) }
 
+// Update 修改树中节点的优先级并重新定位，复杂度为 O(log n)。节点保留原有序号，
+// 在新优先级的同级节点中仍按最初 Push 的先后排序。
+func (tree *RBTree) Update(node *lst.Node, priority int64) {
+	tree.delete(node)
+	node.Priority = priority
+	tree.insert(node)
+}
+
 func inOrderTraverse(node *lst.Node, fn func(*lst.Node) bool) bool {
 	if node == nil {
 		return true
@@ -436,8 +481,11 @@ func (tree *RBTree) Cleanup() {
 	tree.count = 0
 }
 
+// Push 为节点分配新的序号后插入。
 func (tree *RBTree) Push(node *lst.Node) {
 	if node != nil {
+		tree.sequence++
+		node.Sequence = tree.sequence
 		tree.insert(node)
 	}
 }
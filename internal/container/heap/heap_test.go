@@ -2,6 +2,8 @@ package heap
 
 import (
 	"fmt"
+	"math/rand"
+	"sort"
 	"testing"
 
 	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
@@ -303,6 +305,71 @@ func TestHeap_Remove(t *testing.T) {
 	assert.Nil(t, h.Back(), "back value should be nil")
 }
 
+func TestHeap_RemoveInner(t *testing.T) {
+	h := New()
+	count := 8
+	nodes := make([]*lst.Node, count)
+
+	for i := 0; i < count; i++ {
+		n := &lst.Node{Priority: int64(i), Value: i}
+		nodes[i] = n
+		h.Push(n)
+	}
+
+	// 删除拥有两个子节点的节点时，被删除的必须是该节点本身，其余节点的数据保持不变。
+	removed := h.Root()
+	assert.NotNil(t, removed.Left, "root should have left child")
+	assert.NotNil(t, removed.Right, "root should have right child")
+	h.Remove(removed)
+
+	assert.Nil(t, removed.Parent, "removed node should be detached")
+	assert.Nil(t, removed.Left, "removed node should be detached")
+	assert.Nil(t, removed.Right, "removed node should be detached")
+	assert.Equal(t, int64(count-1), h.Len(), fmt.Sprintf("heap length should be %d", count-1))
+
+	h.Remove(nodes[0])
+	h.Remove(nodes[count-1])
+
+	var values []interface{}
+	h.Range(func(n *lst.Node) bool {
+		assert.Equal(t, n.Priority, int64(n.Value.(int)), "node value should match priority")
+		values = append(values, n.Value)
+		return true
+	})
+	assert.Equal(t, count-3, len(values), fmt.Sprintf("heap should have %d nodes", count-3))
+	assert.Equal(t, int64(1), h.Front().Priority, "front priority should be 1")
+	assert.Equal(t, int64(count-2), h.Back().Priority, fmt.Sprintf("back priority should be %d", count-2))
+	assert.Equal(t, lst.BLACK, h.Root().Color, "root should be black")
+
+	for n := h.Pop(); n != nil; n = h.Pop() {
+		assert.NotEqual(t, removed, n, "removed node should not be popped")
+	}
+	assert.Equal(t, int64(0), h.Len(), "heap length should be 0")
+}
+
+func TestHeap_Update(t *testing.T) {
+	h := New()
+	nodes := make([]*lst.Node, 5)
+	for i := range nodes {
+		nodes[i] = &lst.Node{Priority: int64(i), Value: i}
+		h.Push(nodes[i])
+	}
+
+	h.Update(nodes[4], -1)
+	h.Update(nodes[0], 10)
+	h.Update(nodes[2], 2)
+
+	assert.Equal(t, int64(5), h.Len(), "heap length should be 5")
+	assert.Equal(t, nodes[4], h.Front(), "front should be the promoted node")
+	assert.Equal(t, nodes[0], h.Back(), "back should be the demoted node")
+
+	var values []interface{}
+	for n := h.Pop(); n != nil; n = h.Pop() {
+		values = append(values, n.Value)
+	}
+	assert.Equal(t, []interface{}{4, 1, 2, 3, 0}, values, "values should follow updated priorities")
+}
+
 func TestHeap_ExtremeValues(t *testing.T) {
 	h := New()
 
@@ -353,6 +420,48 @@ func TestHeap_DuplicatePriorities(t *testing.T) {
 	assert.Equal(t, int64(0), h.Len(), "heap should be empty")
 }
 
+func TestHeap_StableTies(t *testing.T) {
+	h := New()
+
+	// 三档优先级交错插入，同档内的值即插入先后。
+	nodes := make([]*lst.Node, 300)
+	for i := range nodes {
+		nodes[i] = &lst.Node{Priority: int64(i % 3), Value: i}
+		h.Push(nodes[i])
+	}
+	assert.Equal(t, nodes[0], h.Front(), "front should be the first node with priority 0")
+	assert.Equal(t, nodes[len(nodes)-1], h.Back(), "back should be the last node with priority 2")
+
+	// 删除与更新不影响其余节点的先后，更新后的节点按原序号插入新的同级节点中。
+	for i := 30; i < 60; i++ {
+		h.Remove(nodes[i])
+	}
+	h.Update(nodes[2], 0)
+
+	last := map[int64]int{0: -1, 1: -1, 2: -1}
+	for n := h.Pop(); n != nil; n = h.Pop() {
+		value := n.Value.(int)
+		assert.Greater(t, value, last[n.Priority], "nodes with the same priority should pop in insertion order")
+		last[n.Priority] = value
+	}
+}
+
+func TestHeap_Comparator(t *testing.T) {
+	h := NewWithComparator(func(a, b interface{}) int { return a.(int)/10 - b.(int)/10 })
+
+	for _, v := range []int{25, 11, 21, 15, 3, 27} {
+		h.Push(&lst.Node{Priority: 1, Value: v})
+	}
+	h.Push(&lst.Node{Priority: 0, Value: 99})
+
+	// 先按优先级，再按十位，十位相同按插入先后。
+	var values []interface{}
+	for n := h.Pop(); n != nil; n = h.Pop() {
+		values = append(values, n.Value)
+	}
+	assert.Equal(t, []interface{}{99, 3, 11, 15, 25, 21, 27}, values, "values should follow priority, comparator, then insertion order")
+}
+
 func TestHeap_RemoveNilAndInvalid(t *testing.T) {
 	h := New()
 
@@ -390,3 +499,136 @@ func TestHeap_LargeDataSet(t *testing.T) {
 		prev = current
 	}
 }
+
+// checkInvariants 校验红黑树性质、父指针、节点计数以及 head/tail 缓存，返回按顺序排列的节点。
+func checkInvariants(t *testing.T, tree *RBTree) []*lst.Node {
+	t.Helper()
+
+	if tree.root == nil {
+		assert.Equal(t, int64(0), tree.count)
+		assert.Nil(t, tree.head)
+		assert.Nil(t, tree.tail)
+		return nil
+	}
+	assert.Nil(t, tree.root.Parent, "root should have no parent")
+	assert.Equal(t, lst.BLACK, tree.root.Color, "root should be black")
+
+	var nodes []*lst.Node
+	var walk func(node *lst.Node) int
+	walk = func(node *lst.Node) int {
+		if node == nil {
+			return 1
+		}
+		if node.Left != nil {
+			assert.Same(t, node, node.Left.Parent, "left child should point back to its parent")
+		}
+		if node.Right != nil {
+			assert.Same(t, node, node.Right.Parent, "right child should point back to its parent")
+		}
+		if node.Color == lst.RED {
+			assert.True(t, isBlack(node.Left) && isBlack(node.Right), "red node should not have red children")
+		}
+		left := walk(node.Left)
+		nodes = append(nodes, node)
+		right := walk(node.Right)
+		assert.Equal(t, left, right, "every path should have the same black height")
+		if node.Color == lst.BLACK {
+			left++
+		}
+		return left
+	}
+	walk(tree.root)
+
+	assert.Equal(t, tree.count, int64(len(nodes)))
+	for i := 1; i < len(nodes); i++ {
+		assert.True(t, tree.less(nodes[i-1], nodes[i]), "in-order traversal should be strictly ordered")
+	}
+	assert.Same(t, nodes[0], tree.head, "head should be the minimum")
+	assert.Same(t, nodes[len(nodes)-1], tree.tail, "tail should be the maximum")
+	return nodes
+}
+
+func TestHeap_Invariants_Random(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		tree := New()
+		var live []*lst.Node
+
+		for op := 0; op < 2000; op++ {
+			switch r := rng.Intn(10); {
+			case r < 4 || len(live) == 0:
+				node := &lst.Node{Priority: int64(rng.Intn(50) - 25)}
+				tree.Push(node)
+				live = append(live, node)
+			case r < 6:
+				node := tree.Pop()
+				assert.NotNil(t, node)
+				for i, n := range live {
+					if n == node {
+						live = append(live[:i], live[i+1:]...)
+						break
+					}
+				}
+			case r < 8:
+				i := rng.Intn(len(live))
+				tree.Remove(live[i])
+				live = append(live[:i], live[i+1:]...)
+			default:
+				tree.Update(live[rng.Intn(len(live))], int64(rng.Intn(50)-25))
+			}
+
+			nodes := checkInvariants(t, tree)
+			if t.Failed() {
+				t.Fatalf("invariants broken at seed %d, op %d", seed, op)
+			}
+
+			// 与按 (Priority, Sequence) 排序的参考结果一致。
+			expected := append([]*lst.Node(nil), live...)
+			sort.Slice(expected, func(i, j int) bool {
+				if expected[i].Priority != expected[j].Priority {
+					return expected[i].Priority < expected[j].Priority
+				}
+				return expected[i].Sequence < expected[j].Sequence
+			})
+			if !assert.Equal(t, len(expected), len(nodes)) {
+				t.FailNow()
+			}
+			for i := range expected {
+				if !assert.Same(t, expected[i], nodes[i], "seed %d, op %d, index %d", seed, op, i) {
+					t.FailNow()
+				}
+			}
+		}
+	}
+}
+
+func TestHeap_Invariants_DrainByRemove(t *testing.T) {
+	tree := New()
+	nodes := make([]*lst.Node, 0, 256)
+	for i := 0; i < 256; i++ {
+		node := &lst.Node{Priority: int64(i)}
+		tree.Push(node)
+		nodes = append(nodes, node)
+	}
+
+	// 交替从两端与中间删除，覆盖删除修复的全部分支。
+	rng := rand.New(rand.NewSource(42))
+	for len(nodes) > 0 {
+		var i int
+		switch len(nodes) % 3 {
+		case 0:
+			i = 0
+		case 1:
+			i = len(nodes) - 1
+		default:
+			i = rng.Intn(len(nodes))
+		}
+		tree.Remove(nodes[i])
+		nodes = append(nodes[:i], nodes[i+1:]...)
+		checkInvariants(t, tree)
+		if t.Failed() {
+			t.FailNow()
+		}
+	}
+	assert.Equal(t, int64(0), tree.Len())
+}
@@ -14,8 +14,17 @@ func toUnsafePtr(lp *List) unsafe.Pointer {
 type List struct {
 	head, tail *Node
 	count      int64
+
+	// owner 为持有该链表的外部容器，供容器以 O(1) 判断节点是否属于自己。
+	owner interface{}
 }
 
+// SetOwner 登记持有该链表的外部容器，Cleanup 不会清除该登记。
+func (l *List) SetOwner(owner interface{}) { l.owner = owner }
+
+// Owner 返回 SetOwner 登记的外部容器，未登记时返回 nil。
+func (l *List) Owner() interface{} { return l.owner }
+
 func New() *List { return &List{} }
 
 func (l *List) Len() int64 { return l.count }
@@ -1434,3 +1434,14 @@ func TestList_ConcurrentMoveOperations(t *testing.T) {
 		prev = node
 	}
 }
+
+func TestList_Owner(t *testing.T) {
+	l := New()
+	assert.Nil(t, l.Owner(), "owner should be nil by default")
+
+	owner := &struct{ name string }{"wheel"}
+	l.SetOwner(owner)
+	l.PushBack(NewNode())
+	l.Cleanup()
+	assert.Equal(t, owner, l.Owner(), "Cleanup should keep the owner")
+}
@@ -26,6 +26,12 @@ type Node struct {
 
 	Priority int64
 
+	// Timestamp 记录节点进入就绪队列的时刻（Unix 纳秒），用于统计排队时长。
+	Timestamp int64
+
+	// Sequence 为节点进入红黑树时分配的序号，优先级相同的节点按其先后排序。
+	Sequence uint64
+
 	Color uint8
 
 	_ [7]uint8
@@ -38,11 +44,16 @@ func (n *Node) Reset() {
 	n.Parent = nil
 	n.parentRef = nil
 	n.Priority = 0
+	n.Timestamp = 0
+	n.Sequence = 0
 	n.Color = RED
 }
 
 func NewNode() *Node { return &Node{} }
 
+// List 返回节点当前所在的链表，不在任何链表中时返回 nil。
+func (n *Node) List() *List { return (*List)(n.parentRef) }
+
 type NodePool struct {
 	pool sync.Pool
 }
@@ -0,0 +1,229 @@
+package wheel
+
+import (
+	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
+)
+
+// 每层 64 个槽位，10 层时间轮可以覆盖 2^60 个刻度。
+const (
+	slotBits  = 6
+	slotCount = 1 << slotBits
+	slotMask  = slotCount - 1
+	numLevels = 10
+)
+
+// Wheel 是分层时间轮，节点的 Priority 为到期时间，单位与 tick 相同（例如 Unix 毫秒）。
+// 插入与删除为 O(1)；推进时逐层将高层槽位中的节点下沉到更精细的槽位，到期的节点进入就绪链表。
+// 节点在到期时间所在刻度的下一个刻度边界之前不会被视为到期，因此最多晚于到期时间一个 tick。
+type Wheel struct {
+	tick    int64
+	current int64
+	count   int64
+
+	// lists 的前 numLevels*slotCount 个链表为各层槽位，最后一个为就绪链表，均登记时间轮为 owner 以便 O(1) 判断节点归属。
+	lists [numLevels*slotCount + 1]lst.List
+	ready *lst.List
+}
+
+// New 创建以 tick 为刻度、从 now 开始计时的时间轮，tick 小于 1 时按 1 处理。
+func New(tick, now int64) *Wheel {
+	if tick < 1 {
+		tick = 1
+	}
+	w := &Wheel{tick: tick, current: now / tick}
+	for i := range w.lists {
+		w.lists[i].SetOwner(w)
+	}
+	w.ready = &w.lists[len(w.lists)-1]
+	return w
+}
+
+func (w *Wheel) slot(level, index int) *lst.List {
+	return &w.lists[level*slotCount+index]
+}
+
+// Tick 返回时间轮的刻度。
+func (w *Wheel) Tick() int64 { return w.tick }
+
+func (w *Wheel) Len() int64 { return w.count }
+
+// Push 按节点的到期时间挂入对应槽位，复杂度为 O(1)。
+func (w *Wheel) Push(node *lst.Node) {
+	if node == nil {
+		return
+	}
+	w.place(node)
+	w.count++
+}
+
+// Remove 将节点从其所在槽位中摘除，复杂度为 O(1)。不属于该时间轮的节点会被忽略。
+func (w *Wheel) Remove(node *lst.Node) {
+	if node == nil {
+		return
+	}
+	list := node.List()
+	if list == nil || !w.owns(list) {
+		return
+	}
+	list.Remove(node)
+	w.count--
+}
+
+// PopExpired 推进时间轮至 now 并弹出一个已到期的节点，没有到期节点时返回 nil。
+func (w *Wheel) PopExpired(now int64) *lst.Node {
+	if w.ready.Len() == 0 {
+		w.advance(now / w.tick)
+	}
+	node := w.ready.PopFront()
+	if node != nil {
+		w.count--
+	}
+	return node
+}
+
+// Next 返回下一次需要推进时间轮的时刻，该时刻不晚于最早节点的到期时刻；时间轮为空时 ok 为 false。
+func (w *Wheel) Next() (at int64, ok bool) {
+	if w.count == 0 {
+		return 0, false
+	}
+	if w.ready.Len() > 0 {
+		return w.current * w.tick, true
+	}
+	return w.nextTick() * w.tick, true
+}
+
+// Range 先遍历已到期的节点，再按层级由低到高遍历各槽位中的节点，顺序大致按到期时间。
+func (w *Wheel) Range(fn func(node *lst.Node) bool) {
+	next := true
+	visit := func(node *lst.Node) bool {
+		next = fn(node)
+		return next
+	}
+
+	if w.ready.Range(visit); !next {
+		return
+	}
+	for level := 0; level < numLevels; level++ {
+		shift := uint(level * slotBits)
+		index := int(w.current>>shift) & slotMask
+		for i := 0; i < slotCount; i++ {
+			if w.slot(level, (index+i)&slotMask).Range(visit); !next {
+				return
+			}
+		}
+	}
+}
+
+func (w *Wheel) Cleanup() {
+	for i := range w.lists {
+		w.lists[i].Cleanup()
+	}
+	w.count = 0
+}
+
+// expiry 返回节点到期的刻度，向上取整以保证不会提前到期。
+func (w *Wheel) expiry(node *lst.Node) int64 {
+	at := node.Priority
+	if at <= 0 {
+		return 0
+	}
+	return (at-1)/w.tick + 1
+}
+
+// place 按到期刻度与当前刻度的距离选择层级：距离小于 64^(level+1) 的节点放入第 level 层，
+// 槽位由到期刻度在该层的位决定。已过期的节点放入当前刻度的槽位，在下一次推进时到期。
+func (w *Wheel) place(node *lst.Node) {
+	expiry := w.expiry(node)
+	if expiry < w.current {
+		expiry = w.current
+	}
+
+	delta := expiry - w.current
+	level := 0
+	for level < numLevels-1 && delta >= int64(1)<<uint((level+1)*slotBits) {
+		level++
+	}
+	slot := int(expiry>>uint(level*slotBits)) & slotMask
+	w.slot(level, slot).PushBack(node)
+}
+
+// advance 逐刻度推进至 target（含），途中在各层的边界处下沉高层槽位，并将到期节点移入就绪链表。
+// 连续的空刻度会被一次跳过。
+func (w *Wheel) advance(target int64) {
+	for w.current <= target {
+		if w.count == 0 {
+			w.current = target + 1
+			return
+		}
+
+		if index := w.current & slotMask; index == 0 {
+			for level := 1; level < numLevels; level++ {
+				index := int(w.current>>uint(level*slotBits)) & slotMask
+				w.cascade(level, index)
+				if index != 0 {
+					break
+				}
+			}
+		}
+
+		expired := w.slot(0, int(w.current&slotMask))
+		for node := expired.PopFront(); node != nil; node = expired.PopFront() {
+			w.ready.PushBack(node)
+		}
+		w.current++
+
+		if w.ready.Len() > 0 {
+			return
+		}
+		if next := w.nextTick(); next > w.current {
+			if next > target+1 {
+				next = target + 1
+			}
+			w.current = next
+		}
+	}
+}
+
+// cascade 将第 level 层 index 槽位中的节点按当前刻度重新放置。
+func (w *Wheel) cascade(level, index int) {
+	slot := w.slot(level, index)
+	for node := slot.PopFront(); node != nil; node = slot.PopFront() {
+		w.place(node)
+	}
+}
+
+// nextTick 返回不早于当前刻度、需要处理的最早刻度：第 0 层为槽位的到期刻度，更高层为槽位的下沉刻度。
+func (w *Wheel) nextTick() int64 {
+	next := int64(-1)
+	for level := 0; level < numLevels; level++ {
+		shift := uint(level * slotBits)
+		chunk := w.current >> shift
+		aligned := w.current&(int64(1)<<shift-1) == 0
+
+		for i := 0; i < slotCount; i++ {
+			// 更高层中与当前刻度同一槽位的节点，仅在当前刻度恰好位于边界时属于本轮，否则属于下一轮。
+			c := chunk + int64(i)
+			if level > 0 && i == 0 && !aligned {
+				c += slotCount
+			}
+			if w.slot(level, int(c)&slotMask).Len() == 0 {
+				continue
+			}
+			if at := c << shift; next < 0 || at < next {
+				next = at
+			}
+			if i > 0 || level == 0 {
+				break
+			}
+		}
+	}
+	if next < 0 {
+		return w.current
+	}
+	return next
+}
+
+// owns 判断链表是否为该时间轮的槽位或就绪链表。
+func (w *Wheel) owns(list *lst.List) bool {
+	return list.Owner() == w
+}
@@ -0,0 +1,169 @@
+package wheel
+
+import (
+	"math/rand"
+	"testing"
+
+	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWheel_PopExpired(t *testing.T) {
+	w := New(1, 1000)
+
+	for _, at := range []int64{1005, 1001, 1003, 999} {
+		w.Push(&lst.Node{Priority: at, Value: at})
+	}
+	assert.Equal(t, int64(4), w.Len(), "wheel should contain 4 nodes")
+
+	// 已过期的节点在下一个刻度到期。
+	assert.Nil(t, w.PopExpired(999), "no node should expire before the current tick")
+	assert.Equal(t, int64(999), w.PopExpired(1000).Value, "expired node should pop first")
+	assert.Nil(t, w.PopExpired(1000), "no other node should expire at 1000")
+
+	var values []interface{}
+	for now := int64(1001); now <= 1005; now++ {
+		for n := w.PopExpired(now); n != nil; n = w.PopExpired(now) {
+			assert.Equal(t, now, n.Priority, "node should expire exactly at its tick")
+			values = append(values, n.Value)
+		}
+	}
+	assert.Equal(t, []interface{}{int64(1001), int64(1003), int64(1005)}, values, "values should expire in order")
+	assert.Equal(t, int64(0), w.Len(), "wheel should be empty")
+}
+
+func TestWheel_Tick(t *testing.T) {
+	w := New(10, 0)
+
+	// 到期时间向上取整到刻度边界，节点不会提前到期。
+	w.Push(&lst.Node{Priority: 15, Value: 15})
+	assert.Nil(t, w.PopExpired(15), "node should not expire before the tick boundary")
+	assert.Nil(t, w.PopExpired(19), "node should not expire before the tick boundary")
+	assert.Equal(t, 15, w.PopExpired(20).Value, "node should expire at the next tick boundary")
+}
+
+func TestWheel_Remove(t *testing.T) {
+	w := New(1, 0)
+
+	nodes := make([]*lst.Node, 5)
+	for i := range nodes {
+		nodes[i] = &lst.Node{Priority: int64(i+1) * 1000, Value: i}
+		w.Push(nodes[i])
+	}
+
+	w.Remove(nodes[1])
+	w.Remove(nodes[3])
+	w.Remove(nodes[3])
+	w.Remove(&lst.Node{Priority: 1})
+	assert.Equal(t, int64(3), w.Len(), "removed nodes should not be counted")
+
+	// 不属于该时间轮的节点不会被摘除。
+	other := lst.New()
+	foreign := &lst.Node{Value: "foreign"}
+	other.PushBack(foreign)
+	w.Remove(foreign)
+	assert.Equal(t, int64(1), other.Len(), "foreign node should stay in its list")
+
+	// 另一个时间轮的节点同样不会被摘除。
+	another := New(1, 0)
+	borrowed := &lst.Node{Priority: 2000, Value: "borrowed"}
+	another.Push(borrowed)
+	w.Remove(borrowed)
+	assert.Equal(t, int64(1), another.Len(), "node of another wheel should stay there")
+	assert.Equal(t, int64(3), w.Len())
+
+	var values []interface{}
+	for n := w.PopExpired(10000); n != nil; n = w.PopExpired(10000) {
+		values = append(values, n.Value)
+	}
+	assert.Equal(t, []interface{}{0, 2, 4}, values, "only remaining nodes should expire")
+}
+
+func TestWheel_Next(t *testing.T) {
+	w := New(1, 0)
+
+	_, ok := w.Next()
+	assert.False(t, ok, "empty wheel should have no next tick")
+
+	w.Push(&lst.Node{Priority: 300000})
+	w.Push(&lst.Node{Priority: 5000})
+
+	// Next 不晚于最早的到期时间，沿着 Next 推进最终在到期时刻弹出节点。
+	var popped []int64
+	for len(popped) < 2 {
+		at, ok := w.Next()
+		assert.True(t, ok, "wheel should have a next tick")
+		assert.LessOrEqual(t, at, int64(300000), "next tick should not pass the latest expiry")
+		if n := w.PopExpired(at); n != nil {
+			assert.Equal(t, n.Priority, at, "node should expire at its own tick")
+			popped = append(popped, n.Priority)
+		}
+	}
+	assert.Equal(t, []int64{5000, 300000}, popped, "nodes should expire in order")
+}
+
+func TestWheel_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	start := int64(1_700_000_000_000)
+	w := New(1, start)
+
+	const count = 20000
+	nodes := make([]*lst.Node, count)
+	removed := make(map[*lst.Node]bool)
+	for i := range nodes {
+		// 覆盖多个层级：毫秒到数天。
+		span := int64(1) << uint(r.Intn(34))
+		nodes[i] = &lst.Node{Priority: start + r.Int63n(span), Value: i}
+		w.Push(nodes[i])
+	}
+	for i := 0; i < count/10; i++ {
+		n := nodes[r.Intn(count)]
+		if !removed[n] {
+			w.Remove(n)
+			removed[n] = true
+		}
+	}
+	assert.Equal(t, int64(count-len(removed)), w.Len(), "length should exclude removed nodes")
+
+	seen := 0
+	last := start
+	for w.Len() > 0 {
+		at, ok := w.Next()
+		assert.True(t, ok, "non-empty wheel should have a next tick")
+		assert.GreaterOrEqual(t, at, last, "time should not go backwards")
+		last = at
+		for n := w.PopExpired(at); n != nil; n = w.PopExpired(at) {
+			assert.False(t, removed[n], "removed node should not expire")
+			if n.Priority > start {
+				assert.Equal(t, n.Priority, at, "node should expire exactly at its tick")
+			}
+			seen++
+		}
+	}
+	assert.Equal(t, count-len(removed), seen, "every remaining node should expire once")
+}
+
+func TestWheel_RangeAndCleanup(t *testing.T) {
+	w := New(1, 0)
+	for i := 0; i < 100; i++ {
+		w.Push(&lst.Node{Priority: int64(i * i * 100), Value: i})
+	}
+
+	count := 0
+	w.Range(func(*lst.Node) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, 100, count, "range should visit every node")
+
+	count = 0
+	w.Range(func(*lst.Node) bool {
+		count++
+		return count < 10
+	})
+	assert.Equal(t, 10, count, "range should stop when fn returns false")
+
+	w.Cleanup()
+	assert.Equal(t, int64(0), w.Len(), "wheel should be empty after cleanup")
+	assert.Nil(t, w.PopExpired(1<<40), "no node should expire after cleanup")
+}
@@ -0,0 +1,262 @@
+// Package cron 解析标准 cron 表达式并计算下一次触发时间。
+//
+// 支持 5 个字段（分 时 日 月 周）与带秒的 6 个字段（秒 分 时 日 月 周），
+// 每个字段可以使用 *、?、列表 a,b、范围 a-b、步长 */n 或 a-b/n，月份与星期可以使用英文缩写。
+// 另外支持 @yearly、@annually、@monthly、@weekly、@daily、@midnight 与 @hourly。
+// 与 Vixie cron 一致，日与周均受限时二者满足其一即可触发。
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidExpression 表示 cron 表达式不合法。
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// searchYears 为 Next 向后查找的年数上限，超过后视为表达式不会再触发（例如 2 月 30 日）。
+// 闰年之间最长相隔 8 年（如 2096 年至 2104 年），上限不能小于该值。
+const searchYears = 8
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	seconds = bounds{min: 0, max: 59}
+	minutes = bounds{min: 0, max: 59}
+	hours   = bounds{min: 0, max: 23}
+	doms    = bounds{min: 1, max: 31}
+	months  = bounds{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 星期 0 与 7 均表示周日。
+	dows = bounds{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Schedule 为解析后的 cron 表达式，各字段以位图记录允许的取值。
+type Schedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// domStar 与 dowStar 记录日、周字段是否以 * 或 ? 开头，决定二者按与还是按或匹配。
+	domStar, dowStar bool
+}
+
+// Parse 解析 5 或 6 个字段的 cron 表达式。
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@") {
+		spec, ok := descriptors[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown descriptor %q", ErrInvalidExpression, expr)
+		}
+		expr = spec
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("%w: expected 5 or 6 fields, got %d", ErrInvalidExpression, len(fields))
+	}
+
+	s := &Schedule{
+		domStar: strings.HasPrefix(fields[3], "*") || strings.HasPrefix(fields[3], "?"),
+		dowStar: strings.HasPrefix(fields[5], "*") || strings.HasPrefix(fields[5], "?"),
+	}
+	targets := []*uint64{&s.second, &s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, b := range []bounds{seconds, minutes, hours, doms, months, dows} {
+		bits, err := parseField(fields[i], b)
+		if err != nil {
+			return nil, err
+		}
+		*targets[i] = bits
+	}
+
+	// 星期 7 与 0 同为周日。
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	return s, nil
+}
+
+// Next 返回严格晚于 t 的下一次触发时间，按 t 所在的时区计算；在查找范围内没有触发时间时返回零值。
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	limit := t.Year() + searchYears
+
+	// reset 表示更高位的字段已经前进过，更低位的字段需要从最小值开始。
+	reset := false
+
+wrap:
+	if t.Year() > limit {
+		return time.Time{}
+	}
+
+	for !has(s.month, int(t.Month())) {
+		if !reset {
+			reset = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !reset {
+			reset = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// 夏令时切换可能使零点不存在，将时间校正回当天零点附近。
+		if h := t.Hour(); h != 0 {
+			if h > 12 {
+				t = t.Add(time.Duration(24-h) * time.Hour)
+			} else {
+				t = t.Add(-time.Duration(h) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for !has(s.hour, t.Hour()) {
+		if !reset {
+			reset = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for !has(s.minute, t.Minute()) {
+		if !reset {
+			reset = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	for !has(s.second, t.Second()) {
+		if !reset {
+			reset = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+// parseField 将逗号分隔的字段解析为位图。
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		v, err := parseRange(part, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= v
+	}
+	return bits, nil
+}
+
+// parseRange 解析 *、?、a、a-b 以及带 /n 步长的形式。单个值带步长时表示从该值到上限。
+func parseRange(part string, b bounds) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+	var lo, hi int
+	switch rangePart {
+	case "*", "?":
+		lo, hi = b.min, b.max
+	default:
+		var err error
+		loPart, hiPart, isRange := strings.Cut(rangePart, "-")
+		if lo, err = parseValue(loPart, b); err != nil {
+			return 0, err
+		}
+		hi = lo
+		if isRange {
+			if hi, err = parseValue(hiPart, b); err != nil {
+				return 0, err
+			}
+		} else if hasStep {
+			hi = b.max
+		}
+	}
+	if lo > hi {
+		return 0, fmt.Errorf("%w: range %q is reversed", ErrInvalidExpression, part)
+	}
+
+	step := 1
+	if hasStep {
+		var err error
+		if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+			return 0, fmt.Errorf("%w: invalid step in %q", ErrInvalidExpression, part)
+		}
+	}
+
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid value %q", ErrInvalidExpression, s)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("%w: value %d out of range [%d, %d]", ErrInvalidExpression, v, b.min, b.max)
+	}
+	return v, nil
+}
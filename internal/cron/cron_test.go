@@ -0,0 +1,103 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@never",
+	} {
+		_, err := Parse(expr)
+		assert.ErrorIs(t, err, ErrInvalidExpression, "expression %q should be invalid", expr)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	from := time.Date(2024, 1, 30, 10, 7, 30, 500, time.UTC)
+
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/5 * * * *", time.Date(2024, 1, 30, 10, 10, 0, 0, time.UTC)},
+		{"* * * * *", time.Date(2024, 1, 30, 10, 8, 0, 0, time.UTC)},
+		{"*/10 * * * * *", time.Date(2024, 1, 30, 10, 7, 40, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)},
+		{"30 8-18/2 * * *", time.Date(2024, 1, 30, 10, 30, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * SUN", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * mon-fri", time.Date(2024, 1, 30, 12, 0, 0, 0, time.UTC)},
+		// 日与周均受限时满足其一即可：2 月 1 日先于下一个周一。
+		{"0 0 1 * 1", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 30, 11, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 jan,jul *", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		s, err := Parse(tc.expr)
+		assert.NoError(t, err, "expression %q should parse", tc.expr)
+		assert.Equal(t, tc.want, s.Next(from), "next fire of %q", tc.expr)
+	}
+}
+
+func TestSchedule_NextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero(), "February 30th should never fire")
+}
+
+func TestSchedule_NextLeapGap(t *testing.T) {
+	s, err := Parse("0 0 29 feb *")
+	assert.NoError(t, err)
+
+	// 2100 年不是闰年，2096 年之后的下一个 2 月 29 日在 2104 年。
+	next := s.Next(time.Date(2096, 3, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2104, 2, 29, 0, 0, 0, 0, time.UTC), next)
+}
+
+func TestSchedule_NextLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	s, err := Parse("0 9 * * *")
+	assert.NoError(t, err)
+
+	next := s.Next(time.Date(2024, 1, 30, 2, 0, 0, 0, time.UTC).In(loc))
+	assert.Equal(t, time.Date(2024, 1, 31, 9, 0, 0, 0, loc), next, "schedule should follow the location of the given time")
+	assert.Equal(t, time.Date(2024, 1, 31, 1, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestSchedule_NextDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database is unavailable")
+	}
+
+	// 2024-03-10 02:00 不存在，下一次每日 02:30 的触发落在 3 月 11 日。
+	s, err := Parse("30 2 * * *")
+	assert.NoError(t, err)
+	next := s.Next(time.Date(2024, 3, 9, 12, 0, 0, 0, loc))
+	assert.Equal(t, time.Date(2024, 3, 11, 2, 30, 0, 0, loc), next)
+
+	s, err = Parse("0 0 * * *")
+	assert.NoError(t, err)
+	from := time.Date(2024, 3, 9, 12, 0, 0, 0, loc)
+	for i := 0; i < 3; i++ {
+		from = s.Next(from)
+		assert.Equal(t, 0, from.Hour(), "midnight should stay at midnight across DST")
+	}
+}
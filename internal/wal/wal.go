@@ -0,0 +1,681 @@
+// Package wal 实现基于分段文件的追加写日志，为持久化队列提供崩溃恢复能力。
+//
+// 日志由若干按序号命名的段文件组成，每条记录为：
+//
+//	| length uint32 | crc32 uint32 | type uint8 | id uint64 | due int64 | payload ... |
+//
+// length 与 crc32 覆盖 type 之后的全部字节。Put 记录登记元素，Ack 记录确认元素已完成；
+// 重放时按段序号依次应用。只有最后一个段末尾损坏或截断的记录会被丢弃并截断文件，
+// 其余段在滚动前已经 fsync，其中的损坏返回 ErrCorrupted。
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed 表示日志已关闭。
+var ErrClosed = errors.New("wal is closed")
+
+// ErrCorrupted 表示记录长度不合法或校验失败，通常意味着文件损坏。
+var ErrCorrupted = errors.New("wal record is corrupted")
+
+// SyncMode 定义写入后的刷盘策略。
+type SyncMode uint8
+
+const (
+	// SyncAlways 每次写入后立即 fsync，崩溃不丢失已确认的写入。
+	SyncAlways SyncMode = iota
+
+	// SyncInterval 由后台协程按固定周期 fsync，崩溃最多丢失一个周期内的写入。
+	SyncInterval
+
+	// SyncNone 不主动 fsync，由操作系统决定落盘时机。
+	SyncNone
+)
+
+const (
+	recordPut = uint8(1)
+	recordAck = uint8(2)
+
+	frameHeaderSize = 8
+	recordFixedSize = 1 + 8 + 8
+
+	// maxRecordSize 限制单条记录的长度，防止损坏的长度字段导致超大内存分配。
+	maxRecordSize = 64 << 20
+
+	segmentSuffix = ".wal"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Options 定义日志参数。
+type Options struct {
+	// SegmentSize 为单个段文件的滚动阈值（字节）。
+	SegmentSize int64
+
+	Sync SyncMode
+
+	// SyncInterval 为 SyncInterval 模式下的刷盘周期。
+	SyncInterval time.Duration
+
+	// CompactInterval 为后台压缩的检查周期，小于等于 0 时关闭后台压缩。
+	CompactInterval time.Duration
+
+	// CompactRatio 为后台压缩的触发比例：已确认记录数不少于存活记录数的 CompactRatio 倍时才压缩，
+	// 使重写存活记录的开销分摊到确认上。小于等于 0 时为 1。
+	CompactRatio float64
+}
+
+// Record 为一条尚未确认的元素记录。
+type Record struct {
+	ID uint64
+
+	// Due 为元素可被消费的 Unix 毫秒时间戳，0 表示立即可用。
+	Due int64
+
+	Payload []byte
+}
+
+type segment struct {
+	index uint64
+	path  string
+}
+
+// Log 是追加写日志，可并发使用。
+type Log struct {
+	dir  string
+	opts Options
+
+	lock     sync.Mutex
+	closed   bool
+	nextID   uint64
+	live     map[uint64]*Record
+	dead     int
+	segments []segment
+	active   *os.File
+	writer   *bufio.Writer
+	size     int64
+	unsynced bool
+	// failed 为回滚失败后的错误，此时活动段末尾可能残留半条记录，之后的写入全部返回该错误，
+	// 直到压缩重写存活记录并删除旧段。
+	failed error
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Open 打开或创建 dir 下的日志，并重放已有段文件恢复未确认的记录。
+func Open(dir string, opts Options) (*Log, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = 64 << 20
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = time.Second
+	}
+	if opts.CompactRatio <= 0 {
+		opts.CompactRatio = 1
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		dir:    dir,
+		opts:   opts,
+		nextID: 1,
+		live:   make(map[uint64]*Record),
+		stop:   make(chan struct{}),
+	}
+
+	if err := l.replay(); err != nil {
+		return nil, err
+	}
+
+	if err := l.openActive(); err != nil {
+		return nil, err
+	}
+
+	if opts.Sync == SyncInterval {
+		l.wg.Add(1)
+		go l.loop(opts.SyncInterval, l.syncPeriodically)
+	}
+	if opts.CompactInterval > 0 {
+		l.wg.Add(1)
+		go l.loop(opts.CompactInterval, l.compactPeriodically)
+	}
+
+	return l, nil
+}
+
+// Append 追加一条 Put 记录并返回其编号。
+func (l *Log) Append(due int64, payload []byte) (uint64, error) {
+	ids, err := l.AppendBatch([]int64{due}, [][]byte{payload})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// AppendBatch 追加多条 Put 记录，SyncAlways 模式下只 fsync 一次。
+func (l *Log) AppendBatch(dues []int64, payloads [][]byte) ([]uint64, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if err := l.writableLocked(); err != nil {
+		return nil, err
+	}
+
+	start := l.size
+	ids := make([]uint64, 0, len(payloads))
+	for i, payload := range payloads {
+		record := &Record{ID: l.nextID, Due: dues[i], Payload: payload}
+		if err := l.writeLocked(recordPut, record); err != nil {
+			l.discardLocked(ids)
+			return nil, l.rollbackLocked(start, err)
+		}
+		l.nextID++
+		l.live[record.ID] = record
+		ids = append(ids, record.ID)
+	}
+
+	if err := l.commitLocked(start); err != nil {
+		l.discardLocked(ids)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// discardLocked 撤销写入失败批次的内存登记。写入与刷盘失败时文件已回滚；段滚动失败时记录已经落盘，
+// 重放时仍会出现，由上层按至少一次语义处理。
+func (l *Log) discardLocked(ids []uint64) {
+	for _, id := range ids {
+		delete(l.live, id)
+	}
+}
+
+// Ack 追加一条 Ack 记录，确认 id 对应的元素已完成。
+func (l *Log) Ack(id uint64) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if err := l.writableLocked(); err != nil {
+		return err
+	}
+	if _, ok := l.live[id]; !ok {
+		return nil
+	}
+
+	start := l.size
+	if err := l.writeLocked(recordAck, &Record{ID: id}); err != nil {
+		return l.rollbackLocked(start, err)
+	}
+	if err := l.commitLocked(start); err != nil {
+		return err
+	}
+	delete(l.live, id)
+	l.dead++
+	return nil
+}
+
+// writableLocked 判断日志能否继续写入，调用方需持有锁。
+func (l *Log) writableLocked() error {
+	if l.closed {
+		return ErrClosed
+	}
+	return l.failed
+}
+
+// Records 返回全部未确认的记录，按编号升序排列。
+func (l *Log) Records() []Record {
+	l.lock.Lock()
+	records := make([]Record, 0, len(l.live))
+	for _, record := range l.live {
+		records = append(records, *record)
+	}
+	l.lock.Unlock()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records
+}
+
+// Len 返回未确认记录的数量。
+func (l *Log) Len() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return len(l.live)
+}
+
+// Sync 将缓冲区写入并 fsync 当前段文件。
+func (l *Log) Sync() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	return l.syncLocked()
+}
+
+// Compact 将全部未确认记录重写到新的段文件并删除旧段文件。
+func (l *Log) Compact() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	return l.compactLocked()
+}
+
+// Close 刷盘并关闭日志，停止后台协程。
+func (l *Log) Close() error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.stop)
+
+	err := l.syncLocked()
+	if cerr := l.active.Close(); err == nil {
+		err = cerr
+	}
+	l.lock.Unlock()
+
+	l.wg.Wait()
+	return err
+}
+
+// replay 按段序号重放全部段文件。
+func (l *Log) replay() error {
+	segments, err := listSegments(l.dir)
+	if err != nil {
+		return err
+	}
+
+	for i, seg := range segments {
+		if err := l.replaySegment(seg, i == len(segments)-1); err != nil {
+			return err
+		}
+	}
+
+	l.segments = segments
+	return nil
+}
+
+// replaySegment 重放一个段文件。last 为真时末尾不完整或校验失败的记录视为崩溃时的残留写入并被截断，
+// 否则返回 ErrCorrupted。
+func (l *Log) replaySegment(seg segment, last bool) error {
+	file, err := os.OpenFile(seg.path, os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		kind, record, n, err := readRecord(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if !last {
+				return fmt.Errorf("%w: %s at offset %d: %v", ErrCorrupted, seg.path, offset, err)
+			}
+			// 末尾写入不完整或校验失败：丢弃其后的内容，保证后续追加从完整记录处开始。
+			return file.Truncate(offset)
+		}
+		offset += n
+
+		switch kind {
+		case recordPut:
+			l.live[record.ID] = record
+		case recordAck:
+			if _, ok := l.live[record.ID]; ok {
+				delete(l.live, record.ID)
+				l.dead++
+			}
+		}
+		if record.ID >= l.nextID {
+			l.nextID = record.ID + 1
+		}
+	}
+}
+
+// openActive 打开最新的段文件用于追加，不存在时创建。
+func (l *Log) openActive() error {
+	if len(l.segments) == 0 {
+		return l.createSegmentLocked(1)
+	}
+
+	seg := l.segments[len(l.segments)-1]
+	file, err := os.OpenFile(seg.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.setActive(file, info.Size())
+	return nil
+}
+
+func (l *Log) createSegmentLocked(index uint64) error {
+	path := segmentPath(l.dir, index)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(l.dir); err != nil {
+		file.Close()
+		return err
+	}
+
+	l.segments = append(l.segments, segment{index: index, path: path})
+	l.setActive(file, 0)
+	return nil
+}
+
+func (l *Log) setActive(file *os.File, size int64) {
+	l.active = file
+	l.writer = bufio.NewWriter(file)
+	l.size = size
+}
+
+func (l *Log) writeLocked(kind uint8, record *Record) error {
+	body := recordFixedSize + len(record.Payload)
+	if body > maxRecordSize {
+		return ErrCorrupted
+	}
+
+	var header [frameHeaderSize + recordFixedSize]byte
+	binary.LittleEndian.PutUint32(header[0:4], uint32(body))
+	header[8] = kind
+	binary.LittleEndian.PutUint64(header[9:17], record.ID)
+	binary.LittleEndian.PutUint64(header[17:25], uint64(record.Due))
+
+	crc := crc32.Update(0, crcTable, header[8:])
+	crc = crc32.Update(crc, crcTable, record.Payload)
+	binary.LittleEndian.PutUint32(header[4:8], crc)
+
+	if _, err := l.writer.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := l.writer.Write(record.Payload); err != nil {
+		return err
+	}
+
+	l.size += int64(frameHeaderSize + body)
+	l.unsynced = true
+	return nil
+}
+
+// commitLocked 按刷盘策略提交自 start 起写入的记录，失败时回滚到 start，并在段文件超限时滚动。
+func (l *Log) commitLocked(start int64) error {
+	if err := l.writer.Flush(); err != nil {
+		return l.rollbackLocked(start, err)
+	}
+	if l.opts.Sync == SyncAlways {
+		if err := l.syncLocked(); err != nil {
+			return l.rollbackLocked(start, err)
+		}
+	}
+
+	if l.size >= l.opts.SegmentSize {
+		return l.rotateLocked()
+	}
+	return nil
+}
+
+// rollbackLocked 丢弃缓冲区中未写出的内容并将活动段截断回 size，撤销写入失败的整批记录，返回 cause。
+// bufio.Writer 出错后会一直返回同一错误，因此需要重置；截断失败时日志进入不可写状态。
+func (l *Log) rollbackLocked(size int64, cause error) error {
+	l.writer.Reset(l.active)
+	if err := l.active.Truncate(size); err != nil {
+		l.failed = fmt.Errorf("%w (rollback: %v)", cause, err)
+		return l.failed
+	}
+	l.size = size
+	return cause
+}
+
+func (l *Log) syncLocked() error {
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	if !l.unsynced {
+		return nil
+	}
+	if err := l.active.Sync(); err != nil {
+		return err
+	}
+	l.unsynced = false
+	return nil
+}
+
+func (l *Log) rotateLocked() error {
+	if err := l.syncLocked(); err != nil {
+		return err
+	}
+	if err := l.active.Close(); err != nil {
+		return err
+	}
+	return l.createSegmentLocked(l.segments[len(l.segments)-1].index + 1)
+}
+
+// compactLocked 先将存活记录写入临时文件并原子重命名为新段，再删除旧段。
+// 重命名后、删除前崩溃时旧段与新段会被一同重放，Put 记录按编号幂等，结果不变。
+func (l *Log) compactLocked() error {
+	if err := l.syncLocked(); err != nil {
+		return err
+	}
+	if err := l.active.Close(); err != nil {
+		return err
+	}
+
+	index := l.segments[len(l.segments)-1].index + 1
+	path := segmentPath(l.dir, index)
+	tmp := path + ".tmp"
+
+	if err := l.writeSnapshot(tmp); err != nil {
+		os.Remove(tmp)
+		return l.reopenActiveLocked(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return l.reopenActiveLocked(err)
+	}
+	if err := syncDir(l.dir); err != nil {
+		return l.reopenActiveLocked(err)
+	}
+
+	for _, seg := range l.segments {
+		if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	l.segments = []segment{{index: index, path: path}}
+	l.dead = 0
+
+	if err := syncDir(l.dir); err != nil {
+		return err
+	}
+	if err := l.openActive(); err != nil {
+		return err
+	}
+	// 残留半条记录的旧段已被删除，日志恢复可写。
+	l.failed = nil
+	return nil
+}
+
+// reopenActiveLocked 在压缩失败后恢复追加写，并返回原始错误。
+func (l *Log) reopenActiveLocked(cause error) error {
+	if err := l.openActive(); err != nil {
+		return fmt.Errorf("%w (reopen: %v)", cause, err)
+	}
+	return cause
+}
+
+func (l *Log) writeSnapshot(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	records := make([]*Record, 0, len(l.live))
+	for _, record := range l.live {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	// 临时借用写入路径，完成后由 openActive 重新设置活动段。
+	l.setActive(file, 0)
+	for _, record := range records {
+		if err := l.writeLocked(recordPut, record); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := l.writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	l.unsynced = false
+	return file.Close()
+}
+
+func (l *Log) loop(interval time.Duration, fn func()) {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
+
+func (l *Log) syncPeriodically() {
+	l.lock.Lock()
+	if !l.closed {
+		_ = l.syncLocked()
+	}
+	l.lock.Unlock()
+}
+
+// compactPeriodically 仅在已确认记录达到存活记录的 CompactRatio 倍时压缩，避免为少量确认重写大量存活记录；
+// 日志因回滚失败不可写时也会压缩以恢复写入。
+func (l *Log) compactPeriodically() {
+	l.lock.Lock()
+	if !l.closed && (l.failed != nil || l.shouldCompactLocked()) {
+		_ = l.compactLocked()
+	}
+	l.lock.Unlock()
+}
+
+func (l *Log) shouldCompactLocked() bool {
+	return l.dead > 0 && float64(l.dead) >= float64(len(l.live))*l.opts.CompactRatio
+}
+
+// readRecord 读取一条记录，返回记录类型、内容与占用的字节数。
+func readRecord(r *bufio.Reader) (uint8, *Record, int64, error) {
+	var header [frameHeaderSize]byte
+	// 恰好读到文件末尾时返回 io.EOF，读到半条记录时返回 io.ErrUnexpectedEOF。
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, 0, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size < recordFixedSize || size > maxRecordSize {
+		return 0, nil, 0, ErrCorrupted
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil, 0, io.ErrUnexpectedEOF
+		}
+		return 0, nil, 0, err
+	}
+	if crc32.Checksum(body, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+		return 0, nil, 0, ErrCorrupted
+	}
+
+	record := &Record{
+		ID:  binary.LittleEndian.Uint64(body[1:9]),
+		Due: int64(binary.LittleEndian.Uint64(body[9:17])),
+	}
+	if len(body) > recordFixedSize {
+		record.Payload = body[recordFixedSize:]
+	}
+
+	return body[0], record, int64(frameHeaderSize + size), nil
+}
+
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []segment
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		index, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{index: index, path: filepath.Join(dir, name)})
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i].index < segments[j].index })
+	return segments, nil
+}
+
+func segmentPath(dir string, index uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", index, segmentSuffix))
+}
+
+// syncDir 持久化目录项变更（创建、重命名、删除）。
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+	return nil
+}
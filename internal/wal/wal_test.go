@@ -0,0 +1,253 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog_AppendAckRecover(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, Options{})
+	assert.NoError(t, err)
+
+	id1, err := l.Append(0, []byte("a"))
+	assert.NoError(t, err)
+	id2, err := l.Append(123, []byte("b"))
+	assert.NoError(t, err)
+	ids, err := l.AppendBatch([]int64{0, 0}, [][]byte{[]byte("c"), []byte("d")})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{id2 + 1, id2 + 2}, ids)
+
+	assert.NoError(t, l.Ack(id1))
+	assert.NoError(t, l.Ack(id1), "Acking twice should be a no-op")
+	assert.NoError(t, l.Ack(ids[0]))
+	assert.NoError(t, l.Close())
+
+	_, err = l.Append(0, []byte("e"))
+	assert.ErrorIs(t, err, ErrClosed)
+
+	l, err = Open(dir, Options{})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	assert.Equal(t, []Record{
+		{ID: id2, Due: 123, Payload: []byte("b")},
+		{ID: ids[1], Payload: []byte("d")},
+	}, l.Records())
+
+	id, err := l.Append(0, []byte("e"))
+	assert.NoError(t, err)
+	assert.Equal(t, ids[1]+1, id, "Ids should continue after recovery")
+}
+
+func TestLog_TruncatedTail(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, Options{})
+	assert.NoError(t, err)
+	_, err = l.Append(0, []byte("complete"))
+	assert.NoError(t, err)
+	_, err = l.Append(0, []byte("torn"))
+	assert.NoError(t, err)
+	assert.NoError(t, l.Close())
+
+	// 模拟崩溃时最后一条记录只写入了一部分。
+	path := segmentPath(dir, 1)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Truncate(path, info.Size()-2))
+
+	l, err = Open(dir, Options{})
+	assert.NoError(t, err)
+
+	records := l.Records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, []byte("complete"), records[0].Payload)
+
+	// 截断后的文件可以继续追加并被正确重放。
+	_, err = l.Append(0, []byte("after"))
+	assert.NoError(t, err)
+	assert.NoError(t, l.Close())
+
+	l, err = Open(dir, Options{})
+	assert.NoError(t, err)
+	defer l.Close()
+	assert.Len(t, l.Records(), 2)
+}
+
+func TestLog_CorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, Options{})
+	assert.NoError(t, err)
+	_, err = l.Append(0, []byte("first"))
+	assert.NoError(t, err)
+	_, err = l.Append(0, []byte("second"))
+	assert.NoError(t, err)
+	assert.NoError(t, l.Close())
+
+	path := segmentPath(dir, 1)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+
+	l, err = Open(dir, Options{})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	records := l.Records()
+	assert.Len(t, records, 1, "Records failing the checksum should be dropped")
+	assert.Equal(t, []byte("first"), records[0].Payload)
+}
+
+func TestLog_RotateAndCompact(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, Options{SegmentSize: 64})
+	assert.NoError(t, err)
+
+	var ids []uint64
+	for i := 0; i < 10; i++ {
+		id, err := l.Append(0, []byte("payload-payload"))
+		assert.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	segments, err := listSegments(dir)
+	assert.NoError(t, err)
+	assert.Greater(t, len(segments), 1, "Log should rotate segments")
+
+	for _, id := range ids[:8] {
+		assert.NoError(t, l.Ack(id))
+	}
+	assert.NoError(t, l.Compact())
+
+	segments, err = listSegments(dir)
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1, "Compaction should drop old segments")
+
+	_, err = l.Append(0, []byte("tail"))
+	assert.NoError(t, err)
+	assert.NoError(t, l.Close())
+
+	l, err = Open(dir, Options{})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	records := l.Records()
+	assert.Len(t, records, 3)
+	assert.Equal(t, ids[8], records[0].ID)
+	assert.Equal(t, ids[9], records[1].ID)
+	assert.Equal(t, []byte("tail"), records[2].Payload)
+}
+
+func TestLog_CompactCrashBeforeDelete(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, Options{})
+	assert.NoError(t, err)
+	id1, _ := l.Append(0, []byte("a"))
+	_, _ = l.Append(0, []byte("b"))
+	assert.NoError(t, l.Ack(id1))
+	assert.NoError(t, l.Close())
+
+	// 模拟压缩完成重命名但尚未删除旧段：新段只包含存活记录。
+	old, err := os.ReadFile(segmentPath(dir, 1))
+	assert.NoError(t, err)
+
+	l, err = Open(dir, Options{})
+	assert.NoError(t, err)
+	assert.NoError(t, l.Compact())
+	assert.NoError(t, l.Close())
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000001.wal"), old, 0o644))
+
+	l, err = Open(dir, Options{})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	records := l.Records()
+	assert.Len(t, records, 1, "Replaying both segments should not resurrect acked records")
+	assert.Equal(t, []byte("b"), records[0].Payload)
+}
+
+func TestLog_CorruptedMiddleSegment(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, Options{SegmentSize: 64})
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err := l.Append(0, []byte("payload-payload"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, l.Close())
+
+	segments, err := listSegments(dir)
+	assert.NoError(t, err)
+	assert.Greater(t, len(segments), 2)
+
+	// 已滚动的段在崩溃前已经 fsync，其中的损坏不能按残缺末尾截断。
+	path := segments[0].path
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+
+	_, err = Open(dir, Options{SegmentSize: 64})
+	assert.ErrorIs(t, err, ErrCorrupted, "Corruption before the last segment should fail the open")
+
+	after, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, data, after, "The corrupted segment should be left untouched")
+}
+
+func TestLog_RollbackFailedBatch(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, Options{})
+	assert.NoError(t, err)
+	_, err = l.Append(0, []byte("before"))
+	assert.NoError(t, err)
+
+	// 第一条记录超过缓冲区大小会直接写入文件，第二条超限失败，整批都不应留在日志中。
+	_, err = l.AppendBatch([]int64{0, 0}, [][]byte{make([]byte, 8<<10), make([]byte, maxRecordSize)})
+	assert.ErrorIs(t, err, ErrCorrupted)
+	assert.Equal(t, 1, l.Len())
+
+	_, err = l.Append(0, []byte("after"))
+	assert.NoError(t, err)
+	assert.NoError(t, l.Close())
+
+	l, err = Open(dir, Options{})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	records := l.Records()
+	assert.Len(t, records, 2, "The failed batch should be rolled back")
+	assert.Equal(t, []byte("before"), records[0].Payload)
+	assert.Equal(t, []byte("after"), records[1].Payload)
+}
+
+func TestLog_CompactRatio(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, Options{CompactRatio: 1})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	ids, err := l.AppendBatch(make([]int64, 4), [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")})
+	assert.NoError(t, err)
+
+	assert.NoError(t, l.Ack(ids[0]))
+	l.compactPeriodically()
+	assert.Equal(t, 1, l.dead, "One ack should not rewrite three live records")
+
+	assert.NoError(t, l.Ack(ids[1]))
+	l.compactPeriodically()
+	assert.Equal(t, 0, l.dead, "Compaction should run once acks reach the live records")
+	assert.Equal(t, 2, l.Len())
+}
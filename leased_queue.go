@@ -1,6 +1,10 @@
 package workqueue
 
 import (
+	"context"
+	"errors"
+	"io"
+	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -20,9 +24,9 @@ type leasedQueueImpl struct {
 	leases  map[string]leasedItem
 	leaseID atomic.Uint64
 
-	closed chan struct{}
-	once   sync.Once
-	wg     sync.WaitGroup
+	once      sync.Once
+	scheduler *scheduler
+	transit   transit
 }
 
 // NewLeasedQueue 创建租约队列。
@@ -30,14 +34,14 @@ func NewLeasedQueue(config *LeasedQueueConfig) LeasedQueue {
 	config = isLeasedQueueConfigEffective(config)
 
 	q := &leasedQueueImpl{
-		Queue:  NewQueue(&config.QueueConfig),
-		config: config,
-		leases: make(map[string]leasedItem),
-		closed: make(chan struct{}),
+		Queue:     NewQueue(&config.QueueConfig),
+		config:    config,
+		leases:    make(map[string]leasedItem),
+		scheduler: newScheduler(config.clock),
 	}
+	q.transit.init(&q.lock)
 
-	q.wg.Add(1)
-	go q.requeueExpiredLeases()
+	q.scheduler.start(q.requeueExpiredLeases)
 
 	return q
 }
@@ -55,19 +59,23 @@ func (q *leasedQueueImpl) GetWithLease(timeout time.Duration) (value interface{}
 		return nil, "", err
 	}
 
-	seq := q.leaseID.Add(1)
-	var raw [16]byte
-	leaseID = string(strconv.AppendUint(raw[:0], seq, 36))
-	deadline := time.Now().Add(timeout)
+	return value, q.grantLease(value, timeout), nil
+}
 
-	q.lock.Lock()
-	q.leases[leaseID] = leasedItem{
-		value:    value,
-		deadline: deadline,
+func (q *leasedQueueImpl) GetWithLeaseContext(ctx context.Context, timeout time.Duration) (value interface{}, leaseID string, err error) {
+	if timeout <= 0 {
+		timeout = q.config.leaseDuration
+	}
+	if timeout <= 0 {
+		return nil, "", ErrInvalidLeaseDuration
 	}
-	q.lock.Unlock()
 
-	return value, leaseID, nil
+	value, err = q.Queue.GetWithContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return value, q.grantLease(value, timeout), nil
 }
 
 func (q *leasedQueueImpl) Ack(leaseID string) error {
@@ -86,8 +94,11 @@ func (q *leasedQueueImpl) Nack(leaseID string, _ error) error {
 		return ErrLeaseNotFound
 	}
 
-	q.Queue.Done(value)
-	return q.Queue.Put(value)
+	// 租约期间已有新的入队请求时，Done 会把元素重新入队，Nack 与之合并。
+	if err := q.requeue(value); err != nil && !errors.Is(err, ErrElementAlreadyExist) {
+		return err
+	}
+	return nil
 }
 
 func (q *leasedQueueImpl) ExtendLease(leaseID string, timeout time.Duration) error {
@@ -98,7 +109,7 @@ func (q *leasedQueueImpl) ExtendLease(leaseID string, timeout time.Duration) err
 	q.lock.Lock()
 	item, ok := q.leases[leaseID]
 	if ok {
-		item.deadline = time.Now().Add(timeout)
+		item.deadline = q.config.clock.Now().Add(timeout)
 		q.leases[leaseID] = item
 	}
 	q.lock.Unlock()
@@ -110,17 +121,135 @@ func (q *leasedQueueImpl) ExtendLease(leaseID string, timeout time.Duration) err
 	return nil
 }
 
+func (q *leasedQueueImpl) RangeLeases(fn func(leaseID string, value interface{}, deadline time.Time) bool) {
+	if fn == nil {
+		return
+	}
+
+	type lease struct {
+		id string
+		leasedItem
+	}
+
+	q.lock.Lock()
+	leases := make([]lease, 0, len(q.leases))
+	for id, item := range q.leases {
+		leases = append(leases, lease{id: id, leasedItem: item})
+	}
+	q.lock.Unlock()
+
+	// 在锁外回调，fn 中可以安全地调用 Ack、Nack 等方法。
+	sort.Slice(leases, func(i, j int) bool { return leases[i].deadline.Before(leases[j].deadline) })
+	for i := range leases {
+		if !fn(leases[i].id, leases[i].value, leases[i].deadline) {
+			return
+		}
+	}
+}
+
 func (q *leasedQueueImpl) Shutdown() {
+	q.shutdown(false)
+}
+
+// ShutdownWithDrain 等待剩余元素被消费且全部租约被 Ack 后关闭队列。
+// ctx 先结束时，未确认租约的元素与队列中的剩余元素一并返回，避免至少一次语义下的丢失。
+func (q *leasedQueueImpl) ShutdownWithDrain(ctx context.Context) ([]interface{}, error) {
+	if err := q.base().drain(ctx, nil); err != nil {
+		if errors.Is(err, ErrQueueIsClosed) {
+			return nil, err
+		}
+		return q.shutdown(true), err
+	}
+
+	q.shutdown(false)
+	return nil, nil
+}
+
+func (q *leasedQueueImpl) shutdown(collect bool) []interface{} {
+	var leased []interface{}
+
 	q.once.Do(func() {
-		close(q.closed)
-		q.wg.Wait()
+		q.scheduler.stop()
 
 		q.lock.Lock()
+		if collect {
+			for _, item := range q.leases {
+				leased = append(leased, item.value)
+			}
+		}
 		q.leases = nil
 		q.lock.Unlock()
 	})
 
-	q.Queue.Shutdown()
+	return append(q.base().shutdown(collect), leased...)
+}
+
+// Snapshot 在基础队列快照之上额外保存尚未确认的租约元素及其截止时间。
+// 持有租约的消费者在重启后已不存在，因此 Restore 将租约元素作为就绪元素排在最前重新投递。
+func (q *leasedQueueImpl) Snapshot(w io.Writer) error {
+	s := &queueSnapshot{}
+	base := q.base()
+
+	q.lock.Lock()
+	q.transit.waitLocked()
+
+	base.lock.Lock()
+	err := base.snapshotLocked(s, nil)
+	base.lock.Unlock()
+
+	if err == nil {
+		for _, item := range q.leases {
+			var leased snapshotItem
+			if leased, err = encodeItem(base.config.codec, item.value, item.deadline.UnixNano(), 0); err != nil {
+				break
+			}
+			s.Leases = append(s.Leases, leased)
+		}
+	}
+	q.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// 租约按截止时间排序，恢复时先到期的元素先被重新投递。
+	sort.SliceStable(s.Leases, func(i, j int) bool { return s.Leases[i].Time < s.Leases[j].Time })
+	return writeSnapshot(w, s)
+}
+
+// requeue 结束元素的处理状态并将其放回队列，排空期间仍然允许。
+func (q *leasedQueueImpl) requeue(value interface{}) error {
+	base := q.base()
+
+	// Done 与重新入队之间元素不在任何容器内，登记为处理中以免排空提前结束。
+	base.retain()
+	defer base.release()
+
+	base.Done(value)
+	return base.requeue(value)
+}
+
+func (q *leasedQueueImpl) base() *queueImpl {
+	return q.Queue.(*queueImpl)
+}
+
+// grantLease 为已出队元素登记租约并返回租约 ID，租约从登记时刻开始计时。
+func (q *leasedQueueImpl) grantLease(value interface{}, timeout time.Duration) string {
+	seq := q.leaseID.Add(1)
+	var raw [16]byte
+	leaseID := string(strconv.AppendUint(raw[:0], seq, 36))
+	deadline := q.config.clock.Now().Add(timeout)
+
+	q.lock.Lock()
+	// Shutdown 后租约表被置空，此时不再登记租约。
+	if q.leases != nil {
+		q.leases[leaseID] = leasedItem{
+			value:    value,
+			deadline: deadline,
+		}
+	}
+	q.lock.Unlock()
+
+	return leaseID
 }
 
 func (q *leasedQueueImpl) removeLease(leaseID string) (value interface{}, ok bool) {
@@ -141,26 +270,20 @@ func (q *leasedQueueImpl) removeLease(leaseID string) (value interface{}, ok boo
 	return item.value, true
 }
 
-func (q *leasedQueueImpl) requeueExpiredLeases() {
-	ticker := time.NewTicker(q.config.scanInterval)
-	defer func() {
-		ticker.Stop()
-		q.wg.Done()
-	}()
+// requeueExpiredLeases 每隔 scanInterval 由调度器调用一次，将过期租约的元素重新入队。
+func (q *leasedQueueImpl) requeueExpiredLeases() (bool, time.Duration) {
+	expired := q.collectExpired(q.config.clock.Now())
+	for _, value := range expired {
+		_ = q.requeue(value)
+		q.base().metrics.leaseExpired()
+	}
 
-	for {
-		select {
-		case <-q.closed:
-			return
-		case <-ticker.C:
-			now := time.Now()
-			expired := q.collectExpired(now)
-			for _, value := range expired {
-				q.Queue.Done(value)
-				_ = q.Queue.Put(value)
-			}
-		}
+	if len(expired) > 0 {
+		q.lock.Lock()
+		q.transit.endLocked(len(expired))
+		q.lock.Unlock()
 	}
+	return false, q.config.scanInterval
 }
 
 func (q *leasedQueueImpl) collectExpired(now time.Time) []interface{} {
@@ -172,6 +295,8 @@ func (q *leasedQueueImpl) collectExpired(now time.Time) []interface{} {
 			delete(q.leases, id)
 		}
 	}
+	// 过期元素在重新入队前不在任何容器内，登记后快照会等待其完成。
+	q.transit.beginLocked(len(expired))
 	q.lock.Unlock()
 	return expired
 }
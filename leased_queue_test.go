@@ -1,6 +1,7 @@
 package workqueue
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -86,6 +87,38 @@ func TestLeasedQueue_ExtendLease(t *testing.T) {
 	assert.Equal(t, "job-4", requeued)
 }
 
+func TestLeasedQueue_RangeLeases(t *testing.T) {
+	q := NewLeasedQueue(nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("job-1"))
+	assert.NoError(t, q.Put("job-2"))
+
+	_, first, err := q.GetWithLease(time.Minute)
+	assert.NoError(t, err)
+	_, second, err := q.GetWithLease(time.Second)
+	assert.NoError(t, err)
+
+	var ids []string
+	var values []interface{}
+	q.RangeLeases(func(leaseID string, value interface{}, _ time.Time) bool {
+		ids = append(ids, leaseID)
+		values = append(values, value)
+		// 回调中确认租约不会死锁。
+		return assert.NoError(t, q.Ack(leaseID))
+	})
+
+	assert.Equal(t, []string{second, first}, ids)
+	assert.Equal(t, []interface{}{"job-2", "job-1"}, values)
+
+	count := 0
+	q.RangeLeases(func(string, interface{}, time.Time) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, 0, count)
+}
+
 func TestLeasedQueue_InvalidLease(t *testing.T) {
 	q := NewLeasedQueue(nil)
 	defer q.Shutdown()
@@ -96,6 +129,21 @@ func TestLeasedQueue_InvalidLease(t *testing.T) {
 	assert.ErrorIs(t, q.ExtendLease("missing", 0), ErrInvalidLeaseDuration)
 }
 
+func TestLeasedQueue_Idempotent_NackCoalescesWithPut(t *testing.T) {
+	config := NewLeasedQueueConfig().WithScanInterval(5 * time.Millisecond)
+	config.WithValueIdempotent()
+	q := NewLeasedQueue(config)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("job-5"))
+
+	_, leaseID, err := q.GetWithLease(time.Second)
+	assert.NoError(t, err)
+	assert.NoError(t, q.Put("job-5"))
+	assert.NoError(t, q.Nack(leaseID, errors.New("retry")))
+	assert.Equal(t, 1, q.Len())
+}
+
 func waitQueueGet(t *testing.T, q Queue, timeout time.Duration) (interface{}, error) {
 	t.Helper()
 
@@ -113,3 +161,20 @@ func waitQueueGet(t *testing.T, q Queue, timeout time.Duration) (interface{}, er
 
 	return nil, ErrQueueIsEmpty
 }
+
+func TestLeasedQueue_ShutdownWithDrain_Timeout(t *testing.T) {
+	q := NewLeasedQueue(NewLeasedQueueConfig().WithScanInterval(5 * time.Millisecond))
+
+	assert.NoError(t, q.Put("job-6"))
+	assert.NoError(t, q.Put("job-7"))
+
+	_, _, err := q.GetWithLease(time.Minute)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	pending, err := q.ShutdownWithDrain(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []interface{}{"job-7", "job-6"}, pending, "Unacked leases should be reported as pending")
+}
@@ -0,0 +1,302 @@
+package workqueue
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// metricsUpdatePeriod 为处理中耗时类指标的刷新周期，与 client-go 保持一致。
+const metricsUpdatePeriod = 500 * time.Millisecond
+
+// defaultDurationBuckets 为排队时长与处理时长直方图的桶上界（秒），覆盖 10ns 到 1000s。
+var defaultDurationBuckets = []float64{
+	1e-8, 1e-7, 1e-6, 1e-5, 1e-4, 1e-3, 1e-2, 1e-1, 1, 10, 100, 1000,
+}
+
+// HistogramSnapshot 为直方图快照：Buckets 为各桶上界（秒），Counts 为对应桶的累计计数。
+type HistogramSnapshot struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64
+}
+
+// Mean 返回观测值的平均时长。
+func (h HistogramSnapshot) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return time.Duration(h.Sum / float64(h.Count) * float64(time.Second))
+}
+
+// QueueStats 为队列健康状况快照，指标含义与 client-go workqueue 一致。
+type QueueStats struct {
+	Name string
+
+	// Depth 为当前可消费的元素数量。
+	Depth int
+
+	// InFlight 为已出队但尚未 Done 的元素数量。
+	InFlight int
+
+	// Adds 为累计接收的入队次数。
+	Adds uint64
+
+	// Retries 为累计重试次数，仅 RetryQueue 会产生。
+	Retries uint64
+
+	// LeaseExpirations 为累计租约过期次数，仅 LeasedQueue 会产生。
+	LeaseExpirations uint64
+
+	// JournalAckFailures 为累计写入失败的日志确认次数，仅配置了 WAL 的队列会产生，对应记录会在下次打开时重新投递。
+	JournalAckFailures uint64
+
+	// Latency 为元素从入队到被 Get 的等待时长分布。
+	Latency HistogramSnapshot
+
+	// WorkDuration 为元素从 Get 到 Done 的处理时长分布。
+	WorkDuration HistogramSnapshot
+
+	// UnfinishedWork 为全部处理中元素已耗费时长之和。
+	UnfinishedWork time.Duration
+
+	// LongestRunning 为处理中元素里耗时最长者的已耗费时长。
+	LongestRunning time.Duration
+}
+
+type metricImpl struct{}
+
+func (impl *metricImpl) Inc() {}
+
+func (impl *metricImpl) Dec() {}
+
+func (impl *metricImpl) Set(float64) {}
+
+func (impl *metricImpl) Observe(float64) {}
+
+type metricsProviderImpl struct{}
+
+// NewNopMetricsProvider 返回空实现指标提供者。
+func NewNopMetricsProvider() *metricsProviderImpl { return &metricsProviderImpl{} }
+
+func (impl *metricsProviderImpl) NewDepthMetric(string) GaugeMetric { return &metricImpl{} }
+
+func (impl *metricsProviderImpl) NewAddsMetric(string) CounterMetric { return &metricImpl{} }
+
+func (impl *metricsProviderImpl) NewLatencyMetric(string) HistogramMetric { return &metricImpl{} }
+
+func (impl *metricsProviderImpl) NewWorkDurationMetric(string) HistogramMetric { return &metricImpl{} }
+
+func (impl *metricsProviderImpl) NewUnfinishedWorkSecondsMetric(string) SettableGaugeMetric {
+	return &metricImpl{}
+}
+
+func (impl *metricsProviderImpl) NewLongestRunningProcessorSecondsMetric(string) SettableGaugeMetric {
+	return &metricImpl{}
+}
+
+func (impl *metricsProviderImpl) NewRetriesMetric(string) CounterMetric { return &metricImpl{} }
+
+func (impl *metricsProviderImpl) NewLeaseExpirationsMetric(string) CounterMetric {
+	return &metricImpl{}
+}
+
+// histogram 是固定桶的直方图，由所属队列的锁保护。
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(value float64) {
+	h.count++
+	h.sum += value
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.counts) {
+		h.counts[i]++
+	}
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	counts := make([]uint64, len(h.counts))
+	var cumulative uint64
+	for i, c := range h.counts {
+		cumulative += c
+		counts[i] = cumulative
+	}
+
+	return HistogramSnapshot{
+		Buckets: append([]float64(nil), h.buckets...),
+		Counts:  counts,
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+}
+
+// queueMetrics 汇总单个队列的指标，除原子计数器外均由所属队列的锁保护。
+type queueMetrics struct {
+	name string
+
+	depth            GaugeMetric
+	adds             CounterMetric
+	latency          HistogramMetric
+	workDuration     HistogramMetric
+	unfinishedWork   SettableGaugeMetric
+	longestRunning   SettableGaugeMetric
+	retries          CounterMetric
+	leaseExpirations CounterMetric
+
+	addsTotal             atomic.Uint64
+	retriesTotal          atomic.Uint64
+	leaseExpirationsTotal atomic.Uint64
+	journalAckFailures    atomic.Uint64
+
+	latencyHist      *histogram
+	workDurationHist *histogram
+
+	// tracking 为真时逐个记录处理中元素的开始时刻，用于处理时长与处理中耗时类指标。
+	// 仅在幂等模式且配置了指标提供者时开启：非幂等模式下元素可以不调用 Done，逐个记录会无限增长。
+	tracking   bool
+	processing map[interface{}]int64
+
+	stop chan struct{}
+	once sync.Once
+}
+
+func newQueueMetrics(name string, provider MetricsProvider, idempotent bool) *queueMetrics {
+	tracking := provider != nil && idempotent
+	if provider == nil {
+		provider = NewNopMetricsProvider()
+	}
+
+	return &queueMetrics{
+		name:             name,
+		depth:            provider.NewDepthMetric(name),
+		adds:             provider.NewAddsMetric(name),
+		latency:          provider.NewLatencyMetric(name),
+		workDuration:     provider.NewWorkDurationMetric(name),
+		unfinishedWork:   provider.NewUnfinishedWorkSecondsMetric(name),
+		longestRunning:   provider.NewLongestRunningProcessorSecondsMetric(name),
+		retries:          provider.NewRetriesMetric(name),
+		leaseExpirations: provider.NewLeaseExpirationsMetric(name),
+		latencyHist:      newHistogram(defaultDurationBuckets),
+		workDurationHist: newHistogram(defaultDurationBuckets),
+		tracking:         tracking,
+		processing:       make(map[interface{}]int64),
+		stop:             make(chan struct{}),
+	}
+}
+
+// added 记录一次被接受的入队。
+func (m *queueMetrics) added() {
+	m.addsTotal.Add(1)
+	m.adds.Inc()
+}
+
+// retried 记录一次重试。
+func (m *queueMetrics) retried() {
+	m.retriesTotal.Add(1)
+	m.retries.Inc()
+}
+
+// leaseExpired 记录一次租约过期。
+func (m *queueMetrics) leaseExpired() {
+	m.leaseExpirationsTotal.Add(1)
+	m.leaseExpirations.Inc()
+}
+
+// journalAckFailed 记录一次写入失败的日志确认。
+func (m *queueMetrics) journalAckFailed() {
+	m.journalAckFailures.Add(1)
+}
+
+// pushedLocked 记录元素进入就绪队列。
+func (m *queueMetrics) pushedLocked() {
+	m.depth.Inc()
+}
+
+// discardedLocked 记录元素未经消费即被丢弃。
+func (m *queueMetrics) discardedLocked() {
+	m.depth.Dec()
+}
+
+// poppedLocked 记录元素出队。
+func (m *queueMetrics) poppedLocked(enqueuedAt, now int64) {
+	m.depth.Dec()
+
+	if enqueuedAt > 0 {
+		seconds := float64(now-enqueuedAt) / float64(time.Second)
+		m.latencyHist.observe(seconds)
+		m.latency.Observe(seconds)
+	}
+}
+
+// startedLocked 开始计算 key 的处理时长，未开启逐个记录时忽略。
+func (m *queueMetrics) startedLocked(key interface{}, now int64) {
+	if m.tracking {
+		m.processing[key] = now
+	}
+}
+
+// doneLocked 结束 key 的处理计时。
+func (m *queueMetrics) doneLocked(key interface{}, now int64) {
+	start, ok := m.processing[key]
+	if !ok {
+		return
+	}
+	delete(m.processing, key)
+
+	seconds := float64(now-start) / float64(time.Second)
+	m.workDurationHist.observe(seconds)
+	m.workDuration.Observe(seconds)
+}
+
+// runningLocked 返回处理中元素的总耗时与最长耗时。
+func (m *queueMetrics) runningLocked(now int64) (unfinished, longest time.Duration) {
+	for _, start := range m.processing {
+		elapsed := time.Duration(now - start)
+		unfinished += elapsed
+		if elapsed > longest {
+			longest = elapsed
+		}
+	}
+	return
+}
+
+func (m *queueMetrics) resetLocked() {
+	m.processing = make(map[interface{}]int64)
+	m.unfinishedWork.Set(0)
+	m.longestRunning.Set(0)
+}
+
+func (m *queueMetrics) snapshotLocked(now int64) QueueStats {
+	unfinished, longest := m.runningLocked(now)
+
+	return QueueStats{
+		Name:               m.name,
+		Adds:               m.addsTotal.Load(),
+		Retries:            m.retriesTotal.Load(),
+		LeaseExpirations:   m.leaseExpirationsTotal.Load(),
+		JournalAckFailures: m.journalAckFailures.Load(),
+		Latency:            m.latencyHist.snapshot(),
+		WorkDuration:       m.workDurationHist.snapshot(),
+		UnfinishedWork:     unfinished,
+		LongestRunning:     longest,
+	}
+}
+
+// close 停止周期刷新协程。
+func (m *queueMetrics) close() {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+}
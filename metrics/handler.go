@@ -0,0 +1,218 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+const (
+	// contentTypeText 为 Prometheus 文本格式 0.0.4。
+	contentTypeText = "text/plain; version=0.0.4; charset=utf-8"
+
+	// contentTypeOpenMetrics 为 OpenMetrics 文本格式 1.0.0。
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+type metricType string
+
+const (
+	gauge     metricType = "gauge"
+	counter   metricType = "counter"
+	histogram metricType = "histogram"
+)
+
+// family 描述一个指标族及其取值方式。
+type family struct {
+	name      string
+	help      string
+	kind      metricType
+	deadOnly  bool
+	value     func(stats *wkq.QueueStats) float64
+	histogram func(stats *wkq.QueueStats) *wkq.HistogramSnapshot
+}
+
+// families 与 client-go workqueue 指标命名保持一致，额外提供租约、死信与处理中数量。
+var families = []family{
+	{
+		name:  "workqueue_depth",
+		help:  "Current depth of workqueue.",
+		kind:  gauge,
+		value: func(s *wkq.QueueStats) float64 { return float64(s.Depth) },
+	},
+	{
+		name:  "workqueue_in_flight",
+		help:  "Number of items taken from workqueue but not yet done.",
+		kind:  gauge,
+		value: func(s *wkq.QueueStats) float64 { return float64(s.InFlight) },
+	},
+	{
+		name:  "workqueue_adds",
+		help:  "Total number of adds handled by workqueue.",
+		kind:  counter,
+		value: func(s *wkq.QueueStats) float64 { return float64(s.Adds) },
+	},
+	{
+		name:      "workqueue_queue_duration_seconds",
+		help:      "How long in seconds an item stays in workqueue before being requested.",
+		kind:      histogram,
+		histogram: func(s *wkq.QueueStats) *wkq.HistogramSnapshot { return &s.Latency },
+	},
+	{
+		name:      "workqueue_work_duration_seconds",
+		help:      "How long in seconds processing an item from workqueue takes.",
+		kind:      histogram,
+		histogram: func(s *wkq.QueueStats) *wkq.HistogramSnapshot { return &s.WorkDuration },
+	},
+	{
+		name:  "workqueue_unfinished_work_seconds",
+		help:  "How many seconds of work has been done that is in progress.",
+		kind:  gauge,
+		value: func(s *wkq.QueueStats) float64 { return s.UnfinishedWork.Seconds() },
+	},
+	{
+		name:  "workqueue_longest_running_processor_seconds",
+		help:  "How many seconds has the longest running processor for workqueue been running.",
+		kind:  gauge,
+		value: func(s *wkq.QueueStats) float64 { return s.LongestRunning.Seconds() },
+	},
+	{
+		name:  "workqueue_retries",
+		help:  "Total number of retries handled by workqueue.",
+		kind:  counter,
+		value: func(s *wkq.QueueStats) float64 { return float64(s.Retries) },
+	},
+	{
+		name:  "workqueue_lease_expirations",
+		help:  "Total number of leases that expired and were requeued.",
+		kind:  counter,
+		value: func(s *wkq.QueueStats) float64 { return float64(s.LeaseExpirations) },
+	},
+	{
+		name:  "workqueue_journal_ack_failures",
+		help:  "Total number of WAL acknowledgements that failed to be written.",
+		kind:  counter,
+		value: func(s *wkq.QueueStats) float64 { return float64(s.JournalAckFailures) },
+	},
+	{
+		name:     "workqueue_dead_letters",
+		help:     "Total number of dead letters received by a dead letter queue.",
+		kind:     counter,
+		deadOnly: true,
+		value:    func(s *wkq.QueueStats) float64 { return float64(s.Adds) },
+	},
+}
+
+type handlerImpl struct {
+	registry *Registry
+}
+
+// NewHandler 返回导出 registry 中全部队列指标的 http.Handler。
+// 请求头 Accept 包含 application/openmetrics-text 时输出 OpenMetrics 格式，否则输出 Prometheus 文本格式。
+func NewHandler(registry *Registry) http.Handler {
+	if registry == nil {
+		registry = NewRegistry()
+	}
+	return &handlerImpl{registry: registry}
+}
+
+func (h *handlerImpl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", contentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", contentTypeText)
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	bw := bufio.NewWriter(w)
+	render(bw, h.registry.collect(), openMetrics)
+	_ = bw.Flush()
+}
+
+// render 按指标族输出全部队列的样本。
+func render(w *bufio.Writer, entries []entry, openMetrics bool) {
+	for i := range families {
+		f := &families[i]
+
+		// Prometheus 文本格式中计数器的 TYPE 需要带 _total 后缀，OpenMetrics 则使用族名。
+		name := f.name
+		if f.kind == counter && !openMetrics {
+			name += "_total"
+		}
+
+		header := false
+		for j := range entries {
+			e := &entries[j]
+			if f.deadOnly && !e.dead {
+				continue
+			}
+			if !header {
+				writeHeader(w, name, f.help, f.kind)
+				header = true
+			}
+
+			label := `name="` + escapeLabel(e.name) + `"`
+			switch f.kind {
+			case histogram:
+				writeHistogram(w, f.name, label, f.histogram(&e.stats))
+			case counter:
+				writeSample(w, f.name+"_total", label, f.value(&e.stats))
+			default:
+				writeSample(w, f.name, label, f.value(&e.stats))
+			}
+		}
+	}
+
+	if openMetrics {
+		_, _ = w.WriteString("# EOF\n")
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help string, kind metricType) {
+	_, _ = w.WriteString("# HELP " + name + " " + help + "\n")
+	_, _ = w.WriteString("# TYPE " + name + " " + string(kind) + "\n")
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	_, _ = w.WriteString(name + "{" + labels + "} " + formatFloat(value) + "\n")
+}
+
+func writeHistogram(w *bufio.Writer, name, label string, h *wkq.HistogramSnapshot) {
+	for i, bound := range h.Buckets {
+		writeSample(w, name+"_bucket", label+`,le="`+formatFloat(bound)+`"`, float64(h.Counts[i]))
+	}
+	writeSample(w, name+"_bucket", label+`,le="+Inf"`, float64(h.Count))
+	writeSample(w, name+"_sum", label, h.Sum)
+	writeSample(w, name+"_count", label, float64(h.Count))
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
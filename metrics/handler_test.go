@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, handler http.Handler, accept string) (string, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String(), rec.Header().Get("Content-Type")
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := NewRegistry()
+	q := wkq.NewQueue(nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, registry.Register("orders", q))
+	assert.ErrorIs(t, registry.Register("orders", q), ErrQueueAlreadyRegistered)
+	assert.ErrorIs(t, registry.Register("", q), ErrInvalidQueueName)
+	assert.ErrorIs(t, registry.Register("nil", nil), wkq.ErrQueueIsNil)
+
+	registry.Unregister("orders")
+	assert.NoError(t, registry.Register("orders", q))
+}
+
+func TestHandler_PrometheusText(t *testing.T) {
+	registry := NewRegistry()
+
+	q := wkq.NewQueue(wkq.NewQueueConfig().WithValueIdempotent().WithMetricsProvider(wkq.NewNopMetricsProvider()))
+	defer q.Shutdown()
+	dlq := wkq.NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+
+	assert.NoError(t, registry.Register("orders", q))
+	assert.NoError(t, registry.Register(`dead"letters`, dlq))
+
+	assert.NoError(t, q.Put("test1"))
+	assert.NoError(t, q.Put("test2"))
+	v, err := q.Get()
+	assert.NoError(t, err)
+	q.Done(v)
+	assert.NoError(t, dlq.PutDead(&wkq.DeadLetter{Payload: "bad"}))
+
+	body, contentType := scrape(t, NewHandler(registry), "")
+	assert.Equal(t, contentTypeText, contentType)
+
+	assert.Contains(t, body, "# TYPE workqueue_depth gauge\n")
+	assert.Contains(t, body, `workqueue_depth{name="orders"} 1`+"\n")
+	assert.Contains(t, body, "# TYPE workqueue_adds_total counter\n")
+	assert.Contains(t, body, `workqueue_adds_total{name="orders"} 2`+"\n")
+	assert.Contains(t, body, `workqueue_queue_duration_seconds_bucket{name="orders",le="+Inf"} 1`+"\n")
+	assert.Contains(t, body, `workqueue_queue_duration_seconds_count{name="orders"} 1`+"\n")
+	assert.Contains(t, body, `workqueue_work_duration_seconds_count{name="orders"} 1`+"\n")
+	assert.Contains(t, body, `workqueue_retries_total{name="orders"} 0`+"\n")
+	assert.Contains(t, body, `workqueue_dead_letters_total{name="dead\"letters"} 1`+"\n", "Label values should be escaped")
+	assert.NotContains(t, body, `workqueue_dead_letters_total{name="orders"}`, "Dead letter counter should only cover dead letter queues")
+	assert.NotContains(t, body, "# EOF")
+
+	// 分组按名称排序，且每个指标族只输出一次元数据。
+	assert.Equal(t, 1, strings.Count(body, "# TYPE workqueue_depth "))
+	assert.True(t, strings.Index(body, `workqueue_depth{name="dead\"letters"}`) < strings.Index(body, `workqueue_depth{name="orders"}`))
+}
+
+func TestHandler_OpenMetrics(t *testing.T) {
+	registry := NewRegistry()
+	q := wkq.NewLeasedQueue(nil)
+	defer q.Shutdown()
+	assert.NoError(t, registry.Register("leases", q))
+
+	body, contentType := scrape(t, NewHandler(registry), "application/openmetrics-text;version=1.0.0,text/plain;q=0.5")
+	assert.Equal(t, contentTypeOpenMetrics, contentType)
+
+	assert.Contains(t, body, "# TYPE workqueue_lease_expirations counter\n")
+	assert.Contains(t, body, `workqueue_lease_expirations_total{name="leases"} 0`+"\n")
+	assert.Contains(t, body, `workqueue_journal_ack_failures_total{name="leases"} 0`+"\n")
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"), "OpenMetrics exposition should end with # EOF")
+}
+
+func TestHandler_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	NewHandler(nil).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
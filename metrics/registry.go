@@ -0,0 +1,86 @@
+// Package metrics 以 Prometheus/OpenMetrics 文本格式导出 workqueue 队列指标，仅依赖标准库。
+package metrics
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// ErrInvalidQueueName 表示注册名称为空。
+var ErrInvalidQueueName = errors.New("invalid queue name")
+
+// ErrQueueAlreadyRegistered 表示同名队列已注册。
+var ErrQueueAlreadyRegistered = errors.New("queue already registered")
+
+// StatsProvider 是可被导出的队列，workqueue 与 generic 包中的全部队列均满足该接口。
+type StatsProvider = interface {
+	Stats() wkq.QueueStats
+}
+
+// Registry 维护需要导出指标的队列集合，可并发使用。
+type Registry struct {
+	lock   sync.RWMutex
+	queues map[string]StatsProvider
+}
+
+// NewRegistry 创建空的注册表。
+func NewRegistry() *Registry {
+	return &Registry{queues: make(map[string]StatsProvider)}
+}
+
+// Register 以 name 作为 name 标签注册队列。
+func (r *Registry) Register(name string, queue StatsProvider) error {
+	if name == "" {
+		return ErrInvalidQueueName
+	}
+	if queue == nil {
+		return wkq.ErrQueueIsNil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.queues[name]; ok {
+		return ErrQueueAlreadyRegistered
+	}
+	r.queues[name] = queue
+	return nil
+}
+
+// Unregister 移除已注册的队列，队列关闭后应及时移除。
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()
+	delete(r.queues, name)
+	r.lock.Unlock()
+}
+
+// entry 为一次采集中单个队列的快照。
+type entry struct {
+	name  string
+	stats wkq.QueueStats
+	dead  bool
+}
+
+// collect 按名称排序采集全部队列的快照，保证输出稳定。
+func (r *Registry) collect() []entry {
+	r.lock.RLock()
+	entries := make([]entry, 0, len(r.queues))
+	queues := make([]StatsProvider, 0, len(r.queues))
+	for name, queue := range r.queues {
+		entries = append(entries, entry{name: name})
+		queues = append(queues, queue)
+	}
+	r.lock.RUnlock()
+
+	// Stats 需要获取队列锁，在注册表锁外调用。
+	for i, queue := range queues {
+		entries[i].stats = queue.Stats()
+		_, entries[i].dead = queue.(wkq.DeadLetterQueue)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries
+}
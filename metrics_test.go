@@ -0,0 +1,217 @@
+package workqueue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMetric struct {
+	mu     sync.Mutex
+	value  float64
+	values []float64
+}
+
+func (m *testMetric) Inc() { m.add(1) }
+
+func (m *testMetric) Dec() { m.add(-1) }
+
+func (m *testMetric) Set(value float64) {
+	m.mu.Lock()
+	m.value = value
+	m.mu.Unlock()
+}
+
+func (m *testMetric) Observe(value float64) {
+	m.mu.Lock()
+	m.values = append(m.values, value)
+	m.mu.Unlock()
+}
+
+func (m *testMetric) add(delta float64) {
+	m.mu.Lock()
+	m.value += delta
+	m.mu.Unlock()
+}
+
+func (m *testMetric) get() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.value
+}
+
+func (m *testMetric) observed() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.values)
+}
+
+type testMetricsProvider struct {
+	name                                           string
+	depth, adds, latency, workDuration             testMetric
+	unfinished, longest, retries, leaseExpirations testMetric
+}
+
+func (p *testMetricsProvider) NewDepthMetric(name string) GaugeMetric {
+	p.name = name
+	return &p.depth
+}
+
+func (p *testMetricsProvider) NewAddsMetric(string) CounterMetric { return &p.adds }
+
+func (p *testMetricsProvider) NewLatencyMetric(string) HistogramMetric { return &p.latency }
+
+func (p *testMetricsProvider) NewWorkDurationMetric(string) HistogramMetric {
+	return &p.workDuration
+}
+
+func (p *testMetricsProvider) NewUnfinishedWorkSecondsMetric(string) SettableGaugeMetric {
+	return &p.unfinished
+}
+
+func (p *testMetricsProvider) NewLongestRunningProcessorSecondsMetric(string) SettableGaugeMetric {
+	return &p.longest
+}
+
+func (p *testMetricsProvider) NewRetriesMetric(string) CounterMetric { return &p.retries }
+
+func (p *testMetricsProvider) NewLeaseExpirationsMetric(string) CounterMetric {
+	return &p.leaseExpirations
+}
+
+func TestQueueImpl_Stats(t *testing.T) {
+	q := NewQueue(NewQueueConfig().WithName("orders").WithValueIdempotent().WithMetricsProvider(NewNopMetricsProvider()))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("test1"))
+	assert.NoError(t, q.Put("test2"))
+	assert.NoError(t, q.Put("test3"))
+
+	time.Sleep(10 * time.Millisecond)
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	_, err = q.Get()
+	assert.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	q.Done(v)
+
+	stats := q.Stats()
+	assert.Equal(t, "orders", stats.Name)
+	assert.Equal(t, 1, stats.Depth, "One value should remain queued")
+	assert.Equal(t, 1, stats.InFlight, "One value should still be processing")
+	assert.Equal(t, uint64(3), stats.Adds)
+	assert.Equal(t, uint64(2), stats.Latency.Count)
+	assert.True(t, stats.Latency.Mean() >= 10*time.Millisecond, "Latency should cover the time spent queued")
+	assert.Equal(t, uint64(1), stats.WorkDuration.Count)
+	assert.True(t, stats.WorkDuration.Mean() >= 10*time.Millisecond, "Work duration should cover the time spent processing")
+	assert.True(t, stats.LongestRunning >= 10*time.Millisecond, "Longest running should track the unfinished value")
+	assert.Equal(t, stats.LongestRunning, stats.UnfinishedWork)
+	assert.Equal(t, len(stats.Latency.Buckets), len(stats.Latency.Counts))
+	assert.Equal(t, uint64(2), stats.Latency.Counts[len(stats.Latency.Counts)-1], "Bucket counts should be cumulative")
+}
+
+func TestQueueImpl_Stats_Idempotent(t *testing.T) {
+	q := NewQueue(NewQueueConfig().WithValueIdempotent().WithMetricsProvider(NewNopMetricsProvider()))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("test1"))
+	assert.ErrorIs(t, q.Put("test1"), ErrElementAlreadyExist)
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, q.Put("test1"), "Update of a processing value should be deferred")
+	q.Done(v)
+
+	stats := q.Stats()
+	assert.Equal(t, uint64(2), stats.Adds, "Rejected duplicates should not be counted")
+	assert.Equal(t, 1, stats.Depth, "Deferred value should be re-enqueued on Done")
+	assert.Equal(t, 0, stats.InFlight)
+	assert.Equal(t, uint64(1), stats.WorkDuration.Count)
+}
+
+func TestQueueImpl_MetricsProvider(t *testing.T) {
+	provider := &testMetricsProvider{}
+	q := NewQueue(NewQueueConfig().WithName("jobs").WithValueIdempotent().WithMetricsProvider(provider))
+	defer q.Shutdown()
+
+	assert.Equal(t, "jobs", provider.name)
+
+	assert.NoError(t, q.PutBatch([]interface{}{"test1", "test2"}))
+	assert.Equal(t, float64(2), provider.adds.get())
+	assert.Equal(t, float64(2), provider.depth.get())
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), provider.depth.get())
+	assert.Equal(t, 1, provider.latency.observed())
+
+	assert.Eventually(t, func() bool {
+		return provider.longest.get() > 0 && provider.unfinished.get() > 0
+	}, 2*time.Second, 20*time.Millisecond, "Running metrics should be refreshed periodically")
+
+	q.Done(v)
+	assert.Equal(t, 1, provider.workDuration.observed())
+}
+
+func TestQueueImpl_Stats_GetWithoutDone(t *testing.T) {
+	q := NewQueue(NewQueueConfig().WithMetricsProvider(NewNopMetricsProvider()))
+	defer q.Shutdown()
+
+	// 非幂等模式下 Done 是可选的，只出队不 Done 不应逐个记录处理中的元素。
+	const n = 10000
+	for i := 0; i < n; i++ {
+		assert.NoError(t, q.Put(i))
+		_, err := q.Get()
+		assert.NoError(t, err)
+	}
+
+	stats := q.Stats()
+	assert.Equal(t, n, stats.InFlight)
+	assert.Equal(t, uint64(n), stats.Latency.Count)
+	assert.Equal(t, uint64(0), stats.WorkDuration.Count, "Work duration is only tracked for idempotent queues")
+	assert.Empty(t, q.(*queueImpl).metrics.processing, "Start times should not be retained without Done")
+}
+
+func TestRetryQueue_Stats_Retries(t *testing.T) {
+	provider := &testMetricsProvider{}
+	config := NewRetryQueueConfig().WithPolicy(&testImmediateRetryPolicy{})
+	config.WithValueIdempotent().WithMetricsProvider(provider)
+	q := NewRetryQueue(config)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("task"))
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, q.Retry(v, errors.New("failed")))
+
+	stats := q.Stats()
+	assert.Equal(t, uint64(1), stats.Retries)
+	assert.Equal(t, uint64(2), stats.Adds, "Retried value should be counted as an add")
+	assert.Equal(t, uint64(1), stats.WorkDuration.Count, "Retry should finish the processing span")
+	assert.Equal(t, float64(1), provider.retries.get())
+}
+
+func TestLeasedQueue_Stats_LeaseExpirations(t *testing.T) {
+	config := NewLeasedQueueConfig().WithScanInterval(5 * time.Millisecond)
+	q := NewLeasedQueue(config)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("job"))
+
+	_, _, err := q.GetWithLease(10 * time.Millisecond)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return q.Stats().LeaseExpirations == 1
+	}, time.Second, 5*time.Millisecond)
+
+	stats := q.Stats()
+	assert.Equal(t, 1, stats.Depth, "Expired lease should be requeued")
+	assert.Equal(t, 0, stats.InFlight)
+}
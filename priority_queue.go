@@ -1,8 +1,9 @@
 package workqueue
 
 import (
+	"io"
 	"math"
-	"sync"
+	"math/bits"
 
 	hp "github.com/shengyanli1982/workqueue/v2/internal/container/heap"
 	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
@@ -17,13 +18,175 @@ const (
 	PRIORITY_FASTEST = math.MinInt64
 )
 
+// priorityAging 描述优先级老化。credit(at) 为从队列创建到 at 累积的提升量，
+// 元素在 e 时刻入队、以 p 为优先级时，其在 now 时刻的有效优先级为 p - (credit(now) - credit(e))。
+// 各元素的有效优先级同时减去相同的 credit(now)，按 p + credit(e) 比较即可得到相同的顺序，
+// 相对顺序不随时间变化，因此无需定时重排或扫描节点。节点保留原始优先级与入队时刻，
+// 比较时以 128 位整数计算 p + credit(e)，贴近 int64 边界的优先级与长期运行的队列同样可以老化。
+type priorityAging struct {
+	start  int64
+	step   uint64
+	every  uint64
+	linear bool
+}
+
+func newPriorityAging(config *PriorityQueueConfig) *priorityAging {
+	if config.agingStep <= 0 {
+		return nil
+	}
+	return &priorityAging{
+		start:  config.clock.Now().UnixNano(),
+		step:   uint64(config.agingStep),
+		every:  uint64(config.agingEvery),
+		linear: config.agingLinear,
+	}
+}
+
+// credit 返回从队列创建到 at 累积的提升量，结果不超过 2^126，不会溢出。
+func (a *priorityAging) credit(at int64) int128 {
+	elapsed := at - a.start
+	if elapsed <= 0 {
+		return int128{}
+	}
+	if a.linear {
+		hi, lo := bits.Mul64(uint64(elapsed), a.step)
+		qhi := hi / a.every
+		qlo, _ := bits.Div64(hi%a.every, lo, a.every)
+		return int128{hi: int64(qhi), lo: qlo}
+	}
+	hi, lo := bits.Mul64(uint64(elapsed)/a.every, a.step)
+	return int128{hi: int64(hi), lo: lo}
+}
+
+// compare 比较两个节点老化后的优先级，返回负数表示 x 排在 y 之前。
+func (a *priorityAging) compare(x, y *lst.Node) int {
+	return int128Of(x.Priority).add(a.credit(x.Timestamp)).cmp(int128Of(y.Priority).add(a.credit(y.Timestamp)))
+}
+
+// effective 返回节点在累积提升为 now 时的有效优先级，超出 int64 范围时饱和。
+func (a *priorityAging) effective(node *lst.Node, now int128) int64 {
+	return int128Of(node.Priority).sub(now.sub(a.credit(node.Timestamp))).saturate()
+}
+
+// int128 为 128 位有符号整数，仅用于老化后的优先级计算。
+type int128 struct {
+	hi int64
+	lo uint64
+}
+
+func int128Of(v int64) int128 { return int128{hi: v >> 63, lo: uint64(v)} }
+
+func (x int128) add(y int128) int128 {
+	lo, carry := bits.Add64(x.lo, y.lo, 0)
+	return int128{hi: x.hi + y.hi + int64(carry), lo: lo}
+}
+
+func (x int128) sub(y int128) int128 {
+	lo, borrow := bits.Sub64(x.lo, y.lo, 0)
+	return int128{hi: x.hi - y.hi - int64(borrow), lo: lo}
+}
+
+func (x int128) cmp(y int128) int {
+	switch {
+	case x.hi < y.hi:
+		return -1
+	case x.hi > y.hi:
+		return 1
+	case x.lo < y.lo:
+		return -1
+	case x.lo > y.lo:
+		return 1
+	}
+	return 0
+}
+
+// saturate 将 x 截断到 int64 范围。
+func (x int128) saturate() int64 {
+	switch {
+	case x.hi > 0 || (x.hi == 0 && x.lo > math.MaxInt64):
+		return math.MaxInt64
+	case x.hi < -1 || (x.hi == -1 && x.lo <= math.MaxInt64):
+		return math.MinInt64
+	}
+	return int64(x.lo)
+}
+
+// priorityHeap 在红黑树之上维护从元素 key 到节点的索引，使按值更新与移除无需遍历整棵树。
+// 开启老化时红黑树按老化后的优先级比较节点，入队时刻由基础队列在挂接前记录。
+// 全部方法由基础队列在持有锁时调用，无法生成 key 的元素不进入索引。
+type priorityHeap struct {
+	wrapInternalHeap
+	aging *priorityAging
+	keyOf func(value interface{}) (interface{}, error)
+	index map[interface{}][]*lst.Node
+}
+
+func (h *priorityHeap) Push(value interface{}) {
+	node := value.(*lst.Node)
+	h.RBTree.Push(node)
+	if key, err := h.keyOf(node.Value); err == nil {
+		h.index[key] = append(h.index[key], node)
+	}
+}
+
+func (h *priorityHeap) Pop() interface{} {
+	node := h.RBTree.Pop()
+	if node != nil {
+		h.unindex(node)
+	}
+	return node
+}
+
+func (h *priorityHeap) Remove(node *lst.Node) {
+	h.RBTree.Remove(node)
+	h.unindex(node)
+}
+
+func (h *priorityHeap) Cleanup() {
+	h.RBTree.Cleanup()
+	h.index = make(map[interface{}][]*lst.Node)
+}
+
+func (h *priorityHeap) unindex(node *lst.Node) {
+	key, err := h.keyOf(node.Value)
+	if err != nil {
+		return
+	}
+	nodes := h.index[key]
+	for i, n := range nodes {
+		if n == node {
+			nodes = append(nodes[:i], nodes[i+1:]...)
+			break
+		}
+	}
+	if len(nodes) == 0 {
+		delete(h.index, key)
+	} else {
+		h.index[key] = nodes
+	}
+}
+
+// lookup 返回与 value 对应的全部就绪节点的副本。
+func (h *priorityHeap) lookup(value interface{}) ([]*lst.Node, error) {
+	key, err := h.keyOf(value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*lst.Node(nil), h.index[key]...), nil
+}
+
+// update 修改节点的优先级，节点保留入队时刻，开启老化时已累积的等待提升不受影响。
+func (h *priorityHeap) update(node *lst.Node, priority int64) {
+	h.RBTree.Update(node, priority)
+}
+
 // priorityQueueImpl 使用红黑树按优先级排序。
 type priorityQueueImpl struct {
 	Queue
 	config      *PriorityQueueConfig
-	sorting     *hp.RBTree
+	sorting     *priorityHeap
+	aging       *priorityAging
 	elementpool *lst.NodePool
-	lock        sync.Mutex
 }
 
 // NewPriorityQueue 创建优先级队列。
@@ -33,11 +196,21 @@ func NewPriorityQueue(config *PriorityQueueConfig) PriorityQueue {
 
 	q := &priorityQueueImpl{
 		config:      config,
-		sorting:     hp.New(),
+		aging:       newPriorityAging(config),
 		elementpool: lst.NewNodePool(),
 	}
 
-	q.Queue = newQueue(&wrapInternalHeap{RBTree: q.sorting}, q.elementpool, &config.QueueConfig)
+	q.sorting = &priorityHeap{
+		wrapInternalHeap: wrapInternalHeap{RBTree: hp.NewWithComparator(config.comparator)},
+		aging:            q.aging,
+		index:            make(map[interface{}][]*lst.Node),
+	}
+	if q.aging != nil {
+		q.sorting.RBTree.SetPriorityComparator(q.aging.compare)
+	}
+	base := newQueue(q.sorting, q.elementpool, &config.QueueConfig)
+	q.sorting.keyOf = base.keyOf
+	q.Queue = base
 
 	return q
 }
@@ -64,19 +237,171 @@ func (q *priorityQueueImpl) PutWithPriority(value interface{}, priority int64) e
 	last.Value = value
 	last.Priority = priority
 
-	q.lock.Lock()
-	q.sorting.Push(last)
-	q.lock.Unlock()
+	// 经由基础队列挂接节点，共享其锁并唤醒阻塞中的消费者。
+	if err := q.Queue.(*queueImpl).pushNodes(last); err != nil {
+		q.elementpool.Put(last)
+		return err
+	}
 
 	q.config.callback.OnPriority(value, priority)
 
 	return nil
 }
 
+func (q *priorityQueueImpl) PutBatch(values []interface{}) error {
+
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+
+	errs := make([]error, len(values))
+	nodes := make([]*lst.Node, 0, len(values))
+	for i, value := range values {
+		if value == nil {
+			errs[i] = ErrElementIsNil
+			continue
+		}
+
+		last := q.elementpool.Get()
+		last.Value = value
+		last.Priority = PRIORITY_NORMAL
+		nodes = append(nodes, last)
+	}
+
+	if err := q.Queue.(*queueImpl).pushNodes(nodes...); err != nil {
+		for _, node := range nodes {
+			q.elementpool.Put(node)
+		}
+		return err
+	}
+
+	for i, value := range values {
+		if errs[i] == nil {
+			q.config.callback.OnPriority(value, PRIORITY_NORMAL)
+		}
+	}
+
+	return newBatchError(errs)
+}
+
+// UpdatePriority 修改队列中与 value 对应的全部就绪元素的优先级。PutWithPriority 与 PutBatch 不做幂等判重，
+// 因此幂等模式下同一 key 同样可能对应多个元素，它们会被一并修改。
+// 元素不在队列中时返回 ErrElementNotExist，已出队或等待 Done 后重新入队的元素不受影响。
+func (q *priorityQueueImpl) UpdatePriority(value interface{}, priority int64) error {
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+
+	if value == nil {
+		return ErrElementIsNil
+	}
+
+	base := q.Queue.(*queueImpl)
+	base.lock.Lock()
+	nodes, err := q.sorting.lookup(value)
+	for _, node := range nodes {
+		q.sorting.update(node, priority)
+	}
+	base.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return ErrElementNotExist
+	}
+
+	q.config.callback.OnUpdatePriority(value, priority)
+
+	return nil
+}
+
+// Remove 移除队列中与 value 对应的全部就绪元素，返回是否移除了元素。
+func (q *priorityQueueImpl) Remove(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+
+	removed := q.Queue.(*queueImpl).removeWith(func() []*lst.Node {
+		nodes, _ := q.sorting.lookup(value)
+		return nodes
+	})
+	if removed == 0 {
+		return false
+	}
+
+	q.config.callback.OnRemove(value)
+
+	return true
+}
+
+// Contains 判断队列中是否有与 value 对应的就绪元素。
+func (q *priorityQueueImpl) Contains(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+
+	base := q.Queue.(*queueImpl)
+	base.lock.Lock()
+	nodes, _ := q.sorting.lookup(value)
+	base.lock.Unlock()
+
+	return len(nodes) > 0
+}
+
+// HeapRange 按出队顺序遍历元素，开启老化时给出当前的有效优先级。
 func (q *priorityQueueImpl) HeapRange(fn func(value interface{}, delay int64) bool) {
-	q.lock.Lock()
-	q.sorting.Range(func(node *lst.Node) bool {
-		return fn(node.Value, node.Priority)
+	base := q.Queue.(*queueImpl)
+	base.lock.Lock()
+	priorityOf := q.priorityOf()
+	q.sorting.RBTree.Range(func(node *lst.Node) bool {
+		return fn(node.Value, priorityOf(node))
 	})
-	q.lock.Unlock()
+	base.lock.Unlock()
+}
+
+// priorityOf 返回读取节点当前有效优先级的函数。
+func (q *priorityQueueImpl) priorityOf() func(node *lst.Node) int64 {
+	if q.aging == nil {
+		return func(node *lst.Node) int64 { return node.Priority }
+	}
+	now := q.aging.credit(q.config.clock.Now().UnixNano())
+	return func(node *lst.Node) int64 { return q.aging.effective(node, now) }
+}
+
+// Snapshot 保存就绪元素及其优先级，开启老化时保存快照时刻的有效优先级，恢复后保留已累积的等待提升。
+func (q *priorityQueueImpl) Snapshot(w io.Writer) error {
+	s := &queueSnapshot{}
+
+	base := q.Queue.(*queueImpl)
+	base.lock.Lock()
+	err := base.snapshotLocked(s, q.priorityOf())
+	base.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return writeSnapshot(w, s)
+}
+
+// Restore 按快照中的优先级恢复元素，其他类型队列的快照按 PRIORITY_NORMAL 恢复。
+func (q *priorityQueueImpl) Restore(r io.Reader) error {
+	s, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+	items := s.readyItems(true)
+	values, err := decodeItems(q.config.codec, items)
+	if err != nil {
+		return err
+	}
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+
+	errs := make([]error, len(values))
+	for i, value := range values {
+		errs[i] = q.PutWithPriority(value, items[i].Priority)
+	}
+	return newBatchError(errs)
 }
@@ -1,6 +1,7 @@
 package workqueue
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -70,7 +71,7 @@ func TestPriorityQueueImpl_PutWithPriority_Parallel(t *testing.T) {
 }
 
 type testPriorityQueueCallback struct {
-	puts, gets, dones, priorities []interface{}
+	puts, gets, dones, priorities, updates, removes []interface{}
 }
 
 func (c *testPriorityQueueCallback) OnPut(value interface{}) {
@@ -89,6 +90,14 @@ func (c *testPriorityQueueCallback) OnPriority(value interface{}, priority int64
 	c.priorities = append(c.priorities, value)
 }
 
+func (c *testPriorityQueueCallback) OnUpdatePriority(value interface{}, priority int64) {
+	c.updates = append(c.updates, value)
+}
+
+func (c *testPriorityQueueCallback) OnRemove(value interface{}) {
+	c.removes = append(c.removes, value)
+}
+
 func TestPriorityQueueImpl_Callback(t *testing.T) {
 	callback := &testPriorityQueueCallback{}
 	config := NewPriorityQueueConfig().WithCallback(callback)
@@ -268,3 +277,316 @@ func TestPriorityQueueImpl_LargeNumberOfItems(t *testing.T) {
 		previousPriority = currentPriority
 	}
 }
+
+func TestPriorityQueueImpl_GetWithContext(t *testing.T) {
+	q := NewPriorityQueue(nil)
+	defer q.Shutdown()
+
+	result := make(chan interface{}, 1)
+	go func() {
+		v, err := q.GetWithContext(context.Background())
+		assert.NoError(t, err, "GetWithContext should not return an error")
+		result <- v
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, q.PutWithPriority("test1", PRIORITY_LOW))
+
+	select {
+	case v := <-result:
+		assert.Equal(t, "test1", v, "GetWithContext value should be test1")
+	case <-time.After(time.Second):
+		t.Fatal("GetWithContext should be woken by PutWithPriority")
+	}
+}
+
+func TestPriorityQueueImpl_PutBatch(t *testing.T) {
+	q := NewPriorityQueue(nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithPriority("high", PRIORITY_HIGH))
+	assert.NoError(t, q.PutWithPriority("low", PRIORITY_LOW))
+
+	err := q.PutBatch([]interface{}{"normal1", nil, "normal2"})
+	assert.ErrorIs(t, err, ErrElementIsNil, "PutBatch should report nil elements")
+
+	values, err := q.GetBatch(10)
+	assert.NoError(t, err, "GetBatch should not return an error")
+	assert.Equal(t, []interface{}{"high", "normal1", "normal2", "low"}, values, "Batch values should follow priority order")
+}
+
+// newAgingPriorityQueue 创建使用 FakeClock 的老化优先级队列。
+func newAgingPriorityQueue(config *PriorityQueueConfig) (PriorityQueue, *FakeClock) {
+	clock := NewFakeClock(fakeEpoch)
+	return NewPriorityQueue(config.WithClock(clock)), clock
+}
+
+func TestPriorityQueueImpl_LinearAging(t *testing.T) {
+	// 每毫秒提升 1000，等待 20ms 的低优先级元素应排在新到达的高优先级元素之前。
+	q, clock := newAgingPriorityQueue(NewPriorityQueueConfig().WithLinearAging(1000, time.Millisecond))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithPriority("old", 10000))
+	clock.Advance(20 * time.Millisecond)
+	assert.NoError(t, q.PutWithPriority("new", 0))
+	assert.NoError(t, q.PutWithPriority("newer", 1))
+
+	assert.Equal(t, []interface{}{"old", "new", "newer"}, q.Values())
+
+	// HeapRange 给出当前的有效优先级。
+	var priorities []int64
+	q.HeapRange(func(_ interface{}, priority int64) bool {
+		priorities = append(priorities, priority)
+		return true
+	})
+	assert.Equal(t, []int64{-10000, 0, 1}, priorities)
+
+	clock.Advance(time.Millisecond)
+	priorities = priorities[:0]
+	q.HeapRange(func(_ interface{}, priority int64) bool {
+		priorities = append(priorities, priority)
+		return true
+	})
+	assert.Equal(t, []int64{-11000, -1000, -999}, priorities, "Every value should keep aging")
+}
+
+func TestPriorityQueueImpl_StepAging(t *testing.T) {
+	q, clock := newAgingPriorityQueue(NewPriorityQueueConfig().WithStepAging(PRIORITY_LOW, 5*time.Millisecond))
+	defer q.Shutdown()
+
+	// 持续到达的高优先级元素不会让低优先级元素无限等待。
+	assert.NoError(t, q.PutWithPriority("low", PRIORITY_LOW))
+	var got []interface{}
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, q.PutWithPriority("high", PRIORITY_HIGH))
+		clock.Advance(2 * time.Millisecond)
+		value, err := q.Get()
+		assert.NoError(t, err)
+		q.Done(value)
+		got = append(got, value)
+	}
+	// high 与 low 相差两个阶梯多一点，第 3 个边界（15ms）之后入队的 high 排在 low 之后。
+	assert.Equal(t, "low", got[8])
+}
+
+func TestPriorityQueueImpl_AgingSlowest(t *testing.T) {
+	q, clock := newAgingPriorityQueue(NewPriorityQueueConfig().WithLinearAging(PRIORITY_LOW, time.Millisecond))
+	defer q.Shutdown()
+
+	// 贴近 int64 上限的优先级同样会老化，不会在入队时饱和。
+	assert.NoError(t, q.PutWithPriority("slowest", PRIORITY_SLOWEST))
+	clock.Advance(50 * time.Millisecond)
+	assert.NoError(t, q.PutWithPriority("high", PRIORITY_HIGH))
+	assert.Equal(t, []interface{}{"high", "slowest"}, q.Values())
+
+	priorities := make(map[interface{}]int64)
+	q.HeapRange(func(value interface{}, priority int64) bool {
+		priorities[value] = priority
+		return true
+	})
+	assert.Equal(t, int64(PRIORITY_SLOWEST-50*PRIORITY_LOW), priorities["slowest"], "Slowest value should age while waiting")
+
+	// 每毫秒提升 PRIORITY_LOW 时，与 PRIORITY_HIGH 相差约 2^32 毫秒（约 50 天）。
+	clock.Advance(50 * 24 * time.Hour)
+	assert.NoError(t, q.PutWithPriority("high2", PRIORITY_HIGH))
+	assert.NoError(t, q.PutWithPriority("fastest", PRIORITY_FASTEST))
+	assert.Equal(t, []interface{}{"high", "fastest", "slowest", "high2"}, q.Values(), "Slowest value should overtake newly added high values")
+
+	q.HeapRange(func(value interface{}, priority int64) bool {
+		priorities[value] = priority
+		return true
+	})
+	assert.Equal(t, int64(PRIORITY_FASTEST), priorities["high"], "Reported priority should saturate at the int64 limit")
+	assert.Equal(t, int64(PRIORITY_HIGH), priorities["high2"])
+}
+
+func TestPriorityQueueImpl_AgingLongRunning(t *testing.T) {
+	q, clock := newAgingPriorityQueue(NewPriorityQueueConfig().WithLinearAging(PRIORITY_LOW, time.Nanosecond))
+	defer q.Shutdown()
+
+	// 累积提升远超 int64 范围后，新入队元素之间仍按优先级排序。
+	clock.Advance(365 * 24 * time.Hour)
+	assert.NoError(t, q.PutWithPriority("normal", PRIORITY_NORMAL))
+	assert.NoError(t, q.PutWithPriority("high", PRIORITY_HIGH))
+	assert.NoError(t, q.PutWithPriority("low", PRIORITY_LOW))
+	assert.Equal(t, []interface{}{"high", "normal", "low"}, q.Values())
+}
+
+func TestPriorityQueueImpl_AgingDisabled(t *testing.T) {
+	q, clock := newAgingPriorityQueue(NewPriorityQueueConfig().WithLinearAging(0, time.Millisecond))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithPriority("old", 1))
+	clock.Advance(5 * time.Millisecond)
+	assert.NoError(t, q.PutWithPriority("new", 0))
+	assert.Equal(t, []interface{}{"new", "old"}, q.Values())
+}
+
+func TestPriorityQueueImpl_UpdatePriority(t *testing.T) {
+	callback := &testPriorityQueueCallback{}
+	q := NewPriorityQueue(NewPriorityQueueConfig().WithCallback(callback))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithPriority("test1", 1))
+	assert.NoError(t, q.PutWithPriority("test2", 2))
+	assert.NoError(t, q.PutWithPriority("test3", 3))
+
+	assert.NoError(t, q.UpdatePriority("test3", 0))
+	assert.NoError(t, q.UpdatePriority("test1", 5))
+	assert.ErrorIs(t, q.UpdatePriority("missing", 0), ErrElementNotExist)
+	assert.ErrorIs(t, q.UpdatePriority(nil, 0), ErrElementIsNil)
+	assert.ErrorIs(t, q.UpdatePriority([]int{1}, 0), ErrElementNotHashable)
+
+	assert.Equal(t, []interface{}{"test3", "test2", "test1"}, q.Values())
+	assert.Equal(t, []interface{}{"test3", "test1"}, callback.updates)
+
+	// 已出队的元素不再能被修改。
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "test3", v)
+	assert.ErrorIs(t, q.UpdatePriority("test3", 0), ErrElementNotExist)
+	q.Done(v)
+
+	q.Shutdown()
+	assert.ErrorIs(t, q.UpdatePriority("test2", 0), ErrQueueIsClosed)
+}
+
+func TestPriorityQueueImpl_UpdatePriority_Duplicates(t *testing.T) {
+	config := NewPriorityQueueConfig()
+	config.WithValueIdempotent()
+	q := NewPriorityQueue(config)
+	defer q.Shutdown()
+
+	// PutWithPriority 不做幂等判重，同一值在幂等模式下也可以有多个元素，UpdatePriority 一并修改。
+	assert.NoError(t, q.PutWithPriority("dup", 5))
+	assert.NoError(t, q.PutWithPriority("other", 3))
+	assert.NoError(t, q.PutWithPriority("dup", 7))
+
+	assert.NoError(t, q.UpdatePriority("dup", 1))
+
+	var priorities []int64
+	q.HeapRange(func(value interface{}, priority int64) bool {
+		if value == "dup" {
+			priorities = append(priorities, priority)
+		}
+		return true
+	})
+	assert.Equal(t, []int64{1, 1}, priorities)
+	assert.Equal(t, []interface{}{"dup", "dup", "other"}, q.Values())
+}
+
+func TestPriorityQueueImpl_RemoveAndContains(t *testing.T) {
+	callback := &testPriorityQueueCallback{}
+	q := NewPriorityQueue(NewPriorityQueueConfig().WithCallback(callback))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithPriority("test1", 1))
+	assert.NoError(t, q.PutWithPriority("test2", 2))
+	assert.NoError(t, q.PutWithPriority("test1", 3))
+
+	assert.True(t, q.Contains("test1"))
+	assert.False(t, q.Contains("missing"))
+
+	// 重复入队的元素一并移除。
+	assert.True(t, q.Remove("test1"))
+	assert.False(t, q.Remove("test1"))
+	assert.False(t, q.Contains("test1"))
+	assert.Equal(t, []interface{}{"test2"}, q.Values())
+	assert.Equal(t, 1, q.Len())
+	assert.Equal(t, []interface{}{"test1"}, callback.removes)
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.False(t, q.Contains(v))
+	q.Done(v)
+}
+
+func TestPriorityQueueImpl_RemoveByKey(t *testing.T) {
+	type job struct {
+		id   string
+		data []byte
+	}
+	config := NewPriorityQueueConfig()
+	config.WithKeyFunc(func(value interface{}) string { return value.(*job).id })
+	q := NewPriorityQueue(config)
+	defer q.Shutdown()
+
+	// 不可哈希的元素按 key 定位。
+	assert.NoError(t, q.PutWithPriority(&job{id: "a"}, 1))
+	assert.NoError(t, q.PutWithPriority(&job{id: "b"}, 2))
+	assert.True(t, q.Contains(&job{id: "a"}))
+	assert.NoError(t, q.UpdatePriority(&job{id: "b"}, 0))
+	assert.True(t, q.Remove(&job{id: "a"}))
+	assert.False(t, q.Contains(&job{id: "a"}))
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "b", v.(*job).id)
+	q.Done(v)
+}
+
+func TestPriorityQueueImpl_StableFIFO(t *testing.T) {
+	q := NewPriorityQueue(nil)
+	defer q.Shutdown()
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, q.PutWithPriority(i, int64(i%2)))
+	}
+	// 出队一部分后继续写入，同级元素仍按写入先后出队。
+	for i := 0; i < 20; i++ {
+		v, err := q.Get()
+		assert.NoError(t, err)
+		assert.Equal(t, i*2, v)
+		q.Done(v)
+	}
+	for i := 100; i < 120; i++ {
+		assert.NoError(t, q.PutWithPriority(i, int64(i%2)))
+	}
+
+	var evens, odds []interface{}
+	for _, v := range drainQueue(t, q) {
+		if v.(int)%2 == 0 {
+			evens = append(evens, v)
+		} else {
+			odds = append(odds, v)
+		}
+	}
+	assert.Len(t, evens, 40)
+	for i := 1; i < len(evens); i++ {
+		assert.Less(t, evens[i-1], evens[i])
+	}
+	for i := 1; i < len(odds); i++ {
+		assert.Less(t, odds[i-1], odds[i])
+	}
+}
+
+func TestPriorityQueueImpl_Comparator(t *testing.T) {
+	type task struct {
+		name     string
+		deadline time.Time
+	}
+	now := time.Now()
+	q := NewPriorityQueue(NewPriorityQueueConfig().WithComparator(func(a, b interface{}) int {
+		da, db := a.(*task).deadline, b.(*task).deadline
+		switch {
+		case da.Before(db):
+			return -1
+		case da.After(db):
+			return 1
+		}
+		return 0
+	}))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithPriority(&task{"late", now.Add(time.Hour)}, PRIORITY_NORMAL))
+	assert.NoError(t, q.PutWithPriority(&task{"soon-1", now.Add(time.Minute)}, PRIORITY_NORMAL))
+	assert.NoError(t, q.PutWithPriority(&task{"urgent", now.Add(time.Hour)}, PRIORITY_HIGH))
+	assert.NoError(t, q.PutWithPriority(&task{"soon-2", now.Add(time.Minute)}, PRIORITY_NORMAL))
+
+	// 先按优先级，再按截止时间，截止时间相同按入队先后。
+	var names []string
+	for _, v := range drainQueue(t, q) {
+		names = append(names, v.(*task).name)
+	}
+	assert.Equal(t, []string{"urgent", "soon-1", "soon-2", "late"}, names)
+}
@@ -1,8 +1,12 @@
 package workqueue
 
 import (
+	"context"
+	"errors"
+	"reflect"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
 )
@@ -10,6 +14,7 @@ import (
 // queueImpl 是基础队列实现，支持可选幂等语义。
 type queueImpl struct {
 	closed      atomic.Bool
+	draining    atomic.Bool
 	once        sync.Once
 	lock        sync.Mutex
 	config      *QueueConfig
@@ -17,11 +22,26 @@ type queueImpl struct {
 	elementpool *lst.NodePool
 	processing  Set
 	dirty       Set
+	deferred    map[interface{}]interface{}
+	// deferredAttach 为处理中被合并的元素保留入队时的 attach，Done 重新入队时调用。
+	deferredAttach map[interface{}]func(node *lst.Node)
+	notify         chan struct{}
+	// inflight 为非幂等模式下已出队但尚未 Done 的元素数量，只是计数而不逐个记录。
+	inflight int
+	// retained 为经 retain 登记、暂时不在任何容器中的元素数量。
+	retained int
+	metrics  *queueMetrics
+	journal  *journal
 }
 
-// NewQueue 创建基础队列。
+// NewQueue 创建基础队列，配置了 WAL 时会先恢复日志中尚未确认的元素。
 func NewQueue(config *QueueConfig) Queue {
-	return newQueue(&wrapInternalList{List: lst.New()}, lst.NewNodePool(), config)
+	q := newQueue(&wrapInternalList{List: lst.New()}, lst.NewNodePool(), config)
+	if q.config.wal != nil {
+		q.journal = newJournal(q.config.wal, q.config.codec, q.metrics)
+		q.replay(nil)
+	}
+	return q
 }
 
 func newQueue(list container, elementpool *lst.NodePool, config *QueueConfig) *queueImpl {
@@ -35,33 +55,131 @@ func newQueue(list container, elementpool *lst.NodePool, config *QueueConfig) *q
 	if q.config.idempotent {
 		q.processing = q.config.setCreator()
 		q.dirty = q.config.setCreator()
+		q.deferred = make(map[interface{}]interface{})
+		q.deferredAttach = make(map[interface{}]func(node *lst.Node))
+	}
+
+	q.metrics = newQueueMetrics(q.config.name, q.config.metrics, q.config.idempotent)
+	if q.config.metrics != nil {
+		go q.updateRunningMetrics()
 	}
 
 	return q
 }
 
 func (q *queueImpl) Shutdown() {
+	q.shutdown(false)
+}
 
-	q.once.Do(func() {
+// ShutdownWithDrain 停止接收新元素，等待剩余元素被消费且处理中元素全部 Done 后关闭队列。
+// ctx 先结束时立即关闭队列，并返回尚未投递给消费端的元素。
+func (q *queueImpl) ShutdownWithDrain(ctx context.Context) ([]interface{}, error) {
+	if err := q.drain(ctx, nil); err != nil {
+		if errors.Is(err, ErrQueueIsClosed) {
+			return nil, err
+		}
+		return q.shutdown(true), err
+	}
 
-		q.closed.Store(true)
+	q.shutdown(false)
+	return nil, nil
+}
+
+// shutdown 关闭队列并释放全部元素，collect 为真时返回尚未投递的元素。
+func (q *queueImpl) shutdown(collect bool) (pending []interface{}) {
+
+	q.once.Do(func() {
 
 		q.lock.Lock()
 
+		q.closed.Store(true)
+
+		// 回收会重置节点的链接，遍历结束后再回收，避免中断遍历。
+		var nodes []*lst.Node
 		q.list.Range(func(value interface{}) bool {
-			q.elementpool.Put(value.(*lst.Node))
+			node := value.(*lst.Node)
+			if collect {
+				pending = append(pending, node.Value)
+			}
+			q.metrics.discardedLocked()
+			nodes = append(nodes, node)
 			return true
 		})
 
 		q.list.Cleanup()
+		for _, node := range nodes {
+			q.elementpool.Put(node)
+		}
 
 		if q.config.idempotent {
+			// 处理期间收到的更新尚未重新入队，同样视为未投递。
+			if collect {
+				for _, value := range q.deferred {
+					pending = append(pending, value)
+				}
+			}
 			q.processing.Cleanup()
 			q.dirty.Cleanup()
+			q.deferred = make(map[interface{}]interface{})
+			q.deferredAttach = make(map[interface{}]func(node *lst.Node))
 		}
 
+		// 日志中的记录保持未确认，下次打开时重新投递。
+		if q.journal != nil {
+			q.journal.resetLocked()
+		}
+
+		q.inflight = 0
+		q.retained = 0
+		q.metrics.resetLocked()
+		q.metrics.close()
+
+		// 唤醒所有阻塞中的消费者，使其观察到关闭状态后返回。
+		q.broadcastLocked()
+
 		q.lock.Unlock()
 	})
+
+	return pending
+}
+
+// drain 进入排空状态并等待队列为空、处理中元素全部 Done，且 idle 报告外层容器也已清空。
+// 排空期间任何状态变化都会广播，idle 检查前后唤醒通道未变即可确认没有遗漏的元素。
+func (q *queueImpl) drain(ctx context.Context, idle func() bool) error {
+
+	q.lock.Lock()
+	if q.IsClosed() {
+		q.lock.Unlock()
+		return ErrQueueIsClosed
+	}
+	q.draining.Store(true)
+	q.lock.Unlock()
+
+	for {
+		q.lock.Lock()
+		if q.IsClosed() {
+			q.lock.Unlock()
+			return ErrQueueIsClosed
+		}
+		busy := q.list.Len() > 0 || q.inflightLocked() > 0
+		wait := q.waitLocked()
+		q.lock.Unlock()
+
+		if !busy && (idle == nil || idle()) {
+			select {
+			case <-wait:
+				continue
+			default:
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wait:
+		}
+	}
 }
 
 func (q *queueImpl) IsClosed() bool {
@@ -99,6 +217,35 @@ func (q *queueImpl) Range(fn func(interface{}) bool) {
 }
 
 func (q *queueImpl) Put(value interface{}) error {
+	return q.put(value, putExternal)
+}
+
+// requeue 供内部组件把已出队的元素放回队列，排空期间仍然允许。
+func (q *queueImpl) requeue(value interface{}) error {
+	return q.put(value, putRequeue)
+}
+
+// transfer 供内部组件搬运已写入日志的元素（例如到期的延迟元素），排空期间仍然允许。
+func (q *queueImpl) transfer(value interface{}) error {
+	return q.put(value, putTransfer)
+}
+
+// putMode 区分元素来源，决定排空期间是否接收以及是否需要写入日志。
+type putMode uint8
+
+const (
+	putExternal putMode = iota
+	putRequeue
+	putTransfer
+)
+
+func (q *queueImpl) put(value interface{}, mode putMode) error {
+	return q.putAttached(value, mode, nil)
+}
+
+// putAttached 与 put 相同，attach 非空时在队列锁内、节点挂接前调用，供外层队列向容器登记节点的附加信息。
+// 幂等模式下元素处理中被合并时，attach 随最新值保留到 Done 重新入队时调用。
+func (q *queueImpl) putAttached(value interface{}, mode putMode, attach func(node *lst.Node)) error {
 
 	if q.IsClosed() {
 		return ErrQueueIsClosed
@@ -108,27 +255,71 @@ func (q *queueImpl) Put(value interface{}) error {
 		return ErrElementIsNil
 	}
 
-	if q.config.idempotent {
-		// 幂等模式先判重再分配节点，减少重复入队时的对象池开销。
-		q.lock.Lock()
-		if q.dirty.Contains(value) || q.processing.Contains(value) {
-			q.lock.Unlock()
-			return ErrElementAlreadyExist
+	var key interface{}
+	if q.config.idempotent || q.journal != nil {
+		var err error
+		if key, err = q.keyOf(value); err != nil {
+			return err
 		}
-		last := q.elementpool.Get()
-		last.Value = value
-		q.list.Push(last)
-		q.dirty.Add(value)
-		q.lock.Unlock()
-	} else {
-		// 非幂等模式在锁外申请节点，缩短临界区。
-		last := q.elementpool.Get()
+	}
+
+	// 持久化模式先写日志再入队，入队失败时追加确认记录撤销。
+	var id uint64
+	logged := q.journal != nil && mode != putTransfer
+	if logged {
+		var err error
+		if id, err = q.journal.append(value, 0); err != nil {
+			return err
+		}
+	}
+
+	// 非幂等模式在锁外申请节点，缩短临界区；幂等模式先判重再分配节点，减少重复入队时的对象池开销。
+	var last *lst.Node
+	if !q.config.idempotent {
+		last = q.elementpool.Get()
 		last.Value = value
-		q.lock.Lock()
-		q.list.Push(last)
-		q.lock.Unlock()
 	}
 
+	q.lock.Lock()
+	err := q.acceptLocked(mode != putExternal)
+	if err == nil {
+		if q.config.idempotent {
+			err = q.putIdempotentLocked(value, key, attach)
+		} else {
+			if attach != nil {
+				attach(last)
+			}
+			q.pushLocked(last)
+			q.broadcastLocked()
+			last = nil
+		}
+	}
+
+	var rollback uint64
+	if q.journal != nil {
+		switch {
+		case logged && err == nil:
+			q.journal.commitLocked(key, id)
+		case logged:
+			rollback = id
+		case errors.Is(err, ErrElementAlreadyExist):
+			// 搬运的元素被合并时，其日志记录随之确认。
+			rollback, _ = q.journal.dropLocked(key)
+		}
+	}
+	q.lock.Unlock()
+
+	if last != nil {
+		q.elementpool.Put(last)
+	}
+	if rollback > 0 {
+		q.journal.ack(rollback)
+	}
+	if err != nil {
+		return err
+	}
+
+	q.metrics.added()
 	q.config.callback.OnPut(value)
 	return nil
 }
@@ -146,20 +337,217 @@ func (q *queueImpl) Get() (interface{}, error) {
 		return nil, ErrQueueIsEmpty
 	}
 
-	front := q.list.Pop().(*lst.Node)
-	value := front.Value
+	value := q.popLocked()
+	q.lock.Unlock()
 
-	if q.config.idempotent {
-		q.processing.Add(value)
-		q.dirty.Remove(value)
+	q.config.callback.OnGet(value)
+
+	return value, nil
+}
+
+func (q *queueImpl) GetWithContext(ctx context.Context) (interface{}, error) {
+
+	for {
+		q.lock.Lock()
+
+		// 关闭状态需在锁内判断，避免与 Shutdown 的广播交错导致永久阻塞。
+		if q.IsClosed() {
+			q.lock.Unlock()
+			return nil, ErrQueueIsClosed
+		}
+
+		if q.list.Len() > 0 {
+			value := q.popLocked()
+			q.lock.Unlock()
+
+			q.config.callback.OnGet(value)
+			return value, nil
+		}
+
+		wait := q.waitLocked()
+		q.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-wait:
+		}
+	}
+}
+
+func (q *queueImpl) GetBlocking() (interface{}, error) {
+	return q.GetWithContext(context.Background())
+}
+
+func (q *queueImpl) PutBatch(values []interface{}) error {
+
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(values))
+
+	// key 在锁外计算，反射判定不占用临界区。
+	var keys []interface{}
+	if q.config.idempotent || q.journal != nil {
+		keys = make([]interface{}, len(values))
+		for i, value := range values {
+			if value == nil {
+				continue
+			}
+			keys[i], errs[i] = q.keyOf(value)
+		}
+	}
+
+	// 持久化模式批量写入日志，只刷盘一次。
+	var ids []uint64
+	if q.journal != nil {
+		ids = q.appendBatch(values, errs)
+	}
+
+	var rollback []uint64
+	q.lock.Lock()
+	if err := q.acceptLocked(false); err != nil {
+		q.lock.Unlock()
+		for _, id := range ids {
+			if id > 0 {
+				q.journal.ack(id)
+			}
+		}
+		return err
 	}
+	for i, value := range values {
+		if errs[i] != nil {
+			continue
+		}
+		if value == nil {
+			errs[i] = ErrElementIsNil
+			continue
+		}
+
+		if q.config.idempotent {
+			errs[i] = q.putIdempotentLocked(value, keys[i], nil)
+		} else {
+			last := q.elementpool.Get()
+			last.Value = value
+			q.pushLocked(last)
+		}
+
+		if ids != nil {
+			if errs[i] == nil {
+				q.journal.commitLocked(keys[i], ids[i])
+			} else {
+				rollback = append(rollback, ids[i])
+			}
+		}
+	}
+	q.broadcastLocked()
 	q.lock.Unlock()
 
-	q.elementpool.Put(front)
+	for _, id := range rollback {
+		q.journal.ack(id)
+	}
 
-	q.config.callback.OnGet(value)
+	for i, value := range values {
+		if errs[i] == nil {
+			q.metrics.added()
+			q.config.callback.OnPut(value)
+		}
+	}
 
-	return value, nil
+	return newBatchError(errs)
+}
+
+// appendBatch 将尚未出错的元素批量写入日志，返回与 values 下标对应的记录编号，写入失败时记录到 errs。
+func (q *queueImpl) appendBatch(values []interface{}, errs []error) []uint64 {
+	ids := make([]uint64, len(values))
+	index := make([]int, 0, len(values))
+	pending := make([]interface{}, 0, len(values))
+	for i, value := range values {
+		if value != nil && errs[i] == nil {
+			index = append(index, i)
+			pending = append(pending, value)
+		}
+	}
+	if len(pending) == 0 {
+		return ids
+	}
+
+	appended, err := q.journal.appendBatch(pending)
+	for j, i := range index {
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		ids[i] = appended[j]
+	}
+	return ids
+}
+
+func (q *queueImpl) GetBatch(max int) ([]interface{}, error) {
+
+	if q.IsClosed() {
+		return nil, ErrQueueIsClosed
+	}
+
+	if max <= 0 {
+		return nil, ErrInvalidBatchSize
+	}
+
+	q.lock.Lock()
+
+	if q.list.Len() == 0 {
+		q.lock.Unlock()
+		return nil, ErrQueueIsEmpty
+	}
+
+	values := q.popBatchLocked(max)
+	q.lock.Unlock()
+
+	for _, value := range values {
+		q.config.callback.OnGet(value)
+	}
+
+	return values, nil
+}
+
+func (q *queueImpl) GetBatchWithContext(ctx context.Context, max int) ([]interface{}, error) {
+
+	if max <= 0 {
+		return nil, ErrInvalidBatchSize
+	}
+
+	for {
+		q.lock.Lock()
+
+		if q.IsClosed() {
+			q.lock.Unlock()
+			return nil, ErrQueueIsClosed
+		}
+
+		if q.list.Len() > 0 {
+			values := q.popBatchLocked(max)
+			q.lock.Unlock()
+
+			for _, value := range values {
+				q.config.callback.OnGet(value)
+			}
+			return values, nil
+		}
+
+		wait := q.waitLocked()
+		q.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-wait:
+		}
+	}
 }
 
 func (q *queueImpl) Done(value interface{}) {
@@ -168,17 +556,568 @@ func (q *queueImpl) Done(value interface{}) {
 		return
 	}
 
-	if q.config.idempotent {
+	var id uint64
+
+	if !q.config.idempotent {
+		// 非幂等模式下处理中数量只是计数，无法区分 value 是否真的出队过，减到零为止。
+		// 持久化队列按 key 确认日志记录，非持久化队列不需要计算 key。
+		var key interface{}
+		var err error
+		if q.journal != nil {
+			key, err = q.keyOf(value)
+		}
+
 		q.lock.Lock()
+		if q.inflight > 0 {
+			q.inflight--
+			if q.journal != nil && err == nil {
+				id = q.releaseLocked(key)
+			}
+			q.wakeDrainLocked()
+		}
+		q.lock.Unlock()
 
-		if !q.processing.Contains(value) {
-			q.lock.Unlock()
+		if id > 0 {
+			q.journal.ack(id)
+		}
+		return
+	}
+
+	key, err := q.keyOf(value)
+	if err != nil {
+		return
+	}
+
+	q.lock.Lock()
+
+	if !q.processing.Contains(key) {
+		q.lock.Unlock()
+		return
+	}
+
+	q.processing.Remove(key)
+	q.metrics.doneLocked(key, q.config.clock.Now().UnixNano())
+	id = q.releaseLocked(key)
+	if q.dirty.Contains(key) {
+		// 重新入队处理期间收到的最新值，未配置 key 函数时即为原值。
+		last := q.elementpool.Get()
+		last.Value = value
+		if latest, ok := q.deferred[key]; ok {
+			last.Value = latest
+			delete(q.deferred, key)
+		}
+		if attach, ok := q.deferredAttach[key]; ok {
+			attach(last)
+			delete(q.deferredAttach, key)
+		}
+		q.pushLocked(last)
+		q.broadcastLocked()
+	}
+	q.wakeDrainLocked()
+	q.lock.Unlock()
+
+	if id > 0 {
+		q.journal.ack(id)
+	}
+
+	q.config.callback.OnDone(value)
+}
+
+// Stats 返回队列指标快照。
+func (q *queueImpl) Stats() QueueStats {
+	q.lock.Lock()
+	stats := q.metrics.snapshotLocked(q.config.clock.Now().UnixNano())
+	stats.Depth = int(q.list.Len())
+	stats.InFlight = q.inflightLocked()
+	q.lock.Unlock()
+	return stats
+}
+
+// updateRunningMetrics 周期刷新处理中耗时类指标，直至队列关闭。
+func (q *queueImpl) updateRunningMetrics() {
+	ticker := time.NewTicker(metricsUpdatePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.metrics.stop:
 			return
+		case <-ticker.C:
+			q.lock.Lock()
+			unfinished, longest := q.metrics.runningLocked(q.config.clock.Now().UnixNano())
+			q.lock.Unlock()
+
+			q.metrics.unfinishedWork.Set(unfinished.Seconds())
+			q.metrics.longestRunning.Set(longest.Seconds())
+		}
+	}
+}
+
+// retain 登记一个暂时不在任何容器中的元素（例如正在重新入队），排空会等待其 release。
+func (q *queueImpl) retain() {
+	q.lock.Lock()
+	q.retained++
+	q.wakeDrainLocked()
+	q.lock.Unlock()
+}
+
+// release 撤销 retain 的登记。
+func (q *queueImpl) release() {
+	q.lock.Lock()
+	if q.retained > 0 {
+		q.retained--
+	}
+	q.wakeDrainLocked()
+	q.lock.Unlock()
+}
+
+// wakeDrain 通知排空方外层容器发生了变化。
+func (q *queueImpl) wakeDrain() {
+	q.lock.Lock()
+	q.wakeDrainLocked()
+	q.lock.Unlock()
+}
+
+// isDraining 报告队列是否处于排空状态。
+func (q *queueImpl) isDraining() bool {
+	return q.draining.Load()
+}
+
+// replay 将日志中尚未确认的记录恢复到队列，仅在创建队列时调用。
+// schedule 非空时由其接管尚未到期的记录；无法解码或无法生成 key 的记录保留在日志中。
+// 幂等模式下同一 key 只恢复最新的记录，其余记录直接确认。
+func (q *queueImpl) replay(schedule func(value interface{}, due int64)) {
+	type restored struct {
+		id    uint64
+		due   int64
+		value interface{}
+		key   interface{}
+	}
+
+	now := q.config.clock.Now().UnixMilli()
+	records := q.journal.wal.log.Records()
+	items := make([]restored, 0, len(records))
+	latest := make(map[interface{}]uint64)
+	for _, record := range records {
+		value, err := q.journal.decode(record.Payload)
+		if err != nil || value == nil {
+			continue
+		}
+		key, err := q.keyOf(value)
+		if err != nil {
+			continue
+		}
+		items = append(items, restored{id: record.ID, due: record.Due, value: value, key: key})
+		if schedule == nil || record.Due <= now {
+			latest[key] = record.ID
+		}
+	}
+
+	var rollback []uint64
+	for _, item := range items {
+		if schedule != nil && item.due > now {
+			schedule(item.value, item.due)
+			q.lock.Lock()
+			q.journal.commitLocked(item.key, item.id)
+			q.lock.Unlock()
+			continue
+		}
+
+		q.lock.Lock()
+		if q.config.idempotent && (latest[item.key] != item.id || q.putIdempotentLocked(item.value, item.key, nil) != nil) {
+			q.lock.Unlock()
+			rollback = append(rollback, item.id)
+			continue
+		}
+		if !q.config.idempotent {
+			last := q.elementpool.Get()
+			last.Value = item.value
+			q.pushLocked(last)
+		}
+		q.journal.commitLocked(item.key, item.id)
+		q.lock.Unlock()
+
+		q.metrics.added()
+	}
+
+	for _, id := range rollback {
+		q.journal.ack(id)
+	}
+}
+
+// commit 登记已写入日志的元素，供外层容器在接收元素时调用。
+func (q *queueImpl) commit(key interface{}, id uint64) {
+	q.lock.Lock()
+	q.journal.commitLocked(key, id)
+	q.lock.Unlock()
+}
+
+// releaseLocked 取出 key 待确认的日志记录编号，未开启持久化时返回 0，调用方需持有队列锁。
+func (q *queueImpl) releaseLocked(key interface{}) uint64 {
+	if q.journal == nil {
+		return 0
+	}
+	id, _ := q.journal.releaseLocked(key)
+	return id
+}
+
+// remove 移除第一个满足 match 的就绪元素，同时维护幂等集合与日志，返回是否找到。
+func (q *queueImpl) remove(match func(value interface{}) bool) bool {
+	return q.removeWith(func() []*lst.Node {
+		var target *lst.Node
+		q.list.Range(func(value interface{}) bool {
+			node := value.(*lst.Node)
+			if match(node.Value) {
+				target = node
+				return false
+			}
+			return true
+		})
+		if target == nil {
+			return nil
+		}
+		return []*lst.Node{target}
+	}) > 0
+}
+
+// purgeBatch 为 purge 单次加锁移除的元素数量上限，批次之间释放锁以免长时间阻塞生产与消费。
+const purgeBatch = 256
+
+// purge 按出队顺序移除至多 max 个就绪元素并返回移除的数量。元素经 removeWith 移除，
+// 不触发 OnGet、OnDone 等消费端回调，也不计入出队与处理耗时指标。
+func (q *queueImpl) purge(max int) int {
+	purged := 0
+	for purged < max {
+		limit := max - purged
+		if limit > purgeBatch {
+			limit = purgeBatch
+		}
+		n := q.removeWith(func() []*lst.Node {
+			nodes := make([]*lst.Node, 0, limit)
+			q.list.Range(func(value interface{}) bool {
+				nodes = append(nodes, value.(*lst.Node))
+				return len(nodes) < limit
+			})
+			return nodes
+		})
+		if n == 0 {
+			break
+		}
+		purged += n
+	}
+	return purged
+}
+
+// Purge 丢弃队列中当前的全部就绪元素并返回丢弃的数量，延迟、定时与处理中的元素不受影响。
+// 元素直接从容器中移除，不触发消费端回调与死信确认，数量以开始时的队列长度为上限，
+// 清空期间持续入队的新元素不会使其无法结束。只支持本包创建的队列，其他实现返回 ErrUnsupportedQueue。
+func Purge(queue Queue) (int, error) {
+	if queue == nil {
+		return 0, ErrQueueIsNil
+	}
+	return purgeAtMost(queue, -1)
+}
+
+// purgeAtMost 丢弃至多 max 个就绪元素，max 小于 0 时以当前长度为上限。
+func purgeAtMost(queue Queue, max int) (int, error) {
+	switch q := queue.(type) {
+	case *boundedBlockingQueueImpl:
+		return q.purge(), nil
+	case *boundedFairQueueImpl:
+		return q.boundedBlockingQueueImpl.purge(), nil
+	}
+
+	base, ok := baseOf(queue)
+	if !ok {
+		return 0, ErrUnsupportedQueue
+	}
+	if n := base.Len(); max < 0 || max > n {
+		max = n
+	}
+	return base.purge(max), nil
+}
+
+// Requeue 结束已出队元素的处理并立即放回队列，用于消费者放弃处理（例如无法投递）的场景。
+// 与先 Done 再 Put 不同，两步之间元素仍登记为处理中，排空期间同样允许放回，元素不会因排空结束而丢失。
+// 幂等模式下处理期间已有新的入队请求时与之合并。只支持本包创建的队列，其他实现返回 ErrUnsupportedQueue。
+func Requeue(queue Queue, value interface{}) error {
+	if queue == nil {
+		return ErrQueueIsNil
+	}
+	if value == nil {
+		return ErrElementIsNil
+	}
+
+	switch q := queue.(type) {
+	case *boundedBlockingQueueImpl:
+		return q.requeue(value)
+	case *boundedFairQueueImpl:
+		return q.boundedBlockingQueueImpl.requeue(value)
+	}
+
+	base, ok := baseOf(queue)
+	if !ok {
+		return ErrUnsupportedQueue
+	}
+
+	base.retain()
+	defer base.release()
+
+	// 死信放回不是确认：只撤销取出登记，不调用 AckDead 及其回调。
+	if dlq, ok := queue.(*deadLetterQueueImpl); ok {
+		dlq.untake(value)
+	} else {
+		queue.Done(value)
+	}
+	if err := base.requeue(value); err != nil && !errors.Is(err, ErrElementAlreadyExist) {
+		return err
+	}
+	return nil
+}
+
+// baseOf 沿包装关系找到承载就绪元素的基础队列。有界队列的容量需要单独维护，不在此展开。
+func baseOf(queue Queue) (*queueImpl, bool) {
+	for {
+		switch q := queue.(type) {
+		case *queueImpl:
+			return q, true
+		case *delayingQueueImpl:
+			queue = q.Queue
+		case *priorityQueueImpl:
+			queue = q.Queue
+		case *fairQueueImpl:
+			queue = q.Queue
+		case *timerQueueImpl:
+			queue = q.Queue
+		case *leasedQueueImpl:
+			queue = q.Queue
+		case *deadLetterQueueImpl:
+			queue = q.Queue
+		case *ratelimitingQueueImpl:
+			queue = q.DelayingQueue
+		case *retryQueueImpl:
+			queue = q.DelayingQueue
+		default:
+			return nil, false
+		}
+	}
+}
+
+// removeWith 在队列锁内调用 find 查找就绪节点并逐个移除，同时维护幂等集合与日志，返回移除的数量。
+func (q *queueImpl) removeWith(find func() []*lst.Node) int {
+	var ids []uint64
+
+	q.lock.Lock()
+	targets := find()
+	for _, target := range targets {
+		q.list.Remove(target)
+		if q.config.idempotent || q.journal != nil {
+			if key, err := q.keyOf(target.Value); err == nil {
+				if q.config.idempotent {
+					q.dirty.Remove(key)
+				}
+				if id := q.releaseLocked(key); id > 0 {
+					ids = append(ids, id)
+				}
+			}
 		}
+		q.metrics.discardedLocked()
+	}
+	if len(targets) > 0 {
+		q.wakeDrainLocked()
+	}
+	q.lock.Unlock()
 
-		q.processing.Remove(value)
+	for _, target := range targets {
+		q.elementpool.Put(target)
+	}
+	for _, id := range ids {
+		q.journal.ack(id)
+	}
+	return len(targets)
+}
+
+// pushNodes 直接挂接已填充的节点并唤醒等待者，跳过幂等判重与 OnPut 回调。
+// 队列已关闭或正在排空时返回错误，节点归还由调用方负责。
+func (q *queueImpl) pushNodes(nodes ...*lst.Node) error {
+
+	q.lock.Lock()
+	if err := q.acceptLocked(false); err != nil {
 		q.lock.Unlock()
+		return err
+	}
+	for _, node := range nodes {
+		q.pushLocked(node)
+	}
+	q.broadcastLocked()
+	q.lock.Unlock()
+
+	for range nodes {
+		q.metrics.added()
+	}
+
+	return nil
+}
+
+// acceptLocked 判断队列当前能否接收元素：关闭后一律拒绝，排空期间仅允许内部重新入队。
+func (q *queueImpl) acceptLocked(requeue bool) error {
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+	if !requeue && q.draining.Load() {
+		return ErrQueueIsDraining
+	}
+	return nil
+}
+
+// putIdempotentLocked 按幂等语义登记并挂接元素，调用方需持有队列锁。
+func (q *queueImpl) putIdempotentLocked(value, key interface{}, attach func(node *lst.Node)) error {
+	if q.dirty.Contains(key) {
+		return ErrElementAlreadyExist
+	}
+	q.dirty.Add(key)
+
+	// 处理中的元素只标记为 dirty，待 Done 时再重新入队，保证更新不丢失且只合并一次。
+	if q.processing.Contains(key) {
+		q.deferred[key] = value
+		if attach != nil {
+			q.deferredAttach[key] = attach
+		}
+		return nil
+	}
 
-		q.config.callback.OnDone(value)
+	last := q.elementpool.Get()
+	last.Value = value
+	if attach != nil {
+		attach(last)
 	}
+	q.pushLocked(last)
+	q.broadcastLocked()
+	return nil
+}
+
+// pushLocked 将节点挂入就绪队列并记录入队时刻，调用方需持有队列锁。
+func (q *queueImpl) pushLocked(node *lst.Node) {
+	node.Timestamp = q.config.clock.Now().UnixNano()
+	q.list.Push(node)
+	q.metrics.pushedLocked()
+}
+
+// popLocked 弹出队首元素并维护幂等集合，调用方需持有队列锁。
+func (q *queueImpl) popLocked() interface{} {
+	front := q.list.Pop().(*lst.Node)
+	value := front.Value
+
+	now := q.config.clock.Now().UnixNano()
+	if !q.config.idempotent {
+		q.inflight++
+	} else if key, err := q.keyOf(value); err == nil {
+		// 经 pushNodes 挂接的节点未做校验，无法生成 key 时跳过幂等集合维护与处理计时。
+		q.processing.Add(key)
+		q.dirty.Remove(key)
+		q.metrics.startedLocked(key, now)
+	}
+	q.metrics.poppedLocked(front.Timestamp, now)
+
+	q.elementpool.Put(front)
+	q.wakeDrainLocked()
+
+	return value
+}
+
+// popBatchLocked 最多弹出 max 个元素，调用方需持有队列锁。
+func (q *queueImpl) popBatchLocked(max int) []interface{} {
+	n := int(q.list.Len())
+	if n > max {
+		n = max
+	}
+
+	values := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		values = append(values, q.popLocked())
+	}
+	return values
+}
+
+// inflightLocked 返回已出队但尚未 Done 的元素数量，调用方需持有队列锁。
+func (q *queueImpl) inflightLocked() int {
+	if q.config.idempotent {
+		return q.processing.Len() + q.retained
+	}
+	return q.inflight + q.retained
+}
+
+// wakeDrainLocked 在排空期间广播状态变化，平时不打扰阻塞中的消费者，调用方需持有队列锁。
+func (q *queueImpl) wakeDrainLocked() {
+	if q.draining.Load() {
+		q.broadcastLocked()
+	}
+}
+
+// waitLocked 返回当前的唤醒通道，调用方需持有队列锁。
+func (q *queueImpl) waitLocked() <-chan struct{} {
+	if q.notify == nil {
+		q.notify = make(chan struct{})
+	}
+	return q.notify
+}
+
+// broadcastLocked 关闭当前唤醒通道以通知所有等待者，调用方需持有队列锁。
+func (q *queueImpl) broadcastLocked() {
+	if q.notify != nil {
+		close(q.notify)
+		q.notify = nil
+	}
+}
+
+// keyOf 返回幂等集合使用的 key：优先使用配置的 key 函数，否则要求值本身可哈希。
+func (q *queueImpl) keyOf(value interface{}) (interface{}, error) {
+	if q.config.keyFunc != nil {
+		return q.config.keyFunc(value), nil
+	}
+	if !isHashable(value) {
+		return nil, ErrElementNotHashable
+	}
+	return value, nil
+}
+
+// isHashable 判断值能否作为 map key 使用，避免集合操作触发运行时 panic。
+func isHashable(value interface{}) bool {
+	switch value.(type) {
+	case string, bool, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, uintptr,
+		float32, float64, complex64, complex128:
+		return true
+	}
+
+	return isHashableValue(reflect.ValueOf(value))
+}
+
+func isHashableValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	if !v.Type().Comparable() {
+		return false
+	}
+
+	// 可比较类型中只有接口字段可能在运行时持有不可哈希的动态值。
+	switch v.Kind() {
+	case reflect.Interface:
+		return v.IsNil() || isHashableValue(v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !isHashableValue(v.Index(i)) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !isHashableValue(v.Field(i)) {
+				return false
+			}
+		}
+	}
+
+	return true
 }
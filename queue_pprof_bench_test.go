@@ -156,3 +156,53 @@ func BenchmarkTimerQueue_Cancel(b *testing.B) {
 		}
 	}
 }
+
+// benchmarkTimerQueueCancelLoaded 在已有 10 万个定时元素的队列上测量插入并取消一个元素的开销。
+func benchmarkTimerQueueCancelLoaded(b *testing.B, config *TimerQueueConfig) {
+	q := NewTimerQueue(config)
+	b.Cleanup(q.Shutdown)
+
+	for i := 0; i < 100000; i++ {
+		if err := q.PutAfter(-i-1, time.Hour+time.Duration(i)*time.Millisecond); err != nil {
+			b.Fatalf("put after failed: %v", err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := q.PutAfter(i, time.Minute); err != nil {
+			b.Fatalf("put after failed: %v", err)
+		}
+		if !q.Cancel(i) {
+			b.Fatalf("cancel failed at %d", i)
+		}
+	}
+}
+
+func BenchmarkTimerQueue_CancelLoaded(b *testing.B) {
+	benchmarkTimerQueueCancelLoaded(b, nil)
+}
+
+func BenchmarkTimerQueue_CancelLoadedTimingWheel(b *testing.B) {
+	benchmarkTimerQueueCancelLoaded(b, NewTimerQueueConfig().WithTimingWheel(time.Millisecond))
+}
+
+func BenchmarkTimerQueue_CancelByID(b *testing.B) {
+	q := NewTimerQueue(nil)
+	b.Cleanup(q.Shutdown)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		id, err := q.PutAtWithID(i, time.Now().Add(time.Second))
+		if err != nil {
+			b.Fatalf("put at failed: %v", err)
+		}
+		if !q.CancelByID(id) {
+			b.Fatalf("cancel failed at %d", i)
+		}
+	}
+}
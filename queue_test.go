@@ -1,6 +1,7 @@
 package workqueue
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"sync/atomic"
@@ -485,6 +486,31 @@ func TestQueueImpl_DuplicateDone(t *testing.T) {
 	assert.Equal(t, 0, len(callback.dones), "Done callback should only be called once")
 }
 
+func TestQueueImpl_DoneWithoutGet(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	q.Done("never")
+	assert.Equal(t, 0, q.Stats().InFlight, "Done of a value never taken should not go negative")
+
+	assert.NoError(t, q.Put("test"))
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, q.Stats().InFlight)
+
+	q.Done(v)
+	q.Done(v)
+	assert.Equal(t, 0, q.Stats().InFlight, "Duplicate Done should clamp at zero")
+
+	// 内部重新入队登记的元素单独计数，多余的 Done 不会提前结束排空。
+	base := q.(*queueImpl)
+	base.retain()
+	q.Done("never")
+	assert.Equal(t, 1, q.Stats().InFlight)
+	base.release()
+	assert.Equal(t, 0, q.Stats().InFlight)
+}
+
 func TestQueueImpl_Idempotent_DuplicateDone(t *testing.T) {
 	callback := &testQueueCallback{}
 	config := NewQueueConfig().WithCallback(callback).WithValueIdempotent()
@@ -541,3 +567,505 @@ func TestQueueImpl_RangeEarlyExit(t *testing.T) {
 	assert.Equal(t, 5, count, "Range should have processed exactly 5 items")
 	assert.Equal(t, 10, q.Len(), "Queue length should remain unchanged")
 }
+
+func TestQueueImpl_GetWithContext_WaitsForPut(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	result := make(chan interface{}, 1)
+	go func() {
+		v, err := q.GetWithContext(context.Background())
+		assert.NoError(t, err, "GetWithContext should not return an error")
+		result <- v
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, q.Put("test1"))
+
+	select {
+	case v := <-result:
+		assert.Equal(t, "test1", v, "GetWithContext value should be test1")
+	case <-time.After(time.Second):
+		t.Fatal("GetWithContext should be woken by Put")
+	}
+}
+
+func TestQueueImpl_GetWithContext_Canceled(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	v, err := q.GetWithContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "GetWithContext should return context.DeadlineExceeded")
+	assert.Nil(t, v, "GetWithContext value should be nil")
+}
+
+func TestQueueImpl_GetBlocking_ShutdownWakeup(t *testing.T) {
+	q := NewQueue(nil)
+
+	count := 10
+	result := make(chan error, count)
+	for i := 0; i < count; i++ {
+		go func() {
+			_, err := q.GetBlocking()
+			result <- err
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	q.Shutdown()
+
+	for i := 0; i < count; i++ {
+		select {
+		case err := <-result:
+			assert.ErrorIs(t, err, ErrQueueIsClosed, "GetBlocking should return ErrQueueIsClosed")
+		case <-time.After(time.Second):
+			t.Fatal("GetBlocking should be released by Shutdown")
+		}
+	}
+}
+
+func TestQueueImpl_GetBlocking_Parallel(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	count := 1000
+	var received atomic.Int64
+
+	wg := sync.WaitGroup{}
+	wg.Add(count)
+	for i := 0; i < count; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := q.GetBlocking()
+			assert.NoError(t, err, "GetBlocking should not return an error")
+			received.Add(1)
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		assert.NoError(t, q.Put(i))
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(count), received.Load(), "All consumers should receive a value")
+	assert.Equal(t, 0, q.Len(), "Queue length should be 0")
+}
+
+func TestQueueImpl_Idempotent_PutWhileProcessing(t *testing.T) {
+	callback := &testQueueCallback{}
+	config := NewQueueConfig().WithCallback(callback).WithValueIdempotent()
+	q := NewQueue(config)
+	defer q.Shutdown()
+
+	err := q.Put("test1")
+	assert.NoError(t, err, "Put should not return an error")
+
+	v, err := q.Get()
+	assert.NoError(t, err, "Get should not return an error")
+
+	err = q.Put("test1")
+	assert.NoError(t, err, "Put of a processing value should be accepted and marked dirty")
+	err = q.Put("test1")
+	assert.ErrorIs(t, err, ErrElementAlreadyExist, "Repeated Put should coalesce into the pending dirty mark")
+	assert.Equal(t, 0, q.Len(), "Dirty processing value should not be queued before Done")
+
+	q.Done(v)
+	assert.Equal(t, 1, q.Len(), "Done should re-enqueue the dirty value exactly once")
+
+	v, err = q.Get()
+	assert.NoError(t, err, "Get should not return an error")
+	assert.Equal(t, "test1", v, "Get value should be test1")
+
+	q.Done(v)
+	assert.Equal(t, 0, q.Len(), "Done without a dirty mark should not re-enqueue")
+	assert.Equal(t, []interface{}{"test1", "test1"}, callback.puts, "Callback puts should be [test1, test1]")
+	assert.Equal(t, []interface{}{"test1", "test1"}, callback.dones, "Callback dones should be [test1, test1]")
+}
+
+func TestQueueImpl_Idempotent_PutWhileProcessing_WakesWaiter(t *testing.T) {
+	q := NewQueue(NewQueueConfig().WithValueIdempotent())
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("test1"))
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, q.Put("test1"))
+
+	result := make(chan interface{}, 1)
+	go func() {
+		v, err := q.GetBlocking()
+		assert.NoError(t, err)
+		result <- v
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	q.Done(v)
+
+	select {
+	case v := <-result:
+		assert.Equal(t, "test1", v)
+	case <-time.After(time.Second):
+		t.Fatal("Done should wake consumers when re-enqueueing a dirty value")
+	}
+}
+
+type testKeyedJob struct {
+	ID      string
+	Payload []byte
+}
+
+type testWrappedValue struct {
+	Value interface{}
+}
+
+func TestQueueImpl_Idempotent_UnhashableValue(t *testing.T) {
+	q := NewQueue(NewQueueConfig().WithValueIdempotent())
+	defer q.Shutdown()
+
+	err := q.Put([]int{1, 2, 3})
+	assert.ErrorIs(t, err, ErrElementNotHashable, "Put should reject a slice")
+	err = q.Put(map[string]int{"a": 1})
+	assert.ErrorIs(t, err, ErrElementNotHashable, "Put should reject a map")
+	err = q.Put(testKeyedJob{ID: "job"})
+	assert.ErrorIs(t, err, ErrElementNotHashable, "Put should reject a struct containing a slice")
+	err = q.Put(testWrappedValue{Value: []int{1}})
+	assert.ErrorIs(t, err, ErrElementNotHashable, "Put should reject an interface field holding a slice")
+
+	err = q.Put(testWrappedValue{Value: "ok"})
+	assert.NoError(t, err, "Put should accept an interface field holding a hashable value")
+	assert.Equal(t, 1, q.Len(), "Queue length should be 1")
+
+	q.Done([]int{1, 2, 3})
+}
+
+func TestQueueImpl_UnhashableValue_NonIdempotent(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	err := q.Put([]int{1, 2, 3})
+	assert.NoError(t, err, "Non-idempotent Put should accept unhashable values")
+}
+
+func TestQueueImpl_Idempotent_KeyFunc(t *testing.T) {
+	config := NewQueueConfig().
+		WithValueIdempotent().
+		WithKeyFunc(func(value interface{}) string { return value.(*testKeyedJob).ID })
+	q := NewQueue(config)
+	defer q.Shutdown()
+
+	err := q.Put(&testKeyedJob{ID: "job-1", Payload: []byte("v1")})
+	assert.NoError(t, err, "Put should not return an error")
+	err = q.Put(&testKeyedJob{ID: "job-1", Payload: []byte("v2")})
+	assert.ErrorIs(t, err, ErrElementAlreadyExist, "Pointers with the same key should be deduplicated")
+
+	v, err := q.Get()
+	assert.NoError(t, err, "Get should not return an error")
+
+	err = q.Put(&testKeyedJob{ID: "job-1", Payload: []byte("v3")})
+	assert.NoError(t, err, "Put of a processing key should be marked dirty")
+
+	q.Done(v)
+
+	v, err = q.Get()
+	assert.NoError(t, err, "Get should not return an error")
+	assert.Equal(t, []byte("v3"), v.(*testKeyedJob).Payload, "Done should re-enqueue the latest value for the key")
+}
+
+func TestQueueImpl_PutBatch(t *testing.T) {
+	callback := &testQueueCallback{}
+	q := NewQueue(NewQueueConfig().WithCallback(callback))
+	defer q.Shutdown()
+
+	err := q.PutBatch([]interface{}{"test1", "test2", "test3"})
+	assert.NoError(t, err, "PutBatch should not return an error")
+
+	assert.Equal(t, 3, q.Len(), "Queue length should be 3")
+	assert.Equal(t, []interface{}{"test1", "test2", "test3"}, q.Values(), "Queue values should be [test1, test2, test3]")
+	assert.Equal(t, []interface{}{"test1", "test2", "test3"}, callback.puts, "Callback puts should be [test1, test2, test3]")
+}
+
+func TestQueueImpl_PutBatch_PartialFailure(t *testing.T) {
+	q := NewQueue(NewQueueConfig().WithValueIdempotent())
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("exist"))
+
+	err := q.PutBatch([]interface{}{"test1", nil, "exist", []int{1}, "test1", "test2"})
+	assert.Error(t, err, "PutBatch should report failed elements")
+
+	var batchErr *BatchError
+	assert.True(t, errors.As(err, &batchErr), "PutBatch should return *BatchError")
+	assert.Nil(t, batchErr.Errors[0])
+	assert.ErrorIs(t, batchErr.Errors[1], ErrElementIsNil)
+	assert.ErrorIs(t, batchErr.Errors[2], ErrElementAlreadyExist)
+	assert.ErrorIs(t, batchErr.Errors[3], ErrElementNotHashable)
+	assert.ErrorIs(t, batchErr.Errors[4], ErrElementAlreadyExist, "Duplicates within one batch should be deduplicated")
+	assert.Nil(t, batchErr.Errors[5])
+	assert.ErrorIs(t, err, ErrElementAlreadyExist, "errors.Is should match any element failure")
+
+	assert.Equal(t, []interface{}{"exist", "test1", "test2"}, q.Values(), "Queue values should be [exist, test1, test2]")
+}
+
+func TestQueueImpl_PutBatch_Closed(t *testing.T) {
+	q := NewQueue(nil)
+	q.Shutdown()
+
+	err := q.PutBatch([]interface{}{"test1"})
+	assert.ErrorIs(t, err, ErrQueueIsClosed, "PutBatch should return ErrQueueIsClosed")
+}
+
+func TestQueueImpl_GetBatch(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, q.Put(i))
+	}
+
+	values, err := q.GetBatch(3)
+	assert.NoError(t, err, "GetBatch should not return an error")
+	assert.Equal(t, []interface{}{0, 1, 2}, values, "GetBatch values should be [0, 1, 2]")
+
+	values, err = q.GetBatch(10)
+	assert.NoError(t, err, "GetBatch should not return an error")
+	assert.Equal(t, []interface{}{3, 4}, values, "GetBatch values should be [3, 4]")
+
+	_, err = q.GetBatch(10)
+	assert.ErrorIs(t, err, ErrQueueIsEmpty, "GetBatch should return ErrQueueIsEmpty")
+
+	_, err = q.GetBatch(0)
+	assert.ErrorIs(t, err, ErrInvalidBatchSize, "GetBatch should return ErrInvalidBatchSize")
+}
+
+func TestQueueImpl_GetBatchWithContext(t *testing.T) {
+	q := NewQueue(NewQueueConfig().WithValueIdempotent())
+	defer q.Shutdown()
+
+	result := make(chan []interface{}, 1)
+	go func() {
+		values, err := q.GetBatchWithContext(context.Background(), 10)
+		assert.NoError(t, err, "GetBatchWithContext should not return an error")
+		result <- values
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, q.PutBatch([]interface{}{"test1", "test2"}))
+
+	select {
+	case values := <-result:
+		assert.Equal(t, []interface{}{"test1", "test2"}, values, "GetBatchWithContext values should be [test1, test2]")
+		for _, v := range values {
+			q.Done(v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetBatchWithContext should be woken by PutBatch")
+	}
+
+	queue := q.(*queueImpl)
+	assert.Equal(t, 0, queue.processing.Len(), "Queue processing should be empty")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := q.GetBatchWithContext(ctx, 10)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "GetBatchWithContext should honor ctx")
+}
+
+func TestQueueImpl_ShutdownWithDrain(t *testing.T) {
+	q := NewQueue(nil)
+
+	assert.NoError(t, q.Put("test1"))
+	assert.NoError(t, q.Put("test2"))
+
+	inflight, err := q.Get()
+	assert.NoError(t, err, "Get should not return an error")
+
+	result := make(chan error, 1)
+	go func() {
+		pending, err := q.ShutdownWithDrain(context.Background())
+		assert.Empty(t, pending, "Drained queue should not report pending values")
+		result <- err
+	}()
+
+	assert.Eventually(t, func() bool {
+		return errors.Is(q.Put("test3"), ErrQueueIsDraining)
+	}, time.Second, time.Millisecond, "Put should be rejected while draining")
+	assert.False(t, q.IsClosed(), "Queue should stay open while draining")
+
+	v, err := q.GetWithContext(context.Background())
+	assert.NoError(t, err, "Consumers should keep receiving remaining values")
+	assert.Equal(t, "test2", v)
+	q.Done(v)
+
+	select {
+	case <-result:
+		t.Fatal("ShutdownWithDrain should wait for in-flight values")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	q.Done(inflight)
+
+	select {
+	case err := <-result:
+		assert.NoError(t, err, "ShutdownWithDrain should not return an error")
+	case <-time.After(time.Second):
+		t.Fatal("ShutdownWithDrain should return after the last Done")
+	}
+	assert.True(t, q.IsClosed(), "Queue should be closed after draining")
+}
+
+func TestQueueImpl_ShutdownWithDrain_Timeout(t *testing.T) {
+	q := NewQueue(NewQueueConfig().WithValueIdempotent())
+
+	assert.NoError(t, q.Put("test1"))
+	assert.NoError(t, q.Put("test2"))
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, q.Put(v), "Update of a processing value should be deferred")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	pending, err := q.ShutdownWithDrain(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "ShutdownWithDrain should honor ctx")
+	assert.ElementsMatch(t, []interface{}{"test1", "test2"}, pending, "Pending should contain queued and deferred values")
+	assert.True(t, q.IsClosed(), "Queue should be closed after ctx expiry")
+
+	_, err = q.ShutdownWithDrain(context.Background())
+	assert.ErrorIs(t, err, ErrQueueIsClosed, "ShutdownWithDrain on a closed queue should return ErrQueueIsClosed")
+}
+
+func TestQueueImpl_ShutdownWithDrain_TimeoutReturnsAllPending(t *testing.T) {
+	q := NewQueue(nil)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, q.Put(i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// 保留一个处理中的元素，使排空无法完成。
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, v)
+
+	pending, err := q.ShutdownWithDrain(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []interface{}{1, 2, 3, 4}, pending, "Every queued value should be returned")
+}
+
+func TestQueueImpl_ShutdownWithDrain_WakesConsumers(t *testing.T) {
+	q := NewQueue(nil)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := q.GetBlocking()
+		result <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	pending, err := q.ShutdownWithDrain(context.Background())
+	assert.NoError(t, err, "Empty queue should drain immediately")
+	assert.Nil(t, pending)
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, ErrQueueIsClosed, "Blocked consumers should observe the shutdown")
+	case <-time.After(time.Second):
+		t.Fatal("Blocked consumers should be woken after draining")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	callback := &testQueueCallback{}
+	q := NewQueue(NewQueueConfig().WithCallback(callback))
+	defer q.Shutdown()
+
+	for i := 0; i < purgeBatch*2+10; i++ {
+		assert.NoError(t, q.Put(i))
+	}
+	v, err := q.Get()
+	assert.NoError(t, err)
+
+	purged, err := Purge(q)
+	assert.NoError(t, err)
+	assert.Equal(t, purgeBatch*2+9, purged)
+	assert.Equal(t, 0, q.Len())
+	assert.Empty(t, callback.dones, "Purge should not fire consumer callbacks")
+	assert.Equal(t, []interface{}{0}, callback.gets)
+	assert.Equal(t, 1, q.Stats().InFlight, "Values held by consumers should be untouched")
+	q.Done(v)
+
+	_, err = Purge(nil)
+	assert.ErrorIs(t, err, ErrQueueIsNil)
+	_, err = Purge(struct{ Queue }{q})
+	assert.ErrorIs(t, err, ErrUnsupportedQueue)
+}
+
+func TestPurge_LiveProducer(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, q.Put(i))
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = q.Put(i)
+			}
+		}
+	}()
+
+	purged, err := Purge(q)
+	close(stop)
+	<-done
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, purged, 1000, "Purge should stop at the length seen when it started")
+	assert.Greater(t, purged, 0)
+}
+
+func TestRequeue_DuringDrain(t *testing.T) {
+	q := NewQueue(nil)
+
+	assert.NoError(t, q.Put("job"))
+	v, err := q.Get()
+	assert.NoError(t, err)
+
+	result := make(chan []interface{}, 1)
+	go func() {
+		pending, _ := q.ShutdownWithDrain(context.Background())
+		result <- pending
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// 先 Done 再 Put 会结束排空并丢失元素，Requeue 在两步之间保持处理中登记。
+	assert.NoError(t, Requeue(q, v))
+	assert.ErrorIs(t, q.Put("other"), ErrQueueIsDraining)
+	assert.False(t, q.IsClosed())
+
+	v, err = q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "job", v)
+	q.Done(v)
+
+	select {
+	case pending := <-result:
+		assert.Nil(t, pending)
+	case <-time.After(time.Second):
+		t.Fatal("Drain should finish once the requeued value is done")
+	}
+
+	assert.ErrorIs(t, Requeue(nil, v), ErrQueueIsNil)
+	assert.ErrorIs(t, Requeue(struct{ Queue }{q}, v), ErrUnsupportedQueue)
+}
@@ -1,6 +1,7 @@
 package workqueue
 
 import (
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -14,17 +15,44 @@ func (rl *nopRateLimiterImpl) When(interface{}) time.Duration { return 0 }
 func NewNopRateLimiterImpl() Limiter { return &nopRateLimiterImpl{} }
 
 type bucketRateLimiterImpl struct {
-	r *rate.Limiter
+	r     *rate.Limiter
+	clock atomic.Value
 }
 
 func (rl *bucketRateLimiterImpl) When(interface{}) time.Duration {
-	return rl.r.Reserve().Delay()
+	now := rl.clock.Load().(clockHolder).clock.Now()
+	return rl.r.ReserveN(now, 1).DelayFrom(now)
 }
 
-// NewBucketRateLimiterImpl 使用 token bucket 策略创建限流器。
+// clockHolder 包装时钟，使不同实现的时钟可以存入同一个 atomic.Value。
+// bound 为假表示时钟未显式指定，可以由限流队列换成队列配置的时钟。
+type clockHolder struct {
+	clock Clock
+	bound bool
+}
+
+// bindClock 在未显式指定时钟时改用 clock，供限流队列传入其配置的时钟。
+func (rl *bucketRateLimiterImpl) bindClock(clock Clock) {
+	if !rl.clock.Load().(clockHolder).bound {
+		rl.clock.Store(clockHolder{clock: clock, bound: true})
+	}
+}
+
+// NewBucketRateLimiterImpl 使用 token bucket 策略创建限流器。用于限流队列时令牌按队列配置的时钟补充，
+// 否则使用系统时钟。
 func NewBucketRateLimiterImpl(r float64, burst int64) Limiter {
+	rl := &bucketRateLimiterImpl{r: rate.NewLimiter(rate.Limit(r), int(burst))}
+	rl.clock.Store(clockHolder{clock: NewRealClock()})
+	return rl
+}
 
-	return &bucketRateLimiterImpl{
-		r: rate.NewLimiter(rate.Limit(r), int(burst)),
+// NewBucketRateLimiterImplWithClock 使用 token bucket 策略创建限流器，令牌按 clock 的时间补充。
+func NewBucketRateLimiterImplWithClock(r float64, burst int64, clock Clock) Limiter {
+	if clock == nil {
+		clock = NewRealClock()
 	}
+
+	rl := &bucketRateLimiterImpl{r: rate.NewLimiter(rate.Limit(r), int(burst))}
+	rl.clock.Store(clockHolder{clock: clock, bound: true})
+	return rl
 }
@@ -15,6 +15,11 @@ func NewRateLimitingQueue(config *RateLimitingQueueConfig) RateLimitingQueue {
 		config:        config,
 		DelayingQueue: NewDelayingQueue(&config.DelayingQueueConfig),
 	}
+
+	// 未显式指定时钟的限流器与队列共用时钟，FakeClock 推进时令牌同步补充。
+	if binder, ok := config.limiter.(interface{ bindClock(clock Clock) }); ok {
+		binder.bindClock(config.DelayingQueueConfig.QueueConfig.clock)
+	}
 	return q
 }
 
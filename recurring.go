@@ -0,0 +1,116 @@
+package workqueue
+
+import (
+	"time"
+
+	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
+	"github.com/shengyanli1982/workqueue/v2/internal/cron"
+)
+
+// recurringNextFunc 返回严格晚于 after 的下一次触发时间，没有时返回零值。
+type recurringNextFunc = func(after time.Time) time.Time
+
+// recurringImpl 为定时队列中的周期调度。任意时刻最多只有一个待触发的节点位于定时容器中，
+// 触发后再按 next 计算并挂入下一个节点。全部字段由所属定时队列的锁保护。
+type recurringImpl struct {
+	queue *timerQueueImpl
+	value interface{}
+	next  recurringNextFunc
+	endAt time.Time
+
+	// node 与 at 为待触发的节点及其触发时间，暂停或终止时 node 为 nil。
+	node   *lst.Node
+	at     time.Time
+	paused bool
+	done   bool
+}
+
+// everyNext 以 anchor 为相位按 interval 对齐：anchor 晚于 after 时首次触发即为 anchor。
+func everyNext(anchor time.Time, interval time.Duration) recurringNextFunc {
+	return func(after time.Time) time.Time {
+		if anchor.After(after) {
+			return anchor
+		}
+		return anchor.Add((after.Sub(anchor)/interval + 1) * interval)
+	}
+}
+
+// cronNext 在 loc 时区内匹配表达式，startAt 之前的时刻从 startAt（含）开始匹配。
+func cronNext(schedule *cron.Schedule, startAt time.Time, loc *time.Location) recurringNextFunc {
+	return func(after time.Time) time.Time {
+		if !startAt.IsZero() && after.Before(startAt) {
+			after = startAt.Add(-time.Nanosecond)
+		}
+		return schedule.Next(after.In(loc))
+	}
+}
+
+func (r *recurringImpl) Pause() bool {
+	q := r.queue
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if r.done || r.paused {
+		return false
+	}
+	q.unscheduleRecurringLocked(r)
+	r.paused = true
+	return true
+}
+
+func (r *recurringImpl) Resume() bool {
+	q := r.queue
+	q.lock.Lock()
+	if r.done || !r.paused {
+		q.lock.Unlock()
+		return false
+	}
+	r.paused = false
+	wake := q.scheduleRecurringLocked(r, q.config.clock.Now())
+	q.lock.Unlock()
+
+	if wake {
+		q.scheduler.notify()
+	}
+	return !r.done
+}
+
+func (r *recurringImpl) Cancel() bool {
+	q := r.queue
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if r.done {
+		return false
+	}
+	q.unscheduleRecurringLocked(r)
+	r.done = true
+	return true
+}
+
+func (r *recurringImpl) Next() time.Time {
+	q := r.queue
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if r.node == nil {
+		return time.Time{}
+	}
+	return r.at
+}
+
+func (r *recurringImpl) IsPaused() bool {
+	q := r.queue
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return r.paused && !r.done
+}
+
+func (r *recurringImpl) IsDone() bool {
+	q := r.queue
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return r.done
+}
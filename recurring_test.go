@@ -0,0 +1,225 @@
+package workqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/workqueue/v2/internal/cron"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeTimerQueue 创建使用假时钟的定时队列，时钟从 fakeEpoch 开始。
+func newFakeTimerQueue() (TimerQueue, *FakeClock) {
+	clock := NewFakeClock(fakeEpoch)
+	return NewTimerQueue(NewTimerQueueConfig().WithClock(clock)), clock
+}
+
+func TestTimerQueue_PutEvery(t *testing.T) {
+	q, clock := newFakeTimerQueue()
+	defer q.Shutdown()
+
+	r, err := q.PutEvery("tick", 20*time.Millisecond, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, fakeEpoch.Add(20*time.Millisecond), r.Next())
+
+	clock.Advance(19 * time.Millisecond)
+	_, err = q.Get()
+	assert.ErrorIs(t, err, ErrQueueIsEmpty, "Value should not fire before the interval")
+
+	clock.Advance(time.Millisecond)
+	for i := 0; i < 3; i++ {
+		v, err := q.Get()
+		assert.NoError(t, err, "Value should fire once per interval")
+		assert.Equal(t, "tick", v)
+		q.Done(v)
+
+		_, err = q.Get()
+		assert.ErrorIs(t, err, ErrQueueIsEmpty, "Value should fire only once per interval")
+		clock.Advance(20 * time.Millisecond)
+	}
+	assert.False(t, r.IsDone())
+	assert.Equal(t, fakeEpoch.Add(100*time.Millisecond), r.Next(), "Next fire should move forward")
+}
+
+func TestTimerQueue_PutEvery_PauseResumeCancel(t *testing.T) {
+	q, clock := newFakeTimerQueue()
+	defer q.Shutdown()
+
+	r, err := q.PutEvery("tick", 20*time.Millisecond, nil)
+	assert.NoError(t, err)
+
+	assert.True(t, r.Pause())
+	assert.False(t, r.Pause(), "Pausing twice should report no change")
+	assert.True(t, r.IsPaused())
+	assert.True(t, r.Next().IsZero(), "Paused schedule should have no next fire")
+	assert.Equal(t, 0, q.Len())
+
+	clock.Advance(50 * time.Millisecond)
+	_, err = q.Get()
+	assert.ErrorIs(t, err, ErrQueueIsEmpty, "Paused schedule should not fire")
+
+	assert.True(t, r.Resume())
+	assert.False(t, r.Resume(), "Resuming a running schedule should report no change")
+	assert.Equal(t, fakeEpoch.Add(60*time.Millisecond), r.Next(), "Resumed schedule should keep its phase")
+
+	clock.Advance(10 * time.Millisecond)
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "tick", v)
+	q.Done(v)
+
+	assert.True(t, r.Cancel())
+	assert.False(t, r.Cancel())
+	assert.False(t, r.Resume(), "Cancelled schedule should not resume")
+	assert.True(t, r.IsDone())
+	assert.Equal(t, 0, q.Len())
+
+	clock.Advance(time.Second)
+	_, err = q.Get()
+	assert.ErrorIs(t, err, ErrQueueIsEmpty, "Cancelled schedule should not fire")
+}
+
+func TestTimerQueue_PutEvery_Window(t *testing.T) {
+	q, clock := newFakeTimerQueue()
+	defer q.Shutdown()
+
+	config := NewRecurringConfig().
+		WithStartAt(fakeEpoch.Add(30 * time.Millisecond)).
+		WithEndAt(fakeEpoch.Add(75 * time.Millisecond))
+	r, err := q.PutEvery("tick", 20*time.Millisecond, config)
+	assert.NoError(t, err)
+	assert.Equal(t, fakeEpoch.Add(30*time.Millisecond), r.Next(), "First fire should be the start time")
+
+	// 触发时刻为 30、50、70 毫秒，90 毫秒超出结束时间。
+	clock.Advance(time.Second)
+	count := 0
+	for {
+		v, err := q.Get()
+		if err != nil {
+			break
+		}
+		q.Done(v)
+		count++
+	}
+	assert.Equal(t, 3, count, "Schedule should only fire within its window")
+	assert.True(t, r.IsDone(), "Schedule should end after its end time")
+}
+
+func TestTimerQueue_PutEvery_Invalid(t *testing.T) {
+	q := NewTimerQueue(nil)
+
+	_, err := q.PutEvery("x", 0, nil)
+	assert.ErrorIs(t, err, ErrInvalidInterval)
+	_, err = q.PutEvery(nil, time.Second, nil)
+	assert.ErrorIs(t, err, ErrElementIsNil)
+	_, err = q.PutCron("x", "* * *", nil)
+	assert.ErrorIs(t, err, ErrInvalidCronExpression)
+
+	now := time.Now()
+	_, err = q.PutEvery("x", time.Second, NewRecurringConfig().WithStartAt(now.Add(time.Hour)).WithEndAt(now))
+	assert.ErrorIs(t, err, ErrRecurringNeverFires)
+	_, err = q.PutCron("x", "0 0 30 2 *", nil)
+	assert.ErrorIs(t, err, ErrRecurringNeverFires)
+
+	q.Shutdown()
+	_, err = q.PutEvery("x", time.Second, nil)
+	assert.ErrorIs(t, err, ErrQueueIsClosed)
+}
+
+func TestTimerQueue_PutCron(t *testing.T) {
+	q, _ := newFakeTimerQueue()
+	defer q.Shutdown()
+
+	// fakeEpoch 为东八区周一 08:00，下一次触发为同日 09:00。
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	r, err := q.PutCron("report", "0 9 * * mon", NewRecurringConfig().WithLocation(loc))
+	assert.NoError(t, err)
+	assert.Equal(t, fakeEpoch.Add(time.Hour), r.Next().UTC())
+	assert.Equal(t, time.Monday, r.Next().In(loc).Weekday())
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestTimerQueue_Cancel_SkipsRecurring(t *testing.T) {
+	q, clock := newFakeTimerQueue()
+	defer q.Shutdown()
+
+	r, err := q.PutEvery("report", time.Minute, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, q.PutAt("report", fakeEpoch.Add(time.Hour)))
+
+	// 按值取消只命中一次性定时元素，即使周期调度的待触发节点更早。
+	assert.True(t, q.Cancel("report"))
+	assert.False(t, q.Cancel("report"), "Recurring schedules should not be cancelled by value")
+	assert.False(t, r.IsDone())
+	assert.Equal(t, fakeEpoch.Add(time.Minute), r.Next())
+
+	clock.Advance(time.Minute)
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "report", v)
+	q.Done(v)
+}
+
+func TestTimerQueue_PutCron_Fire(t *testing.T) {
+	q, clock := newFakeTimerQueue()
+	defer q.Shutdown()
+
+	r, err := q.PutCron("second", "* * * * * *", nil)
+	assert.NoError(t, err)
+
+	first := r.Next()
+	assert.Equal(t, fakeEpoch.Add(time.Second), first.UTC())
+
+	clock.Advance(999 * time.Millisecond)
+	_, err = q.Get()
+	assert.ErrorIs(t, err, ErrQueueIsEmpty, "Value should not fire before its cron time")
+
+	clock.Advance(time.Millisecond)
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "second", v)
+	assert.Equal(t, first.Add(time.Second), r.Next(), "Next fire should be the following second")
+}
+
+func TestCronNext_StartAt(t *testing.T) {
+	schedule, err := cron.Parse("*/15 * * * *")
+	assert.NoError(t, err)
+
+	start := time.Date(2024, 1, 30, 10, 15, 0, 0, time.UTC)
+	next := cronNext(schedule, start, time.UTC)
+
+	assert.Equal(t, start, next(start.Add(-time.Hour)), "Start time itself should match")
+	assert.Equal(t, start.Add(15*time.Minute), next(start))
+}
+
+func TestTimerQueue_Recurring_Shutdown(t *testing.T) {
+	q, _ := newFakeTimerQueue()
+
+	r, err := q.PutEvery("tick", time.Hour, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, q.PutAfter("later", time.Hour))
+
+	// 排空不等待周期调度，剩余的一次性元素随超时返回。
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	pending, err := q.ShutdownWithDrain(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []interface{}{"later"}, pending, "Recurring values should not be returned as pending")
+	assert.True(t, r.IsDone(), "Shutdown should end recurring schedules")
+	assert.False(t, r.Resume())
+}
+
+func TestTimerQueue_Recurring_Drain(t *testing.T) {
+	q, _ := newFakeTimerQueue()
+
+	r, err := q.PutEvery("tick", time.Hour, nil)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	pending, err := q.ShutdownWithDrain(ctx)
+	assert.NoError(t, err, "Drain should not wait for recurring schedules")
+	assert.Empty(t, pending)
+	assert.True(t, r.IsDone())
+}
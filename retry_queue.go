@@ -1,6 +1,9 @@
 package workqueue
 
 import (
+	"context"
+	"errors"
+	"io"
 	"sync"
 	"time"
 )
@@ -49,21 +52,29 @@ func (q *retryQueueImpl) Retry(value interface{}, reason error) error {
 		delay = 0
 	}
 
+	// Done 与重新入队之间元素不在任何容器内，登记为处理中以免排空提前结束。
+	dq := q.DelayingQueue.(*delayingQueueImpl)
+	dq.base().retain()
+	defer dq.base().release()
+
 	// 先标记处理完成，避免幂等模式下重入队失败。
 	q.Done(value)
 
 	// PutWithDelay 以毫秒为粒度，子毫秒延迟会被截断为 0。
 	// 直接走 Put 可避免进入延迟搬运路径的额外轮询开销。
+	// 重试属于内部重新入队，排空期间仍然允许。
 	if delay < time.Millisecond {
-		err = q.Put(value)
+		err = dq.base().requeue(value)
 	} else {
-		err = q.PutWithDelay(value, delay.Milliseconds())
+		err = dq.putWithDelay(value, delay.Milliseconds(), true)
 	}
 
-	if err != nil {
+	// 处理期间已有新的入队请求时，Done 会把元素重新入队，重试请求与之合并。
+	if err != nil && !errors.Is(err, ErrElementAlreadyExist) {
 		return err
 	}
 
+	dq.base().metrics.retried()
 	q.config.callback.OnRetry(value, attempt, delay, reason)
 	return nil
 }
@@ -106,6 +117,56 @@ func (q *retryQueueImpl) Shutdown() {
 	q.lock.Unlock()
 }
 
+func (q *retryQueueImpl) ShutdownWithDrain(ctx context.Context) ([]interface{}, error) {
+	pending, err := q.DelayingQueue.ShutdownWithDrain(ctx)
+
+	q.lock.Lock()
+	q.attempts = make(map[string]int)
+	q.lock.Unlock()
+
+	return pending, err
+}
+
+// Snapshot 在延迟队列快照之上额外保存各元素的重试计数。
+func (q *retryQueueImpl) Snapshot(w io.Writer) error {
+	s, err := q.DelayingQueue.(*delayingQueueImpl).snapshot()
+	if err != nil {
+		return err
+	}
+
+	q.lock.RLock()
+	s.Attempts = make(map[string]int, len(q.attempts))
+	for key, attempt := range q.attempts {
+		s.Attempts[key] = attempt
+	}
+	q.lock.RUnlock()
+
+	return writeSnapshot(w, s)
+}
+
+// Restore 恢复快照中的元素与重试计数，已有计数以快照为准。
+func (q *retryQueueImpl) Restore(r io.Reader) error {
+	s, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	// 部分元素入队失败时仍恢复重试计数，返回的 *BatchError 由调用方处理。
+	var batchErr *BatchError
+	err = q.DelayingQueue.(*delayingQueueImpl).restore(s)
+	if err != nil && !errors.As(err, &batchErr) {
+		return err
+	}
+
+	q.lock.Lock()
+	for key, attempt := range s.Attempts {
+		q.attempts[key] = attempt
+	}
+	q.lock.Unlock()
+
+	return err
+}
+
 func (q *retryQueueImpl) keyOf(value interface{}) (string, error) {
 	key := q.config.keyFunc(value)
 	if key == "" {
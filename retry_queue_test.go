@@ -1,6 +1,7 @@
 package workqueue
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"testing"
@@ -168,3 +169,87 @@ func TestRetryQueue_Callback(t *testing.T) {
 	assert.Equal(t, []interface{}{"task"}, callback.exhausted)
 	assert.Equal(t, []interface{}{"task"}, callback.forgets)
 }
+
+func TestRetryQueue_GetWithContext_WakesOnRetry(t *testing.T) {
+	config := NewRetryQueueConfig().WithPolicy(NewExponentialRetryPolicy(50*time.Millisecond, 50*time.Millisecond, 3))
+	q := NewRetryQueue(config)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("task"))
+
+	value, err := q.GetBlocking()
+	assert.NoError(t, err)
+	assert.NoError(t, q.Retry(value, errors.New("failed")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	value, err = q.GetWithContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "task", value)
+}
+
+type testImmediateRetryPolicy struct{}
+
+func (p *testImmediateRetryPolicy) NextDelay(interface{}, int, error) (time.Duration, bool) {
+	return 0, true
+}
+
+func TestRetryQueue_Idempotent_RetryCoalescesWithPut(t *testing.T) {
+	config := NewRetryQueueConfig().WithPolicy(&testImmediateRetryPolicy{})
+	config.WithValueIdempotent()
+	q := NewRetryQueue(config)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("task"))
+
+	value, err := q.Get()
+	assert.NoError(t, err)
+
+	// 处理期间到达的更新被标记为 dirty，重试与之合并为一次重新入队。
+	assert.NoError(t, q.Put("task"))
+	assert.NoError(t, q.Retry(value, errors.New("failed")))
+	assert.Equal(t, 1, q.Len())
+	assert.Equal(t, 1, q.NumRequeues("task"))
+
+	value, err = q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "task", value)
+	q.Done(value)
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestRetryQueue_ShutdownWithDrain_AcceptsRetry(t *testing.T) {
+	config := NewRetryQueueConfig().WithPolicy(NewExponentialRetryPolicy(10*time.Millisecond, 10*time.Millisecond, 3))
+	q := NewRetryQueue(config)
+
+	assert.NoError(t, q.Put("task"))
+
+	value, err := q.Get()
+	assert.NoError(t, err)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := q.ShutdownWithDrain(context.Background())
+		result <- err
+	}()
+
+	assert.Eventually(t, func() bool {
+		return errors.Is(q.Put("other"), ErrQueueIsDraining)
+	}, time.Second, time.Millisecond)
+
+	// 处理中元素的重试属于内部重新入队，排空期间仍然允许。
+	assert.NoError(t, q.Retry(value, errors.New("failed")))
+
+	value, err = q.GetBlocking()
+	assert.NoError(t, err)
+	assert.Equal(t, "task", value)
+	q.Done(value)
+
+	select {
+	case err := <-result:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("ShutdownWithDrain should return after the retried value is done")
+	}
+}
@@ -0,0 +1,277 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// runnerImpl 以固定数量的工作协程驱动队列消费，并按队列类型自动串联重试、租约与死信语义：
+//   - LeasedQueue：成功 Ack，失败 Nack 重新入队；
+//   - RetryQueue：成功 Forget + Done，失败 Retry，重试耗尽或无法重新入队时投递死信；
+//   - 其他队列：成功 Done，失败投递死信后 Done。
+type runnerImpl struct {
+	queue   Queue
+	retry   RetryQueue
+	leased  LeasedQueue
+	handler Handler
+	config  *RunnerConfig
+	clock   Clock
+
+	// running 表示 Run 正在进行；stopped 为当前（或下一次）Run 的停止信号，closed 表示其已被 Stop 关闭。
+	// Run 返回时若信号已关闭则换上新的信号，使 Runner 可以再次运行。
+	lock    sync.Mutex
+	running bool
+	stopped chan struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// NewRunner 创建驱动 queue 的 Runner，queue 与 handler 的合法性在 Run 时校验。
+func NewRunner(queue Queue, handler Handler, config *RunnerConfig) Runner {
+	r := &runnerImpl{
+		queue:   queue,
+		handler: handler,
+		config:  isRunnerConfigEffective(config),
+		stopped: make(chan struct{}),
+	}
+
+	if queue != nil {
+		r.retry, _ = queue.(RetryQueue)
+		r.leased, _ = queue.(LeasedQueue)
+	}
+	r.clock = r.config.clock
+	if r.clock == nil {
+		r.clock = clockOf(queue)
+	}
+
+	return r
+}
+
+// clockOf 返回 queue 配置的时钟，无法识别的队列使用系统时钟。
+func clockOf(queue Queue) Clock {
+	switch q := queue.(type) {
+	case *boundedBlockingQueueImpl:
+		queue = q.Queue
+	case *boundedFairQueueImpl:
+		queue = q.boundedBlockingQueueImpl.Queue
+	}
+	if base, ok := baseOf(queue); ok {
+		return base.config.clock
+	}
+	return NewRealClock()
+}
+
+func (r *runnerImpl) Run(ctx context.Context) error {
+	if r.queue == nil {
+		return ErrQueueIsNil
+	}
+	if r.handler == nil {
+		return ErrHandlerIsNil
+	}
+
+	r.lock.Lock()
+	if r.running {
+		r.lock.Unlock()
+		return ErrRunnerIsRunning
+	}
+	r.running = true
+	stopped := r.stopped
+	r.lock.Unlock()
+	defer r.finish()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.wg.Add(r.config.workers)
+	for i := 0; i < r.config.workers; i++ {
+		go r.worker(ctx)
+	}
+
+	// Stop 通过取消 ctx 唤醒阻塞在 Get 上的工作协程。
+	go func() {
+		select {
+		case <-stopped:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	r.wg.Wait()
+	return nil
+}
+
+// finish 在全部工作协程退出后结束本次运行，已被 Stop 关闭的停止信号换成新的，之后可以再次调用 Run。
+func (r *runnerImpl) finish() {
+	r.lock.Lock()
+	r.running = false
+	if r.closed {
+		r.stopped = make(chan struct{})
+		r.closed = false
+	}
+	r.lock.Unlock()
+}
+
+// Stop 通知 Runner 停止，传给 Handler 的 ctx 随之取消，Run 在全部工作协程退出后返回。
+// 未在运行时调用 Stop，下一次 Run 会立即返回。
+func (r *runnerImpl) Stop() {
+	r.lock.Lock()
+	if !r.closed {
+		close(r.stopped)
+		r.closed = true
+	}
+	r.lock.Unlock()
+}
+
+// 读取元素持续失败（例如远程队列不可达）时，工作协程按指数退避等待后重试，成功读取后复位。
+const (
+	runnerMinBackoff = 10 * time.Millisecond
+	runnerMaxBackoff = time.Second
+)
+
+func (r *runnerImpl) worker(ctx context.Context) {
+	defer r.wg.Done()
+
+	backoff := time.Duration(0)
+	for {
+		value, leaseID, err := r.next(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrQueueIsClosed) {
+				return
+			}
+
+			backoff *= 2
+			if backoff < runnerMinBackoff {
+				backoff = runnerMinBackoff
+			}
+			if backoff > runnerMaxBackoff {
+				backoff = runnerMaxBackoff
+			}
+			if !sleepWithContext(ctx, backoff) {
+				return
+			}
+			continue
+		}
+		backoff = 0
+
+		r.process(ctx, value, leaseID)
+	}
+}
+
+// sleepWithContext 等待 d，ctx 先结束时返回 false。
+func sleepWithContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (r *runnerImpl) next(ctx context.Context) (interface{}, string, error) {
+	if r.leased != nil {
+		return r.leased.GetWithLeaseContext(ctx, r.config.leaseDuration)
+	}
+
+	value, err := r.queue.GetWithContext(ctx)
+	return value, "", err
+}
+
+func (r *runnerImpl) process(ctx context.Context, value interface{}, leaseID string) {
+	start := r.clock.Now()
+
+	if err := r.invoke(ctx, value); err != nil {
+		r.config.callback.OnFailure(value, err)
+		r.fail(value, leaseID, err)
+		return
+	}
+
+	r.succeed(value, leaseID)
+	r.config.callback.OnSuccess(value, r.clock.Now().Sub(start))
+}
+
+// invoke 执行 Handler。配置超时后 Handler 收到带截止时间的 ctx，Runner 始终等待 Handler 返回后才确认或重试元素，
+// 因此不响应 ctx 的 Handler 会一直占用工作协程，但同一元素不会被并发处理。
+// 超过截止时间才返回的 Handler 即使返回 nil 也按超时失败处理。
+func (r *runnerImpl) invoke(ctx context.Context, value interface{}) error {
+	if r.config.timeout <= 0 {
+		return r.call(ctx, value)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout)
+	defer cancel()
+
+	err := r.call(ctx, value)
+	if err == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = context.DeadlineExceeded
+	}
+	return err
+}
+
+func (r *runnerImpl) call(ctx context.Context, value interface{}) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			r.config.callback.OnPanic(value, recovered)
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, recovered)
+		}
+	}()
+
+	return r.handler(ctx, value)
+}
+
+func (r *runnerImpl) succeed(value interface{}, leaseID string) {
+	if r.leased != nil {
+		// 租约已过期时元素已被重新入队，忽略 ErrLeaseNotFound。
+		_ = r.leased.Ack(leaseID)
+		return
+	}
+
+	if r.retry != nil {
+		r.retry.Forget(value)
+	}
+	r.queue.Done(value)
+}
+
+func (r *runnerImpl) fail(value interface{}, leaseID string, reason error) {
+	switch {
+	case r.leased != nil:
+		_ = r.leased.Nack(leaseID, reason)
+
+	case r.retry != nil:
+		attempts := r.retry.NumRequeues(value) + 1
+
+		// Retry 成功时已完成 Done 并重新入队。重试耗尽以外的失败（例如队列已关闭、限流器出错）
+		// 同样无法重新入队，投递死信并附上失败原因，避免元素被静默丢弃。
+		err := r.retry.Retry(value, reason)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, ErrRetryExhausted) {
+			reason = fmt.Errorf("%v (retry: %v)", reason, err)
+		}
+		r.bury(value, attempts, reason)
+		r.queue.Done(value)
+
+	default:
+		r.bury(value, 1, reason)
+		r.queue.Done(value)
+	}
+}
+
+func (r *runnerImpl) bury(value interface{}, attempts int, reason error) {
+	if r.config.deadLetter == nil {
+		return
+	}
+
+	_ = r.config.deadLetter.PutDead(&DeadLetter{
+		Payload:     value,
+		SourceQueue: r.config.source,
+		Attempts:    attempts,
+		LastError:   reason.Error(),
+	})
+}
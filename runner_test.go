@@ -0,0 +1,376 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRunnerCallback struct {
+	mu        sync.Mutex
+	successes []interface{}
+	durations []time.Duration
+	failures  []interface{}
+	panics    []interface{}
+}
+
+func (c *testRunnerCallback) OnSuccess(value interface{}, duration time.Duration) {
+	c.mu.Lock()
+	c.successes = append(c.successes, value)
+	c.durations = append(c.durations, duration)
+	c.mu.Unlock()
+}
+
+func (c *testRunnerCallback) OnFailure(value interface{}, _ error) {
+	c.mu.Lock()
+	c.failures = append(c.failures, value)
+	c.mu.Unlock()
+}
+
+func (c *testRunnerCallback) OnPanic(value interface{}, _ interface{}) {
+	c.mu.Lock()
+	c.panics = append(c.panics, value)
+	c.mu.Unlock()
+}
+
+func startRunner(t *testing.T, r Runner) (stop func()) {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(context.Background())
+	}()
+
+	return func() {
+		r.Stop()
+		select {
+		case err := <-done:
+			assert.NoError(t, err, "Run should return nil after Stop")
+		case <-time.After(2 * time.Second):
+			t.Fatal("Run should return after Stop")
+		}
+	}
+}
+
+func TestRunner_ProcessAll(t *testing.T) {
+	q := NewQueue(NewQueueConfig().WithValueIdempotent())
+	defer q.Shutdown()
+
+	count := 100
+	var processed atomic.Int64
+	r := NewRunner(q, func(_ context.Context, _ interface{}) error {
+		processed.Add(1)
+		return nil
+	}, NewRunnerConfig().WithWorkers(4))
+
+	stop := startRunner(t, r)
+	for i := 0; i < count; i++ {
+		assert.NoError(t, q.Put(i))
+	}
+
+	assert.Eventually(t, func() bool { return processed.Load() == int64(count) }, 2*time.Second, 5*time.Millisecond)
+	stop()
+
+	// 成功处理后应已调用 Done，幂等模式下可再次入队。
+	assert.NoError(t, q.Put(0))
+}
+
+func TestRunner_InvalidArguments(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	assert.ErrorIs(t, NewRunner(nil, func(context.Context, interface{}) error { return nil }, nil).Run(context.Background()), ErrQueueIsNil)
+	assert.ErrorIs(t, NewRunner(q, nil, nil).Run(context.Background()), ErrHandlerIsNil)
+}
+
+func TestRunner_RunTwice(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	r := NewRunner(q, func(context.Context, interface{}) error { return nil }, nil)
+	stop := startRunner(t, r)
+	defer stop()
+
+	time.Sleep(10 * time.Millisecond)
+	assert.ErrorIs(t, r.Run(context.Background()), ErrRunnerIsRunning)
+}
+
+func TestRunner_RunAgain(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	var processed atomic.Int64
+	r := NewRunner(q, func(context.Context, interface{}) error {
+		processed.Add(1)
+		return nil
+	}, NewRunnerConfig().WithWorkers(1))
+
+	// Stop 与 ctx 结束后都可以再次运行。
+	stop := startRunner(t, r)
+	assert.NoError(t, q.Put("a"))
+	assert.Eventually(t, func() bool { return processed.Load() == 1 }, time.Second, time.Millisecond)
+	stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(t, r.Run(ctx))
+
+	stop = startRunner(t, r)
+	assert.NoError(t, q.Put("b"))
+	assert.Eventually(t, func() bool { return processed.Load() == 2 }, time.Second, time.Millisecond)
+	stop()
+}
+
+func TestRunner_QueueShutdownStopsRun(t *testing.T) {
+	q := NewQueue(nil)
+
+	r := NewRunner(q, func(context.Context, interface{}) error { return nil }, NewRunnerConfig().WithWorkers(2))
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(context.Background())
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	q.Shutdown()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run should return after the queue is shut down")
+	}
+}
+
+func TestRunner_RetryThenDeadLetter(t *testing.T) {
+	q := NewRetryQueue(NewRetryQueueConfig().WithPolicy(NewExponentialRetryPolicy(5*time.Millisecond, 5*time.Millisecond, 2)))
+	defer q.Shutdown()
+	dlq := NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+
+	var calls atomic.Int64
+	r := NewRunner(q, func(context.Context, interface{}) error {
+		calls.Add(1)
+		return errors.New("boom")
+	}, NewRunnerConfig().WithWorkers(1).WithDeadLetterQueue(dlq).WithSourceName("orders"))
+
+	stop := startRunner(t, r)
+	defer stop()
+
+	assert.NoError(t, q.Put("task"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	letter, err := dlq.GetDeadWithContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "task", letter.Payload)
+	assert.Equal(t, "orders", letter.SourceQueue)
+	assert.Equal(t, 3, letter.Attempts)
+	assert.Equal(t, "boom", letter.LastError)
+	assert.Equal(t, int64(3), calls.Load(), "Handler should run once plus two retries")
+	assert.Equal(t, 0, q.NumRequeues("task"))
+}
+
+func TestRunner_PanicRecovery(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+	dlq := NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+
+	callback := &testRunnerCallback{}
+	r := NewRunner(q, func(_ context.Context, value interface{}) error {
+		if value == "bad" {
+			panic("unexpected")
+		}
+		return nil
+	}, NewRunnerConfig().WithWorkers(1).WithCallback(callback).WithDeadLetterQueue(dlq))
+
+	stop := startRunner(t, r)
+	defer stop()
+
+	assert.NoError(t, q.Put("bad"))
+	assert.NoError(t, q.Put("good"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	letter, err := dlq.GetDeadWithContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "bad", letter.Payload)
+	assert.Contains(t, letter.LastError, ErrHandlerPanic.Error())
+
+	assert.Eventually(t, func() bool {
+		callback.mu.Lock()
+		defer callback.mu.Unlock()
+		return len(callback.successes) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	callback.mu.Lock()
+	defer callback.mu.Unlock()
+	assert.Equal(t, []interface{}{"bad"}, callback.panics)
+	assert.Equal(t, []interface{}{"bad"}, callback.failures)
+	assert.Equal(t, []interface{}{"good"}, callback.successes)
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+	dlq := NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+
+	r := NewRunner(q, func(ctx context.Context, _ interface{}) error {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}, NewRunnerConfig().WithWorkers(1).WithTimeout(20*time.Millisecond).WithDeadLetterQueue(dlq))
+
+	stop := startRunner(t, r)
+	defer stop()
+
+	assert.NoError(t, q.Put("slow"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	letter, err := dlq.GetDeadWithContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, context.DeadlineExceeded.Error(), letter.LastError)
+}
+
+func TestRunner_LeasedQueueAckAndNack(t *testing.T) {
+	q := NewLeasedQueue(NewLeasedQueueConfig().WithScanInterval(5 * time.Millisecond))
+	defer q.Shutdown()
+
+	var attempts atomic.Int64
+	done := make(chan struct{})
+	r := NewRunner(q, func(context.Context, interface{}) error {
+		if attempts.Add(1) == 1 {
+			return errors.New("first attempt fails")
+		}
+		close(done)
+		return nil
+	}, NewRunnerConfig().WithWorkers(1).WithLeaseDuration(time.Second))
+
+	stop := startRunner(t, r)
+	defer stop()
+
+	assert.NoError(t, q.Put("job"))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Nacked job should be redelivered")
+	}
+
+	// Ack 之后不应再次投递。
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int64(2), attempts.Load())
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestRunner_TimeoutWaitsForHandler(t *testing.T) {
+	q := NewRetryQueue(NewRetryQueueConfig().WithPolicy(NewExponentialRetryPolicy(time.Millisecond, time.Millisecond, 2)))
+	defer q.Shutdown()
+	dlq := NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+
+	var running, overlaps atomic.Int64
+	r := NewRunner(q, func(context.Context, interface{}) error {
+		// 忽略 ctx 的 Handler：超时后仍会继续执行。
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(30 * time.Millisecond)
+		running.Add(-1)
+		return nil
+	}, NewRunnerConfig().WithWorkers(2).WithTimeout(5*time.Millisecond).WithDeadLetterQueue(dlq))
+
+	stop := startRunner(t, r)
+	defer stop()
+
+	assert.NoError(t, q.Put("slow"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	letter, err := dlq.GetDeadWithContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, letter.Attempts)
+	assert.Equal(t, context.DeadlineExceeded.Error(), letter.LastError)
+	assert.Equal(t, int64(0), overlaps.Load(), "A retry should not start while the timed out handler is still running")
+}
+
+// failingQueue 的读取始终失败，用于观察工作协程的退避。
+type failingQueue struct {
+	Queue
+	gets atomic.Int64
+}
+
+func (q *failingQueue) GetWithContext(context.Context) (interface{}, error) {
+	q.gets.Add(1)
+	return nil, errors.New("unreachable")
+}
+
+func TestRunner_BackoffOnError(t *testing.T) {
+	q := &failingQueue{Queue: NewQueue(nil)}
+	defer q.Shutdown()
+
+	r := NewRunner(q, func(context.Context, interface{}) error { return nil }, NewRunnerConfig().WithWorkers(1))
+	stop := startRunner(t, r)
+	time.Sleep(100 * time.Millisecond)
+	stop()
+
+	// 10ms 起步逐次翻倍，100ms 内至多读取 4 次。
+	assert.LessOrEqual(t, q.gets.Load(), int64(5), "Workers should back off on persistent errors")
+	assert.Greater(t, q.gets.Load(), int64(1))
+}
+
+func TestRunner_RetryErrorDeadLetter(t *testing.T) {
+	q := NewRetryQueue(nil)
+	dlq := NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+
+	// 处理期间队列被关闭，Retry 无法重新入队，元素应进入死信而不是被丢弃。
+	r := NewRunner(q, func(context.Context, interface{}) error {
+		q.Shutdown()
+		return errors.New("boom")
+	}, NewRunnerConfig().WithWorkers(1).WithDeadLetterQueue(dlq))
+
+	assert.NoError(t, q.Put("task"))
+	assert.NoError(t, r.Run(context.Background()))
+
+	letter, err := dlq.GetDead()
+	assert.NoError(t, err)
+	assert.Equal(t, "task", letter.Payload)
+	assert.Equal(t, 1, letter.Attempts)
+	assert.Contains(t, letter.LastError, "boom")
+	assert.Contains(t, letter.LastError, ErrQueueIsClosed.Error())
+}
+
+func TestRunner_FakeClockDuration(t *testing.T) {
+	clock := NewFakeClock(fakeEpoch)
+	q := NewQueue(NewQueueConfig().WithClock(clock))
+	defer q.Shutdown()
+
+	callback := &testRunnerCallback{}
+	r := NewRunner(q, func(context.Context, interface{}) error {
+		clock.Advance(5 * time.Second)
+		return nil
+	}, NewRunnerConfig().WithWorkers(1).WithCallback(callback))
+
+	stop := startRunner(t, r)
+	assert.NoError(t, q.Put("task"))
+	assert.Eventually(t, func() bool {
+		callback.mu.Lock()
+		defer callback.mu.Unlock()
+		return len(callback.durations) == 1
+	}, time.Second, time.Millisecond)
+	stop()
+
+	assert.Equal(t, []time.Duration{5 * time.Second}, callback.durations, "Durations should follow the queue clock")
+}
@@ -0,0 +1,133 @@
+package workqueue
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
+)
+
+// scheduleFunc 搬运一个已到期的元素，或返回距离最早到期元素的等待时长。
+// fired 为真时调度器立即再次调用；wait <= 0 表示当前没有待调度元素，调度器休眠直至被唤醒。
+type scheduleFunc = func() (fired bool, wait time.Duration)
+
+// scheduler 是延迟队列、定时队列与租约队列共用的调度器：通过时钟的计时器休眠至最早的到期时刻，
+// 插入更早到期的元素或取消元素时通过 notify 唤醒并重新计算等待时长。
+// 计时器回调同一时刻只有一个在执行，使用 FakeClock 时回调在 Advance 的协程中同步执行。
+type scheduler struct {
+	clock Clock
+	fn    scheduleFunc
+	timer ClockTimer
+
+	lock    sync.Mutex
+	running bool
+	// pending 表示回调执行期间收到了 notify，回调结束前需要再检查一轮。
+	pending bool
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+func newScheduler(clock Clock) *scheduler {
+	return &scheduler{clock: clock}
+}
+
+// start 启动调度器，首次检查在计时器回调中进行。
+func (s *scheduler) start(fn scheduleFunc) {
+	s.lock.Lock()
+	s.fn = fn
+	s.timer = s.clock.AfterFunc(0, s.run)
+	s.lock.Unlock()
+}
+
+// stop 停止调度器并等待正在执行的回调退出，正在搬运的元素会先完成搬运。
+func (s *scheduler) stop() {
+	s.lock.Lock()
+	if s.stopped {
+		s.lock.Unlock()
+		return
+	}
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *scheduler) isStopped() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.stopped
+}
+
+// notify 唤醒调度器，回调执行期间的多次唤醒合并为一次额外的检查。
+func (s *scheduler) notify() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch {
+	case s.stopped || s.timer == nil:
+	case s.running:
+		s.pending = true
+	default:
+		s.timer.Reset(0)
+	}
+}
+
+// run 为计时器回调：反复调用 fn 直至没有到期元素，再按返回的等待时长重新设置计时器。
+func (s *scheduler) run() {
+	s.lock.Lock()
+	if s.stopped || s.running {
+		s.pending = s.pending || s.running
+		s.lock.Unlock()
+		return
+	}
+	s.running = true
+	s.wg.Add(1)
+	s.lock.Unlock()
+	defer s.wg.Done()
+
+	for {
+		fired, wait := s.fn()
+
+		s.lock.Lock()
+		switch {
+		case s.stopped:
+		case fired || s.pending:
+			s.pending = false
+			s.lock.Unlock()
+			continue
+		case wait > 0:
+			s.timer.Reset(wait)
+		default:
+			s.timer.Stop()
+		}
+		s.running = false
+		s.lock.Unlock()
+		return
+	}
+}
+
+// popExpired 从调度容器中弹出一个在 now（Unix 毫秒）之前到期的节点。没有到期节点时返回距离下一次
+// 需要醒来的时长，并将该时刻记入 wakeAt；容器为空时 wakeAt 为最大值，等待时长为 0。
+// 调用方需持有保护容器的锁。
+func popExpired(sorting schedule, wakeAt *int64, now int64) (*lst.Node, time.Duration) {
+	if node := sorting.PopExpired(now); node != nil {
+		*wakeAt = 0
+		return node, 0
+	}
+
+	at, ok := sorting.Next()
+	if !ok {
+		*wakeAt = math.MaxInt64
+		return nil, 0
+	}
+	if at <= now {
+		at = now + 1
+	}
+	*wakeAt = at
+	return nil, time.Duration(at-now) * time.Millisecond
+}
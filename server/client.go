@@ -0,0 +1,558 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+var (
+	_ wkq.Queue           = (*Client)(nil)
+	_ wkq.LeasedQueue     = (*Client)(nil)
+	_ wkq.DeadLetterQueue = (*Client)(nil)
+)
+
+// Client 访问服务端上的一个具名队列，实现 Queue、LeasedQueue 与 DeadLetterQueue，可并发使用。
+// 远程队列不支持的操作返回 ErrUnsupportedOperation。没有错误返回值的方法（Done、Len、Values、Range、Stats、RangeDead）
+// 在请求失败时静默返回零值。
+// Shutdown 与 ShutdownWithDrain 只关闭客户端并中止进行中的请求，远程队列的生命周期由服务端管理。
+type Client struct {
+	config   *ClientConfig
+	codec    wkq.Codec
+	baseURL  string
+	name     string
+	endpoint string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewClient 创建访问 baseURL 上名为 name 的队列的客户端，baseURL 形如 http://127.0.0.1:8080。
+func NewClient(baseURL, name string, config *ClientConfig) *Client {
+	config = isClientConfigEffective(config)
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	c := &Client{
+		config:   config,
+		codec:    wkq.NewJSONCodec(config.registry),
+		baseURL:  baseURL,
+		name:     name,
+		endpoint: baseURL + pathPrefix + url.PathEscape(name) + "/",
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c
+}
+
+func (c *Client) Put(value interface{}) error {
+	if value == nil {
+		return wkq.ErrElementIsNil
+	}
+	data, err := c.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	return c.call(c.ctx, http.MethodPost, opPut, &valueRequest{Value: data}, nil)
+}
+
+func (c *Client) PutBatch(values []interface{}) error {
+	if c.IsClosed() {
+		return wkq.ErrQueueIsClosed
+	}
+
+	errs := make([]error, len(values))
+	req := &valuesRequest{Values: make([]json.RawMessage, 0, len(values))}
+	index := make([]int, 0, len(values))
+	for i, value := range values {
+		if value == nil {
+			errs[i] = wkq.ErrElementIsNil
+			continue
+		}
+		data, err := c.codec.Encode(value)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		req.Values = append(req.Values, data)
+		index = append(index, i)
+	}
+
+	if err := c.call(c.ctx, http.MethodPost, opPutBatch, req, nil); err != nil {
+		var batchErr *wkq.BatchError
+		if !errors.As(err, &batchErr) || len(batchErr.Errors) != len(index) {
+			return err
+		}
+		// 将服务端的批量结果映射回原始输入下标。
+		for j, e := range batchErr.Errors {
+			errs[index[j]] = e
+		}
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return &wkq.BatchError{Errors: errs}
+		}
+	}
+	return nil
+}
+
+func (c *Client) Get() (interface{}, error) {
+	values, err := c.GetBatch(1)
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// GetWithContext 以长轮询阻塞等待直至有可消费元素、ctx 结束或客户端关闭。
+func (c *Client) GetWithContext(ctx context.Context) (interface{}, error) {
+	values, err := c.GetBatchWithContext(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+func (c *Client) GetBlocking() (interface{}, error) {
+	return c.GetWithContext(context.Background())
+}
+
+func (c *Client) GetBatch(max int) ([]interface{}, error) {
+	if max <= 0 {
+		return nil, wkq.ErrInvalidBatchSize
+	}
+
+	values, err := c.get(c.ctx, max, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, wkq.ErrQueueIsEmpty
+	}
+	return values, nil
+}
+
+func (c *Client) GetBatchWithContext(ctx context.Context, max int) ([]interface{}, error) {
+	if max <= 0 {
+		return nil, wkq.ErrInvalidBatchSize
+	}
+
+	var values []interface{}
+	err := c.poll(ctx, func(ctx context.Context, wait time.Duration) (found bool, err error) {
+		values, err = c.get(ctx, max, wait)
+		return len(values) > 0, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (c *Client) get(ctx context.Context, max int, wait time.Duration) ([]interface{}, error) {
+	var resp valuesResponse
+	if err := c.call(ctx, http.MethodPost, opGet, &getRequest{Max: max, WaitMS: wait.Milliseconds()}, &resp); err != nil {
+		return nil, err
+	}
+	return c.decodeValues(resp.Values)
+}
+
+// Done 通知服务端元素处理完成，服务端按编码结果找回投递出去的原始元素。
+func (c *Client) Done(value interface{}) {
+	if value == nil {
+		return
+	}
+	data, err := c.codec.Encode(value)
+	if err != nil {
+		return
+	}
+	_ = c.call(c.ctx, http.MethodPost, opDone, &valueRequest{Value: data}, nil)
+}
+
+// Retry 请求远程 RetryQueue 按其重试策略重新投递元素，语义与 RetryQueue.Retry 一致。
+func (c *Client) Retry(value interface{}, reason error) error {
+	if value == nil {
+		return wkq.ErrElementIsNil
+	}
+	data, err := c.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	req := &retryRequest{Value: data}
+	if reason != nil {
+		req.Reason = reason.Error()
+	}
+	return c.call(c.ctx, http.MethodPost, opRetry, req, nil)
+}
+
+func (c *Client) Len() int {
+	var resp lenResponse
+	if err := c.call(c.ctx, http.MethodGet, opLen, nil, &resp); err != nil {
+		return 0
+	}
+	return resp.Len
+}
+
+func (c *Client) Values() []interface{} {
+	var resp valuesResponse
+	if err := c.call(c.ctx, http.MethodGet, opValues, nil, &resp); err != nil {
+		return nil
+	}
+	values, err := c.decodeValues(resp.Values)
+	if err != nil {
+		return nil
+	}
+	return values
+}
+
+// Range 遍历调用时刻远程队列中元素的副本。
+func (c *Client) Range(fn func(value interface{}) bool) {
+	if fn == nil {
+		return
+	}
+	for _, value := range c.Values() {
+		if !fn(value) {
+			return
+		}
+	}
+}
+
+// Shutdown 关闭客户端，不影响远程队列。
+func (c *Client) Shutdown() {
+	c.cancel()
+}
+
+// ShutdownWithDrain 关闭客户端，不影响远程队列，也不会返回远程队列中的元素。
+func (c *Client) ShutdownWithDrain(context.Context) ([]interface{}, error) {
+	c.cancel()
+	return nil, nil
+}
+
+// IsClosed 返回客户端是否已关闭。
+func (c *Client) IsClosed() bool {
+	return c.ctx.Err() != nil
+}
+
+func (c *Client) Stats() wkq.QueueStats {
+	var stats wkq.QueueStats
+	_ = c.call(c.ctx, http.MethodGet, opStats, nil, &stats)
+	return stats
+}
+
+// Snapshot 将远程队列的快照写入 w，快照使用远程队列配置的 Codec 编码。
+func (c *Client) Snapshot(w io.Writer) error {
+	resp, err := c.do(c.ctx, http.MethodGet, opSnapshot, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Restore 将 Snapshot 写出的数据恢复到远程队列。
+func (c *Client) Restore(r io.Reader) error {
+	resp, err := c.do(c.ctx, http.MethodPost, opRestore, "application/octet-stream", r)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (c *Client) GetWithLease(timeout time.Duration) (interface{}, string, error) {
+	value, leaseID, err := c.lease(c.ctx, timeout, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	if leaseID == "" {
+		return nil, "", wkq.ErrQueueIsEmpty
+	}
+	return value, leaseID, nil
+}
+
+func (c *Client) GetWithLeaseContext(ctx context.Context, timeout time.Duration) (value interface{}, leaseID string, err error) {
+	err = c.poll(ctx, func(ctx context.Context, wait time.Duration) (found bool, err error) {
+		value, leaseID, err = c.lease(ctx, timeout, wait)
+		return leaseID != "", err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return value, leaseID, nil
+}
+
+func (c *Client) lease(ctx context.Context, timeout, wait time.Duration) (interface{}, string, error) {
+	var resp leaseResponse
+	req := &leaseRequest{TimeoutMS: timeout.Milliseconds(), WaitMS: wait.Milliseconds()}
+	if err := c.call(ctx, http.MethodPost, opLease, req, &resp); err != nil {
+		return nil, "", err
+	}
+	if resp.LeaseID == "" {
+		return nil, "", nil
+	}
+	value, err := c.codec.Decode(resp.Value)
+	if err != nil {
+		// 无法解码的元素交还服务端重新投递。
+		_ = c.Nack(resp.LeaseID, err)
+		return nil, "", err
+	}
+	return value, resp.LeaseID, nil
+}
+
+func (c *Client) Ack(leaseID string) error {
+	return c.call(c.ctx, http.MethodPost, opAck, &leaseIDRequest{LeaseID: leaseID}, nil)
+}
+
+func (c *Client) Nack(leaseID string, reason error) error {
+	req := &leaseIDRequest{LeaseID: leaseID}
+	if reason != nil {
+		req.Reason = reason.Error()
+	}
+	return c.call(c.ctx, http.MethodPost, opNack, req, nil)
+}
+
+func (c *Client) ExtendLease(leaseID string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return wkq.ErrInvalidLeaseDuration
+	}
+	return c.call(c.ctx, http.MethodPost, opExtend, &leaseIDRequest{LeaseID: leaseID, TimeoutMS: timeout.Milliseconds()}, nil)
+}
+
+// RangeLeases 遍历调用时刻远程队列中尚未确认的租约，请求失败时不调用 fn。
+func (c *Client) RangeLeases(fn func(leaseID string, value interface{}, deadline time.Time) bool) {
+	if fn == nil {
+		return
+	}
+
+	var resp leasesResponse
+	if err := c.call(c.ctx, http.MethodGet, opLeases, nil, &resp); err != nil {
+		return
+	}
+	for i := range resp.Leases {
+		value, err := c.codec.Decode(resp.Leases[i].Value)
+		if err != nil {
+			continue
+		}
+		if !fn(resp.Leases[i].LeaseID, value, resp.Leases[i].Deadline) {
+			return
+		}
+	}
+}
+
+func (c *Client) PutDead(letter *wkq.DeadLetter) error {
+	if letter == nil {
+		return wkq.ErrInvalidDeadLetter
+	}
+	data, err := c.codec.Encode(letter)
+	if err != nil {
+		return err
+	}
+	return c.call(c.ctx, http.MethodPost, opDeadPut, &valueRequest{Value: data}, nil)
+}
+
+func (c *Client) GetDead() (*wkq.DeadLetter, error) {
+	value, err := c.Get()
+	if err != nil {
+		return nil, err
+	}
+	return toDeadLetter(value)
+}
+
+func (c *Client) GetDeadWithContext(ctx context.Context) (*wkq.DeadLetter, error) {
+	value, err := c.GetWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toDeadLetter(value)
+}
+
+func (c *Client) AckDead(letter *wkq.DeadLetter) error {
+	if letter == nil {
+		return wkq.ErrInvalidDeadLetter
+	}
+	data, err := c.codec.Encode(letter)
+	if err != nil {
+		return err
+	}
+	return c.call(c.ctx, http.MethodPost, opDeadAck, &valueRequest{Value: data}, nil)
+}
+
+// RequeueDead 将死信的 Payload 重新投递到 target。target 为同一服务端上的队列客户端时由服务端直接完成，
+// 否则先写入 target 再确认死信。
+func (c *Client) RequeueDead(letter *wkq.DeadLetter, target wkq.Queue) error {
+	if letter == nil {
+		return wkq.ErrInvalidDeadLetter
+	}
+	if target == nil {
+		return wkq.ErrInvalidTargetQueue
+	}
+
+	remote, ok := target.(*Client)
+	if !ok || remote.baseURL != c.baseURL {
+		if err := target.Put(letter.Payload); err != nil {
+			return err
+		}
+		return c.AckDead(letter)
+	}
+
+	data, err := c.codec.Encode(letter)
+	if err != nil {
+		return err
+	}
+	return c.call(c.ctx, http.MethodPost, opDeadRequeue, &requeueDeadRequest{Letter: data, Target: remote.name}, nil)
+}
+
+func (c *Client) RangeDead(fn func(letter *wkq.DeadLetter) bool) {
+	if fn == nil {
+		return
+	}
+	c.Range(func(value interface{}) bool {
+		letter, err := toDeadLetter(value)
+		if err != nil {
+			return true
+		}
+		return fn(letter)
+	})
+}
+
+func toDeadLetter(value interface{}) (*wkq.DeadLetter, error) {
+	letter, ok := value.(*wkq.DeadLetter)
+	if !ok || letter == nil {
+		return nil, wkq.ErrInvalidDeadLetter
+	}
+	return letter, nil
+}
+
+func (c *Client) decodeValues(raw []json.RawMessage) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(raw))
+	for i := range raw {
+		value, err := c.codec.Decode(raw[i])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// poll 以长轮询反复调用 fetch，直至取得元素、出错、ctx 结束或客户端关闭。
+// 每次轮询的等待时间不超过配置的上限与 ctx 的剩余时间。
+func (c *Client) poll(ctx context.Context, fetch func(ctx context.Context, wait time.Duration) (bool, error)) error {
+	bound, cancel := c.bind(ctx)
+	defer cancel()
+
+	for {
+		if bound.Err() != nil {
+			return c.contextErr(ctx)
+		}
+
+		wait := c.config.maxWait
+		if deadline, ok := ctx.Deadline(); ok {
+			if remain := time.Until(deadline); remain < wait {
+				wait = remain
+			}
+		}
+		if wait < time.Millisecond {
+			wait = time.Millisecond
+		}
+
+		found, err := fetch(bound, wait)
+		if err == nil && found {
+			return nil
+		}
+		if bound.Err() != nil {
+			return c.contextErr(ctx)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// bind 返回在 ctx 结束或客户端关闭时取消的 context。
+func (c *Client) bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	bound, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-bound.Done():
+		}
+	}()
+	return bound, cancel
+}
+
+func (c *Client) contextErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return wkq.ErrQueueIsClosed
+}
+
+// call 发送 JSON 请求并将响应解码到 out，out 为空时丢弃响应体。
+func (c *Client) call(ctx context.Context, method, op string, in, out interface{}) error {
+	var (
+		body        io.Reader
+		contentType string
+	)
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	resp, err := c.do(ctx, method, op, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// do 发送请求，非 200 响应会被还原为错误。
+func (c *Client) do(ctx context.Context, method, op, contentType string, body io.Reader) (*http.Response, error) {
+	if c.IsClosed() {
+		return nil, wkq.ErrQueueIsClosed
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+op, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.config.httpClient.Do(req)
+	if err != nil {
+		if c.IsClosed() {
+			return nil, wkq.ErrQueueIsClosed
+		}
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var e errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == nil {
+		return nil, &remoteError{err: ErrRequestFailed, message: "request failed: " + resp.Status}
+	}
+	return nil, fromWireError(e.Error)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// defaultMaxWait 为单次长轮询的默认最长等待时间。
+const defaultMaxWait = 30 * time.Second
+
+// defaultMaxBodySize 为请求体的默认大小上限，恢复较大的快照时需要调大。
+const defaultMaxBodySize = 32 << 20
+
+// defaultVisibilityTimeout 为已投递元素等待 Done 的默认最长时间。
+const defaultVisibilityTimeout = 5 * time.Minute
+
+// Authorizer 在执行操作前校验请求，返回错误时拒绝请求。queue 为队列名称，
+// op 为路径中队列名称之后的部分，例如 "put"、"snapshot"、"restore"、"dead/ack"。
+type Authorizer = func(r *http.Request, queue, op string) error
+
+// Config 定义服务端配置。
+type Config struct {
+	registry          *wkq.CodecRegistry
+	maxWait           time.Duration
+	maxBodySize       int64
+	visibilityTimeout time.Duration
+	authorizer        Authorizer
+	clock             wkq.Clock
+}
+
+// NewConfig 返回带默认值的服务端配置。
+func NewConfig() *Config {
+	return &Config{
+		maxWait:           defaultMaxWait,
+		maxBodySize:       defaultMaxBodySize,
+		visibilityTimeout: defaultVisibilityTimeout,
+		clock:             wkq.NewRealClock(),
+	}
+}
+
+// WithRegistry 设置元素 JSON 编解码使用的类型注册表，未设置时使用 workqueue.RegisterPayloadType 维护的默认注册表。
+func (c *Config) WithRegistry(registry *wkq.CodecRegistry) *Config {
+	c.registry = registry
+
+	return c
+}
+
+// WithMaxWait 设置单次长轮询的最长等待时间，客户端请求更长的等待时间会被截断。
+func (c *Config) WithMaxWait(wait time.Duration) *Config {
+	c.maxWait = wait
+
+	return c
+}
+
+// WithMaxBodySize 设置请求体的大小上限（字节），包括 restore 上传的快照，超出时返回 ErrRequestTooLarge。
+func (c *Config) WithMaxBodySize(size int64) *Config {
+	c.maxBodySize = size
+
+	return c
+}
+
+// WithVisibilityTimeout 设置经 get 投递的元素等待 Done 的最长时间。超时的元素被放回队列并不再登记，
+// 客户端之后对其调用 Done 时按普通元素处理，因此单个元素的处理时间应小于该值。
+// 超时在之后对同一队列的请求中检查。
+func (c *Config) WithVisibilityTimeout(timeout time.Duration) *Config {
+	c.visibilityTimeout = timeout
+
+	return c
+}
+
+// WithAuthorizer 设置请求的校验函数，例如只允许携带管理凭证的请求执行 snapshot 与 restore。
+// 客户端可以通过 ClientConfig.WithHTTPClient 配置的 Transport 附加凭证。
+func (c *Config) WithAuthorizer(authorizer Authorizer) *Config {
+	c.authorizer = authorizer
+
+	return c
+}
+
+// WithClock 设置可见性超时使用的时钟，默认为系统时钟，测试中可以传入 workqueue.FakeClock。
+func (c *Config) WithClock(clock wkq.Clock) *Config {
+	c.clock = clock
+
+	return c
+}
+
+func isConfigEffective(c *Config) *Config {
+	if c != nil {
+		if c.maxWait <= 0 {
+			c.maxWait = defaultMaxWait
+		}
+
+		if c.maxBodySize <= 0 {
+			c.maxBodySize = defaultMaxBodySize
+		}
+
+		if c.visibilityTimeout <= 0 {
+			c.visibilityTimeout = defaultVisibilityTimeout
+		}
+
+		if c.clock == nil {
+			c.clock = wkq.NewRealClock()
+		}
+	} else {
+		c = NewConfig()
+	}
+
+	return c
+}
+
+// ClientConfig 定义客户端配置。
+type ClientConfig struct {
+	httpClient *http.Client
+	registry   *wkq.CodecRegistry
+	maxWait    time.Duration
+}
+
+// NewClientConfig 返回带默认值的客户端配置。
+func NewClientConfig() *ClientConfig {
+	return &ClientConfig{
+		httpClient: http.DefaultClient,
+		maxWait:    defaultMaxWait,
+	}
+}
+
+// WithHTTPClient 设置发送请求使用的 http.Client，其超时时间应大于长轮询的等待时间。
+func (c *ClientConfig) WithHTTPClient(client *http.Client) *ClientConfig {
+	c.httpClient = client
+
+	return c
+}
+
+// WithRegistry 设置元素 JSON 编解码使用的类型注册表，应与服务端注册相同的类型名。
+func (c *ClientConfig) WithRegistry(registry *wkq.CodecRegistry) *ClientConfig {
+	c.registry = registry
+
+	return c
+}
+
+// WithMaxWait 设置单次长轮询的最长等待时间，阻塞读取会按该时长分段轮询。
+func (c *ClientConfig) WithMaxWait(wait time.Duration) *ClientConfig {
+	c.maxWait = wait
+
+	return c
+}
+
+func isClientConfigEffective(c *ClientConfig) *ClientConfig {
+	if c != nil {
+		if c.httpClient == nil {
+			c.httpClient = http.DefaultClient
+		}
+
+		if c.maxWait <= 0 {
+			c.maxWait = defaultMaxWait
+		}
+	} else {
+		c = NewClientConfig()
+	}
+
+	return c
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// pathPrefix 为队列接口的路径前缀，完整路径为 /v1/queues/{name}/{op}。
+const pathPrefix = "/v1/queues/"
+
+// 队列操作名称。
+const (
+	opPut         = "put"
+	opPutBatch    = "put-batch"
+	opGet         = "get"
+	opDone        = "done"
+	opRetry       = "retry"
+	opLease       = "lease"
+	opAck         = "ack"
+	opNack        = "nack"
+	opExtend      = "extend"
+	opLeases      = "leases"
+	opDeadPut     = "dead/put"
+	opDeadAck     = "dead/ack"
+	opDeadRequeue = "dead/requeue"
+	opValues      = "values"
+	opLen         = "len"
+	opStats       = "stats"
+	opSnapshot    = "snapshot"
+	opRestore     = "restore"
+)
+
+// ErrInvalidQueueName 表示注册名称为空。
+var ErrInvalidQueueName = errors.New("invalid queue name")
+
+// ErrQueueAlreadyRegistered 表示同名队列已注册。
+var ErrQueueAlreadyRegistered = errors.New("queue already registered")
+
+// ErrQueueNotFound 表示服务端不存在该名称的队列。
+var ErrQueueNotFound = errors.New("queue not found")
+
+// ErrUnsupportedOperation 表示远程队列的类型不支持该操作。
+var ErrUnsupportedOperation = errors.New("unsupported operation")
+
+// ErrInvalidRequest 表示请求体无法解析。
+var ErrInvalidRequest = errors.New("invalid request")
+
+// ErrRequestTooLarge 表示请求体超过服务端配置的大小上限。
+var ErrRequestTooLarge = errors.New("request too large")
+
+// ErrForbidden 表示请求未通过服务端的校验。
+var ErrForbidden = errors.New("forbidden")
+
+// ErrRequestFailed 表示请求失败且服务端未返回可识别的错误。
+var ErrRequestFailed = errors.New("request failed")
+
+// 请求与响应中的元素均为 workqueue.NewJSONCodec 的编码结果。
+
+type valueRequest struct {
+	Value json.RawMessage `json:"value"`
+}
+
+type valuesRequest struct {
+	Values []json.RawMessage `json:"values"`
+}
+
+type getRequest struct {
+	Max    int   `json:"max"`
+	WaitMS int64 `json:"wait_ms,omitempty"`
+}
+
+type valuesResponse struct {
+	Values []json.RawMessage `json:"values"`
+}
+
+type retryRequest struct {
+	Value  json.RawMessage `json:"value"`
+	Reason string          `json:"reason,omitempty"`
+}
+
+type leaseRequest struct {
+	TimeoutMS int64 `json:"timeout_ms,omitempty"`
+	WaitMS    int64 `json:"wait_ms,omitempty"`
+}
+
+// leaseResponse 中 LeaseID 为空表示等待期间没有可消费元素。
+type leaseResponse struct {
+	Value   json.RawMessage `json:"value,omitempty"`
+	LeaseID string          `json:"lease_id,omitempty"`
+}
+
+type leaseIDRequest struct {
+	LeaseID   string `json:"lease_id"`
+	TimeoutMS int64  `json:"timeout_ms,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type leaseEntry struct {
+	LeaseID  string          `json:"lease_id"`
+	Value    json.RawMessage `json:"value"`
+	Deadline time.Time       `json:"deadline"`
+}
+
+type leasesResponse struct {
+	Leases []leaseEntry `json:"leases"`
+}
+
+type requeueDeadRequest struct {
+	Letter json.RawMessage `json:"letter"`
+	Target string          `json:"target"`
+}
+
+type lenResponse struct {
+	Len int `json:"len"`
+}
+
+type errorResponse struct {
+	Error *wireError `json:"error"`
+}
+
+// wireError 为错误的传输形式，Errors 仅在批量操作部分失败时出现，与输入按下标对应。
+type wireError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Errors  []*wireError `json:"errors,omitempty"`
+}
+
+const (
+	codeBatchFailed = "batch_failed"
+	codeInternal    = "internal"
+)
+
+// wireErrors 为可在两端还原的错误，code 为传输中的稳定标识。
+var wireErrors = []struct {
+	code   string
+	err    error
+	status int
+}{
+	{"queue_not_found", ErrQueueNotFound, http.StatusNotFound},
+	{"unsupported_operation", ErrUnsupportedOperation, http.StatusBadRequest},
+	{"invalid_request", ErrInvalidRequest, http.StatusBadRequest},
+	{"request_too_large", ErrRequestTooLarge, http.StatusRequestEntityTooLarge},
+	{"forbidden", ErrForbidden, http.StatusForbidden},
+	{"queue_closed", wkq.ErrQueueIsClosed, http.StatusServiceUnavailable},
+	{"queue_draining", wkq.ErrQueueIsDraining, http.StatusServiceUnavailable},
+	{"queue_empty", wkq.ErrQueueIsEmpty, http.StatusNotFound},
+	{"queue_full", wkq.ErrQueueIsFull, http.StatusConflict},
+	{"element_nil", wkq.ErrElementIsNil, http.StatusBadRequest},
+	{"element_exists", wkq.ErrElementAlreadyExist, http.StatusConflict},
+	{"element_not_hashable", wkq.ErrElementNotHashable, http.StatusBadRequest},
+	{"unknown_codec_type", wkq.ErrUnknownCodecType, http.StatusBadRequest},
+	{"unsupported_codec_value", wkq.ErrUnsupportedCodecValue, http.StatusBadRequest},
+	{"invalid_batch_size", wkq.ErrInvalidBatchSize, http.StatusBadRequest},
+	{"invalid_lease_duration", wkq.ErrInvalidLeaseDuration, http.StatusBadRequest},
+	{"lease_not_found", wkq.ErrLeaseNotFound, http.StatusNotFound},
+	{"retry_exhausted", wkq.ErrRetryExhausted, http.StatusConflict},
+	{"retry_key_empty", wkq.ErrRetryKeyEmpty, http.StatusBadRequest},
+	{"invalid_dead_letter", wkq.ErrInvalidDeadLetter, http.StatusBadRequest},
+	{"invalid_target_queue", wkq.ErrInvalidTargetQueue, http.StatusBadRequest},
+	{"invalid_snapshot", wkq.ErrInvalidSnapshot, http.StatusBadRequest},
+	{"wal_closed", wkq.ErrWALIsClosed, http.StatusServiceUnavailable},
+}
+
+// toWireError 将错误转换为传输形式及对应的 HTTP 状态码。
+func toWireError(err error) (*wireError, int) {
+	var batchErr *wkq.BatchError
+	if errors.As(err, &batchErr) {
+		errs := make([]*wireError, len(batchErr.Errors))
+		for i, e := range batchErr.Errors {
+			if e != nil {
+				errs[i], _ = toWireError(e)
+			}
+		}
+		return &wireError{Code: codeBatchFailed, Message: err.Error(), Errors: errs}, http.StatusUnprocessableEntity
+	}
+
+	for i := range wireErrors {
+		if errors.Is(err, wireErrors[i].err) {
+			return &wireError{Code: wireErrors[i].code, Message: err.Error()}, wireErrors[i].status
+		}
+	}
+	return &wireError{Code: codeInternal, Message: err.Error()}, http.StatusInternalServerError
+}
+
+// fromWireError 将传输形式还原为错误，可识别的错误仍可用 errors.Is 匹配。
+func fromWireError(e *wireError) error {
+	if e == nil {
+		return nil
+	}
+
+	if e.Code == codeBatchFailed {
+		errs := make([]error, len(e.Errors))
+		for i := range e.Errors {
+			errs[i] = fromWireError(e.Errors[i])
+		}
+		return &wkq.BatchError{Errors: errs}
+	}
+
+	for i := range wireErrors {
+		if e.Code == wireErrors[i].code {
+			// 消息与哨兵错误一致时直接返回哨兵错误，否则保留服务端附加的上下文。
+			if e.Message == wireErrors[i].err.Error() {
+				return wireErrors[i].err
+			}
+			return &remoteError{err: wireErrors[i].err, message: e.Message}
+		}
+	}
+	return &remoteError{err: ErrRequestFailed, message: e.Message}
+}
+
+// remoteError 保留服务端的错误消息，并可用 errors.Is 匹配对应的哨兵错误。
+type remoteError struct {
+	err     error
+	message string
+}
+
+func (e *remoteError) Error() string { return e.message }
+
+func (e *remoteError) Unwrap() error { return e.err }
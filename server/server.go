@@ -0,0 +1,663 @@
+// Package server 以 HTTP/JSON 暴露一组具名的 workqueue 队列，并提供实现 workqueue 队列接口的客户端，
+// 使多个进程可以共享同一个队列。仅依赖标准库。
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+)
+
+// endpoint 为一个已注册的队列。
+// 通过 Get 投递出去的元素按编码结果登记，Done 等操作据此找回原始元素，
+// 使依赖元素身份的队列（如以指针判重的幂等队列、死信队列）在远程调用下行为不变。
+type endpoint struct {
+	queue wkq.Queue
+
+	lock      sync.Mutex
+	delivered map[string][]delivery
+	swept     time.Time
+}
+
+// delivery 为一个已投递的元素及其投递时刻。
+type delivery struct {
+	value interface{}
+	at    time.Time
+}
+
+// deliver 登记一个已投递的元素。同一 key 的登记按投递时刻先后排列。
+func (e *endpoint) deliver(key string, value interface{}, now time.Time) {
+	e.lock.Lock()
+	e.delivered[key] = append(e.delivered[key], delivery{value: value, at: now})
+	e.lock.Unlock()
+}
+
+// claim 取回最早投递且编码为 key 的元素，未找到时返回 fallback。
+func (e *endpoint) claim(key string, fallback interface{}) interface{} {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	deliveries, ok := e.delivered[key]
+	if !ok {
+		return fallback
+	}
+	value := deliveries[0].value
+	if len(deliveries) == 1 {
+		delete(e.delivered, key)
+	} else {
+		deliveries[0] = delivery{}
+		e.delivered[key] = deliveries[1:]
+	}
+	return value
+}
+
+// expire 移除投递时间超过 timeout 的登记并返回对应的元素。登记表按 key 组织，
+// 检查需要遍历全部登记，因此两次检查至少间隔 timeout 的四分之一。
+func (e *endpoint) expire(now time.Time, timeout time.Duration) []interface{} {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if now.Sub(e.swept) < timeout/4 {
+		return nil
+	}
+	e.swept = now
+
+	var expired []interface{}
+	for key, deliveries := range e.delivered {
+		n := 0
+		for n < len(deliveries) && now.Sub(deliveries[n].at) >= timeout {
+			expired = append(expired, deliveries[n].value)
+			deliveries[n] = delivery{}
+			n++
+		}
+		switch {
+		case n == len(deliveries):
+			delete(e.delivered, key)
+		case n > 0:
+			e.delivered[key] = deliveries[n:]
+		}
+	}
+	return expired
+}
+
+// Server 以 HTTP/JSON 暴露已注册的队列，路径为 /v1/queues/{name}/{op}，可并发使用。
+// 元素使用 workqueue.NewJSONCodec 编码，自定义类型需在两端以相同名称注册。
+type Server struct {
+	config *Config
+	codec  wkq.Codec
+
+	lock   sync.RWMutex
+	queues map[string]*endpoint
+}
+
+// NewServer 创建服务端。
+func NewServer(config *Config) *Server {
+	config = isConfigEffective(config)
+
+	return &Server{
+		config: config,
+		codec:  wkq.NewJSONCodec(config.registry),
+		queues: make(map[string]*endpoint),
+	}
+}
+
+// Register 以 name 注册队列。队列的生命周期仍由调用方管理，关闭前应先移除。
+func (s *Server) Register(name string, queue wkq.Queue) error {
+	if name == "" {
+		return ErrInvalidQueueName
+	}
+	if queue == nil {
+		return wkq.ErrQueueIsNil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.queues[name]; ok {
+		return ErrQueueAlreadyRegistered
+	}
+	s.queues[name] = &endpoint{queue: queue, delivered: make(map[string][]delivery)}
+	return nil
+}
+
+// Unregister 移除已注册的队列。
+func (s *Server) Unregister(name string) {
+	s.lock.Lock()
+	delete(s.queues, name)
+	s.lock.Unlock()
+}
+
+func (s *Server) lookup(name string) (*endpoint, bool) {
+	s.lock.RLock()
+	e, ok := s.queues[name]
+	s.lock.RUnlock()
+	return e, ok
+}
+
+// route 描述一个操作的请求方法与处理函数。
+type route struct {
+	method string
+	handle func(s *Server, e *endpoint, r *http.Request) (interface{}, error)
+}
+
+var routes = map[string]route{
+	opPut:         {http.MethodPost, (*Server).put},
+	opPutBatch:    {http.MethodPost, (*Server).putBatch},
+	opGet:         {http.MethodPost, (*Server).get},
+	opDone:        {http.MethodPost, (*Server).done},
+	opRetry:       {http.MethodPost, (*Server).retry},
+	opLease:       {http.MethodPost, (*Server).lease},
+	opAck:         {http.MethodPost, (*Server).ack},
+	opNack:        {http.MethodPost, (*Server).nack},
+	opExtend:      {http.MethodPost, (*Server).extend},
+	opLeases:      {http.MethodGet, (*Server).leases},
+	opDeadPut:     {http.MethodPost, (*Server).putDead},
+	opDeadAck:     {http.MethodPost, (*Server).ackDead},
+	opDeadRequeue: {http.MethodPost, (*Server).requeueDead},
+	opValues:      {http.MethodGet, (*Server).values},
+	opLen:         {http.MethodGet, (*Server).length},
+	opStats:       {http.MethodGet, (*Server).stats},
+	opSnapshot:    {http.MethodGet, (*Server).snapshot},
+	opRestore:     {http.MethodPost, (*Server).restore},
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 队列名称可能包含转义后的斜杠，需从未解码的路径中切分。
+	path := r.URL.EscapedPath()
+	if !strings.HasPrefix(path, pathPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	escaped, op, ok := strings.Cut(strings.TrimPrefix(path, pathPrefix), "/")
+	name, err := url.PathUnescape(escaped)
+	rt, known := routes[op]
+	if !ok || err != nil || !known {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != rt.method {
+		w.Header().Set("Allow", rt.method)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	e, ok := s.lookup(name)
+	if !ok {
+		writeError(w, ErrQueueNotFound)
+		return
+	}
+	if s.config.authorizer != nil {
+		if err := s.config.authorizer(r, name, op); err != nil {
+			if !errors.Is(err, ErrForbidden) {
+				err = fmt.Errorf("%w: %v", ErrForbidden, err)
+			}
+			writeError(w, err)
+			return
+		}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.config.maxBodySize)
+	s.expire(e)
+
+	resp, err := rt.handle(s, e, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if data, ok := resp.([]byte); ok {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(data)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e, status := toWireError(err)
+	writeJSON(w, status, &errorResponse{Error: e})
+}
+
+func readRequest(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if err = bodyError(err); errors.Is(err, ErrRequestTooLarge) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	return nil
+}
+
+// bodyError 将读取请求体时超出大小上限的错误转换为 ErrRequestTooLarge，其他错误原样返回。
+func bodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("%w: limit is %d bytes", ErrRequestTooLarge, tooLarge.Limit)
+	}
+	return err
+}
+
+// expire 将投递后超过可见性超时仍未 Done 的元素放回队列。
+func (s *Server) expire(e *endpoint) {
+	for _, value := range e.expire(s.config.clock.Now(), s.config.visibilityTimeout) {
+		_ = requeue(e.queue, value)
+	}
+}
+
+// decode 解码请求中的元素，并返回服务端对该元素的编码作为登记 key，使 key 不受客户端 JSON 格式影响。
+func (s *Server) decode(raw json.RawMessage) (value interface{}, key string, err error) {
+	if len(raw) == 0 {
+		return nil, "", wkq.ErrElementIsNil
+	}
+	value, err = s.codec.Decode(raw)
+	if err != nil {
+		if !errors.Is(err, wkq.ErrUnknownCodecType) {
+			err = fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		return nil, "", err
+	}
+	if value == nil {
+		return nil, "", wkq.ErrElementIsNil
+	}
+	data, err := s.codec.Encode(value)
+	if err != nil {
+		return nil, "", err
+	}
+	return value, string(data), nil
+}
+
+// waitOf 将请求的等待毫秒数截断到配置的上限。
+func (s *Server) waitOf(ms int64) time.Duration {
+	wait := time.Duration(ms) * time.Millisecond
+	if wait > s.config.maxWait {
+		wait = s.config.maxWait
+	}
+	return wait
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+func (s *Server) put(e *endpoint, r *http.Request) (interface{}, error) {
+	var req valueRequest
+	if err := readRequest(r, &req); err != nil {
+		return nil, err
+	}
+	value, _, err := s.decode(req.Value)
+	if err != nil {
+		return nil, err
+	}
+	return struct{}{}, e.queue.Put(value)
+}
+
+func (s *Server) putBatch(e *endpoint, r *http.Request) (interface{}, error) {
+	var req valuesRequest
+	if err := readRequest(r, &req); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(req.Values))
+	for i := range req.Values {
+		// 空元素交由队列按下标报告 ErrElementIsNil。
+		value, _, err := s.decode(req.Values[i])
+		if err != nil && !errors.Is(err, wkq.ErrElementIsNil) {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return struct{}{}, e.queue.PutBatch(values)
+}
+
+func (s *Server) get(e *endpoint, r *http.Request) (interface{}, error) {
+	var req getRequest
+	if err := readRequest(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Max <= 0 {
+		return nil, wkq.ErrInvalidBatchSize
+	}
+
+	var (
+		values []interface{}
+		err    error
+	)
+	if wait := s.waitOf(req.WaitMS); wait > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		values, err = getWithContext(ctx, e.queue, req.Max)
+		cancel()
+	} else {
+		values, err = getNow(e.queue, req.Max)
+	}
+	if isContextError(err) || errors.Is(err, wkq.ErrQueueIsEmpty) {
+		return &valuesResponse{Values: []json.RawMessage{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &valuesResponse{Values: make([]json.RawMessage, 0, len(values))}
+	keys := make([]string, 0, len(values))
+	for _, value := range values {
+		data, err := s.codec.Encode(value)
+		if err != nil {
+			requeueAll(e.queue, values)
+			return nil, err
+		}
+		resp.Values = append(resp.Values, data)
+		keys = append(keys, string(data))
+	}
+
+	// 客户端已断开时元素无法送达，放回队列以免丢失。
+	if r.Context().Err() != nil {
+		requeueAll(e.queue, values)
+		return nil, r.Context().Err()
+	}
+
+	now := s.config.clock.Now()
+	for i, value := range values {
+		e.deliver(keys[i], value, now)
+	}
+	return resp, nil
+}
+
+// getNow 非阻塞读取，单个元素时走 Get 以保留具体队列对 Get 的定制。
+func getNow(queue wkq.Queue, max int) ([]interface{}, error) {
+	if max == 1 {
+		value, err := queue.Get()
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{value}, nil
+	}
+	return queue.GetBatch(max)
+}
+
+func getWithContext(ctx context.Context, queue wkq.Queue, max int) ([]interface{}, error) {
+	if max == 1 {
+		value, err := queue.GetWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{value}, nil
+	}
+	return queue.GetBatchWithContext(ctx, max)
+}
+
+// requeue 结束元素的处理并放回队列，排空期间同样允许。非本包创建的队列退化为先 Done 再 Put。
+func requeue(queue wkq.Queue, value interface{}) error {
+	err := wkq.Requeue(queue, value)
+	if errors.Is(err, wkq.ErrUnsupportedQueue) {
+		queue.Done(value)
+		err = queue.Put(value)
+	}
+	return err
+}
+
+// requeueAll 放回一组未能送达的元素。
+func requeueAll(queue wkq.Queue, values []interface{}) {
+	for _, value := range values {
+		_ = requeue(queue, value)
+	}
+}
+
+func (s *Server) done(e *endpoint, r *http.Request) (interface{}, error) {
+	var req valueRequest
+	if err := readRequest(r, &req); err != nil {
+		return nil, err
+	}
+	value, key, err := s.decode(req.Value)
+	if err != nil {
+		return nil, err
+	}
+	e.queue.Done(e.claim(key, value))
+	return struct{}{}, nil
+}
+
+func (s *Server) retry(e *endpoint, r *http.Request) (interface{}, error) {
+	queue, ok := e.queue.(wkq.RetryQueue)
+	if !ok {
+		return nil, ErrUnsupportedOperation
+	}
+
+	var req retryRequest
+	if err := readRequest(r, &req); err != nil {
+		return nil, err
+	}
+	value, key, err := s.decode(req.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	var reason error
+	if req.Reason != "" {
+		reason = errors.New(req.Reason)
+	}
+
+	value = e.claim(key, value)
+	if err := queue.Retry(value, reason); err != nil {
+		// 重试失败时元素仍由客户端持有，重新登记以便随后的 Done 找回原始元素。
+		e.deliver(key, value, s.config.clock.Now())
+		return nil, err
+	}
+	return struct{}{}, nil
+}
+
+func (s *Server) lease(e *endpoint, r *http.Request) (interface{}, error) {
+	queue, ok := e.queue.(wkq.LeasedQueue)
+	if !ok {
+		return nil, ErrUnsupportedOperation
+	}
+
+	var req leaseRequest
+	if err := readRequest(r, &req); err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(req.TimeoutMS) * time.Millisecond
+
+	var (
+		value   interface{}
+		leaseID string
+		err     error
+	)
+	if wait := s.waitOf(req.WaitMS); wait > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		value, leaseID, err = queue.GetWithLeaseContext(ctx, timeout)
+		cancel()
+	} else {
+		value, leaseID, err = queue.GetWithLease(timeout)
+	}
+	if isContextError(err) || errors.Is(err, wkq.ErrQueueIsEmpty) {
+		return &leaseResponse{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.codec.Encode(value)
+	if err == nil {
+		err = r.Context().Err()
+	}
+	if err != nil {
+		_ = queue.Nack(leaseID, err)
+		return nil, err
+	}
+	return &leaseResponse{Value: data, LeaseID: leaseID}, nil
+}
+
+func (s *Server) leaseOf(e *endpoint, r *http.Request) (wkq.LeasedQueue, *leaseIDRequest, error) {
+	queue, ok := e.queue.(wkq.LeasedQueue)
+	if !ok {
+		return nil, nil, ErrUnsupportedOperation
+	}
+
+	var req leaseIDRequest
+	if err := readRequest(r, &req); err != nil {
+		return nil, nil, err
+	}
+	return queue, &req, nil
+}
+
+func (s *Server) ack(e *endpoint, r *http.Request) (interface{}, error) {
+	queue, req, err := s.leaseOf(e, r)
+	if err != nil {
+		return nil, err
+	}
+	return struct{}{}, queue.Ack(req.LeaseID)
+}
+
+func (s *Server) nack(e *endpoint, r *http.Request) (interface{}, error) {
+	queue, req, err := s.leaseOf(e, r)
+	if err != nil {
+		return nil, err
+	}
+	var reason error
+	if req.Reason != "" {
+		reason = errors.New(req.Reason)
+	}
+	return struct{}{}, queue.Nack(req.LeaseID, reason)
+}
+
+func (s *Server) extend(e *endpoint, r *http.Request) (interface{}, error) {
+	queue, req, err := s.leaseOf(e, r)
+	if err != nil {
+		return nil, err
+	}
+	return struct{}{}, queue.ExtendLease(req.LeaseID, time.Duration(req.TimeoutMS)*time.Millisecond)
+}
+
+func (s *Server) leases(e *endpoint, _ *http.Request) (interface{}, error) {
+	queue, ok := e.queue.(wkq.LeasedQueue)
+	if !ok {
+		return nil, ErrUnsupportedOperation
+	}
+
+	var err error
+	resp := &leasesResponse{Leases: []leaseEntry{}}
+	queue.RangeLeases(func(leaseID string, value interface{}, deadline time.Time) bool {
+		var data []byte
+		if data, err = s.codec.Encode(value); err != nil {
+			return false
+		}
+		resp.Leases = append(resp.Leases, leaseEntry{LeaseID: leaseID, Value: data, Deadline: deadline})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// letterOf 解码请求中的死信，并尽量找回投递出去的原始死信。
+func (s *Server) letterOf(e *endpoint, raw json.RawMessage) (wkq.DeadLetterQueue, *wkq.DeadLetter, string, error) {
+	queue, ok := e.queue.(wkq.DeadLetterQueue)
+	if !ok {
+		return nil, nil, "", ErrUnsupportedOperation
+	}
+
+	value, key, err := s.decode(raw)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	letter, ok := value.(*wkq.DeadLetter)
+	if !ok {
+		return nil, nil, "", wkq.ErrInvalidDeadLetter
+	}
+	return queue, letter, key, nil
+}
+
+func (s *Server) putDead(e *endpoint, r *http.Request) (interface{}, error) {
+	var req valueRequest
+	if err := readRequest(r, &req); err != nil {
+		return nil, err
+	}
+	queue, letter, _, err := s.letterOf(e, req.Value)
+	if err != nil {
+		return nil, err
+	}
+	return struct{}{}, queue.PutDead(letter)
+}
+
+func (s *Server) ackDead(e *endpoint, r *http.Request) (interface{}, error) {
+	var req valueRequest
+	if err := readRequest(r, &req); err != nil {
+		return nil, err
+	}
+	queue, letter, key, err := s.letterOf(e, req.Value)
+	if err != nil {
+		return nil, err
+	}
+	return struct{}{}, queue.AckDead(e.claim(key, letter).(*wkq.DeadLetter))
+}
+
+func (s *Server) requeueDead(e *endpoint, r *http.Request) (interface{}, error) {
+	var req requeueDeadRequest
+	if err := readRequest(r, &req); err != nil {
+		return nil, err
+	}
+	queue, letter, key, err := s.letterOf(e, req.Letter)
+	if err != nil {
+		return nil, err
+	}
+	target, ok := s.lookup(req.Target)
+	if !ok {
+		return nil, wkq.ErrInvalidTargetQueue
+	}
+
+	letter = e.claim(key, letter).(*wkq.DeadLetter)
+	if err := queue.RequeueDead(letter, target.queue); err != nil {
+		e.deliver(key, letter, s.config.clock.Now())
+		return nil, err
+	}
+	return struct{}{}, nil
+}
+
+func (s *Server) values(e *endpoint, _ *http.Request) (interface{}, error) {
+	values := e.queue.Values()
+	resp := &valuesResponse{Values: make([]json.RawMessage, 0, len(values))}
+	for _, value := range values {
+		data, err := s.codec.Encode(value)
+		if err != nil {
+			return nil, err
+		}
+		resp.Values = append(resp.Values, data)
+	}
+	return resp, nil
+}
+
+func (s *Server) length(e *endpoint, _ *http.Request) (interface{}, error) {
+	return &lenResponse{Len: e.queue.Len()}, nil
+}
+
+func (s *Server) stats(e *endpoint, _ *http.Request) (interface{}, error) {
+	stats := e.queue.Stats()
+	return &stats, nil
+}
+
+// snapshot 先在内存中生成完整快照，失败时仍能以 JSON 返回错误。
+func (s *Server) snapshot(e *endpoint, _ *http.Request) (interface{}, error) {
+	var buf bytes.Buffer
+	if err := e.queue.Snapshot(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Server) restore(e *endpoint, r *http.Request) (interface{}, error) {
+	if err := e.queue.Restore(r.Body); err != nil {
+		return nil, bodyError(err)
+	}
+	return struct{}{}, nil
+}
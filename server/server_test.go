@@ -0,0 +1,418 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOrder struct {
+	ID    string
+	Total int
+}
+
+func newTestServer(t *testing.T, queues map[string]wkq.Queue) (*Server, string) {
+	t.Helper()
+
+	return newTestServerWithConfig(t, NewConfig(), queues)
+}
+
+func newTestServerWithConfig(t *testing.T, config *Config, queues map[string]wkq.Queue) (*Server, string) {
+	t.Helper()
+
+	s := NewServer(config.WithMaxWait(200 * time.Millisecond))
+	for name, queue := range queues {
+		assert.NoError(t, s.Register(name, queue))
+	}
+
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts.URL
+}
+
+func TestServer_Register(t *testing.T) {
+	q := wkq.NewQueue(nil)
+	defer q.Shutdown()
+
+	s := NewServer(nil)
+	assert.NoError(t, s.Register("orders", q))
+	assert.ErrorIs(t, s.Register("orders", q), ErrQueueAlreadyRegistered)
+	assert.ErrorIs(t, s.Register("", q), ErrInvalidQueueName)
+	assert.ErrorIs(t, s.Register("nil", nil), wkq.ErrQueueIsNil)
+
+	s.Unregister("orders")
+	assert.NoError(t, s.Register("orders", q))
+
+	req := httptest.NewRequest(http.MethodGet, pathPrefix+"orders/put", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, pathPrefix+"orders/unknown", nil)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestClient_Queue(t *testing.T) {
+	q := wkq.NewQueue(wkq.NewQueueConfig().WithValueIdempotent().WithMetricsProvider(wkq.NewNopMetricsProvider()))
+	defer q.Shutdown()
+	_, url := newTestServer(t, map[string]wkq.Queue{"orders": q})
+
+	c := NewClient(url, "orders", nil)
+	defer c.Shutdown()
+
+	assert.NoError(t, c.Put("test1"))
+	assert.NoError(t, c.PutBatch([]interface{}{"test2", "test3"}))
+	assert.ErrorIs(t, c.Put(nil), wkq.ErrElementIsNil)
+	assert.Equal(t, 3, c.Len())
+	assert.Equal(t, []interface{}{"test1", "test2", "test3"}, c.Values())
+
+	v, err := c.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", v)
+	assert.Equal(t, 1, q.Stats().InFlight)
+	c.Done(v)
+	assert.Equal(t, 0, q.Stats().InFlight)
+
+	values, err := c.GetBatch(5)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"test2", "test3"}, values)
+	for _, value := range values {
+		c.Done(value)
+	}
+
+	_, err = c.Get()
+	assert.ErrorIs(t, err, wkq.ErrQueueIsEmpty)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(3), stats.Adds)
+	assert.Equal(t, uint64(3), stats.WorkDuration.Count)
+
+	// 长轮询跨越多个服务端等待周期。
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		_ = q.Put("late")
+	}()
+	v, err = c.GetBlocking()
+	assert.NoError(t, err)
+	assert.Equal(t, "late", v)
+	c.Done(v)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = c.GetWithContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	missing := NewClient(url, "missing", nil)
+	defer missing.Shutdown()
+	assert.ErrorIs(t, missing.Put("test"), ErrQueueNotFound)
+	_, _, err = c.GetWithLease(time.Second)
+	assert.ErrorIs(t, err, ErrUnsupportedOperation)
+
+	// 关闭客户端会中止阻塞读取，远程队列不受影响。
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetBlocking()
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	c.Shutdown()
+	assert.ErrorIs(t, <-done, wkq.ErrQueueIsClosed)
+	assert.True(t, c.IsClosed())
+	assert.ErrorIs(t, c.Put("test"), wkq.ErrQueueIsClosed)
+	assert.False(t, q.IsClosed())
+}
+
+func TestClient_PutBatchError(t *testing.T) {
+	q := wkq.NewQueue(wkq.NewQueueConfig().WithValueIdempotent())
+	defer q.Shutdown()
+	_, url := newTestServer(t, map[string]wkq.Queue{"orders": q})
+
+	c := NewClient(url, "orders", nil)
+	defer c.Shutdown()
+
+	assert.NoError(t, c.Put("test1"))
+	assert.ErrorIs(t, c.Put("test1"), wkq.ErrElementAlreadyExist)
+
+	err := c.PutBatch([]interface{}{"test2", nil, "test1", make(chan int)})
+	var batchErr *wkq.BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.NoError(t, batchErr.Errors[0])
+	assert.ErrorIs(t, batchErr.Errors[1], wkq.ErrElementIsNil)
+	assert.ErrorIs(t, batchErr.Errors[2], wkq.ErrElementAlreadyExist)
+	assert.Error(t, batchErr.Errors[3])
+	assert.Equal(t, 2, q.Len())
+}
+
+func TestClient_RegisteredType(t *testing.T) {
+	registry := wkq.NewCodecRegistry()
+	assert.NoError(t, registry.Register("order", &testOrder{}))
+
+	// 以指针判重的幂等队列依赖元素身份，Done 需要找回投递出去的原始指针。
+	q := wkq.NewQueue(wkq.NewQueueConfig().WithValueIdempotent())
+	defer q.Shutdown()
+
+	s := NewServer(NewConfig().WithRegistry(registry))
+	assert.NoError(t, s.Register("orders", q))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "orders", NewClientConfig().WithRegistry(registry))
+	defer c.Shutdown()
+
+	order := &testOrder{ID: "o1", Total: 42}
+	assert.NoError(t, q.Put(order))
+
+	v, err := c.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, order, v)
+	assert.NotSame(t, order, v)
+
+	c.Done(v)
+	assert.Equal(t, 0, q.Stats().InFlight)
+	assert.NoError(t, q.Put(order))
+}
+
+func TestClient_LeasedQueue(t *testing.T) {
+	q := wkq.NewLeasedQueue(wkq.NewLeasedQueueConfig().WithScanInterval(10 * time.Millisecond))
+	defer q.Shutdown()
+	_, url := newTestServer(t, map[string]wkq.Queue{"jobs": q})
+
+	c := NewClient(url, "jobs", nil)
+	defer c.Shutdown()
+
+	_, _, err := c.GetWithLease(time.Second)
+	assert.ErrorIs(t, err, wkq.ErrQueueIsEmpty)
+
+	assert.NoError(t, c.Put("test1"))
+	v, leaseID, err := c.GetWithLease(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", v)
+	assert.NotEmpty(t, leaseID)
+
+	assert.NoError(t, c.ExtendLease(leaseID, time.Second))
+	assert.NoError(t, c.Nack(leaseID, errors.New("boom")))
+	assert.ErrorIs(t, c.Ack(leaseID), wkq.ErrLeaseNotFound)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	v, leaseID, err = c.GetWithLeaseContext(ctx, 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", v)
+
+	// 租约过期后元素被重新投递。
+	v, second, err := c.GetWithLeaseContext(ctx, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", v)
+	assert.NotEqual(t, leaseID, second)
+	assert.ErrorIs(t, c.Ack(leaseID), wkq.ErrLeaseNotFound)
+	assert.NoError(t, c.Ack(second))
+	assert.Equal(t, 0, q.Stats().InFlight)
+}
+
+func TestClient_DeadLetterQueue(t *testing.T) {
+	dlq := wkq.NewDeadLetterQueue(nil)
+	defer dlq.Shutdown()
+	q := wkq.NewQueue(nil)
+	defer q.Shutdown()
+	other := wkq.NewQueue(nil)
+	defer other.Shutdown()
+	_, url := newTestServer(t, map[string]wkq.Queue{"dead": dlq, "orders": q})
+
+	c := NewClient(url, "dead", nil)
+	defer c.Shutdown()
+	target := NewClient(url, "orders", nil)
+	defer target.Shutdown()
+
+	assert.NoError(t, c.PutDead(&wkq.DeadLetter{Payload: "bad1", LastError: "boom", Attempts: 3}))
+	assert.NoError(t, c.PutDead(&wkq.DeadLetter{Payload: "bad2"}))
+	assert.NoError(t, c.PutDead(&wkq.DeadLetter{Payload: "bad3"}))
+	assert.ErrorIs(t, c.PutDead(&wkq.DeadLetter{}), wkq.ErrElementIsNil)
+	assert.ErrorIs(t, target.PutDead(&wkq.DeadLetter{Payload: "bad"}), ErrUnsupportedOperation)
+
+	var payloads []interface{}
+	c.RangeDead(func(letter *wkq.DeadLetter) bool {
+		payloads = append(payloads, letter.Payload)
+		return true
+	})
+	assert.Equal(t, []interface{}{"bad1", "bad2", "bad3"}, payloads)
+
+	letter, err := c.GetDead()
+	assert.NoError(t, err)
+	assert.Equal(t, "bad1", letter.Payload)
+	assert.Equal(t, "boom", letter.LastError)
+	assert.Equal(t, 3, letter.Attempts)
+	assert.NotEmpty(t, letter.ID)
+	assert.NoError(t, c.AckDead(letter))
+
+	// 目标为同一服务端上的队列时由服务端完成重放。
+	letter, err = c.GetDeadWithContext(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, c.RequeueDead(letter, target))
+	assert.Equal(t, []interface{}{"bad2"}, q.Values())
+
+	// 其他队列先写入目标再确认死信。
+	letter, err = c.GetDead()
+	assert.NoError(t, err)
+	assert.NoError(t, c.RequeueDead(letter, other))
+	assert.Equal(t, []interface{}{"bad3"}, other.Values())
+
+	assert.Equal(t, 0, dlq.Len())
+	assert.Equal(t, 0, dlq.Stats().InFlight)
+}
+
+func TestClient_Retry(t *testing.T) {
+	q := wkq.NewRetryQueue(wkq.NewRetryQueueConfig().WithPolicy(wkq.NewExponentialRetryPolicy(time.Millisecond, time.Millisecond, 1)))
+	defer q.Shutdown()
+	_, url := newTestServer(t, map[string]wkq.Queue{"jobs": q})
+
+	c := NewClient(url, "jobs", nil)
+	defer c.Shutdown()
+
+	assert.NoError(t, c.Put("test1"))
+	v, err := c.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, c.Retry(v, errors.New("boom")))
+
+	v, err = c.GetBlocking()
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", v)
+	assert.ErrorIs(t, c.Retry(v, errors.New("boom")), wkq.ErrRetryExhausted)
+	c.Done(v)
+	assert.Equal(t, 0, q.Stats().InFlight)
+
+	plain := NewClient(url, "missing", nil)
+	defer plain.Shutdown()
+	assert.ErrorIs(t, plain.Retry("test", nil), ErrQueueNotFound)
+}
+
+func TestClient_SnapshotRestore(t *testing.T) {
+	q := wkq.NewDelayingQueue(nil)
+	defer q.Shutdown()
+	restored := wkq.NewDelayingQueue(nil)
+	defer restored.Shutdown()
+	_, url := newTestServer(t, map[string]wkq.Queue{"src": q, "dst": restored})
+
+	src := NewClient(url, "src", nil)
+	defer src.Shutdown()
+	dst := NewClient(url, "dst", nil)
+	defer dst.Shutdown()
+
+	assert.NoError(t, q.Put("test1"))
+	assert.NoError(t, q.PutWithDelay("test2", 60000))
+
+	var buf bytes.Buffer
+	assert.NoError(t, src.Snapshot(&buf))
+	assert.NoError(t, dst.Restore(&buf))
+	assert.Equal(t, []interface{}{"test1"}, restored.Values())
+
+	var delayed []interface{}
+	restored.HeapRange(func(value interface{}, _ int64) bool {
+		delayed = append(delayed, value)
+		return true
+	})
+	assert.Equal(t, []interface{}{"test2"}, delayed)
+
+	assert.ErrorIs(t, dst.Restore(bytes.NewReader([]byte("garbage"))), ErrRequestFailed)
+}
+
+func TestServer_MaxBodySize(t *testing.T) {
+	q := wkq.NewQueue(nil)
+	defer q.Shutdown()
+	_, url := newTestServerWithConfig(t, NewConfig().WithMaxBodySize(64), map[string]wkq.Queue{"orders": q})
+
+	c := NewClient(url, "orders", nil)
+	defer c.Shutdown()
+
+	large := string(bytes.Repeat([]byte("x"), 128))
+	assert.NoError(t, c.Put("small"))
+	assert.ErrorIs(t, c.Put(large), ErrRequestTooLarge)
+
+	src := wkq.NewQueue(nil)
+	defer src.Shutdown()
+	assert.NoError(t, src.Put(large))
+	var buf bytes.Buffer
+	assert.NoError(t, src.Snapshot(&buf))
+	assert.ErrorIs(t, c.Restore(&buf), ErrRequestTooLarge, "Restore should be limited too")
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestServer_VisibilityTimeout(t *testing.T) {
+	q := wkq.NewQueue(wkq.NewQueueConfig().WithValueIdempotent())
+	defer q.Shutdown()
+	clock := wkq.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s, url := newTestServerWithConfig(t, NewConfig().WithVisibilityTimeout(time.Minute).WithClock(clock), map[string]wkq.Queue{"orders": q})
+
+	c := NewClient(url, "orders", nil)
+	defer c.Shutdown()
+
+	assert.NoError(t, c.Put("test1"))
+	v, err := c.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", v)
+	assert.Equal(t, 1, q.Stats().InFlight)
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, 0, c.Len(), "Delivery should stay registered before the timeout")
+	clock.Advance(30 * time.Second)
+
+	// 之后的任意请求都会检查超时，未 Done 的元素被放回队列并移出登记表。
+	assert.Equal(t, 1, c.Len())
+	assert.Equal(t, 0, q.Stats().InFlight)
+	e, _ := s.lookup("orders")
+	e.lock.Lock()
+	assert.Empty(t, e.delivered)
+	e.lock.Unlock()
+
+	v, err = c.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", v)
+	c.Done(v)
+	assert.Equal(t, 0, q.Len())
+	assert.Equal(t, 0, q.Stats().InFlight)
+}
+
+func TestServer_Authorizer(t *testing.T) {
+	q := wkq.NewQueue(nil)
+	defer q.Shutdown()
+	authorizer := func(r *http.Request, queue, op string) error {
+		if (op == opSnapshot || op == opRestore) && r.Header.Get("X-Admin") != "yes" {
+			return errors.New("admin only")
+		}
+		return nil
+	}
+	_, url := newTestServerWithConfig(t, NewConfig().WithAuthorizer(authorizer), map[string]wkq.Queue{"orders": q})
+
+	c := NewClient(url, "orders", nil)
+	defer c.Shutdown()
+
+	assert.NoError(t, c.Put("test1"))
+	var buf bytes.Buffer
+	assert.ErrorIs(t, c.Snapshot(&buf), ErrForbidden)
+	assert.ErrorIs(t, c.Restore(&buf), ErrForbidden)
+
+	admin := NewClient(url, "orders", NewClientConfig().WithHTTPClient(&http.Client{Transport: headerTransport{"X-Admin", "yes"}}))
+	defer admin.Shutdown()
+	assert.NoError(t, admin.Snapshot(&buf))
+	assert.NoError(t, admin.Restore(&buf))
+	assert.Equal(t, 2, q.Len())
+}
+
+// headerTransport 为每个请求附加一个请求头。
+type headerTransport struct {
+	key, value string
+}
+
+func (h headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set(h.key, h.value)
+	return http.DefaultTransport.RoundTrip(r)
+}
@@ -0,0 +1,192 @@
+package workqueue
+
+import (
+	"encoding/gob"
+	"io"
+	"sync"
+
+	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
+)
+
+// snapshotVersion 为快照格式版本，格式不兼容时递增。
+const snapshotVersion = 1
+
+// queueSnapshot 为快照的编码结构，元素以队列配置的 Codec 编码。
+// 各类队列只填写自身拥有的部分，恢复到其他类型的队列时无法识别的部分按就绪元素恢复。
+type queueSnapshot struct {
+	Version uint8
+
+	// Items 为就绪元素，按出队顺序排列。
+	Items []snapshotItem
+
+	// Scheduled 为延迟或定时元素，Time 为到期的 Unix 毫秒时间戳。
+	Scheduled []snapshotItem
+
+	// Leases 为尚未确认的租约元素，Time 为租约截止的 Unix 纳秒时间戳。
+	Leases []snapshotItem
+
+	// Attempts 为 RetryQueue 的重试计数。
+	Attempts map[string]int
+}
+
+type snapshotItem struct {
+	Value    []byte
+	Time     int64
+	Priority int64
+}
+
+func writeSnapshot(w io.Writer, s *queueSnapshot) error {
+	s.Version = snapshotVersion
+	return gob.NewEncoder(w).Encode(s)
+}
+
+func readSnapshot(r io.Reader) (*queueSnapshot, error) {
+	var s queueSnapshot
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version != snapshotVersion {
+		return nil, ErrInvalidSnapshot
+	}
+	return &s, nil
+}
+
+// encodeItem 编码单个元素。
+func encodeItem(codec Codec, value interface{}, at, priority int64) (snapshotItem, error) {
+	data, err := codec.Encode(value)
+	if err != nil {
+		return snapshotItem{}, err
+	}
+	return snapshotItem{Value: data, Time: at, Priority: priority}, nil
+}
+
+// decodeItems 解码一组元素，任一元素失败即返回错误，保证恢复前快照完整可读。
+func decodeItems(codec Codec, items []snapshotItem) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(items))
+	for i := range items {
+		value, err := codec.Decode(items[i].Value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// readyItems 返回按就绪元素恢复的部分：租约元素最早出队因此排在最前，未被接管的延迟元素排在最后。
+func (s *queueSnapshot) readyItems(scheduled bool) []snapshotItem {
+	items := make([]snapshotItem, 0, len(s.Leases)+len(s.Items)+len(s.Scheduled))
+	items = append(items, s.Leases...)
+	items = append(items, s.Items...)
+	if scheduled {
+		items = append(items, s.Scheduled...)
+	}
+	return items
+}
+
+// transit 统计正在两个容器之间搬运的元素，快照需等待搬运完成，避免遗漏途中的元素。
+// 计数由外层容器的锁保护。
+type transit struct {
+	cond  sync.Cond
+	count int
+}
+
+func (t *transit) init(lock sync.Locker) {
+	t.cond.L = lock
+}
+
+// beginLocked 登记 n 个开始搬运的元素，调用方需持有锁。
+func (t *transit) beginLocked(n int) {
+	t.count += n
+}
+
+// endLocked 撤销 n 个搬运完成的元素并唤醒等待中的快照，调用方需持有锁。
+func (t *transit) endLocked(n int) {
+	t.count -= n
+	if t.count <= 0 {
+		t.count = 0
+		t.cond.Broadcast()
+	}
+}
+
+// waitLocked 等待全部搬运完成，调用方需持有锁，等待期间锁会被临时释放。
+func (t *transit) waitLocked() {
+	for t.count > 0 {
+		t.cond.Wait()
+	}
+}
+
+// Snapshot 将尚未投递的元素（包括幂等模式下处理期间收到的更新）写入 w。
+// 已出队但尚未 Done 的元素由消费者持有，不包含在快照中。
+func (q *queueImpl) Snapshot(w io.Writer) error {
+	s := &queueSnapshot{}
+
+	q.lock.Lock()
+	err := q.snapshotLocked(s, nil)
+	q.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return writeSnapshot(w, s)
+}
+
+// Restore 读取 Snapshot 写出的快照并按 Put 语义追加到队列，部分元素失败时返回 *BatchError。
+// 其他类型队列的快照同样可以恢复，延迟元素与租约元素按就绪元素处理。
+func (q *queueImpl) Restore(r io.Reader) error {
+	s, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+	values, err := decodeItems(q.config.codec, s.readyItems(true))
+	if err != nil {
+		return err
+	}
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+	return newBatchError(q.restoreReady(values))
+}
+
+// snapshotLocked 将就绪元素写入快照，priorityOf 非空时按其结果保留节点优先级，调用方需持有队列锁。
+func (q *queueImpl) snapshotLocked(s *queueSnapshot, priorityOf func(node *lst.Node) int64) error {
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
+
+	var err error
+	q.list.Range(func(value interface{}) bool {
+		node := value.(*lst.Node)
+		var p int64
+		if priorityOf != nil {
+			p = priorityOf(node)
+		}
+		var item snapshotItem
+		if item, err = encodeItem(q.config.codec, node.Value, 0, p); err != nil {
+			return false
+		}
+		s.Items = append(s.Items, item)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, value := range q.deferred {
+		item, err := encodeItem(q.config.codec, value, 0, 0)
+		if err != nil {
+			return err
+		}
+		s.Items = append(s.Items, item)
+	}
+	return nil
+}
+
+// restoreReady 按 Put 语义逐个入队恢复的元素，返回与 values 下标对应的错误。
+func (q *queueImpl) restoreReady(values []interface{}) []error {
+	errs := make([]error, len(values))
+	for i, value := range values {
+		errs[i] = q.Put(value)
+	}
+	return errs
+}
@@ -0,0 +1,258 @@
+package workqueue
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueImpl_SnapshotRestore(t *testing.T) {
+	q := NewQueue(nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutBatch([]interface{}{"test1", "test2", "test3"}))
+	_, err := q.Get()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	restored := NewQueue(nil)
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(&buf))
+	assert.Equal(t, []interface{}{"test2", "test3"}, restored.Values(), "Processing values are owned by consumers and should not be captured")
+}
+
+func TestQueueImpl_SnapshotRestore_Idempotent(t *testing.T) {
+	q := NewQueue(NewQueueConfig().WithValueIdempotent())
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("test1"))
+	_, err := q.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, q.Put("test1"))
+	assert.NoError(t, q.Put("test2"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	restored := NewQueue(NewQueueConfig().WithValueIdempotent())
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Put("test2"))
+
+	err = restored.Restore(&buf)
+	assert.ErrorIs(t, err, ErrElementAlreadyExist, "Restore should follow Put semantics")
+	var batchErr *BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.ElementsMatch(t, []interface{}{"test1", "test2"}, restored.Values(), "Deferred updates should be captured")
+}
+
+func TestQueueImpl_SnapshotRestore_Invalid(t *testing.T) {
+	q := NewQueue(nil)
+
+	assert.Error(t, q.Restore(bytes.NewReader([]byte("garbage"))))
+
+	q.Shutdown()
+	var buf bytes.Buffer
+	assert.ErrorIs(t, q.Snapshot(&buf), ErrQueueIsClosed)
+}
+
+func TestDelayingQueue_SnapshotRestore(t *testing.T) {
+	q := NewDelayingQueue(nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("ready"))
+	assert.NoError(t, q.PutWithDelay("later", 60000))
+
+	var due int64
+	q.HeapRange(func(value interface{}, at int64) bool {
+		due = at
+		return true
+	})
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+	data := buf.Bytes()
+
+	restored := NewDelayingQueue(nil)
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(bytes.NewReader(data)))
+
+	assert.Equal(t, []interface{}{"ready"}, restored.Values())
+	restored.HeapRange(func(value interface{}, at int64) bool {
+		assert.Equal(t, "later", value)
+		assert.Equal(t, due, at, "Absolute fire time should survive restore")
+		return true
+	})
+	assert.Equal(t, 2, restored.Len())
+
+	// 普通队列无法识别延迟元素，按就绪元素恢复。
+	plain := NewQueue(nil)
+	defer plain.Shutdown()
+	assert.NoError(t, plain.Restore(bytes.NewReader(data)))
+	assert.Equal(t, []interface{}{"ready", "later"}, plain.Values())
+}
+
+func TestPriorityQueue_SnapshotRestore(t *testing.T) {
+	q := NewPriorityQueue(nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithPriority("low", 10))
+	assert.NoError(t, q.PutWithPriority("high", -10))
+	assert.NoError(t, q.Put("normal"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	restored := NewPriorityQueue(nil)
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(&buf))
+
+	priorities := map[interface{}]int64{}
+	restored.HeapRange(func(value interface{}, priority int64) bool {
+		priorities[value] = priority
+		return true
+	})
+	assert.Equal(t, map[interface{}]int64{"low": 10, "high": -10, "normal": 0}, priorities)
+	assert.Equal(t, []interface{}{"high", "normal", "low"}, restored.Values())
+}
+
+func TestPriorityQueue_SnapshotRestoreAging(t *testing.T) {
+	q := NewPriorityQueue(NewPriorityQueueConfig().WithLinearAging(1000, time.Millisecond))
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutWithPriority("old", 10000))
+	time.Sleep(20 * time.Millisecond)
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	// 快照保存有效优先级，恢复后已累积的等待提升不会丢失。
+	restored := NewPriorityQueue(nil)
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(&buf))
+	assert.NoError(t, restored.PutWithPriority("new", 0))
+	assert.Equal(t, []interface{}{"old", "new"}, restored.Values())
+}
+
+func TestRetryQueue_SnapshotRestore(t *testing.T) {
+	config := NewRetryQueueConfig().WithPolicy(NewExponentialRetryPolicy(time.Hour, time.Hour, 5))
+	q := NewRetryQueue(config)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("task"))
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, q.Retry(v, errors.New("failed")))
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	restored := NewRetryQueue(NewRetryQueueConfig().WithPolicy(NewExponentialRetryPolicy(time.Hour, time.Hour, 5)))
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(&buf))
+
+	assert.Equal(t, 1, restored.NumRequeues("task"), "Attempt counters should survive restore")
+	count := 0
+	restored.HeapRange(func(value interface{}, at int64) bool {
+		count++
+		assert.Equal(t, "task", value)
+		assert.Greater(t, at, time.Now().Add(30*time.Minute).UnixMilli())
+		return true
+	})
+	assert.Equal(t, 1, count)
+}
+
+func TestLeasedQueue_SnapshotRestore(t *testing.T) {
+	q := NewLeasedQueue(nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutBatch([]interface{}{"leased", "queued"}))
+	_, _, err := q.GetWithLease(time.Minute)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	restored := NewLeasedQueue(nil)
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(&buf))
+	assert.Equal(t, []interface{}{"leased", "queued"}, restored.Values(), "Outstanding leases should be redelivered first")
+}
+
+func TestDeadLetterQueue_SnapshotRestore(t *testing.T) {
+	q := NewDeadLetterQueue(nil)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.PutDead(&DeadLetter{Payload: "bad", Attempts: 2}))
+	assert.NoError(t, q.PutDead(&DeadLetter{Payload: "worse"}))
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	restored := NewDeadLetterQueue(nil)
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(&buf))
+
+	var ids []string
+	restored.RangeDead(func(letter *DeadLetter) bool {
+		ids = append(ids, letter.ID)
+		return true
+	})
+	assert.Len(t, ids, 2)
+
+	next := &DeadLetter{Payload: "new"}
+	assert.NoError(t, restored.PutDead(next))
+	assert.NotContains(t, ids, next.ID, "Generated ids should not collide with restored letters")
+
+	letter, err := restored.GetDead()
+	assert.NoError(t, err)
+	assert.Equal(t, "bad", letter.Payload)
+	assert.Equal(t, 2, letter.Attempts)
+}
+
+func TestTimerQueue_SnapshotRestore(t *testing.T) {
+	q := NewTimerQueue(nil)
+	defer q.Shutdown()
+
+	at := time.Now().Add(time.Hour)
+	assert.NoError(t, q.PutAt("timer", at))
+	assert.NoError(t, q.Put("ready"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	restored := NewTimerQueue(nil)
+	defer restored.Shutdown()
+	assert.NoError(t, restored.Restore(&buf))
+
+	assert.Equal(t, []interface{}{"ready"}, restored.Values())
+	restored.HeapRange(func(value interface{}, fireAt int64) bool {
+		assert.Equal(t, "timer", value)
+		assert.Equal(t, at.UnixMilli(), fireAt)
+		return true
+	})
+}
+
+func TestBoundedBlockingQueue_Restore_Full(t *testing.T) {
+	q := NewBoundedBlockingQueue(nil)
+	defer q.Shutdown()
+	assert.NoError(t, q.PutBatch([]interface{}{"test1", "test2", "test3"}))
+
+	var buf bytes.Buffer
+	assert.NoError(t, q.Snapshot(&buf))
+
+	restored := NewBoundedBlockingQueue(NewBoundedBlockingQueueConfig().WithCapacity(2))
+	defer restored.Shutdown()
+
+	err := restored.Restore(&buf)
+	assert.ErrorIs(t, err, ErrQueueIsFull, "Restore should not block on a full queue")
+	assert.Equal(t, []interface{}{"test1", "test2"}, restored.Values())
+
+	v, err := restored.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", v)
+}
@@ -1,24 +1,38 @@
 package workqueue
 
 import (
+	"context"
+	"errors"
+	"io"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	hp "github.com/shengyanli1982/workqueue/v2/internal/container/heap"
 	lst "github.com/shengyanli1982/workqueue/v2/internal/container/list"
+	"github.com/shengyanli1982/workqueue/v2/internal/cron"
 )
 
 type timerQueueImpl struct {
 	Queue
 	config      *TimerQueueConfig
-	sorting     *hp.RBTree
+	sorting     schedule
 	elementpool *lst.NodePool
 	lock        sync.Mutex
 	once        sync.Once
-	wg          sync.WaitGroup
-	wake        chan struct{}
-	closed      chan struct{}
+	scheduler   *scheduler
+	transit     transit
+
+	// index 按基础类型的元素值索引定时节点，使 Cancel 无需遍历定时容器。
+	index map[interface{}][]*lst.Node
+	// ids 与 nodeIDs 为 PutAtWithID 分配的定时 ID 与节点之间的双向索引。
+	ids     map[uint64]*lst.Node
+	nodeIDs map[*lst.Node]uint64
+	lastID  atomic.Uint64
+	// recurring 记录周期调度待触发的节点，这些节点不计入排空等待，也不写入快照。
+	recurring map[*lst.Node]*recurringImpl
+	// wakeAt 为调度协程计划醒来的时刻（Unix 毫秒），更早触发的元素入队时才需要唤醒它。
+	wakeAt int64
 }
 
 // NewTimerQueue 创建定时队列。
@@ -27,30 +41,44 @@ func NewTimerQueue(config *TimerQueueConfig) TimerQueue {
 
 	q := &timerQueueImpl{
 		config:      config,
-		sorting:     hp.New(),
+		sorting:     newSchedule(config.wheelTick, config.clock.Now()),
 		elementpool: lst.NewNodePool(),
-		wake:        make(chan struct{}, 1),
-		closed:      make(chan struct{}),
+		scheduler:   newScheduler(config.clock),
+		index:       make(map[interface{}][]*lst.Node),
+		ids:         make(map[uint64]*lst.Node),
+		nodeIDs:     make(map[*lst.Node]uint64),
+		recurring:   make(map[*lst.Node]*recurringImpl),
 	}
+	q.transit.init(&q.lock)
 
 	q.Queue = newQueue(&wrapInternalList{List: lst.New()}, q.elementpool, &config.QueueConfig)
-	q.wg.Add(1)
-	go q.scheduler()
+	q.scheduler.start(q.fire)
 
 	return q
 }
 
 func (q *timerQueueImpl) PutAt(value interface{}, at time.Time) error {
+	_, err := q.putAt(value, at, false)
+	return err
+}
+
+func (q *timerQueueImpl) PutAtWithID(value interface{}, at time.Time) (uint64, error) {
+	return q.putAt(value, at, true)
+}
+
+// putAt 按绝对时间入队，withID 为真时分配定时 ID 并登记索引。
+func (q *timerQueueImpl) putAt(value interface{}, at time.Time, withID bool) (uint64, error) {
 	if q.IsClosed() {
-		return ErrQueueIsClosed
+		return 0, ErrQueueIsClosed
 	}
 	if value == nil {
-		return ErrElementIsNil
+		return 0, ErrElementIsNil
 	}
 
+	// 已到期的元素直接入队；需要 ID 时仍挂入定时容器并立即唤醒调度协程，触发前 ID 可查、可取消。
 	atMillis := at.UnixMilli()
-	if atMillis <= time.Now().UnixMilli() {
-		return q.Queue.Put(value)
+	if !withID && atMillis <= q.config.clock.Now().UnixMilli() {
+		return 0, q.Queue.Put(value)
 	}
 
 	node := q.elementpool.Get()
@@ -58,31 +86,38 @@ func (q *timerQueueImpl) PutAt(value interface{}, at time.Time) error {
 	node.Priority = atMillis
 
 	q.lock.Lock()
-	front := q.sorting.Front()
+	if err := q.acceptLocked(); err != nil {
+		q.lock.Unlock()
+		q.elementpool.Put(node)
+		return 0, err
+	}
 	q.sorting.Push(node)
-	shouldWake := front == nil || atMillis < front.Priority
+	q.indexLocked(node)
+	var id uint64
+	if withID {
+		id = q.lastID.Add(1)
+		q.ids[id] = node
+		q.nodeIDs[node] = id
+	}
+	shouldWake := atMillis < q.wakeAt
 	q.lock.Unlock()
 
 	if shouldWake {
-		q.notifyWake()
+		q.scheduler.notify()
 	}
-	return nil
+	return id, nil
 }
 
 func (q *timerQueueImpl) PutAfter(value interface{}, after time.Duration) error {
-	return q.PutAt(value, time.Now().Add(after))
+	return q.PutAt(value, q.config.clock.Now().Add(after))
 }
 
 func (q *timerQueueImpl) Cancel(value interface{}) bool {
 	q.lock.Lock()
-	var target *lst.Node
-	if front := q.sorting.Front(); front != nil && matchTimerValue(front.Value, value) {
-		target = q.sorting.Pop()
-	} else {
-		target = q.findNodeLocked(value)
-		if target != nil {
-			q.sorting.Remove(target)
-		}
+	target := q.findNodeLocked(value)
+	if target != nil {
+		q.sorting.Remove(target)
+		q.unindexLocked(target)
 	}
 	q.lock.Unlock()
 
@@ -91,10 +126,147 @@ func (q *timerQueueImpl) Cancel(value interface{}) bool {
 	}
 
 	q.elementpool.Put(target)
-	q.notifyWake()
+	q.scheduler.notify()
+	q.base().wakeDrain()
+	return true
+}
+
+func (q *timerQueueImpl) CancelByID(id uint64) bool {
+	q.lock.Lock()
+	node, ok := q.ids[id]
+	if ok {
+		q.sorting.Remove(node)
+		q.unindexLocked(node)
+	}
+	q.lock.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	q.elementpool.Put(node)
+	q.scheduler.notify()
+	q.base().wakeDrain()
 	return true
 }
 
+// Reschedule 修改尚未触发的定时元素的触发时间，新时间已过时由调度协程立即放入队列。
+func (q *timerQueueImpl) Reschedule(id uint64, at time.Time) error {
+	atMillis := at.UnixMilli()
+
+	q.lock.Lock()
+	node, ok := q.ids[id]
+	if !ok {
+		q.lock.Unlock()
+		return ErrTimerNotFound
+	}
+	q.sorting.Remove(node)
+	node.Priority = atMillis
+	q.sorting.Push(node)
+	shouldWake := atMillis < q.wakeAt
+	q.lock.Unlock()
+
+	// 推迟触发无需唤醒：调度协程在原来的时刻醒来后会重新计算等待时长。
+	if shouldWake {
+		q.scheduler.notify()
+	}
+	return nil
+}
+
+func (q *timerQueueImpl) Lookup(id uint64) (interface{}, time.Time, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	node, ok := q.ids[id]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	return node.Value, time.UnixMilli(node.Priority), true
+}
+
+func (q *timerQueueImpl) PutEvery(value interface{}, interval time.Duration, config *RecurringConfig) (Recurring, error) {
+	if interval < time.Millisecond {
+		return nil, ErrInvalidInterval
+	}
+	config = isRecurringConfigEffective(config)
+
+	anchor := config.startAt
+	if anchor.IsZero() {
+		anchor = q.config.clock.Now().Add(interval)
+	}
+	return q.putRecurring(value, everyNext(anchor, interval), config)
+}
+
+func (q *timerQueueImpl) PutCron(value interface{}, expr string, config *RecurringConfig) (Recurring, error) {
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	config = isRecurringConfigEffective(config)
+
+	return q.putRecurring(value, cronNext(schedule, config.startAt, config.location), config)
+}
+
+func (q *timerQueueImpl) putRecurring(value interface{}, next recurringNextFunc, config *RecurringConfig) (Recurring, error) {
+	if q.IsClosed() {
+		return nil, ErrQueueIsClosed
+	}
+	if value == nil {
+		return nil, ErrElementIsNil
+	}
+
+	r := &recurringImpl{queue: q, value: value, next: next, endAt: config.endAt}
+
+	q.lock.Lock()
+	if err := q.acceptLocked(); err != nil {
+		q.lock.Unlock()
+		return nil, err
+	}
+	wake := q.scheduleRecurringLocked(r, q.config.clock.Now())
+	q.lock.Unlock()
+
+	if r.done {
+		return nil, ErrRecurringNeverFires
+	}
+	if wake {
+		q.scheduler.notify()
+	}
+	return r, nil
+}
+
+// scheduleRecurringLocked 计算晚于 after 的下一次触发并挂入定时容器，超出结束时间时终止调度。
+// 返回是否需要唤醒调度协程，调用方需持有锁。
+func (q *timerQueueImpl) scheduleRecurringLocked(r *recurringImpl, after time.Time) bool {
+	at := r.next(after)
+	if at.IsZero() || (!r.endAt.IsZero() && at.After(r.endAt)) {
+		r.node = nil
+		r.done = true
+		return false
+	}
+
+	node := q.elementpool.Get()
+	node.Value = r.value
+	node.Priority = at.UnixMilli()
+	q.sorting.Push(node)
+	q.indexLocked(node)
+	q.recurring[node] = r
+	r.node = node
+	r.at = at
+	return node.Priority < q.wakeAt
+}
+
+// unscheduleRecurringLocked 撤销周期调度待触发的节点，调用方需持有锁。
+func (q *timerQueueImpl) unscheduleRecurringLocked(r *recurringImpl) {
+	if r.node == nil {
+		return
+	}
+	q.sorting.Remove(r.node)
+	q.unindexLocked(r.node)
+	delete(q.recurring, r.node)
+	q.elementpool.Put(r.node)
+	r.node = nil
+}
+
 func (q *timerQueueImpl) HeapRange(fn func(value interface{}, at int64) bool) {
 	if fn == nil {
 		return
@@ -108,177 +280,255 @@ func (q *timerQueueImpl) HeapRange(fn func(value interface{}, at int64) bool) {
 }
 
 func (q *timerQueueImpl) Shutdown() {
-	q.Queue.Shutdown()
+	q.shutdown(false)
+}
+
+// ShutdownWithDrain 在基础队列排空语义之上，额外等待全部定时元素到期并被消费。
+// ctx 先结束时，尚未到期的元素与基础队列中的剩余元素一并返回。
+func (q *timerQueueImpl) ShutdownWithDrain(ctx context.Context) ([]interface{}, error) {
+	if err := q.base().drain(ctx, q.scheduledEmpty); err != nil {
+		if errors.Is(err, ErrQueueIsClosed) {
+			return nil, err
+		}
+		return q.shutdown(true), err
+	}
+
+	q.shutdown(false)
+	return nil, nil
+}
+
+// shutdown 先停止调度协程并清理定时树，再关闭基础队列，保证调度途中的元素不会丢失。
+func (q *timerQueueImpl) shutdown(collect bool) []interface{} {
+	var scheduled []interface{}
 
 	q.once.Do(func() {
-		close(q.closed)
-		q.notifyWake()
-		q.wg.Wait()
+		q.scheduler.stop()
 
 		q.lock.Lock()
+		var nodes []*lst.Node
 		q.sorting.Range(func(node *lst.Node) bool {
-			q.elementpool.Put(node)
+			if r, ok := q.recurring[node]; ok {
+				r.node = nil
+				r.done = true
+			} else if collect {
+				scheduled = append(scheduled, node.Value)
+			}
+			nodes = append(nodes, node)
 			return true
 		})
 		q.sorting.Cleanup()
+		for _, node := range nodes {
+			q.elementpool.Put(node)
+		}
+		q.index = make(map[interface{}][]*lst.Node)
+		q.ids = make(map[uint64]*lst.Node)
+		q.nodeIDs = make(map[*lst.Node]uint64)
+		q.recurring = make(map[*lst.Node]*recurringImpl)
 		q.lock.Unlock()
 	})
+
+	return append(q.base().shutdown(collect), scheduled...)
 }
 
-func (q *timerQueueImpl) Len() int {
+// Snapshot 在基础队列快照之上额外保存定时树中的元素及其绝对触发时间，周期调度不写入快照。
+func (q *timerQueueImpl) Snapshot(w io.Writer) error {
+	s := &queueSnapshot{}
+	base := q.base()
+
 	q.lock.Lock()
-	count := int(q.sorting.Len())
-	q.lock.Unlock()
-	return count + q.Queue.Len()
-}
+	q.transit.waitLocked()
 
-func (q *timerQueueImpl) scheduler() {
-	defer q.wg.Done()
+	base.lock.Lock()
+	err := base.snapshotLocked(s, nil)
+	base.lock.Unlock()
 
-	timer := time.NewTimer(time.Hour)
-	if !timer.Stop() {
-		select {
-		case <-timer.C:
-		default:
-		}
-	}
-	defer func() {
-		if !timer.Stop() {
-			select {
-			case <-timer.C:
-			default:
+	if err == nil {
+		q.sorting.Range(func(node *lst.Node) bool {
+			if _, ok := q.recurring[node]; ok {
+				return true
 			}
-		}
-	}()
+			var item snapshotItem
+			if item, err = encodeItem(base.config.codec, node.Value, node.Priority, 0); err != nil {
+				return false
+			}
+			s.Scheduled = append(s.Scheduled, item)
+			return true
+		})
+	}
+	q.lock.Unlock()
+	if err != nil {
+		return err
+	}
 
-	for {
-		wait, due, ok := q.nextWait()
-		if !ok {
-			return
-		}
+	return writeSnapshot(w, s)
+}
 
-		if due != nil {
-			value := due.Value
-			q.elementpool.Put(due)
-			_ = q.Queue.Put(value)
-			continue
-		}
+// Restore 恢复快照，定时元素按原触发时间重新调度，已过期的元素立即可用。
+func (q *timerQueueImpl) Restore(r io.Reader) error {
+	s, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+	base := q.base()
+	ready, err := decodeItems(base.config.codec, s.readyItems(false))
+	if err != nil {
+		return err
+	}
+	scheduled, err := decodeItems(base.config.codec, s.Scheduled)
+	if err != nil {
+		return err
+	}
+	if q.IsClosed() {
+		return ErrQueueIsClosed
+	}
 
-		if !q.wait(timer, wait) {
-			return
-		}
+	errs := base.restoreReady(ready)
+	for i, value := range scheduled {
+		errs = append(errs, q.PutAt(value, time.UnixMilli(s.Scheduled[i].Time)))
 	}
+	return newBatchError(errs)
 }
 
-func (q *timerQueueImpl) nextWait() (time.Duration, *lst.Node, bool) {
+func (q *timerQueueImpl) Len() int {
 	q.lock.Lock()
-	defer q.lock.Unlock()
+	count := int(q.sorting.Len())
+	q.lock.Unlock()
+	return count + q.Queue.Len()
+}
 
-	select {
-	case <-q.closed:
-		return 0, nil, false
-	default:
+// fire 将一个已到期的元素重新放回基础队列，否则返回距离下一次需要检查的时长。
+func (q *timerQueueImpl) fire() (bool, time.Duration) {
+	q.lock.Lock()
+	now := q.config.clock.Now()
+	due, wait := popExpired(q.sorting, &q.wakeAt, now.UnixMilli())
+	if due == nil {
+		q.lock.Unlock()
+		return false, wait
 	}
 
-	front := q.sorting.Front()
-	if front == nil {
-		return 0, nil, true
+	// 调度途中元素不在任何容器内，先登记为处理中，避免排空提前结束或快照遗漏。
+	q.unindexLocked(due)
+	if r, ok := q.recurring[due]; ok {
+		delete(q.recurring, due)
+		// 以计划触发时间为起点计算下一次，调度延迟不会累积；落后过多时跳过错过的触发。
+		after := r.at
+		if now.After(after) {
+			after = now
+		}
+		q.scheduleRecurringLocked(r, after)
 	}
+	q.base().retain()
+	q.transit.beginLocked(1)
+	q.lock.Unlock()
 
-	now := time.Now().UnixMilli()
-	if front.Priority <= now {
-		return 0, q.sorting.Pop(), true
-	}
+	value := due.Value
+	q.elementpool.Put(due)
+	_ = q.base().requeue(value)
+	q.base().release()
 
-	return time.Duration(front.Priority-now) * time.Millisecond, nil, true
+	q.lock.Lock()
+	q.transit.endLocked(1)
+	q.lock.Unlock()
+	return true, 0
 }
 
-func (q *timerQueueImpl) wait(timer *time.Timer, d time.Duration) bool {
-	if d <= 0 {
-		select {
-		case <-q.closed:
-			return false
-		case <-q.wake:
-			return true
-		}
+// acceptLocked 判断定时树能否接收新元素，调用方需持有锁。
+func (q *timerQueueImpl) acceptLocked() error {
+	if q.scheduler.isStopped() {
+		return ErrQueueIsClosed
 	}
-
-	if !timer.Stop() {
-		select {
-		case <-timer.C:
-		default:
-		}
+	if q.base().isDraining() {
+		return ErrQueueIsDraining
 	}
-	timer.Reset(d)
+	return nil
+}
 
-	select {
-	case <-q.closed:
-		return false
-	case <-q.wake:
-		return true
-	case <-timer.C:
-		return true
-	}
+// scheduledEmpty 报告定时树中是否只剩周期调度，供排空检查使用。
+func (q *timerQueueImpl) scheduledEmpty() bool {
+	q.lock.Lock()
+	empty := q.sorting.Len() == int64(len(q.recurring))
+	q.lock.Unlock()
+	return empty
 }
 
-func (q *timerQueueImpl) notifyWake() {
-	select {
-	case q.wake <- struct{}{}:
-	default:
-	}
+func (q *timerQueueImpl) base() *queueImpl {
+	return q.Queue.(*queueImpl)
 }
 
+// findNodeLocked 查找与 value 匹配且最早触发的一次性定时节点，周期调度的待触发节点不参与匹配。
+// 配置了 key 函数时按 key 通过索引查找；否则基础类型的值通过索引查找，其余值（指针、结构体等）
+// 退化为遍历定时容器并按 reflect.DeepEqual 比较。
 func (q *timerQueueImpl) findNodeLocked(value interface{}) *lst.Node {
 	var target *lst.Node
-	q.sorting.Range(func(node *lst.Node) bool {
-		if matchTimerValue(node.Value, value) {
+	match := func(node *lst.Node) {
+		if _, recurring := q.recurring[node]; recurring {
+			return
+		}
+		if target == nil || node.Priority < target.Priority {
 			target = node
-			return false
+		}
+	}
+
+	if key, ok := q.indexKeyOf(value); ok {
+		for _, node := range q.index[key] {
+			match(node)
+		}
+		return target
+	}
+
+	q.sorting.Range(func(node *lst.Node) bool {
+		if reflect.DeepEqual(node.Value, value) {
+			match(node)
 		}
 		return true
 	})
 	return target
 }
 
-func matchTimerValue(candidate, target interface{}) bool {
-	switch tv := target.(type) {
-	case int:
-		cv, ok := candidate.(int)
-		return ok && cv == tv
-	case int8:
-		cv, ok := candidate.(int8)
-		return ok && cv == tv
-	case int16:
-		cv, ok := candidate.(int16)
-		return ok && cv == tv
-	case int32:
-		cv, ok := candidate.(int32)
-		return ok && cv == tv
-	case int64:
-		cv, ok := candidate.(int64)
-		return ok && cv == tv
-	case uint:
-		cv, ok := candidate.(uint)
-		return ok && cv == tv
-	case uint8:
-		cv, ok := candidate.(uint8)
-		return ok && cv == tv
-	case uint16:
-		cv, ok := candidate.(uint16)
-		return ok && cv == tv
-	case uint32:
-		cv, ok := candidate.(uint32)
-		return ok && cv == tv
-	case uint64:
-		cv, ok := candidate.(uint64)
-		return ok && cv == tv
-	case uintptr:
-		cv, ok := candidate.(uintptr)
-		return ok && cv == tv
-	case string:
-		cv, ok := candidate.(string)
-		return ok && cv == tv
-	default:
-		return reflect.DeepEqual(candidate, target)
+func (q *timerQueueImpl) indexLocked(node *lst.Node) {
+	if key, ok := q.indexKeyOf(node.Value); ok {
+		q.index[key] = append(q.index[key], node)
+	}
+}
+
+// unindexLocked 从值索引与定时 ID 索引中移除节点，调用方需持有锁。
+func (q *timerQueueImpl) unindexLocked(node *lst.Node) {
+	if id, ok := q.nodeIDs[node]; ok {
+		delete(q.nodeIDs, node)
+		delete(q.ids, id)
+	}
+
+	key, ok := q.indexKeyOf(node.Value)
+	if !ok {
+		return
+	}
+
+	nodes := q.index[key]
+	for i, n := range nodes {
+		if n == node {
+			nodes = append(nodes[:i], nodes[i+1:]...)
+			break
+		}
+	}
+	if len(nodes) == 0 {
+		delete(q.index, key)
+	} else {
+		q.index[key] = nodes
+	}
+}
+
+// indexKeyOf 返回元素在值索引中的键：配置了 key 函数时为其结果，否则仅基础类型的值以自身为键。
+// 指针与结构体等按 == 比较的结果与 reflect.DeepEqual 不同，不进入索引。
+func (q *timerQueueImpl) indexKeyOf(value interface{}) (interface{}, bool) {
+	base := q.base()
+	if base.config.keyFunc != nil {
+		return base.config.keyFunc(value), true
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.String:
+		return value, true
 	}
+	return nil, false
 }
@@ -1,6 +1,7 @@
 package workqueue
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -93,21 +94,109 @@ func TestTimerQueue_Cancel_NonComparableValue(t *testing.T) {
 	assert.ErrorIs(t, err, ErrQueueIsEmpty)
 }
 
+// timerBackends 返回分别使用红黑树与时间轮、以 fakeEpoch 为起点的定时队列配置。
+func timerBackends() map[string]func() (TimerQueue, *FakeClock) {
+	build := func(tick time.Duration) func() (TimerQueue, *FakeClock) {
+		return func() (TimerQueue, *FakeClock) {
+			clock := NewFakeClock(fakeEpoch)
+			return NewTimerQueue(NewTimerQueueConfig().WithTimingWheel(tick).WithClock(clock)), clock
+		}
+	}
+	return map[string]func() (TimerQueue, *FakeClock){"heap": build(0), "wheel": build(time.Millisecond)}
+}
+
 func TestTimerQueue_HeapRange(t *testing.T) {
-	q := NewTimerQueue(nil)
-	defer q.Shutdown()
+	for name, newQueue := range timerBackends() {
+		t.Run(name, func(t *testing.T) {
+			q, _ := newQueue()
+			defer q.Shutdown()
+
+			// 跨越多个时间轮层级与同一槽位内的乱序插入。
+			delays := []time.Duration{40 * time.Millisecond, time.Hour, 20 * time.Millisecond, 30 * time.Millisecond, time.Second, 25 * time.Millisecond}
+			for i, delay := range delays {
+				assert.NoError(t, q.PutAfter(i, delay))
+			}
+
+			var items []interface{}
+			var ats []int64
+			q.HeapRange(func(value interface{}, at int64) bool {
+				items = append(items, value)
+				ats = append(ats, at)
+				return true
+			})
+			assert.Equal(t, []interface{}{2, 5, 3, 0, 4, 1}, items, "HeapRange should follow the fire time on every backend")
+			assert.IsNonDecreasing(t, ats)
+		})
+	}
+}
+
+type timerJob struct {
+	ID string
+}
 
-	assert.NoError(t, q.PutAfter("a", 40*time.Millisecond))
-	assert.NoError(t, q.PutAfter("b", 20*time.Millisecond))
-	assert.NoError(t, q.PutAfter("c", 30*time.Millisecond))
+func TestTimerQueue_Cancel_Earliest(t *testing.T) {
+	for name, newQueue := range timerBackends() {
+		t.Run(name, func(t *testing.T) {
+			q, clock := newQueue()
+			defer q.Shutdown()
 
-	items := make([]interface{}, 0, 3)
-	q.HeapRange(func(value interface{}, _ int64) bool {
-		items = append(items, value)
-		return true
-	})
+			// 结构体与切片都退化为遍历，同样取消最早触发的一个。
+			for _, value := range []interface{}{timerJob{ID: "a"}, []int{1}} {
+				assert.NoError(t, q.PutAfter(value, time.Hour))
+				assert.NoError(t, q.PutAfter(value, 10*time.Millisecond))
+				assert.NoError(t, q.PutAfter(value, time.Minute))
+			}
+
+			assert.True(t, q.Cancel(timerJob{ID: "a"}))
+			assert.True(t, q.Cancel([]int{1}))
+
+			var ats []int64
+			q.HeapRange(func(_ interface{}, at int64) bool {
+				ats = append(ats, at)
+				return true
+			})
+			minute, hour := fakeEpoch.Add(time.Minute).UnixMilli(), fakeEpoch.Add(time.Hour).UnixMilli()
+			assert.Equal(t, []int64{minute, minute, hour, hour}, ats, "The earliest match should be cancelled")
+
+			clock.Advance(time.Second)
+			_, err := q.Get()
+			assert.ErrorIs(t, err, ErrQueueIsEmpty)
+		})
+	}
+}
+
+func TestTimerQueue_Cancel_Pointer(t *testing.T) {
+	for name, newQueue := range timerBackends() {
+		t.Run(name, func(t *testing.T) {
+			q, clock := newQueue()
+			defer q.Shutdown()
+
+			// 未配置 key 函数时指针按指向的内容匹配，与 reflect.DeepEqual 一致。
+			assert.NoError(t, q.PutAfter(&timerJob{ID: "a"}, time.Hour))
+			assert.NoError(t, q.PutAfter(&timerJob{ID: "b"}, time.Hour))
+			assert.True(t, q.Cancel(&timerJob{ID: "a"}), "A pointer to an equal value should cancel the timer")
+			assert.False(t, q.Cancel(&timerJob{ID: "a"}))
+
+			clock.Advance(time.Hour)
+			v, err := q.Get()
+			assert.NoError(t, err)
+			assert.Equal(t, &timerJob{ID: "b"}, v)
+			_, err = q.Get()
+			assert.ErrorIs(t, err, ErrQueueIsEmpty, "The cancelled timer should not fire")
+		})
+	}
+}
+
+func TestTimerQueue_Cancel_KeyFunc(t *testing.T) {
+	config := NewTimerQueueConfig()
+	config.WithKeyFunc(func(value interface{}) string { return value.(*timerJob).ID })
+	q := NewTimerQueue(config)
+	defer q.Shutdown()
 
-	assert.Len(t, items, 3)
+	// 配置了 key 函数时按 key 匹配，指针元素也能通过索引取消。
+	assert.NoError(t, q.PutAfter(&timerJob{ID: "a"}, time.Hour))
+	assert.True(t, q.Cancel(&timerJob{ID: "a"}))
+	assert.Equal(t, 0, q.Len())
 }
 
 func waitTimerQueueGet(t *testing.T, q Queue, timeout time.Duration) (interface{}, error) {
@@ -127,3 +216,174 @@ func waitTimerQueueGet(t *testing.T, q Queue, timeout time.Duration) (interface{
 
 	return nil, ErrQueueIsEmpty
 }
+
+func TestTimerQueue_ShutdownWithDrain(t *testing.T) {
+	q := NewTimerQueue(nil)
+
+	assert.NoError(t, q.PutAfter("soon", 30*time.Millisecond))
+	assert.NoError(t, q.PutAfter("later", time.Hour))
+	assert.True(t, q.Cancel("later"))
+
+	consumed := make(chan interface{}, 1)
+	go func() {
+		v, err := q.GetBlocking()
+		if err == nil {
+			q.Done(v)
+			consumed <- v
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	pending, err := q.ShutdownWithDrain(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.Equal(t, "soon", <-consumed)
+	assert.ErrorIs(t, q.PutAfter("late", time.Millisecond), ErrQueueIsClosed)
+}
+
+func TestTimerQueue_TimingWheel(t *testing.T) {
+	q := NewTimerQueue(NewTimerQueueConfig().WithTimingWheel(time.Millisecond))
+	defer q.Shutdown()
+
+	now := time.Now()
+	assert.NoError(t, q.PutAt("late", now.Add(60*time.Millisecond)))
+	assert.NoError(t, q.PutAt("early", now.Add(20*time.Millisecond)))
+	assert.NoError(t, q.PutAt("cancel-me", now.Add(40*time.Millisecond)))
+	assert.NoError(t, q.PutAt([]int{1}, now.Add(30*time.Millisecond)))
+	assert.NoError(t, q.PutAt("far", now.Add(time.Hour)))
+
+	assert.True(t, q.Cancel("cancel-me"))
+	assert.True(t, q.Cancel([]int{1}))
+	assert.False(t, q.Cancel("cancel-me"))
+	assert.Equal(t, 3, q.Len())
+
+	v1, err := waitQueueGet(t, q, 200*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "early", v1)
+	assert.GreaterOrEqual(t, time.Since(now), 20*time.Millisecond, "Value should not fire before its deadline")
+
+	v2, err := waitQueueGet(t, q, 200*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "late", v2)
+
+	assert.True(t, q.Cancel("far"))
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestTimerQueue_PutAtWithID(t *testing.T) {
+	q := NewTimerQueue(nil)
+	defer q.Shutdown()
+
+	at := time.Now().Add(time.Hour)
+	id1, err := q.PutAtWithID([]int{1}, at)
+	assert.NoError(t, err)
+	id2, err := q.PutAtWithID([]int{1}, at)
+	assert.NoError(t, err)
+	assert.NotEqual(t, id1, id2, "Each timer should get its own id")
+
+	value, got, ok := q.Lookup(id2)
+	assert.True(t, ok)
+	assert.Equal(t, []int{1}, value)
+	assert.Equal(t, at.UnixMilli(), got.UnixMilli())
+
+	// 按 ID 取消只影响对应的元素，即使值相同。
+	assert.True(t, q.CancelByID(id2))
+	assert.False(t, q.CancelByID(id2))
+	_, _, ok = q.Lookup(id2)
+	assert.False(t, ok, "Cancelled timer should not be found")
+	_, _, ok = q.Lookup(id1)
+	assert.True(t, ok, "Other timer should stay scheduled")
+	assert.Equal(t, 1, q.Len())
+
+}
+
+func TestTimerQueue_PutAtWithID_Past(t *testing.T) {
+	for name, newQueue := range timerBackends() {
+		t.Run(name, func(t *testing.T) {
+			q, clock := newQueue()
+			defer q.Shutdown()
+
+			// 已过时的定时元素在调度协程触发前仍可按 ID 查到与取消。
+			past := fakeEpoch.Add(-time.Second)
+			id, err := q.PutAtWithID("past", past)
+			assert.NoError(t, err)
+			value, at, ok := q.Lookup(id)
+			assert.True(t, ok, "Past timer should be found until it fires")
+			assert.Equal(t, "past", value)
+			assert.Equal(t, past.UnixMilli(), at.UnixMilli())
+
+			cancelled, err := q.PutAtWithID("cancelled", past)
+			assert.NoError(t, err)
+			assert.True(t, q.CancelByID(cancelled))
+
+			clock.Advance(0)
+			v, err := q.Get()
+			assert.NoError(t, err)
+			assert.Equal(t, "past", v, "Past timer should fire at once")
+			_, _, ok = q.Lookup(id)
+			assert.False(t, ok, "Fired timer should not be found")
+			_, err = q.Get()
+			assert.ErrorIs(t, err, ErrQueueIsEmpty)
+		})
+	}
+}
+
+func TestTimerQueue_Reschedule(t *testing.T) {
+	q := NewTimerQueue(nil)
+	defer q.Shutdown()
+
+	start := time.Now()
+	id, err := q.PutAtWithID("moved", start.Add(time.Hour))
+	assert.NoError(t, err)
+
+	later := start.Add(2 * time.Hour)
+	assert.NoError(t, q.Reschedule(id, later))
+	_, at, ok := q.Lookup(id)
+	assert.True(t, ok)
+	assert.Equal(t, later.UnixMilli(), at.UnixMilli())
+
+	// 提前触发时间后调度协程被唤醒，元素按新时间触发。
+	assert.NoError(t, q.Reschedule(id, start.Add(20*time.Millisecond)))
+	v, err := waitQueueGet(t, q, 200*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "moved", v)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	_, _, ok = q.Lookup(id)
+	assert.False(t, ok, "Fired timer should not be found")
+	assert.ErrorIs(t, q.Reschedule(id, later), ErrTimerNotFound)
+	assert.False(t, q.CancelByID(id))
+}
+
+func TestTimerQueue_Reschedule_Past(t *testing.T) {
+	q := NewTimerQueue(NewTimerQueueConfig().WithTimingWheel(time.Millisecond))
+	defer q.Shutdown()
+
+	id, err := q.PutAtWithID("now", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.NoError(t, q.Reschedule(id, time.Now().Add(-time.Second)))
+
+	v, err := waitQueueGet(t, q, 100*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "now", v, "Timer moved into the past should fire immediately")
+}
+
+func TestTimerQueue_CancelRemovesID(t *testing.T) {
+	q := NewTimerQueue(nil)
+
+	id, err := q.PutAtWithID("x", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, q.Cancel("x"))
+	_, _, ok := q.Lookup(id)
+	assert.False(t, ok, "Cancel by value should drop the id")
+
+	id, err = q.PutAtWithID("y", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	q.Shutdown()
+	_, _, ok = q.Lookup(id)
+	assert.False(t, ok, "Shutdown should drop every id")
+	_, err = q.PutAtWithID("z", time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrQueueIsClosed)
+}
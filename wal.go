@@ -0,0 +1,165 @@
+package workqueue
+
+import (
+	"github.com/shengyanli1982/workqueue/v2/internal/wal"
+)
+
+// WALSyncPolicy 定义日志写入后的刷盘策略。
+type WALSyncPolicy uint8
+
+const (
+	// WAL_SYNC_ALWAYS 每次写入后立即 fsync，已返回成功的写入在崩溃后不会丢失。
+	WAL_SYNC_ALWAYS WALSyncPolicy = iota
+
+	// WAL_SYNC_INTERVAL 按固定周期 fsync，崩溃时最多丢失一个周期内的写入。
+	WAL_SYNC_INTERVAL
+
+	// WAL_SYNC_NONE 不主动 fsync，由操作系统决定落盘时机。
+	WAL_SYNC_NONE
+)
+
+// WAL 是持久化队列使用的追加写日志，需通过 QueueConfig.WithWAL 交给队列使用。
+// 一个 WAL 只能供一个队列使用，队列关闭时不会关闭 WAL，由调用方在关闭队列后调用 Close。
+//
+// 元素使用队列配置的 Codec 编码，默认为 gob 编解码器。日志按 key 确认记录，即使队列未开启幂等，
+// 元素也需可哈希或配置 key 函数，否则入队返回 ErrElementNotHashable。
+//
+// 只有最后一个段文件末尾的残缺记录会在打开时被截断，其余段中的损坏会使 OpenWAL 返回 ErrWALCorrupted。
+type WAL struct {
+	log *wal.Log
+}
+
+// OpenWAL 打开或创建 dir 下的日志，并重放已有记录。
+func OpenWAL(dir string, config *WALConfig) (*WAL, error) {
+	config = isWALConfigEffective(config)
+
+	log, err := wal.Open(dir, wal.Options{
+		SegmentSize:     config.segmentSize,
+		Sync:            toSyncMode(config.syncPolicy),
+		SyncInterval:    config.syncInterval,
+		CompactInterval: config.compactInterval,
+		CompactRatio:    config.compactRatio,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WAL{log: log}, nil
+}
+
+func toSyncMode(policy WALSyncPolicy) wal.SyncMode {
+	switch policy {
+	case WAL_SYNC_INTERVAL:
+		return wal.SyncInterval
+	case WAL_SYNC_NONE:
+		return wal.SyncNone
+	default:
+		return wal.SyncAlways
+	}
+}
+
+// Len 返回尚未确认的记录数量。
+func (w *WAL) Len() int {
+	return w.log.Len()
+}
+
+// Sync 立即将缓冲区写入磁盘并 fsync。
+func (w *WAL) Sync() error {
+	return w.log.Sync()
+}
+
+// Compact 立即压缩日志，只保留尚未确认的记录。
+func (w *WAL) Compact() error {
+	return w.log.Compact()
+}
+
+// Close 刷盘并关闭日志。
+func (w *WAL) Close() error {
+	return w.log.Close()
+}
+
+// journal 记录队列元素与日志记录编号的对应关系，live 由队列锁保护。
+type journal struct {
+	wal     *WAL
+	codec   Codec
+	metrics *queueMetrics
+	live    map[interface{}][]uint64
+}
+
+func newJournal(w *WAL, codec Codec, metrics *queueMetrics) *journal {
+	return &journal{wal: w, codec: codec, metrics: metrics, live: make(map[interface{}][]uint64)}
+}
+
+// append 编码并写入一条记录，due 为到期的 Unix 毫秒时间戳，0 表示立即可用。
+func (j *journal) append(value interface{}, due int64) (uint64, error) {
+	payload, err := j.codec.Encode(value)
+	if err != nil {
+		return 0, err
+	}
+	return j.wal.log.Append(due, payload)
+}
+
+// appendBatch 编码并批量写入记录，只刷盘一次。
+func (j *journal) appendBatch(values []interface{}) ([]uint64, error) {
+	dues := make([]int64, len(values))
+	payloads := make([][]byte, len(values))
+	for i, value := range values {
+		payload, err := j.codec.Encode(value)
+		if err != nil {
+			return nil, err
+		}
+		payloads[i] = payload
+	}
+	return j.wal.log.AppendBatch(dues, payloads)
+}
+
+// ack 确认记录已完成。确认写入失败（如日志已关闭或写盘出错）时记录保留，下次打开时重新投递，
+// 失败次数计入 QueueStats.JournalAckFailures。
+func (j *journal) ack(id uint64) {
+	if err := j.wal.log.Ack(id); err != nil {
+		j.metrics.journalAckFailed()
+	}
+}
+
+// commitLocked 登记 key 对应的记录，调用方需持有队列锁。
+func (j *journal) commitLocked(key interface{}, id uint64) {
+	j.live[key] = append(j.live[key], id)
+}
+
+// releaseLocked 取出 key 最早登记的记录，用于元素完成时确认，调用方需持有队列锁。
+func (j *journal) releaseLocked(key interface{}) (uint64, bool) {
+	ids := j.live[key]
+	if len(ids) == 0 {
+		return 0, false
+	}
+	if len(ids) == 1 {
+		delete(j.live, key)
+	} else {
+		j.live[key] = ids[1:]
+	}
+	return ids[0], true
+}
+
+// dropLocked 取出 key 最近登记的记录，用于撤销被合并丢弃的元素，调用方需持有队列锁。
+func (j *journal) dropLocked(key interface{}) (uint64, bool) {
+	ids := j.live[key]
+	if len(ids) == 0 {
+		return 0, false
+	}
+	if len(ids) == 1 {
+		delete(j.live, key)
+	} else {
+		j.live[key] = ids[:len(ids)-1]
+	}
+	return ids[len(ids)-1], true
+}
+
+// decode 解码日志中的记录。
+func (j *journal) decode(payload []byte) (interface{}, error) {
+	return j.codec.Decode(payload)
+}
+
+// resetLocked 清空登记，调用方需持有队列锁。
+func (j *journal) resetLocked() {
+	j.live = make(map[interface{}][]uint64)
+}
@@ -0,0 +1,275 @@
+package workqueue
+
+import (
+	"context"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type walTestItem struct {
+	Key   string
+	Value string
+}
+
+func init() {
+	gob.Register(walTestItem{})
+}
+
+func openTestWAL(t *testing.T, dir string) *WAL {
+	t.Helper()
+
+	w, err := OpenWAL(dir, NewWALConfig().WithSyncPolicy(WAL_SYNC_NONE))
+	assert.NoError(t, err)
+	return w
+}
+
+func TestQueueImpl_WAL_Recovery(t *testing.T) {
+	dir := t.TempDir()
+
+	w := openTestWAL(t, dir)
+	q := NewQueue(NewQueueConfig().WithWAL(w))
+	assert.NoError(t, q.Put("test1"))
+	assert.NoError(t, q.PutBatch([]interface{}{"test2", "test3"}))
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	q.Done(v)
+
+	// test2 已出队但尚未 Done，崩溃后应重新投递。
+	_, err = q.Get()
+	assert.NoError(t, err)
+	q.Shutdown()
+	assert.NoError(t, w.Close())
+
+	w = openTestWAL(t, dir)
+	defer w.Close()
+	q = NewQueue(NewQueueConfig().WithWAL(w))
+	defer q.Shutdown()
+
+	assert.Equal(t, []interface{}{"test2", "test3"}, q.Values())
+	assert.Equal(t, uint64(2), q.Stats().Adds)
+
+	for i := 0; i < 2; i++ {
+		v, err := q.Get()
+		assert.NoError(t, err)
+		q.Done(v)
+	}
+	assert.Equal(t, 0, w.Len(), "Done should acknowledge every record")
+}
+
+func TestQueueImpl_WAL_AckFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	w := openTestWAL(t, dir)
+	q := NewQueue(NewQueueConfig().WithWAL(w))
+	assert.NoError(t, q.Put("test1"))
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+
+	// 日志已关闭，确认无法写入，应计入指标而不是静默丢弃。
+	assert.NoError(t, w.Close())
+	q.Done(v)
+	assert.Equal(t, uint64(1), q.Stats().JournalAckFailures)
+	q.Shutdown()
+
+	w = openTestWAL(t, dir)
+	defer w.Close()
+	q = NewQueue(NewQueueConfig().WithWAL(w))
+	defer q.Shutdown()
+
+	assert.Equal(t, []interface{}{"test1"}, q.Values(), "An unacknowledged record should be redelivered")
+	assert.Equal(t, uint64(0), q.Stats().JournalAckFailures)
+}
+
+func TestQueueImpl_WAL_Idempotent(t *testing.T) {
+	dir := t.TempDir()
+	keyFunc := func(value interface{}) string { return value.(walTestItem).Key }
+
+	w := openTestWAL(t, dir)
+	q := NewQueue(NewQueueConfig().WithValueIdempotent().WithKeyFunc(keyFunc).WithWAL(w))
+
+	assert.NoError(t, q.Put(walTestItem{"key", "v1"}))
+	assert.ErrorIs(t, q.Put(walTestItem{"key", "v1"}), ErrElementAlreadyExist)
+	assert.Equal(t, 1, w.Len(), "Rejected values should be rolled back")
+
+	_, err := q.Get()
+	assert.NoError(t, err)
+	assert.NoError(t, q.Put(walTestItem{"key", "v2"}), "Update of a processing value should be deferred")
+	assert.Equal(t, 2, w.Len())
+	q.Shutdown()
+	assert.NoError(t, w.Close())
+
+	w = openTestWAL(t, dir)
+	defer w.Close()
+	q = NewQueue(NewQueueConfig().WithValueIdempotent().WithKeyFunc(keyFunc).WithWAL(w))
+	defer q.Shutdown()
+
+	assert.Equal(t, []interface{}{walTestItem{"key", "v2"}}, q.Values(), "Only the latest value of a key should be recovered")
+	assert.Equal(t, 1, w.Len())
+}
+
+func TestQueueImpl_WAL_NotHashable(t *testing.T) {
+	w := openTestWAL(t, t.TempDir())
+	defer w.Close()
+	q := NewQueue(NewQueueConfig().WithWAL(w))
+	defer q.Shutdown()
+
+	assert.ErrorIs(t, q.Put([]int{1}), ErrElementNotHashable)
+	assert.Equal(t, 0, w.Len())
+
+	// 非幂等的持久化队列同样按 key 确认记录，批量与延迟入队也在写日志前拒绝不可哈希的元素。
+	err := q.PutBatch([]interface{}{"ok", map[string]int{}})
+	var batchErr *BatchError
+	assert.ErrorAs(t, err, &batchErr)
+	assert.ErrorIs(t, batchErr.Errors[1], ErrElementNotHashable)
+	assert.Equal(t, 1, w.Len())
+
+	dw := openTestWAL(t, t.TempDir())
+	defer dw.Close()
+	config := NewDelayingQueueConfig()
+	config.WithWAL(dw)
+	dq := NewDelayingQueue(config)
+	defer dq.Shutdown()
+
+	assert.ErrorIs(t, dq.PutWithDelay([]int{1}, 1000), ErrElementNotHashable)
+	assert.Equal(t, 0, dw.Len())
+}
+
+func TestOpenWAL_CorruptedSegment(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := OpenWAL(dir, NewWALConfig().WithSegmentSize(64))
+	assert.NoError(t, err)
+	q := NewQueue(NewQueueConfig().WithWAL(w))
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, q.Put(i))
+	}
+	q.Shutdown()
+	assert.NoError(t, w.Close())
+
+	// 破坏第一个段中的记录，其后还有完整的段。
+	paths, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	assert.NoError(t, err)
+	assert.Greater(t, len(paths), 1)
+	data, err := os.ReadFile(paths[0])
+	assert.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	assert.NoError(t, os.WriteFile(paths[0], data, 0o644))
+
+	_, err = OpenWAL(dir, nil)
+	assert.ErrorIs(t, err, ErrWALCorrupted)
+}
+
+func TestDelayingQueue_WAL_Recovery(t *testing.T) {
+	dir := t.TempDir()
+
+	w := openTestWAL(t, dir)
+	config := NewDelayingQueueConfig()
+	config.WithWAL(w)
+	q := NewDelayingQueue(config)
+	assert.NoError(t, q.Put("ready"))
+	assert.NoError(t, q.PutWithDelay("later", 60000))
+	assert.NoError(t, q.PutWithDelay("soon", 100))
+
+	var due int64
+	q.HeapRange(func(value interface{}, delay int64) bool {
+		if value == "later" {
+			due = delay
+		}
+		return true
+	})
+	q.Shutdown()
+	assert.NoError(t, w.Close())
+
+	w = openTestWAL(t, dir)
+	defer w.Close()
+	config = NewDelayingQueueConfig()
+	config.WithWAL(w)
+	q = NewDelayingQueue(config)
+	defer q.Shutdown()
+
+	assert.Equal(t, 3, q.Len())
+	scheduled := map[interface{}]int64{}
+	q.HeapRange(func(value interface{}, delay int64) bool {
+		scheduled[value] = delay
+		return true
+	})
+	assert.Equal(t, due, scheduled["later"], "Due time should survive recovery")
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "ready", v)
+	q.Done(v)
+
+	// 到期元素由搬运协程转入就绪队列，不会重复写入日志。
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	v, err = q.GetWithContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "soon", v)
+	assert.Equal(t, 2, w.Len())
+	q.Done(v)
+	assert.Equal(t, 1, w.Len())
+}
+
+func TestDeadLetterQueue_WAL_Recovery(t *testing.T) {
+	dir := t.TempDir()
+
+	w := openTestWAL(t, dir)
+	q := NewDeadLetterQueue(&DeadLetterQueueConfig{QueueConfig: *NewQueueConfig().WithWAL(w)})
+	assert.NoError(t, q.PutDead(&DeadLetter{Payload: "bad", SourceQueue: "orders", Attempts: 3}))
+	q.Shutdown()
+	assert.NoError(t, w.Close())
+
+	w = openTestWAL(t, dir)
+	defer w.Close()
+	q = NewDeadLetterQueue(&DeadLetterQueueConfig{QueueConfig: *NewQueueConfig().WithWAL(w)})
+	defer q.Shutdown()
+
+	letter, err := q.GetDead()
+	assert.NoError(t, err)
+	assert.Equal(t, "bad", letter.Payload)
+	assert.Equal(t, "orders", letter.SourceQueue)
+	assert.Equal(t, 3, letter.Attempts)
+
+	next := &DeadLetter{Payload: "worse"}
+	assert.NoError(t, q.PutDead(next))
+	assert.NotEqual(t, letter.ID, next.ID, "Generated ids should not collide with recovered letters")
+
+	assert.NoError(t, q.AckDead(letter))
+	assert.Equal(t, 1, w.Len())
+}
+
+func TestBoundedBlockingQueue_WAL_Recovery(t *testing.T) {
+	dir := t.TempDir()
+
+	w := openTestWAL(t, dir)
+	config := NewBoundedBlockingQueueConfig().WithCapacity(2)
+	config.WithWAL(w)
+	q := NewBoundedBlockingQueue(config)
+	assert.NoError(t, q.Put("test1"))
+	q.Shutdown()
+	assert.NoError(t, w.Close())
+
+	w = openTestWAL(t, dir)
+	defer w.Close()
+	config = NewBoundedBlockingQueueConfig().WithCapacity(2)
+	config.WithWAL(w)
+	q = NewBoundedBlockingQueue(config)
+	defer q.Shutdown()
+
+	assert.NoError(t, q.Put("test2"))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.PutWithContext(ctx, "test3"), context.DeadlineExceeded, "Recovered values should occupy capacity")
+
+	v, err := q.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", v)
+}